@@ -36,7 +36,10 @@ type BackupClient interface {
 	FetchSnapshot(ctx context.Context, name string) (io.ReadCloser, error)
 }
 
-var _ BackupClient = (*FileBackupClient)(nil)
+var (
+	_ BackupClient  = (*FileBackupClient)(nil)
+	_ RestoreSource = (*FileBackupClient)(nil)
+)
 
 // FileBackupClient is a reference implemenation for BackupClient.
 // This implementation is typically only used for testing.
@@ -260,3 +263,13 @@ func (c *FileBackupClient) FetchSnapshot(ctx context.Context, name string) (_ io
 	}()
 	return pr, nil
 }
+
+// LTXFiles returns the TXID ranges of all LTX files stored for a database.
+func (c *FileBackupClient) LTXFiles(ctx context.Context, name string) ([]LTXFileInfo, error) {
+	return readLTXDir(filepath.Join(c.path, name))
+}
+
+// OpenLTXFile returns a reader for a single LTX file.
+func (c *FileBackupClient) OpenLTXFile(ctx context.Context, name string, minTXID, maxTXID ltx.TXID) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(c.path, name, ltx.FormatFilename(minTXID, maxTXID)))
+}
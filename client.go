@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/superfly/ltx"
 )
@@ -22,8 +24,18 @@ type Client interface {
 	Commit(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64, r io.Reader) error
 
 	// Stream starts a long-running connection to stream changes from another node.
-	// If filter is specified, only those databases will be replicated.
-	Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (Stream, error)
+	// If filter is specified, only those databases will be replicated. Partial
+	// snapshots are resumed by the primary, if possible.
+	Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []PartialSnapshot) (Stream, error)
+
+	// Ack notifies the primary that the replica has applied a database up to txID.
+	// Used by synchronous replication to release commits waiting on the primary.
+	Ack(ctx context.Context, primaryURL string, nodeID uint64, name string, txID ltx.TXID) error
+
+	// FetchPage returns the current contents of a single database page from
+	// the primary & the TXID it was read at. The primary must be at or after
+	// txID. Used by replicas that bootstrapped from a lazy snapshot.
+	FetchPage(ctx context.Context, primaryURL string, nodeID uint64, name string, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error)
 }
 
 // Stream represents a stream of frames.
@@ -32,18 +44,24 @@ type Stream interface {
 
 	// ClusterID of the primary node.
 	ClusterID() string
+
+	// Epoch of the primary node's lease. Zero if the primary did not send one.
+	Epoch() uint64
 }
 
 type StreamFrameType uint32
 
 const (
-	StreamFrameTypeLTX       = StreamFrameType(1)
-	StreamFrameTypeReady     = StreamFrameType(2)
-	StreamFrameTypeEnd       = StreamFrameType(3)
-	StreamFrameTypeDropDB    = StreamFrameType(4)
-	StreamFrameTypeHandoff   = StreamFrameType(5)
-	StreamFrameTypeHWM       = StreamFrameType(6)
-	StreamFrameTypeHeartbeat = StreamFrameType(7)
+	StreamFrameTypeLTX          = StreamFrameType(1)
+	StreamFrameTypeReady        = StreamFrameType(2)
+	StreamFrameTypeEnd          = StreamFrameType(3)
+	StreamFrameTypeDropDB       = StreamFrameType(4)
+	StreamFrameTypeHandoff      = StreamFrameType(5)
+	StreamFrameTypeHWM          = StreamFrameType(6)
+	StreamFrameTypeHeartbeat    = StreamFrameType(7)
+	StreamFrameTypeRenameDB     = StreamFrameType(8)
+	StreamFrameTypeLTXResume    = StreamFrameType(9)
+	StreamFrameTypeLazySnapshot = StreamFrameType(10)
 )
 
 type StreamFrame interface {
@@ -75,6 +93,12 @@ func ReadStreamFrame(r io.Reader) (StreamFrame, error) {
 		f = &HWMStreamFrame{}
 	case StreamFrameTypeHeartbeat:
 		f = &HeartbeatStreamFrame{}
+	case StreamFrameTypeRenameDB:
+		f = &RenameDBStreamFrame{}
+	case StreamFrameTypeLTXResume:
+		f = &LTXResumeStreamFrame{}
+	case StreamFrameTypeLazySnapshot:
+		f = &LazySnapshotStreamFrame{}
 	default:
 		return nil, fmt.Errorf("invalid stream frame type: 0x%02x", typ)
 	}
@@ -156,8 +180,8 @@ func (f *EndStreamFrame) Type() StreamFrameType               { return StreamFra
 func (f *EndStreamFrame) ReadFrom(r io.Reader) (int64, error) { return 0, nil }
 func (f *EndStreamFrame) WriteTo(w io.Writer) (int64, error)  { return 0, nil }
 
-// DropDBStreamFrame notifies replicas that a database has been deleted.
-// DEPRECATED: LTX files with a zero "commit" field now represent deletions.
+// DropDBStreamFrame notifies replicas that a database has been removed from
+// the primary. Replicas delete their local copy, including its LTX files.
 type DropDBStreamFrame struct {
 	Name string // database name
 }
@@ -302,3 +326,172 @@ func (f *HeartbeatStreamFrame) WriteTo(w io.Writer) (int64, error) {
 
 	return 0, nil
 }
+
+// RenameDBStreamFrame notifies replicas that a database has been renamed on
+// the primary. Replicas move their local copy, including its LTX files, so
+// replication continues from the same position under the new name.
+type RenameDBStreamFrame struct {
+	OldName string
+	NewName string
+}
+
+// Type returns the type of stream frame.
+func (*RenameDBStreamFrame) Type() StreamFrameType { return StreamFrameTypeRenameDB }
+
+func (f *RenameDBStreamFrame) ReadFrom(r io.Reader) (int64, error) {
+	for _, v := range []*string{&f.OldName, &f.NewName} {
+		var nameN uint32
+		if err := binary.Read(r, binary.BigEndian, &nameN); err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return 0, err
+		}
+
+		name := make([]byte, nameN)
+		if _, err := io.ReadFull(r, name); err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return 0, err
+		}
+		*v = string(name)
+	}
+
+	return 0, nil
+}
+
+func (f *RenameDBStreamFrame) WriteTo(w io.Writer) (int64, error) {
+	for _, name := range []string{f.OldName, f.NewName} {
+		if err := binary.Write(w, binary.BigEndian, uint32(len(name))); err != nil {
+			return 0, err
+		} else if _, err := w.Write([]byte(name)); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// LTXResumeStreamFrame continues a snapshot that the replica partially
+// received on a previous stream. It is followed by the chunked bytes of the
+// snapshot starting at Offset.
+type LTXResumeStreamFrame struct {
+	Offset int64  // bytes already received by the replica
+	Name   string // database name
+}
+
+// Type returns the type of stream frame.
+func (*LTXResumeStreamFrame) Type() StreamFrameType { return StreamFrameTypeLTXResume }
+
+func (f *LTXResumeStreamFrame) ReadFrom(r io.Reader) (int64, error) {
+	var offset uint64
+	if err := binary.Read(r, binary.BigEndian, &offset); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	f.Offset = int64(offset)
+
+	var nameN uint32
+	if err := binary.Read(r, binary.BigEndian, &nameN); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+
+	name := make([]byte, nameN)
+	if _, err := io.ReadFull(r, name); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	f.Name = string(name)
+
+	return 0, nil
+}
+
+func (f *LTXResumeStreamFrame) WriteTo(w io.Writer) (int64, error) {
+	if err := binary.Write(w, binary.BigEndian, uint64(f.Offset)); err != nil {
+		return 0, err
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint32(len(f.Name))); err != nil {
+		return 0, err
+	} else if _, err := w.Write([]byte(f.Name)); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// LazySnapshotStreamFrame bootstraps a replica without sending every page.
+// It is followed by the chunked bytes of the database position, the checksum
+// of every page & the contents of the first page. The remaining pages are
+// fetched from the primary on demand. See DB.WriteLazySnapshotTo.
+type LazySnapshotStreamFrame struct {
+	Name string // database name
+}
+
+// Type returns the type of stream frame.
+func (*LazySnapshotStreamFrame) Type() StreamFrameType { return StreamFrameTypeLazySnapshot }
+
+func (f *LazySnapshotStreamFrame) ReadFrom(r io.Reader) (int64, error) {
+	var nameN uint32
+	if err := binary.Read(r, binary.BigEndian, &nameN); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+
+	name := make([]byte, nameN)
+	if _, err := io.ReadFull(r, name); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	f.Name = string(name)
+
+	return 0, nil
+}
+
+func (f *LazySnapshotStreamFrame) WriteTo(w io.Writer) (int64, error) {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(f.Name))); err != nil {
+		return 0, err
+	} else if _, err := w.Write([]byte(f.Name)); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// PartialSnapshot describes a snapshot that a replica received part of before
+// its stream was disconnected. The header fields identify the snapshot so the
+// primary can regenerate the same bytes & send only the remainder.
+type PartialSnapshot struct {
+	Name      string   // database name
+	TXID      ltx.TXID // snapshot max TXID
+	NodeID    uint64   // node that generated the snapshot
+	Timestamp int64    // header timestamp, in ms
+	Size      int64    // bytes received
+}
+
+// String returns the string representation used in the stream request.
+func (p PartialSnapshot) String() string {
+	return fmt.Sprintf("%s/%s/%s/%d/%d", p.Name, p.TXID.String(), FormatNodeID(p.NodeID), p.Timestamp, p.Size)
+}
+
+// ParsePartialSnapshot parses the string representation of a partial snapshot.
+func ParsePartialSnapshot(s string) (p PartialSnapshot, err error) {
+	a := strings.Split(s, "/")
+	if len(a) != 5 || a[0] == "" {
+		return p, fmt.Errorf("invalid partial snapshot format: %q", s)
+	}
+	p.Name = a[0]
+
+	if p.TXID, err = ltx.ParseTXID(a[1]); err != nil {
+		return p, fmt.Errorf("invalid partial snapshot txid: %w", err)
+	} else if p.NodeID, err = ParseNodeID(a[2]); err != nil {
+		return p, fmt.Errorf("invalid partial snapshot node id: %w", err)
+	} else if p.Timestamp, err = strconv.ParseInt(a[3], 10, 64); err != nil {
+		return p, fmt.Errorf("invalid partial snapshot timestamp: %w", err)
+	} else if p.Size, err = strconv.ParseInt(a[4], 10, 64); err != nil || p.Size < 0 {
+		return p, fmt.Errorf("invalid partial snapshot size: %q", a[4])
+	}
+	return p, nil
+}
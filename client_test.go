@@ -36,6 +36,46 @@ func TestReadWriteStreamFrame(t *testing.T) {
 			t.Fatalf("got %#v, want %#v", frame, other)
 		}
 	})
+	t.Run("RenameDBStreamFrame", func(t *testing.T) {
+		frame := &litefs.RenameDBStreamFrame{OldName: "old.db", NewName: "new.db"}
+
+		var buf bytes.Buffer
+		if err := litefs.WriteStreamFrame(&buf, frame); err != nil {
+			t.Fatal(err)
+		}
+		if other, err := litefs.ReadStreamFrame(&buf); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(frame, other) {
+			t.Fatalf("got %#v, want %#v", frame, other)
+		}
+	})
+	t.Run("LTXResumeStreamFrame", func(t *testing.T) {
+		frame := &litefs.LTXResumeStreamFrame{Offset: 1000, Name: "test.db"}
+
+		var buf bytes.Buffer
+		if err := litefs.WriteStreamFrame(&buf, frame); err != nil {
+			t.Fatal(err)
+		}
+		if other, err := litefs.ReadStreamFrame(&buf); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(frame, other) {
+			t.Fatalf("got %#v, want %#v", frame, other)
+		}
+	})
+
+	t.Run("LazySnapshotStreamFrame", func(t *testing.T) {
+		frame := &litefs.LazySnapshotStreamFrame{Name: "test.db"}
+
+		var buf bytes.Buffer
+		if err := litefs.WriteStreamFrame(&buf, frame); err != nil {
+			t.Fatal(err)
+		}
+		if other, err := litefs.ReadStreamFrame(&buf); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(frame, other) {
+			t.Fatalf("got %#v, want %#v", frame, other)
+		}
+	})
 
 	t.Run("ErrEOF", func(t *testing.T) {
 		if _, err := litefs.ReadStreamFrame(bytes.NewReader(nil)); err == nil || err != io.EOF {
@@ -159,3 +199,30 @@ func TestHWMStreamFrame_WriteTo(t *testing.T) {
 		}
 	})
 }
+
+func TestParsePartialSnapshot(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		partial := litefs.PartialSnapshot{Name: "test.db", TXID: 100, NodeID: 0xABCD, Timestamp: 1700000000000, Size: 4096}
+		if other, err := litefs.ParsePartialSnapshot(partial.String()); err != nil {
+			t.Fatal(err)
+		} else if got, want := other, partial; got != want {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		for _, s := range []string{
+			"",
+			"test.db",
+			"/0000000000000064/000000000000ABCD/0/0",
+			"test.db/xyz/000000000000ABCD/0/0",
+			"test.db/0000000000000064/xyz/0/0",
+			"test.db/0000000000000064/000000000000ABCD/xyz/0",
+			"test.db/0000000000000064/000000000000ABCD/0/-1",
+		} {
+			if _, err := litefs.ParsePartialSnapshot(s); err == nil {
+				t.Fatalf("expected error for %q", s)
+			}
+		}
+	})
+}
@@ -51,14 +51,26 @@ func NewConfig() Config {
 	config.Data.Compress = true
 	config.Data.Retention = litefs.DefaultRetention
 	config.Data.RetentionMonitorInterval = litefs.DefaultRetentionMonitorInterval
+	config.Data.StartupIntegrityCheck = litefs.CheckNone.String()
 
 	config.FUSE.Dir = DefaultFUSEDir
+	config.FUSE.CacheInvalidation = litefs.InvalidatePerPage.String()
 
 	config.HTTP.Addr = http.DefaultAddr
+	config.HTTP.Readiness.LeaseTimeout = http.DefaultReadinessLeaseTimeout
 
 	config.Lease.Candidate = true
 	config.Lease.ReconnectDelay = litefs.DefaultReconnectDelay
 	config.Lease.DemoteDelay = litefs.DefaultDemoteDelay
+	config.Lease.PriorityDelay = litefs.DefaultCandidatePriorityDelay
+	config.Lease.HeartbeatInterval = litefs.DefaultReplicationHeartbeatInterval
+	config.Lease.SyncReplication.Timeout = litefs.DefaultSyncReplicaTimeout
+	config.Lease.ResyncMode = litefs.ResyncModeFail.String()
+	config.Lease.Backpressure.Replicas = litefs.DefaultBackpressureReplicaN
+	config.Lease.Backpressure.Timeout = litefs.DefaultBackpressureTimeout
+	config.Lease.Halt.AcquireTimeout = litefs.DefaultHaltAcquireTimeout
+	config.Lease.Halt.TTL = litefs.DefaultHaltLockTTL
+	config.Lease.DNS.RefreshInterval = litefs.DefaultDNSLeaserRefreshInterval
 
 	config.Backup.Delay = litefs.DefaultBackupDelay
 	config.Backup.FullSyncInterval = litefs.DefaultBackupFullSyncInterval
@@ -91,10 +103,27 @@ func (a *ExecConfigSlice) UnmarshalYAML(value *yaml.Node) error {
 	}
 }
 
+// Exec roles.
+const (
+	ExecRolePrimary = "primary"
+	ExecRoleReplica = "replica"
+)
+
 // ExecConfig represents a single exec command.
 type ExecConfig struct {
 	Cmd         string `yaml:"cmd"`
 	IfCandidate bool   `yaml:"if-candidate"`
+
+	// Restricts the command to nodes with the given role: "primary" or
+	// "replica". The command runs on all nodes if blank.
+	Role string `yaml:"role"`
+
+	// Behavior of the long-running command when the node's role changes.
+	// If restart is set, the subprocess is stopped & the commands for the
+	// new role are executed. If a signal is set, such as "SIGHUP", it is
+	// sent to the subprocess instead.
+	RestartOnPrimaryChange bool   `yaml:"restart-on-primary-change"`
+	PrimaryChangeSignal    string `yaml:"primary-change-signal"`
 }
 
 // DataConfig represents the configuration for internal LiteFS data. This
@@ -105,6 +134,28 @@ type DataConfig struct {
 
 	Retention                time.Duration `yaml:"retention"`
 	RetentionMonitorInterval time.Duration `yaml:"retention-monitor-interval"`
+	RetentionMaxCount        int           `yaml:"retention-max-count"`
+	RetentionMaxSize         int64         `yaml:"retention-max-size"` // MB
+
+	// Verification of existing databases on startup: "none", "header",
+	// "page-count", or "full".
+	StartupIntegrityCheck string `yaml:"startup-integrity-check"`
+
+	// If true, databases are opened on first access instead of on startup.
+	LazyOpen bool `yaml:"lazy-open"`
+
+	// Periodic checksum verification on replicas. Disabled if zero.
+	ChecksumVerifyInterval time.Duration `yaml:"checksum-verify-interval"`
+	ChecksumVerifyResync   bool          `yaml:"checksum-verify-resync"`
+
+	// If true, data is stored in a new temporary directory within Dir that
+	// is removed on shutdown. Only allowed on non-candidate nodes.
+	Ephemeral bool `yaml:"ephemeral"`
+
+	// If non-zero, new databases of at least this size are bootstrapped from
+	// a lazy snapshot & pages are fetched from the primary on first read.
+	// Only allowed on non-candidate nodes.
+	LazySnapshotMinSize int64 `yaml:"lazy-snapshot-min-size"` // MB
 }
 
 // FUSEConfig represents the configuration for the FUSE file system.
@@ -112,12 +163,39 @@ type FUSEConfig struct {
 	Dir        string `yaml:"dir"`
 	AllowOther bool   `yaml:"allow-other"`
 	Debug      bool   `yaml:"debug"`
+
+	// Kernel page cache invalidation strategy: "per-page", "all", or "none".
+	CacheInvalidation string `yaml:"cache-invalidation"`
+
+	// File extensions treated as databases. If empty, all files are databases.
+	DBExtensions []string `yaml:"db-extensions"`
+
+	// If true, write transactions on replicas are forwarded to the primary.
+	WriteForwarding bool `yaml:"write-forwarding"`
+
+	// Per-transaction limits. Unlimited if zero.
+	MaxTxPages int   `yaml:"max-tx-pages"`
+	MaxTxSize  int64 `yaml:"max-tx-size"` // MB
 }
 
 // HTTPConfig represents the configuration for the HTTP server.
 type HTTPConfig struct {
 	Addr            string        `yaml:"addr"`
 	SnapshotTimeout time.Duration `yaml:"snapshot-timeout"`
+	StreamRateLimit int64         `yaml:"stream-rate-limit"` // KB/s
+	StreamEncoding  string        `yaml:"stream-encoding"`
+	AuthToken       string        `yaml:"auth-token"`
+	TLSCertFile     string        `yaml:"tls-cert-file"`
+	TLSKeyFile      string        `yaml:"tls-key-file"`
+	TLSCAFile       string        `yaml:"tls-ca-file"`
+
+	// Conditions required for GET /readyz to report the node as ready.
+	Readiness struct {
+		MaxLag           time.Duration `yaml:"max-lag"`
+		RequireConnected bool          `yaml:"require-connected"`
+		RequireLease     bool          `yaml:"require-lease"`
+		LeaseTimeout     time.Duration `yaml:"lease-timeout"`
+	} `yaml:"readiness"`
 }
 
 // ProxyConfig represents the configuration for the HTTP proxy server.
@@ -134,7 +212,8 @@ type ProxyConfig struct {
 
 // LeaseConfig represents a generic configuration for all lease types.
 type LeaseConfig struct {
-	// Specifies the type of leasing to use: "consul" or "static"
+	// Specifies the type of leasing to use: "consul", "etcd", "kubernetes",
+	// "static", "dns", or a type registered with litefs.RegisterLeaser.
 	Type string `yaml:"type"`
 
 	// The hostname of this node. Used by the application to forward requests.
@@ -153,6 +232,12 @@ type LeaseConfig struct {
 	// automatically once it connects to the cluster and syncs.
 	Promote bool `yaml:"promote"`
 
+	// Election priority of a candidate. Lower values are preferred. When
+	// there is no primary, a candidate waits "priority * priority-delay"
+	// before attempting to acquire the lease. Defaults to zero.
+	Priority      int           `yaml:"priority"`
+	PriorityDelay time.Duration `yaml:"priority-delay"`
+
 	// After disconnect, time before node tries to reconnect to primary or
 	// becomes primary itself.
 	ReconnectDelay time.Duration `yaml:"reconnect-delay"`
@@ -161,8 +246,42 @@ type LeaseConfig struct {
 	// become primary again.
 	DemoteDelay time.Duration `yaml:"demote-delay"`
 
-	// Specifies a subset of databases to replica.
-	Databases []string `yaml:"databases"`
+	// Interval between heartbeats on an idle replication stream. Replicas
+	// reconnect if no data is received within twice this interval.
+	HeartbeatInterval time.Duration `yaml:"heartbeat-interval"`
+
+	// Specifies a subset of databases to replica. Entries are glob patterns.
+	// Databases matching an exclude pattern are not replicated.
+	Databases        []string `yaml:"databases"`
+	ExcludeDatabases []string `yaml:"exclude-databases"`
+
+	// Number of consecutive apply errors before replication to a database
+	// is suspended. Disabled if zero.
+	DBErrorThreshold int `yaml:"db-error-threshold"`
+
+	// Handling of databases that diverge from the primary: "fail", "auto",
+	// or "prompt".
+	ResyncMode string `yaml:"resync-mode"`
+
+	// Synchronous replication settings. Must be the same on all nodes.
+	SyncReplication struct {
+		Replicas  int           `yaml:"replicas"`
+		Timeout   time.Duration `yaml:"timeout"`
+		Databases []string      `yaml:"databases"`
+	} `yaml:"sync-replication"`
+
+	// Delays commits on the primary while replicas are too far behind.
+	Backpressure struct {
+		MaxLag   int           `yaml:"max-lag"` // TXIDs
+		Replicas int           `yaml:"replicas"`
+		Timeout  time.Duration `yaml:"timeout"`
+	} `yaml:"backpressure"`
+
+	// HALT lock settings used when writing to the primary from a replica.
+	Halt struct {
+		AcquireTimeout time.Duration `yaml:"acquire-timeout"`
+		TTL            time.Duration `yaml:"ttl"`
+	} `yaml:"halt"`
 
 	// Consul lease settings.
 	Consul struct {
@@ -170,16 +289,58 @@ type LeaseConfig struct {
 		Key       string        `yaml:"key"`
 		TTL       time.Duration `yaml:"ttl"`
 		LockDelay time.Duration `yaml:"lock-delay"`
+
+		AcquireDelay       time.Duration `yaml:"acquire-delay"`
+		AcquireDelayJitter time.Duration `yaml:"acquire-delay-jitter"`
+
+		CircuitBreakerThreshold        uint32        `yaml:"circuit-breaker-threshold"`
+		CircuitBreakerHalfOpenInterval time.Duration `yaml:"circuit-breaker-half-open-interval"`
 	} `yaml:"consul"`
+
+	// etcd lease settings.
+	Etcd struct {
+		URL string        `yaml:"url"`
+		Key string        `yaml:"key"`
+		TTL time.Duration `yaml:"ttl"`
+
+		AcquireDelay       time.Duration `yaml:"acquire-delay"`
+		AcquireDelayJitter time.Duration `yaml:"acquire-delay-jitter"`
+	} `yaml:"etcd"`
+
+	// Kubernetes lease settings.
+	Kubernetes struct {
+		Name      string        `yaml:"name"`
+		Namespace string        `yaml:"namespace"` // defaults to service account namespace
+		URL       string        `yaml:"url"`       // defaults to in-cluster API server
+		TTL       time.Duration `yaml:"ttl"`
+	} `yaml:"kubernetes"`
+
+	// DNS lease settings.
+	DNS struct {
+		Name            string        `yaml:"name"`
+		Port            int           `yaml:"port"` // if zero, use SRV lookup
+		RefreshInterval time.Duration `yaml:"refresh-interval"`
+	} `yaml:"dns"`
+
+	// Settings for a lease type registered with litefs.RegisterLeaser.
+	// These are passed through to the leaser factory as-is.
+	Options yaml.Node `yaml:"options"`
 }
 
 // BackupConfig represents a config for backup services.
 type BackupConfig struct {
 	Type             string        `yaml:"type"`
-	Path             string        `yaml:"path"`       // "file" only
-	URL              string        `yaml:"url"`        // "litefs-cloud" only
-	Cluster          string        `yaml:"cluster"`    // "litefs-cloud" only
-	AuthToken        string        `yaml:"auth-token"` // "litefs-cloud" only
+	Path             string        `yaml:"path"`              // "file" only; key prefix for "s3"
+	URL              string        `yaml:"url"`               // "litefs-cloud" only
+	Cluster          string        `yaml:"cluster"`           // "litefs-cloud" only
+	AuthToken        string        `yaml:"auth-token"`        // "litefs-cloud" only
+	Bucket           string        `yaml:"bucket"`            // "s3" only
+	Endpoint         string        `yaml:"endpoint"`          // "s3" only
+	Region           string        `yaml:"region"`            // "s3" only
+	AccessKeyID      string        `yaml:"access-key-id"`     // "s3" only
+	SecretAccessKey  string        `yaml:"secret-access-key"` // "s3" only
+	SnapshotInterval time.Duration `yaml:"snapshot-interval"` // "s3" only
+	Retention        time.Duration `yaml:"retention"`         // "s3" only
 	Delay            time.Duration `yaml:"-"`
 	FullSyncInterval time.Duration `yaml:"-"`
 }
@@ -189,6 +350,10 @@ type LogConfig struct {
 	Format    string `yaml:"format"`    // "text", "json"
 	Timestamp bool   `yaml:"timestamp"` // include timestamp in log output
 	Debug     bool   `yaml:"debug"`     // include debug logging
+
+	// Log level overrides by subsystem: "fuse", "store", "http", "lease".
+	// Subsystems without an override use the global log level.
+	Levels map[string]string `yaml:"levels"`
 }
 
 // Tracing configuration defaults.
@@ -76,4 +76,51 @@ func TestConfig(t *testing.T) {
 			t.Fatalf("Cmd=%q, want %q", got, want)
 		}
 	})
+
+	t.Run("RoleExec", func(t *testing.T) {
+		buf, err := testdata.ReadFile("testdata/config/role_exec.yml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var config main.Config
+		dec := yaml.NewDecoder(bytes.NewReader(buf))
+		dec.KnownFields(true)
+		if err := dec.Decode(&config); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := len(config.Exec), 3; got != want {
+			t.Fatalf("len=%v, want %v", got, want)
+		}
+		if got, want := config.Exec[0].Role, main.ExecRolePrimary; got != want {
+			t.Fatalf("Role=%q, want %q", got, want)
+		}
+		if got, want := config.Exec[1].RestartOnPrimaryChange, true; got != want {
+			t.Fatalf("RestartOnPrimaryChange=%v, want %v", got, want)
+		}
+		if got, want := config.Exec[2].Role, main.ExecRoleReplica; got != want {
+			t.Fatalf("Role=%q, want %q", got, want)
+		} else if got, want := config.Exec[2].PrimaryChangeSignal, "SIGHUP"; got != want {
+			t.Fatalf("PrimaryChangeSignal=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("LeaseOptions", func(t *testing.T) {
+		var config main.Config
+		dec := yaml.NewDecoder(strings.NewReader("lease:\n  type: \"zookeeper\"\n  options:\n    endpoints: [\"zk1:2181\", \"zk2:2181\"]\n"))
+		dec.KnownFields(true)
+		if err := dec.Decode(&config); err != nil {
+			t.Fatal(err)
+		}
+
+		var opt struct {
+			Endpoints []string `yaml:"endpoints"`
+		}
+		if err := config.Lease.Options.Decode(&opt); err != nil {
+			t.Fatal(err)
+		} else if got, want := strings.Join(opt.Endpoints, ","), "zk1:2181,zk2:2181"; got != want {
+			t.Fatalf("Endpoints=%q, want %q", got, want)
+		}
+	})
 }
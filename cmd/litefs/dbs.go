@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/superfly/litefs/http"
+)
+
+// DBsCommand represents a command to list the databases on a node.
+type DBsCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewDBsCommand returns a new instance of DBsCommand.
+func NewDBsCommand() *DBsCommand {
+	return &DBsCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *DBsCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-dbs", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The dbs command lists the databases on a LiteFS node along with the current
+transaction ID & checksum of each database.
+
+Usage:
+
+	litefs dbs [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *DBsCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	posMap, err := client.Pos(ctx, c.URL)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(posMap))
+	for name := range posMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTXID\tCHECKSUM")
+	for _, name := range names {
+		pos := posMap[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, pos.TXID, pos.PostApplyChecksum)
+	}
+	return w.Flush()
+}
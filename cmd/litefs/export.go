@@ -18,6 +18,9 @@ type ExportCommand struct {
 	// Target LiteFS URL
 	URL string
 
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+
 	// Name of database on LiteFS cluster.
 	Name string
 
@@ -36,6 +39,7 @@ func NewExportCommand() *ExportCommand {
 func (c *ExportCommand) ParseFlags(ctx context.Context, args []string) (err error) {
 	fs := flag.NewFlagSet("litefs-export", flag.ContinueOnError)
 	fs.StringVar(&c.URL, "url", "http://localhost:20202", "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
 	fs.StringVar(&c.Name, "name", "", "database name")
 	fs.Usage = func() {
 		fmt.Println(`
@@ -88,6 +92,7 @@ func (c *ExportCommand) Run(ctx context.Context) (err error) {
 
 	// Fetch snapshot from the server.
 	client := http.NewClient()
+	client.AuthToken = c.AuthToken
 	r, err := client.Export(ctx, c.URL, c.Name)
 	if err != nil {
 		return err
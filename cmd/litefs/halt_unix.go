@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+
+	litefsgo "github.com/superfly/litefs-go"
+)
+
+// halt acquires the HALT lock on the database lock file.
+func halt(f *os.File) error { return litefsgo.Halt(f) }
+
+// unhalt releases the HALT lock on the database lock file.
+func unhalt(f *os.File) error { return litefsgo.Unhalt(f) }
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// halt returns an error as the HALT lock requires open file description
+// locks which are not available on Windows.
+func halt(f *os.File) error {
+	return fmt.Errorf("halt lock is not available on Windows")
+}
+
+// unhalt returns an error as the HALT lock is not available on Windows.
+func unhalt(f *os.File) error {
+	return fmt.Errorf("halt lock is not available on Windows")
+}
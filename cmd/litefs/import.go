@@ -15,6 +15,9 @@ type ImportCommand struct {
 	// Target LiteFS URL
 	URL string
 
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+
 	// Name of database on LiteFS cluster.
 	Name string
 
@@ -33,6 +36,7 @@ func NewImportCommand() *ImportCommand {
 func (c *ImportCommand) ParseFlags(ctx context.Context, args []string) (err error) {
 	fs := flag.NewFlagSet("litefs-import", flag.ContinueOnError)
 	fs.StringVar(&c.URL, "url", "http://localhost:20202", "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
 	fs.StringVar(&c.Name, "name", "", "database name")
 	fs.Usage = func() {
 		fmt.Println(`
@@ -78,6 +82,7 @@ func (c *ImportCommand) Run(ctx context.Context) (err error) {
 	t := time.Now()
 
 	client := http.NewClient()
+	client.AuthToken = c.AuthToken
 	if err := client.Import(ctx, c.URL, c.Name, f); err != nil {
 		return err
 	}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 )
 
@@ -41,6 +42,13 @@ func run(ctx context.Context, args []string) error {
 	}
 
 	switch cmd {
+	case "dbs":
+		c := NewDBsCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
 	case "export":
 		c := NewExportCommand()
 		if err := c.ParseFlags(ctx, args); err != nil {
@@ -58,6 +66,48 @@ func run(ctx context.Context, args []string) error {
 	case "mount":
 		return runMount(ctx, args)
 
+	case "nodes":
+		c := NewNodesCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
+	case "pause":
+		c := NewPauseCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
+	case "reload":
+		c := NewReloadCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
+	case "restore":
+		c := NewRestoreCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
+	case "resume":
+		c := NewResumeCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
+	case "resync":
+		c := NewResyncCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
 	case "run":
 		c := NewRunCommand()
 		if err := c.ParseFlags(ctx, args); err != nil {
@@ -65,6 +115,13 @@ func run(ctx context.Context, args []string) error {
 		}
 		return c.Run(ctx)
 
+	case "status":
+		c := NewStatusCommand()
+		if err := c.ParseFlags(ctx, args); err != nil {
+			return err
+		}
+		return c.Run(ctx)
+
 	case "version":
 		fmt.Println(VersionString())
 		return nil
@@ -106,32 +163,54 @@ func runMount(ctx context.Context, args []string) error {
 		os.Exit(2)
 	}
 
-	if err := c.Run(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
-
-		// Only exit the process if enabled in the config. A user want to
-		// continue running so that an ephemeral node can be debugged intsead
-		// of continually restarting on error.
-		if c.Config.ExitOnError {
-			_ = c.Close()
-			os.Exit(1)
-		}
-
-		// Ensure proxy server is closed on error. Otherwise it can be in a
-		// state where it is accepting connections but not processing them.
-		// See: https://github.com/superfly/litefs/pull/278#issuecomment-1419460935
-		if c.ProxyServer != nil {
-			log.Printf("closing proxy server on startup error")
-			_ = c.ProxyServer.Close()
-		}
+	// Run each mount concurrently so replicas waiting on their cluster do not
+	// delay the startup of other mounts.
+	mounts := c.All()
+	var wg sync.WaitGroup
+	for _, m := range mounts {
+		m := m
+		wg.Add(1)
+		go func() { defer wg.Done(); startMount(ctx, c, m) }()
 	}
+	wg.Wait()
+
+	// Reload the config of every mount when a SIGHUP is received.
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupCh:
+				for _, m := range mounts {
+					if err := m.Reload(ctx); err != nil {
+						log.Printf("cannot reload config: %s", err)
+					}
+				}
+			}
+		}
+	}()
 
 	fmt.Println("waiting for signal or subprocess to exit")
 
+	// Forward subprocess exits from every mount to a single channel.
+	execCh := make(chan error, len(mounts))
+	for _, m := range mounts {
+		m := m
+		go func() {
+			select {
+			case <-ctx.Done():
+			case err := <-m.ExecCh():
+				execCh <- err
+			}
+		}()
+	}
+
 	// Wait for signal or subcommand exit to stop program.
 	var exitCode int
 	select {
-	case err := <-c.ExecCh():
+	case err := <-execCh:
 		cancel(fmt.Errorf("canceled, subprocess exited"))
 
 		var exitErr *exec.ExitError
@@ -146,14 +225,22 @@ func runMount(ctx context.Context, args []string) error {
 		}
 
 	case sig := <-signalCh:
-		if cmd := c.Cmd(); cmd != nil {
-			fmt.Println("sending signal to exec process")
-			if err := cmd.Process.Signal(sig); err != nil {
-				return fmt.Errorf("cannot signal exec process: %w", err)
+		var running int
+		for _, m := range mounts {
+			if cmd := m.Cmd(); cmd != nil {
+				fmt.Println("sending signal to exec process")
+				if err := cmd.Process.Signal(sig); err != nil {
+					return fmt.Errorf("cannot signal exec process: %w", err)
+				}
+				running++
 			}
+		}
 
+		if running > 0 {
 			fmt.Println("waiting for exec process to close")
-			if err := <-c.ExecCh(); err != nil && !strings.HasPrefix(err.Error(), "signal:") {
+		}
+		for ; running > 0; running-- {
+			if err := <-execCh; err != nil && !strings.HasPrefix(err.Error(), "signal:") {
 				return fmt.Errorf("cannot wait for exec process: %w", err)
 			}
 		}
@@ -172,6 +259,30 @@ func runMount(ctx context.Context, args []string) error {
 	return nil
 }
 
+// startMount runs a single mount. Errors are reported to STDERR and exit the
+// process if enabled in the mount's config.
+func startMount(ctx context.Context, root, m *MountCommand) {
+	if err := m.Run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+
+		// Only exit the process if enabled in the config. A user want to
+		// continue running so that an ephemeral node can be debugged intsead
+		// of continually restarting on error.
+		if m.Config.ExitOnError {
+			_ = root.Close()
+			os.Exit(1)
+		}
+
+		// Ensure proxy server is closed on error. Otherwise it can be in a
+		// state where it is accepting connections but not processing them.
+		// See: https://github.com/superfly/litefs/pull/278#issuecomment-1419460935
+		if m.ProxyServer != nil {
+			log.Printf("closing proxy server on startup error")
+			_ = m.ProxyServer.Close()
+		}
+	}
+}
+
 func VersionString() string {
 	// Print version & commit information, if available.
 	if Version != "" {
@@ -193,10 +304,18 @@ Usage:
 
 The commands are:
 
+	dbs          lists databases & their positions on a node
 	export       export a database from a LiteFS cluster to disk
 	import       import a SQLite database into a LiteFS cluster
 	mount        mount the LiteFS FUSE file system
+	nodes        lists replicas & how far behind the primary they are
+	pause        pauses replication on a replica
+	reload       reloads the config of a running mount
+	restore      restore a database to a point in time from LTX files
+	resume       resumes replication on a paused replica
+	resync       resyncs a replica database from a primary snapshot
 	run          executes a subcommand for remote writes
+	status       reports the primary, lag & lease state of a node
 	version      prints the version
 `[1:])
 }
@@ -29,6 +29,9 @@ func (c *MountCommand) Close() error { return nil }
 // ExecCh always returns nil.
 func (c *MountCommand) ExecCh() chan error { return nil }
 
+// All returns the command itself.
+func (c *MountCommand) All() []*MountCommand { return []*MountCommand{c} }
+
 // Cmd always returns nil.
 func (c *MountCommand) Cmd() *exec.Cmd { return nil }
 
@@ -44,3 +47,7 @@ func (c *MountCommand) Validate(ctx context.Context) (err error) {
 func (c *MountCommand) Run(ctx context.Context) (err error) {
 	return fmt.Errorf("litefs-mount is not available on macOS")
 }
+
+func (c *MountCommand) Reload(ctx context.Context) error {
+	return fmt.Errorf("litefs-mount is not available on macOS")
+}
@@ -3,15 +3,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"expvar"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,22 +25,37 @@ import (
 	"github.com/mattn/go-shellwords"
 	"github.com/superfly/litefs"
 	"github.com/superfly/litefs/consul"
+	"github.com/superfly/litefs/etcd"
 	"github.com/superfly/litefs/fly"
 	"github.com/superfly/litefs/fuse"
 	"github.com/superfly/litefs/http"
 	"github.com/superfly/litefs/internal"
+	"github.com/superfly/litefs/kubernetes"
 	"github.com/superfly/litefs/lfsc"
+	"github.com/superfly/litefs/s3"
 	"golang.org/x/exp/slog"
+	"golang.org/x/sys/unix"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // MountCommand represents a command to mount the file system.
 type MountCommand struct {
+	execMu sync.Mutex // protects cmd
 	cmd    *exec.Cmd  // subcommand
 	execCh chan error // subcommand error channel
 
+	// Settings used to re-read the config file on reload.
+	reloadMu   sync.Mutex
+	configPath string
+	expandEnv  bool
+	debug      bool
+	parent     *MountCommand // first mount, if this is an additional mount
+
 	Config Config
 
+	// Temporary data directory created for an ephemeral node.
+	ephemeralDir string
+
 	OS   litefs.OS
 	Exit func(int)
 
@@ -48,6 +69,10 @@ type MountCommand struct {
 	AdvertiseURLFn func() string
 
 	OnInitStore func()
+
+	// Additional mounts run within the same process. These are created when
+	// multiple config files are passed to the command.
+	Mounts []*MountCommand
 }
 
 // NewMountCommand returns a new instance of MountCommand.
@@ -60,9 +85,28 @@ func NewMountCommand() *MountCommand {
 	}
 }
 
-func (c *MountCommand) Cmd() *exec.Cmd     { return c.cmd }
 func (c *MountCommand) ExecCh() chan error { return c.execCh }
 
+// Cmd returns the running subcommand. Returns nil while the previous
+// subcommand is stopped after a primary change.
+func (c *MountCommand) Cmd() *exec.Cmd {
+	c.execMu.Lock()
+	defer c.execMu.Unlock()
+	return c.cmd
+}
+
+// setCmd sets the running subcommand.
+func (c *MountCommand) setCmd(cmd *exec.Cmd) {
+	c.execMu.Lock()
+	defer c.execMu.Unlock()
+	c.cmd = cmd
+}
+
+// All returns the command followed by its additional mounts.
+func (c *MountCommand) All() []*MountCommand {
+	return append([]*MountCommand{c}, c.Mounts...)
+}
+
 // ParseFlags parses the command line flags & config file.
 func (c *MountCommand) ParseFlags(ctx context.Context, args []string) (err error) {
 	// Split the args list if there is a double dash arg included. Arguments
@@ -70,7 +114,8 @@ func (c *MountCommand) ParseFlags(ctx context.Context, args []string) (err error
 	args0, args1 := splitArgs(args)
 
 	fs := flag.NewFlagSet("litefs-mount", flag.ContinueOnError)
-	configPath := fs.String("config", "", "config file path")
+	var configPaths stringSliceFlag
+	fs.Var(&configPaths, "config", "config file path, repeat to run multiple mounts")
 	noExpandEnv := fs.Bool("no-expand-env", false, "do not expand env vars in config")
 	fuseDebug := fs.Bool("fuse.debug", false, "enable FUSE debug logging")
 	debug := fs.Bool("debug", false, "enable DEBUG level logging")
@@ -85,6 +130,16 @@ All options are specified in the litefs.yml config file which is searched for in
 the present working directory, the current user's home directory, and then
 finally at /etc/litefs.yml.
 
+Multiple independent mounts can be run in one process by passing the -config
+flag once for each mount. Each config must use its own FUSE directory, data
+directory, HTTP address, and lease key. Logging is shared by the process so
+every config must use the same log settings.
+
+Sending a SIGHUP to the process or running "litefs reload" re-reads the config
+files and applies changes to the lease candidate flag, the replicated
+databases, the LTX retention policy, and the log level. Other settings require
+a restart.
+
 Usage:
 
 	litefs mount [arguments]
@@ -100,23 +155,44 @@ Arguments:
 		return fmt.Errorf("too many arguments, specify a '--' to specify an exec command")
 	}
 
-	if err := ParseConfigPath(ctx, *configPath, !*noExpandEnv, &c.Config); err != nil {
+	var configPath string
+	if len(configPaths) > 0 {
+		configPath = configPaths[0]
+	}
+	if err := ParseConfigPath(ctx, configPath, !*noExpandEnv, &c.Config); err != nil {
 		return err
 	}
+	c.configPath, c.expandEnv = configPath, !*noExpandEnv
+
+	// Load additional mounts, if more than one config was specified.
+	for _, path := range configPaths[min(len(configPaths), 1):] {
+		m := NewMountCommand()
+		if err := ParseConfigPath(ctx, path, !*noExpandEnv, &m.Config); err != nil {
+			return err
+		}
+		m.configPath, m.expandEnv, m.parent = path, !*noExpandEnv, c
+		c.Mounts = append(c.Mounts, m)
+	}
 
 	// Override "exec" field if specified on the CLI.
 	if args1 != nil {
+		if len(c.Mounts) > 0 {
+			return fmt.Errorf("cannot specify an exec command on the CLI with multiple configs")
+		}
 		c.Config.Exec = ExecConfigSlice{{Cmd: strings.Join(args1, " ")}}
 	}
 
-	// Override "debug" field if specified on the CLI.
-	if *fuseDebug {
-		c.Config.FUSE.Debug = true
-	}
+	for _, m := range c.All() {
+		// Override "debug" field if specified on the CLI.
+		if *fuseDebug {
+			m.Config.FUSE.Debug = true
+		}
 
-	// Override debug logging if the flag is enabled.
-	if *debug {
-		c.Config.Log.Debug = true
+		// Override debug logging if the flag is enabled.
+		if *debug {
+			m.Config.Log.Debug = true
+		}
+		m.debug = *debug
 	}
 
 	// Enable trace logging, if specified. The config settings specify a rolling
@@ -145,8 +221,40 @@ Arguments:
 	return nil
 }
 
-// Validate validates the application's configuration.
+// Validate validates the application's configuration, including the
+// configuration of any additional mounts.
 func (c *MountCommand) Validate(ctx context.Context) (err error) {
+	fuseDirs, dataDirs, httpAddrs := make(map[string]struct{}), make(map[string]struct{}), make(map[string]struct{})
+	for _, m := range c.All() {
+		if err := m.validate(ctx); err != nil {
+			return err
+		}
+		if len(c.Mounts) == 0 {
+			continue
+		}
+
+		// Logging is process-wide so every mount must use the same settings.
+		if m.Config.Log.Format != c.Config.Log.Format || m.Config.Log.Timestamp != c.Config.Log.Timestamp || !equalLogLevels(m.Config.Log, c.Config.Log) {
+			return fmt.Errorf("log config must be the same for all mounts: %s", m.configPath)
+		}
+
+		// Separate mounts cannot share directories or ports.
+		if _, ok := fuseDirs[m.Config.FUSE.Dir]; ok {
+			return fmt.Errorf("fuse directory used by multiple mounts: %s", m.Config.FUSE.Dir)
+		} else if _, ok := dataDirs[m.Config.Data.Dir]; ok {
+			return fmt.Errorf("data directory used by multiple mounts: %s", m.Config.Data.Dir)
+		} else if _, ok := httpAddrs[m.Config.HTTP.Addr]; ok {
+			return fmt.Errorf("http address used by multiple mounts: %s", m.Config.HTTP.Addr)
+		}
+		fuseDirs[m.Config.FUSE.Dir] = struct{}{}
+		dataDirs[m.Config.Data.Dir] = struct{}{}
+		httpAddrs[m.Config.HTTP.Addr] = struct{}{}
+	}
+	return nil
+}
+
+// validate validates the configuration of a single mount.
+func (c *MountCommand) validate(ctx context.Context) (err error) {
 	if c.Config.FUSE.Dir == "" {
 		return fmt.Errorf("fuse directory required")
 	} else if c.Config.Data.Dir == "" {
@@ -157,32 +265,129 @@ func (c *MountCommand) Validate(ctx context.Context) (err error) {
 
 	// Enforce a valid lease mode.
 	if !IsValidLeaseType(c.Config.Lease.Type) {
-		return fmt.Errorf("invalid lease type, must be either 'consul' or 'static', got: '%v'", c.Config.Lease.Type)
+		return fmt.Errorf("invalid lease type, must be 'consul', 'etcd', 'kubernetes', 'static', 'dns', or a registered type, got: '%v'", c.Config.Lease.Type)
 	}
 
-	if c.Config.Lease.Candidate && len(c.Config.Lease.Databases) > 0 {
+	if c.Config.Lease.Candidate && (len(c.Config.Lease.Databases) > 0 || len(c.Config.Lease.ExcludeDatabases) > 0) {
 		return fmt.Errorf("cannot specify a database replication filter on candidate nodes")
+	} else if err := litefs.ValidateDatabaseFilter(c.databaseFilter()); err != nil {
+		return err
+	}
+
+	if c.Config.Lease.Backpressure.MaxLag < 0 {
+		return fmt.Errorf("lease backpressure max lag cannot be negative")
+	} else if c.Config.Lease.Backpressure.MaxLag > 0 && c.Config.Lease.Backpressure.Timeout <= 0 {
+		return fmt.Errorf("lease backpressure timeout must be greater than zero")
+	}
+
+	if c.Config.Data.Ephemeral && c.Config.Lease.Candidate {
+		return fmt.Errorf("ephemeral data directory cannot be used by a candidate node")
+	}
+
+	if c.Config.Data.LazySnapshotMinSize < 0 {
+		return fmt.Errorf("lazy snapshot min size cannot be negative")
+	} else if c.Config.Data.LazySnapshotMinSize > 0 && c.Config.Lease.Candidate {
+		return fmt.Errorf("lazy snapshots cannot be used by a candidate node")
+	}
+
+	if c.Config.Lease.Type == LeaseTypeDNS && c.Config.Lease.DNS.Name == "" {
+		return fmt.Errorf("lease dns name required")
+	} else if c.Config.Lease.DNS.Port < 0 || c.Config.Lease.DNS.Port > 65535 {
+		return fmt.Errorf("invalid lease dns port: %d", c.Config.Lease.DNS.Port)
+	}
+
+	if c.Config.Lease.Halt.AcquireTimeout <= 0 {
+		return fmt.Errorf("lease halt acquire timeout must be greater than zero")
+	} else if c.Config.Lease.Halt.TTL <= 0 {
+		return fmt.Errorf("lease halt ttl must be greater than zero")
+	}
+
+	switch c.Config.HTTP.StreamEncoding {
+	case "", http.StreamEncodingLZ4:
+	default:
+		return fmt.Errorf("invalid stream encoding, must be 'lz4' or blank, got: '%v'", c.Config.HTTP.StreamEncoding)
+	}
+
+	if c.Config.FUSE.MaxTxPages < 0 {
+		return fmt.Errorf("fuse max tx pages cannot be negative")
+	} else if c.Config.FUSE.MaxTxSize < 0 {
+		return fmt.Errorf("fuse max tx size cannot be negative")
+	}
+
+	if c.Config.HTTP.StreamRateLimit < 0 {
+		return fmt.Errorf("http stream rate limit cannot be negative")
+	}
+
+	if c.Config.HTTP.Readiness.MaxLag < 0 {
+		return fmt.Errorf("http readiness max lag cannot be negative")
+	} else if c.Config.HTTP.Readiness.LeaseTimeout < 0 {
+		return fmt.Errorf("http readiness lease timeout cannot be negative")
+	}
+
+	if _, err := parseLogLevels(c.Config.Log.Levels); err != nil {
+		return err
+	}
+
+	for i, config := range c.Config.Exec {
+		switch config.Role {
+		case "", ExecRolePrimary, ExecRoleReplica:
+		default:
+			return fmt.Errorf("invalid exec role, must be 'primary', 'replica', or blank, got: '%v'", config.Role)
+		}
+
+		if config.PrimaryChangeSignal != "" && unix.SignalNum(config.PrimaryChangeSignal) == 0 {
+			return fmt.Errorf("invalid exec primary change signal: %q", config.PrimaryChangeSignal)
+		} else if config.PrimaryChangeSignal != "" && config.RestartOnPrimaryChange {
+			return fmt.Errorf("exec command[%d] cannot specify both a primary change signal and restart", i)
+		}
+	}
+
+	if (c.Config.HTTP.TLSCertFile == "") != (c.Config.HTTP.TLSKeyFile == "") {
+		return fmt.Errorf("http tls cert file and key file must be specified together")
+	} else if c.Config.HTTP.TLSCAFile != "" && c.Config.HTTP.TLSCertFile == "" {
+		return fmt.Errorf("http tls ca file requires a tls cert file & key file")
 	}
 
 	return nil
 }
 
 const (
-	LeaseTypeConsul = "consul"
-	LeaseTypeStatic = "static"
+	LeaseTypeConsul     = "consul"
+	LeaseTypeEtcd       = "etcd"
+	LeaseTypeKubernetes = "kubernetes"
+	LeaseTypeStatic     = "static"
+	LeaseTypeDNS        = "dns"
 )
 
-// IsValidLeaseType returns true if s is a valid lease type.
+// stringSliceFlag is a flag that can be specified multiple times.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// IsValidLeaseType returns true if s is a built-in or registered lease type.
 func IsValidLeaseType(s string) bool {
 	switch s {
-	case LeaseTypeConsul, LeaseTypeStatic:
+	case LeaseTypeConsul, LeaseTypeEtcd, LeaseTypeKubernetes, LeaseTypeStatic, LeaseTypeDNS:
 		return true
 	default:
-		return false
+		return litefs.LookupLeaser(s) != nil
 	}
 }
 
 func (c *MountCommand) Close() (err error) {
+	for _, m := range c.Mounts {
+		if e := m.Close(); err == nil {
+			err = e
+		}
+	}
+
 	if c.ProxyServer != nil {
 		if e := internal.Close(c.ProxyServer); err == nil {
 			err = e
@@ -205,6 +410,19 @@ func (c *MountCommand) Close() (err error) {
 		if e := internal.Close(c.Store); err == nil {
 			err = e
 		}
+
+		// Wait for background work, such as S3 snapshots, to finish.
+		if closer, ok := c.Store.BackupClient.(io.Closer); ok {
+			if e := closer.Close(); err == nil {
+				err = e
+			}
+		}
+	}
+
+	if c.ephemeralDir != "" {
+		if e := os.RemoveAll(c.ephemeralDir); err == nil {
+			err = e
+		}
 	}
 
 	return err
@@ -217,6 +435,12 @@ func (c *MountCommand) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("init logger: %w", err)
 	}
 
+	if c.Config.Data.Ephemeral {
+		if err := c.initEphemeralDataDir(); err != nil {
+			return fmt.Errorf("cannot init ephemeral data directory: %w", err)
+		}
+	}
+
 	// Start listening on HTTP server first so we can determine the URL.
 	if err := c.initStore(ctx); err != nil {
 		return fmt.Errorf("cannot init store: %w", err)
@@ -231,12 +455,34 @@ func (c *MountCommand) Run(ctx context.Context) (err error) {
 		if err := c.initConsul(ctx); err != nil {
 			return fmt.Errorf("cannot init consul: %w", err)
 		}
+	case LeaseTypeEtcd:
+		log.Println("Using etcd to determine primary")
+		if err := c.initEtcd(ctx); err != nil {
+			return fmt.Errorf("cannot init etcd: %w", err)
+		}
+	case LeaseTypeKubernetes:
+		log.Println("Using Kubernetes to determine primary")
+		if err := c.initKubernetes(ctx); err != nil {
+			return fmt.Errorf("cannot init kubernetes: %w", err)
+		}
 	case LeaseTypeStatic:
 		log.Printf("Using static primary: primary=%v hostname=%s advertise-url=%s",
 			c.Config.Lease.Candidate, c.Config.Lease.Hostname, c.Config.Lease.AdvertiseURL)
 		c.Leaser = litefs.NewStaticLeaser(c.Config.Lease.Candidate, c.Config.Lease.Hostname, c.Config.Lease.AdvertiseURL)
+	case LeaseTypeDNS:
+		log.Printf("Using DNS to determine primary: primary=%v name=%s", c.Config.Lease.Candidate, c.Config.Lease.DNS.Name)
+		if err := c.initDNS(ctx); err != nil {
+			return fmt.Errorf("cannot init dns: %w", err)
+		}
 	default:
-		return fmt.Errorf("invalid lease type: %q", v)
+		factory := litefs.LookupLeaser(v)
+		if factory == nil {
+			return fmt.Errorf("invalid lease type: %q", v)
+		}
+		log.Printf("Using %s to determine primary", v)
+		if err := c.initRegisteredLeaser(ctx, factory); err != nil {
+			return fmt.Errorf("cannot init %s: %w", v, err)
+		}
 	}
 
 	if err := c.openStore(ctx); err != nil {
@@ -285,20 +531,94 @@ func (c *MountCommand) Run(ctx context.Context) (err error) {
 	// Execute subcommand, if specified in config.
 	// Exit if no subcommand specified.
 	if len(c.Config.Exec) > 0 {
-		if err := c.execCmds(ctx); err != nil {
+		isPrimary := c.Store.IsPrimary()
+		proc, err := c.execCmds(ctx, isPrimary)
+		if err != nil {
 			return fmt.Errorf("cannot exec: %w", err)
 		}
+		go c.superviseExec(ctx, proc, isPrimary)
 	}
 
 	return nil
 }
 
+// Reload re-reads the config file and applies the settings that can be
+// changed without restarting the mount: the lease candidate flag, the
+// replication database filter, the LTX retention policy, and the log level.
+// Other settings, including the backup configuration, require a restart.
+//
+// Log levels apply to the whole process so an additional mount rejects a
+// reload whose log levels differ from those of the first mount.
+func (c *MountCommand) Reload(ctx context.Context) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	// Parse & validate the config file as if the command was starting.
+	other := &MountCommand{Config: NewConfig()}
+	if err := ParseConfigPath(ctx, c.configPath, c.expandEnv, &other.Config); err != nil {
+		return err
+	}
+	other.initBackupConfigFromEnv(ctx)
+	if err := other.validate(ctx); err != nil {
+		return err
+	}
+	config := other.Config
+	config.Log.Debug = config.Log.Debug || c.debug
+
+	if c.parent != nil && !equalLogLevels(config.Log, c.parent.Config.Log) {
+		return fmt.Errorf("log levels must be the same for all mounts, reload the first mount to change them")
+	}
+
+	if config.Backup != c.Config.Backup {
+		log.Printf("backup config changed, restart required to apply")
+	}
+
+	c.Config.Lease.Candidate = config.Lease.Candidate
+	c.Config.Lease.Databases = config.Lease.Databases
+	c.Config.Lease.ExcludeDatabases = config.Lease.ExcludeDatabases
+	c.Config.Data.Retention = config.Data.Retention
+	c.Config.Data.RetentionMaxCount = config.Data.RetentionMaxCount
+	c.Config.Data.RetentionMaxSize = config.Data.RetentionMaxSize
+	c.Config.Log.Debug = config.Log.Debug
+
+	if c.Store != nil {
+		if err := c.Store.SetDatabaseFilter(c.databaseFilter()); err != nil {
+			return err
+		}
+		c.Store.SetCandidate(c.Config.Lease.Candidate)
+		c.Store.SetRetentionPolicy(c.Config.Data.Retention, c.Config.Data.RetentionMaxCount, c.Config.Data.RetentionMaxSize*(1<<20))
+	}
+
+	if c.Config.Log.Debug {
+		litefs.LogLevel.Set(slog.LevelDebug)
+	} else {
+		litefs.LogLevel.Set(slog.LevelInfo)
+	}
+
+	c.Config.Log.Levels = config.Log.Levels
+	levels, _ := parseLogLevels(c.Config.Log.Levels) // validated above
+	if err := litefs.SetSubsystemLogLevels(levels); err != nil {
+		return err
+	}
+
+	log.Printf("config reloaded")
+	return nil
+}
+
 func (c *MountCommand) initLogger(ctx context.Context) error {
 	// Enable debug logging, if set by the config.
 	if c.Config.Log.Debug {
 		litefs.LogLevel.Set(slog.LevelDebug)
 	}
 
+	// Apply log level overrides for individual subsystems.
+	levels, err := parseLogLevels(c.Config.Log.Levels)
+	if err != nil {
+		return err
+	} else if err := litefs.SetSubsystemLogLevels(levels); err != nil {
+		return err
+	}
+
 	opts := slog.HandlerOptions{Level: &litefs.LogLevel}
 
 	if !c.Config.Log.Timestamp {
@@ -319,26 +639,58 @@ func (c *MountCommand) initLogger(ctx context.Context) error {
 	return nil
 }
 
-func (c *MountCommand) initConsul(ctx context.Context) (err error) {
-	// TEMP: Allow non-localhost addresses.
+// equalLogLevels returns true if a & b set the same global & subsystem levels.
+func equalLogLevels(a, b LogConfig) bool {
+	return a.Debug == b.Debug && maps.Equal(a.Levels, b.Levels)
+}
+
+// parseLogLevels parses a set of subsystem log level names, such as "debug".
+func parseLogLevels(m map[string]string) (map[string]slog.Level, error) {
+	subsystems := litefs.LogSubsystems()
+
+	levels := make(map[string]slog.Level, len(m))
+	for name, s := range m {
+		if i := sort.SearchStrings(subsystems, name); i == len(subsystems) || subsystems[i] != name {
+			return nil, fmt.Errorf("invalid log subsystem, must be one of %s, got: %q", strings.Join(subsystems, ", "), name)
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("invalid log level for %q subsystem: %q", name, s)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
 
+// leaseHostname returns the hostname & advertise URL used by distributed leasers.
+func (c *MountCommand) leaseHostname() (hostname, advertiseURL string, err error) {
 	// Use hostname from OS, if not specified.
-	hostname := c.Config.Lease.Hostname
+	hostname = c.Config.Lease.Hostname
 	if hostname == "" {
 		if hostname, err = os.Hostname(); err != nil {
-			return err
+			return "", "", err
 		}
 	}
 
 	// Determine the advertise URL for the LiteFS API.
 	// Default to use the hostname and HTTP port. Also allow injection for tests.
-	advertiseURL := c.Config.Lease.AdvertiseURL
+	advertiseURL = c.Config.Lease.AdvertiseURL
 	if c.AdvertiseURLFn != nil {
 		advertiseURL = c.AdvertiseURLFn()
 	}
 	if advertiseURL == "" && hostname != "" {
 		advertiseURL = fmt.Sprintf("http://%s:%d", hostname, c.HTTPServer.Port())
 	}
+	return hostname, advertiseURL, nil
+}
+
+func (c *MountCommand) initConsul(ctx context.Context) (err error) {
+	// TEMP: Allow non-localhost addresses.
+	hostname, advertiseURL, err := c.leaseHostname()
+	if err != nil {
+		return err
+	}
 
 	leaser := consul.NewLeaser(c.Config.Lease.Consul.URL, c.Config.Lease.Consul.Key, hostname, advertiseURL)
 	if v := c.Config.Lease.Consul.TTL; v > 0 {
@@ -347,6 +699,14 @@ func (c *MountCommand) initConsul(ctx context.Context) (err error) {
 	if v := c.Config.Lease.Consul.LockDelay; v > 0 {
 		leaser.LockDelay = v
 	}
+	leaser.AcquireDelay = c.Config.Lease.Consul.AcquireDelay
+	leaser.AcquireDelayJitter = c.Config.Lease.Consul.AcquireDelayJitter
+	if v := c.Config.Lease.Consul.CircuitBreakerThreshold; v > 0 {
+		leaser.CircuitBreakerThreshold = v
+	}
+	if v := c.Config.Lease.Consul.CircuitBreakerHalfOpenInterval; v > 0 {
+		leaser.CircuitBreakerHalfOpenInterval = v
+	}
 	if err := leaser.Open(); err != nil {
 		return fmt.Errorf("cannot connect to consul: %w", err)
 	}
@@ -357,7 +717,139 @@ func (c *MountCommand) initConsul(ctx context.Context) (err error) {
 	return nil
 }
 
+func (c *MountCommand) initEtcd(ctx context.Context) (err error) {
+	hostname, advertiseURL, err := c.leaseHostname()
+	if err != nil {
+		return err
+	}
+
+	leaser := etcd.NewLeaser(c.Config.Lease.Etcd.URL, c.Config.Lease.Etcd.Key, hostname, advertiseURL)
+	if v := c.Config.Lease.Etcd.TTL; v > 0 {
+		leaser.TTL = v
+	}
+	leaser.AcquireDelay = c.Config.Lease.Etcd.AcquireDelay
+	leaser.AcquireDelayJitter = c.Config.Lease.Etcd.AcquireDelayJitter
+	if err := leaser.Open(); err != nil {
+		return fmt.Errorf("cannot connect to etcd: %w", err)
+	}
+	log.Printf("initializing etcd: key=%s url=%s hostname=%s advertise-url=%s",
+		c.Config.Lease.Etcd.Key, c.Config.Lease.Etcd.URL, hostname, advertiseURL)
+
+	c.Leaser = leaser
+	return nil
+}
+
+func (c *MountCommand) initKubernetes(ctx context.Context) (err error) {
+	hostname, advertiseURL, err := c.leaseHostname()
+	if err != nil {
+		return err
+	}
+
+	leaser := kubernetes.NewLeaser(c.Config.Lease.Kubernetes.Name, hostname, advertiseURL)
+	leaser.APIURL = c.Config.Lease.Kubernetes.URL
+	leaser.Namespace = c.Config.Lease.Kubernetes.Namespace
+	if v := c.Config.Lease.Kubernetes.TTL; v > 0 {
+		leaser.TTL = v
+	}
+	if err := leaser.Open(); err != nil {
+		return fmt.Errorf("cannot connect to kubernetes: %w", err)
+	}
+	log.Printf("initializing kubernetes: lease=%s/%s url=%s hostname=%s advertise-url=%s",
+		leaser.Namespace, c.Config.Lease.Kubernetes.Name, leaser.APIURL, hostname, advertiseURL)
+
+	c.Leaser = leaser
+	return nil
+}
+
+func (c *MountCommand) initDNS(ctx context.Context) (err error) {
+	hostname, advertiseURL, err := c.leaseHostname()
+	if err != nil {
+		return err
+	}
+
+	leaser := litefs.NewDNSLeaser(c.Config.Lease.Candidate, c.Config.Lease.DNS.Name, hostname, advertiseURL)
+	leaser.Port = c.Config.Lease.DNS.Port
+	if v := c.Config.Lease.DNS.RefreshInterval; v > 0 {
+		leaser.RefreshInterval = v
+	}
+	if c.Config.HTTP.TLSCertFile != "" {
+		leaser.Scheme = "https"
+	}
+
+	c.Leaser = leaser
+	return nil
+}
+
+// initRegisteredLeaser creates the leaser from a factory registered with
+// litefs.RegisterLeaser. The "lease.options" config section is passed through.
+func (c *MountCommand) initRegisteredLeaser(ctx context.Context, factory litefs.LeaserFactory) (err error) {
+	hostname, advertiseURL, err := c.leaseHostname()
+	if err != nil {
+		return err
+	}
+
+	options := c.Config.Lease.Options
+	leaser, err := factory.NewLeaser(ctx, litefs.LeaserConfig{
+		Type:         c.Config.Lease.Type,
+		Hostname:     hostname,
+		AdvertiseURL: advertiseURL,
+		Candidate:    c.Config.Lease.Candidate,
+		Decode: func(v any) error {
+			if options.Kind == 0 {
+				return nil
+			}
+			return options.Decode(v)
+		},
+	})
+	if err != nil {
+		return err
+	} else if leaser == nil {
+		return fmt.Errorf("leaser factory returned nil leaser")
+	}
+	log.Printf("initializing %s: hostname=%s advertise-url=%s", c.Config.Lease.Type, hostname, advertiseURL)
+
+	c.Leaser = leaser
+	return nil
+}
+
+// initEphemeralDataDir creates an empty data directory for the store so an
+// ephemeral node always bootstraps from a snapshot of the primary. The same
+// subdirectory is used on every start & any contents left behind by a node
+// that did not shut down cleanly are removed. The parent directory should be
+// on a memory-backed file system such as tmpfs so that no data is written to
+// disk.
+func (c *MountCommand) initEphemeralDataDir() error {
+	parent := c.Config.Data.Dir
+	if err := os.MkdirAll(parent, 0o777); err != nil {
+		return err
+	}
+
+	var stat unix.Statfs_t
+	if err := unix.Statfs(parent, &stat); err != nil {
+		return err
+	} else if typ := uint32(stat.Type); typ != unix.TMPFS_MAGIC && typ != unix.RAMFS_MAGIC {
+		log.Printf("WARN: ephemeral data directory is not on a memory-backed file system: %s", parent)
+	}
+
+	dir := filepath.Join(parent, "ephemeral")
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("remove stale ephemeral data directory: %w", err)
+	} else if err := os.Mkdir(dir, 0o777); err != nil {
+		return err
+	}
+	log.Printf("using ephemeral data directory: %s", dir)
+
+	c.ephemeralDir = dir
+	c.Config.Data.Dir = dir
+	return nil
+}
+
 func (c *MountCommand) initStore(ctx context.Context) error {
+	client, err := c.newHTTPClient()
+	if err != nil {
+		return err
+	}
+
 	c.Store = litefs.NewStore(c.Config.Data.Dir, c.Config.Lease.Candidate)
 	c.Store.OS = c.OS
 	c.Store.Exit = c.Exit
@@ -365,12 +857,51 @@ func (c *MountCommand) initStore(ctx context.Context) error {
 	c.Store.Compress = c.Config.Data.Compress
 	c.Store.Retention = c.Config.Data.Retention
 	c.Store.RetentionMonitorInterval = c.Config.Data.RetentionMonitorInterval
+	c.Store.RetentionMaxCount = c.Config.Data.RetentionMaxCount
+	c.Store.RetentionMaxSize = c.Config.Data.RetentionMaxSize * (1 << 20)
 	c.Store.ReconnectDelay = c.Config.Lease.ReconnectDelay
+	c.Store.CandidatePriority = c.Config.Lease.Priority
+	c.Store.CandidatePriorityDelay = c.Config.Lease.PriorityDelay
+	c.Store.ReplicationHeartbeatInterval = c.Config.Lease.HeartbeatInterval
 	c.Store.DemoteDelay = c.Config.Lease.DemoteDelay
-	c.Store.Client = http.NewClient()
-	c.Store.DatabaseFilter = c.Config.Lease.Databases
+	c.Store.Client = client
+	c.Store.DatabaseFilter = c.databaseFilter()
+	c.Store.DBErrorThreshold = c.Config.Lease.DBErrorThreshold
+	c.Store.SyncReplicaN = c.Config.Lease.SyncReplication.Replicas
+	c.Store.SyncReplicaTimeout = c.Config.Lease.SyncReplication.Timeout
+	c.Store.SyncReplicaDBs = c.Config.Lease.SyncReplication.Databases
+	c.Store.BackpressureMaxLag = c.Config.Lease.Backpressure.MaxLag
+	c.Store.BackpressureReplicaN = c.Config.Lease.Backpressure.Replicas
+	c.Store.BackpressureTimeout = c.Config.Lease.Backpressure.Timeout
+	c.Store.HaltAcquireTimeout = c.Config.Lease.Halt.AcquireTimeout
+	c.Store.HaltLockTTL = c.Config.Lease.Halt.TTL
+	c.Store.LazyDBOpen = c.Config.Data.LazyOpen
+	c.Store.ChecksumVerifyInterval = c.Config.Data.ChecksumVerifyInterval
+	c.Store.ChecksumVerifyResync = c.Config.Data.ChecksumVerifyResync
+	c.Store.DBExtensions = c.Config.FUSE.DBExtensions
+	c.Store.WriteForwarding = c.Config.FUSE.WriteForwarding
+	c.Store.MaxTxPageN = c.Config.FUSE.MaxTxPages
+	c.Store.MaxTxSize = c.Config.FUSE.MaxTxSize * (1 << 20)
 	c.initEnvironment(ctx)
 
+	strategy, err := litefs.ParseCacheInvalidationStrategy(c.Config.FUSE.CacheInvalidation)
+	if err != nil {
+		return err
+	}
+	c.Store.CacheInvalidationStrategy = strategy
+
+	level, err := litefs.ParseIntegrityCheckLevel(c.Config.Data.StartupIntegrityCheck)
+	if err != nil {
+		return err
+	}
+	c.Store.StartupIntegrityCheck = level
+
+	resyncMode, err := litefs.ParseResyncMode(c.Config.Lease.ResyncMode)
+	if err != nil {
+		return err
+	}
+	c.Store.ResyncMode = resyncMode
+
 	if c.OnInitStore != nil {
 		c.OnInitStore()
 	}
@@ -379,12 +910,19 @@ func (c *MountCommand) initStore(ctx context.Context) error {
 		return err
 	}
 
-	// Initialize as a singleton so we can automatically collect metrics.
-	litefs.GlobalStore.Store(c.Store)
-
 	return nil
 }
 
+// databaseFilter returns the replication filter patterns from the config.
+// Excluded databases are prefixed with "!". See litefs.MatchDatabaseFilter().
+func (c *MountCommand) databaseFilter() []string {
+	filter := append([]string{}, c.Config.Lease.Databases...)
+	for _, pattern := range c.Config.Lease.ExcludeDatabases {
+		filter = append(filter, "!"+pattern)
+	}
+	return filter
+}
+
 func (c *MountCommand) initEnvironment(ctx context.Context) {
 	if fly.Available() {
 		c.Store.Environment = fly.NewEnvironment()
@@ -437,6 +975,38 @@ func (c *MountCommand) initStoreBackupClient(ctx context.Context) error {
 		c.Store.BackupClient = client
 		log.Printf("litefs cloud backup client configured: %s", client.URL())
 
+	case "s3":
+		client := s3.NewBackupClient(c.Config.Backup.Bucket, c.Config.Backup.Path)
+		client.Endpoint = c.Config.Backup.Endpoint
+		client.AccessKeyID = c.Config.Backup.AccessKeyID
+		client.SecretAccessKey = c.Config.Backup.SecretAccessKey
+
+		// Fallback to the standard AWS environment variables.
+		if v := c.Config.Backup.Region; v != "" {
+			client.Region = v
+		} else if v := os.Getenv("AWS_REGION"); v != "" {
+			client.Region = v
+		}
+		if client.AccessKeyID == "" && client.SecretAccessKey == "" {
+			client.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+			client.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			client.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+
+		if v := c.Config.Backup.SnapshotInterval; v > 0 {
+			client.SnapshotInterval = v
+		}
+		if v := c.Config.Backup.Retention; v > 0 {
+			client.Retention = v
+		}
+
+		if err := client.Open(); err != nil {
+			return fmt.Errorf("open s3 backup client: %w", err)
+		}
+
+		c.Store.BackupClient = client
+		log.Printf("s3 backup client configured: %s", client.URL())
+
 	default:
 		return fmt.Errorf("invalid backup client type: %q", typ)
 	}
@@ -495,9 +1065,79 @@ func (c *MountCommand) initFileSystem(ctx context.Context) error {
 	return nil
 }
 
+// newHTTPClient returns a client for connecting to other LiteFS nodes.
+func (c *MountCommand) newHTTPClient() (*http.Client, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.NewClient()
+	client.StreamEncoding = c.Config.HTTP.StreamEncoding
+	client.LazySnapshotMinSize = c.Config.Data.LazySnapshotMinSize * (1 << 20)
+	client.AuthToken = c.Config.HTTP.AuthToken
+	client.TLSConfig = tlsConfig
+	return client, nil
+}
+
+// tlsConfig returns the TLS configuration used by the HTTP server & client.
+// Returns nil if TLS is not enabled. If a CA file is specified then it is
+// used to verify both servers & clients so that mutual TLS is enforced.
+func (c *MountCommand) tlsConfig() (*tls.Config, error) {
+	if c.Config.HTTP.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.Config.HTTP.TLSCertFile, c.Config.HTTP.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if path := c.Config.HTTP.TLSCAFile; path != "" {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificates found in tls ca file: %s", path)
+		}
+		cfg.RootCAs = pool
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
 func (c *MountCommand) initHTTPServer(ctx context.Context) error {
+	client, err := c.newHTTPClient()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return err
+	}
+
 	server := http.NewServer(c.Store, c.Config.HTTP.Addr)
 	server.SnapshotTimeout = c.Config.HTTP.SnapshotTimeout
+	server.StreamRateLimit = c.Config.HTTP.StreamRateLimit * (1 << 10)
+	server.AuthToken = c.Config.HTTP.AuthToken
+	server.TLSConfig = tlsConfig
+	server.Client = client
+	server.ReloadFunc = c.Reload
+	server.Readiness = http.ReadinessCriteria{
+		MaxLag:           c.Config.HTTP.Readiness.MaxLag,
+		RequireConnected: c.Config.HTTP.Readiness.RequireConnected,
+		RequireLease:     c.Config.HTTP.Readiness.RequireLease,
+		LeaseTimeout:     c.Config.HTTP.Readiness.LeaseTimeout,
+	}
 	if err := server.Listen(); err != nil {
 		return fmt.Errorf("cannot open http server: %w", err)
 	}
@@ -552,13 +1192,15 @@ func (c *MountCommand) runProxyServer(ctx context.Context) error {
 	return nil
 }
 
-// execCmds sequentially executes the commands in the "exec" config.
-// The last command is run asynchronously and will send its exit to the execCh.
-func (c *MountCommand) execCmds(ctx context.Context) error {
-	for i, config := range c.Config.Exec {
+// execCmds sequentially executes the commands in the "exec" config that apply
+// to the node's role. The last command is run asynchronously & is returned.
+// Returns nil if no asynchronous command was started.
+func (c *MountCommand) execCmds(ctx context.Context, isPrimary bool) (*execProcess, error) {
+	configs := c.execConfigs(isPrimary)
+	for i, config := range configs {
 		args, err := shellwords.Parse(config.Cmd)
 		if err != nil {
-			return fmt.Errorf("cannot parse exec command[%d]: %w", i, err)
+			return nil, fmt.Errorf("cannot parse exec command[%d]: %w", i, err)
 		}
 		cmd, args := args[0], args[1:]
 
@@ -570,47 +1212,223 @@ func (c *MountCommand) execCmds(ctx context.Context) error {
 
 		// Execute all commands synchronously except for the last one.
 		// This is to support migration commands that occur before the app start.
-		if i < len(c.Config.Exec)-1 {
-			if err := c.execSyncCmd(ctx, cmd, args); err != nil {
-				return fmt.Errorf("sync cmd: %w", err)
+		if i < len(configs)-1 {
+			if err := c.execSyncCmd(ctx, cmd, args, isPrimary); err != nil {
+				return nil, fmt.Errorf("sync cmd: %w", err)
 			}
 		} else {
-			if err := c.execBackgroundCmd(ctx, cmd, args); err != nil {
-				return fmt.Errorf("background cmd: %w", err)
+			proc, err := c.execBackgroundCmd(ctx, config, cmd, args, isPrimary)
+			if err != nil {
+				return nil, fmt.Errorf("background cmd: %w", err)
 			}
+			return proc, nil
 		}
 	}
 
-	return nil
+	// Clear the finished command so signals are not sent to it.
+	c.setCmd(nil)
+	return nil, nil
 }
 
-func (c *MountCommand) execSyncCmd(ctx context.Context, cmd string, args []string) error {
+// execConfigs returns the exec commands that apply to the given role.
+func (c *MountCommand) execConfigs(isPrimary bool) []*ExecConfig {
+	role := ExecRoleReplica
+	if isPrimary {
+		role = ExecRolePrimary
+	}
+
+	a := make([]*ExecConfig, 0, len(c.Config.Exec))
+	for _, config := range c.Config.Exec {
+		if config.Role == "" || config.Role == role {
+			a = append(a, config)
+		}
+	}
+	return a
+}
+
+// execBackgroundConfig returns the config of the asynchronous command that is
+// run for the given role. Returns nil if no command is run.
+func (c *MountCommand) execBackgroundConfig(isPrimary bool) *ExecConfig {
+	configs := c.execConfigs(isPrimary)
+	if len(configs) == 0 {
+		return nil
+	}
+
+	config := configs[len(configs)-1]
+	if config.IfCandidate && !c.Store.Candidate() {
+		return nil
+	}
+	return config
+}
+
+// execEnv returns the environment for exec commands. The node's role is
+// passed via LITEFS_PRIMARY & the primary's hostname is passed to replicas
+// via LITEFS_PRIMARY_HOSTNAME, if available.
+//
+// The environment is only set when a command starts so the hostname is stale
+// if the primary moves to another node while this node remains a replica.
+func (c *MountCommand) execEnv(isPrimary bool) []string {
+	env := append(os.Environ(), "LITEFS_PRIMARY="+strconv.FormatBool(isPrimary))
+	if _, info := c.Store.PrimaryInfo(); !isPrimary && info != nil {
+		env = append(env, "LITEFS_PRIMARY_HOSTNAME="+info.Hostname)
+	}
+	return env
+}
+
+func (c *MountCommand) execSyncCmd(ctx context.Context, cmd string, args []string, isPrimary bool) error {
 	log.Printf("executing command: %s %v", cmd, args)
 
-	c.cmd = exec.CommandContext(ctx, cmd, args...)
-	c.cmd.Env = os.Environ()
-	c.cmd.Stdout = os.Stdout
-	c.cmd.Stderr = os.Stderr
-	if err := c.cmd.Run(); err != nil {
+	execCmd := exec.CommandContext(ctx, cmd, args...)
+	execCmd.Env = c.execEnv(isPrimary)
+	execCmd.Stdout = os.Stdout
+	execCmd.Stderr = os.Stderr
+	if err := execCmd.Start(); err != nil {
+		return fmt.Errorf("cannot run command: %w", err)
+	}
+	c.setCmd(execCmd)
+
+	if err := execCmd.Wait(); err != nil {
 		return fmt.Errorf("cannot run command: %w", err)
 	}
 
 	return nil
 }
 
-func (c *MountCommand) execBackgroundCmd(ctx context.Context, cmd string, args []string) error {
+func (c *MountCommand) execBackgroundCmd(ctx context.Context, config *ExecConfig, cmd string, args []string, isPrimary bool) (*execProcess, error) {
 	log.Printf("starting background subprocess: %s %v", cmd, args)
 
-	c.cmd = exec.CommandContext(ctx, cmd, args...)
-	c.cmd.Env = os.Environ()
-	c.cmd.Stdout = os.Stdout
-	c.cmd.Stderr = os.Stderr
-	if err := c.cmd.Start(); err != nil {
-		return fmt.Errorf("cannot start exec command: %w", err)
+	execCmd := exec.CommandContext(ctx, cmd, args...)
+	execCmd.Env = c.execEnv(isPrimary)
+	execCmd.Stdout = os.Stdout
+	execCmd.Stderr = os.Stderr
+	if err := execCmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start exec command: %w", err)
 	}
-	go func() { c.execCh <- c.cmd.Wait() }()
+	c.setCmd(execCmd)
 
-	return nil
+	proc := &execProcess{config: config, cmd: execCmd, waitCh: make(chan error, 1)}
+	go func() { proc.waitCh <- proc.cmd.Wait() }()
+
+	return proc, nil
+}
+
+// superviseExec waits for the asynchronous exec command to exit & sends its
+// exit to the execCh. If the node's role changes then the command is either
+// restarted, signaled, or replaced by the command for the new role.
+func (c *MountCommand) superviseExec(ctx context.Context, proc *execProcess, isPrimary bool) {
+	sub := c.Store.SubscribeEvents()
+	defer func() { sub.Stop() }()
+
+	// Check the role on startup in case it changed while the commands executed.
+	check := true
+	for {
+		if check && c.Store.IsPrimary() != isPrimary {
+			isPrimary = !isPrimary
+
+			var err error
+			if proc, err = c.handleExecPrimaryChange(ctx, proc, isPrimary); err != nil {
+				select {
+				case c.execCh <- err:
+				case <-ctx.Done():
+				}
+				return
+			}
+		}
+
+		check = false
+
+		var waitCh <-chan error
+		if proc != nil {
+			waitCh = proc.waitCh
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-waitCh:
+			select {
+			case c.execCh <- err:
+			case <-ctx.Done():
+			}
+			return
+
+		case event, ok := <-sub.C():
+			// Resubscribe if we fell behind as a primary change may have been missed.
+			if !ok {
+				sub = c.Store.SubscribeEvents()
+				check = true
+			} else if event.Type == litefs.EventTypePrimaryChange {
+				check = true
+			}
+		}
+	}
+}
+
+// handleExecPrimaryChange applies a role change to the asynchronous exec
+// command. Returns the command that is running after the change.
+func (c *MountCommand) handleExecPrimaryChange(ctx context.Context, proc *execProcess, isPrimary bool) (*execProcess, error) {
+	next := c.execBackgroundConfig(isPrimary)
+
+	// Keep the current subprocess if it is also used by the new role.
+	if proc != nil && proc.config == next && !proc.config.RestartOnPrimaryChange {
+		if proc.config.PrimaryChangeSignal != "" {
+			log.Printf("primary status changed (primary=%v), sending %s to subprocess", isPrimary, proc.config.PrimaryChangeSignal)
+			if err := proc.cmd.Process.Signal(unix.SignalNum(proc.config.PrimaryChangeSignal)); err != nil {
+				log.Printf("cannot signal subprocess: %s", err)
+			}
+		}
+		return proc, nil
+	} else if proc == nil && next == nil {
+		return nil, nil
+	}
+
+	log.Printf("primary status changed (primary=%v), restarting exec commands", isPrimary)
+
+	// Detach the subprocess so the main process does not signal & wait on a
+	// subprocess that is being replaced. The lock is only held to swap the
+	// command so Cmd() does not block while the subprocess stops or while
+	// synchronous commands, such as migrations, run for the new role.
+	c.setCmd(nil)
+	if proc != nil {
+		c.stopExecProcess(proc)
+	}
+
+	proc, err := c.execCmds(ctx, isPrimary)
+	if err != nil {
+		c.setCmd(nil)
+		return nil, fmt.Errorf("cannot exec: %w", err)
+	}
+	return proc, nil
+}
+
+// stopExecProcess sends SIGTERM to the subprocess & waits for it to exit.
+// The subprocess is killed if it does not exit within the stop timeout.
+func (c *MountCommand) stopExecProcess(proc *execProcess) {
+	log.Printf("stopping background subprocess: %s", proc.config.Cmd)
+
+	if err := proc.cmd.Process.Signal(unix.SIGTERM); err != nil {
+		log.Printf("cannot signal subprocess: %s", err)
+	}
+
+	select {
+	case <-proc.waitCh:
+	case <-time.After(ExecStopTimeout):
+		log.Printf("subprocess did not exit after %s, killing", ExecStopTimeout)
+		_ = proc.cmd.Process.Kill()
+		<-proc.waitCh
+	}
+}
+
+// ExecStopTimeout is the time to wait for a subprocess to exit after it is
+// signaled to stop on a primary change.
+const ExecStopTimeout = 10 * time.Second
+
+// execProcess represents a running asynchronous exec command.
+type execProcess struct {
+	config *ExecConfig
+	cmd    *exec.Cmd
+	waitCh chan error // receives the exit from cmd.Wait()
 }
 
 // promote issues a lease handoff request to the current primary.
@@ -621,7 +1439,10 @@ func (c *MountCommand) promote(ctx context.Context) (err error) {
 		return nil
 	}
 
-	client := http.NewClient()
+	client, err := c.newHTTPClient()
+	if err != nil {
+		return err
+	}
 	if err := client.Handoff(ctx, info.AdvertiseURL, c.Store.ID()); err != nil {
 		return fmt.Errorf("handoff: %w", err)
 	}
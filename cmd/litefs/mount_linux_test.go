@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+)
+
+func TestMountCommand_execConfigs(t *testing.T) {
+	c := newExecMountCommand(t,
+		&ExecConfig{Cmd: "migrate", Role: ExecRolePrimary},
+		&ExecConfig{Cmd: "app"},
+		&ExecConfig{Cmd: "replica-app", Role: ExecRoleReplica},
+	)
+
+	if got := execConfigCmds(c.execConfigs(true)); len(got) != 2 || got[0] != "migrate" || got[1] != "app" {
+		t.Fatalf("primary=%v", got)
+	}
+	if got := execConfigCmds(c.execConfigs(false)); len(got) != 2 || got[0] != "app" || got[1] != "replica-app" {
+		t.Fatalf("replica=%v", got)
+	}
+
+	if config := c.execBackgroundConfig(true); config == nil || config.Cmd != "app" {
+		t.Fatalf("unexpected primary background config: %#v", config)
+	} else if config := c.execBackgroundConfig(false); config == nil || config.Cmd != "replica-app" {
+		t.Fatalf("unexpected replica background config: %#v", config)
+	}
+}
+
+func TestMountCommand_handleExecPrimaryChange(t *testing.T) {
+	// Ensure the subprocess is replaced by the command for the new role.
+	t.Run("Replace", func(t *testing.T) {
+		c := newExecMountCommand(t,
+			&ExecConfig{Cmd: "sleep 10", Role: ExecRolePrimary},
+			&ExecConfig{Cmd: "sleep 20", Role: ExecRoleReplica},
+		)
+		proc := startExecProcess(t, c, true)
+
+		next, err := c.handleExecPrimaryChange(context.Background(), proc, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.stopExecProcess(next)
+
+		if next == nil || next.config.Cmd != "sleep 20" {
+			t.Fatalf("unexpected process: %#v", next)
+		} else if proc.cmd.ProcessState == nil {
+			t.Fatal("expected previous process to exit")
+		} else if c.Cmd() != next.cmd {
+			t.Fatal("expected command to be replaced")
+		}
+	})
+
+	// Ensure a finished subprocess is cleared if the new role has none.
+	t.Run("NoBackgroundCommand", func(t *testing.T) {
+		c := newExecMountCommand(t, &ExecConfig{Cmd: "sleep 10", Role: ExecRolePrimary})
+		proc := startExecProcess(t, c, true)
+
+		if next, err := c.handleExecPrimaryChange(context.Background(), proc, false); err != nil {
+			t.Fatal(err)
+		} else if next != nil {
+			t.Fatalf("unexpected process: %#v", next)
+		} else if cmd := c.Cmd(); cmd != nil {
+			t.Fatalf("expected no command, got %v", cmd.Args)
+		}
+	})
+
+	// Ensure the command can be read while synchronous commands run.
+	t.Run("CmdNotBlocked", func(t *testing.T) {
+		c := newExecMountCommand(t,
+			&ExecConfig{Cmd: "sleep 10", Role: ExecRolePrimary},
+			&ExecConfig{Cmd: "sleep 1", Role: ExecRoleReplica},
+			&ExecConfig{Cmd: "sleep 20", Role: ExecRoleReplica},
+		)
+		proc := startExecProcess(t, c, true)
+
+		type result struct {
+			proc *execProcess
+			err  error
+		}
+		ch := make(chan result, 1)
+		go func() {
+			next, err := c.handleExecPrimaryChange(context.Background(), proc, false)
+			ch <- result{next, err}
+		}()
+
+		time.Sleep(200 * time.Millisecond) // wait for sync command to start
+		cmdCh := make(chan struct{})
+		go func() { c.Cmd(); close(cmdCh) }()
+		select {
+		case <-cmdCh:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Cmd() blocked while the subprocess was replaced")
+		}
+
+		r := <-ch
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		defer c.stopExecProcess(r.proc)
+
+		if r.proc == nil || r.proc.config.Cmd != "sleep 20" {
+			t.Fatalf("unexpected process: %#v", r.proc)
+		} else if c.Cmd() != r.proc.cmd {
+			t.Fatal("expected command to be replaced")
+		}
+	})
+
+	// Ensure a subprocess shared by both roles is restarted if configured.
+	t.Run("RestartOnPrimaryChange", func(t *testing.T) {
+		c := newExecMountCommand(t, &ExecConfig{Cmd: "sleep 10", RestartOnPrimaryChange: true})
+		proc := startExecProcess(t, c, true)
+
+		next, err := c.handleExecPrimaryChange(context.Background(), proc, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.stopExecProcess(next)
+
+		if next == nil || next == proc {
+			t.Fatal("expected new process")
+		} else if proc.cmd.ProcessState == nil {
+			t.Fatal("expected previous process to exit")
+		}
+	})
+
+	// Ensure a subprocess shared by both roles is signaled if configured.
+	t.Run("PrimaryChangeSignal", func(t *testing.T) {
+		c := newExecMountCommand(t, &ExecConfig{Cmd: `sh -c 'trap "exit 0" HUP; while true; do sleep 0.1; done'`, PrimaryChangeSignal: "SIGHUP"})
+		proc := startExecProcess(t, c, true)
+		time.Sleep(100 * time.Millisecond) // wait for trap to be installed
+
+		if next, err := c.handleExecPrimaryChange(context.Background(), proc, false); err != nil {
+			t.Fatal(err)
+		} else if next != proc {
+			t.Fatal("expected same process")
+		}
+
+		select {
+		case err := <-proc.waitCh:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for signaled process to exit")
+		}
+	})
+}
+
+// Ensure an ephemeral data directory is reused & cleared on each start.
+func TestMountCommand_initEphemeralDataDir(t *testing.T) {
+	parent := t.TempDir()
+	stale := filepath.Join(parent, "ephemeral", "dbs", "db")
+	if err := os.MkdirAll(stale, 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewMountCommand()
+	c.Config.Data.Dir = parent
+	if err := c.initEphemeralDataDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := c.Config.Data.Dir, filepath.Join(parent, "ephemeral"); got != want {
+		t.Fatalf("Data.Dir=%s, want %s", got, want)
+	} else if got, want := c.ephemeralDir, c.Config.Data.Dir; got != want {
+		t.Fatalf("ephemeralDir=%s, want %s", got, want)
+	}
+	if ents, err := os.ReadDir(c.Config.Data.Dir); err != nil {
+		t.Fatal(err)
+	} else if len(ents) != 0 {
+		t.Fatalf("expected empty data directory, found %d entries", len(ents))
+	}
+	if ents, err := os.ReadDir(parent); err != nil {
+		t.Fatal(err)
+	} else if len(ents) != 1 {
+		t.Fatalf("expected a single ephemeral directory, found %d entries", len(ents))
+	}
+}
+
+// newExecMountCommand returns a mount command with the given exec commands.
+func newExecMountCommand(tb testing.TB, configs ...*ExecConfig) *MountCommand {
+	tb.Helper()
+
+	c := NewMountCommand()
+	c.Config.Exec = configs
+	c.Store = litefs.NewStore(tb.TempDir(), true)
+	return c
+}
+
+// startExecProcess executes the commands for the role & returns the subprocess.
+func startExecProcess(tb testing.TB, c *MountCommand, isPrimary bool) *execProcess {
+	tb.Helper()
+
+	proc, err := c.execCmds(context.Background(), isPrimary)
+	if err != nil {
+		tb.Fatal(err)
+	} else if proc == nil {
+		tb.Fatal("expected subprocess")
+	}
+	tb.Cleanup(func() {
+		if proc.cmd.ProcessState == nil {
+			_ = proc.cmd.Process.Kill()
+		}
+	})
+	return proc
+}
+
+func execConfigCmds(configs []*ExecConfig) []string {
+	a := make([]string, len(configs))
+	for i, config := range configs {
+		a[i] = config.Cmd
+	}
+	return a
+}
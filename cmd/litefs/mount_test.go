@@ -384,6 +384,39 @@ func TestSingleNode_ErrCommitWAL(t *testing.T) {
 	}
 }
 
+func TestSingleNode_TxLimit(t *testing.T) {
+	cmd0 := newMountCommand(t, t.TempDir(), nil)
+	cmd0.Config.FUSE.MaxTxPages = 10
+	runMountCommand(t, cmd0)
+	db := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	}
+	txID := cmd0.Store.DB("db").TXID()
+
+	// Ensure a transaction spanning more pages than the limit is rejected.
+	if _, err := db.Exec(`INSERT INTO t VALUES (randomblob(1 << 20))`); err == nil || !strings.Contains(err.Error(), "database or disk is full") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := cmd0.Store.DB("db").TXID(), txID; got != want {
+		t.Fatalf("TXID=%s, want %s", got, want)
+	}
+
+	// Ensure smaller transactions continue to succeed.
+	if _, err := db.Exec(`INSERT INTO t VALUES (200)`); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM t`).Scan(&n); err != nil {
+		t.Fatal(err)
+	} else if got, want := n, 2; got != want {
+		t.Fatalf("n=%d, want %d", got, want)
+	}
+}
+
 func TestSingleNode_BackupClient(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
 		cmd0 := newMountCommand(t, t.TempDir(), nil)
@@ -885,6 +918,172 @@ func TestMultiNode_Simple(t *testing.T) {
 	}
 }
 
+func TestMultiNode_StreamEncoding(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.HTTP.StreamEncoding = "lz4"
+	runMountCommand(t, cmd1)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db0.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the replica can decode the compressed stream.
+	waitForSync(t, "db", cmd0, cmd1)
+	db1 := testingutil.OpenSQLDB(t, filepath.Join(cmd1.Config.FUSE.Dir, "db"))
+	var x int
+	if err := db1.QueryRow(`SELECT x FROM t`).Scan(&x); err != nil {
+		t.Fatal(err)
+	} else if got, want := x, 100; got != want {
+		t.Fatalf("x=%d, want %d", got, want)
+	}
+
+	// Ensure subsequent transactions are streamed as they are committed.
+	if _, err := db0.Exec(`INSERT INTO t VALUES (200)`); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+	if err := db1.QueryRow(`SELECT MAX(x) FROM t`).Scan(&x); err != nil {
+		t.Fatal(err)
+	} else if got, want := x, 200; got != want {
+		t.Fatalf("x=%d, want %d", got, want)
+	}
+}
+
+func TestMultiNode_AuthToken(t *testing.T) {
+	cmd0 := newMountCommand(t, t.TempDir(), nil)
+	cmd0.Config.HTTP.AuthToken = "secret"
+	runMountCommand(t, cmd0)
+	waitForPrimary(t, cmd0)
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.HTTP.AuthToken = "secret"
+	runMountCommand(t, cmd1)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db0.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the replica can authorize with the primary.
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Ensure requests without a token are rejected.
+	resp, err := http.Get(cmd0.HTTPServer.URL() + "/info")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := resp.Body.Close(); err != nil {
+		t.Fatal(err)
+	} else if got, want := resp.StatusCode, http.StatusUnauthorized; got != want {
+		t.Fatalf("StatusCode=%d, want %d", got, want)
+	}
+}
+
+func TestMultiNode_SyncReplication(t *testing.T) {
+	cmd0 := newMountCommand(t, t.TempDir(), nil)
+	cmd0.Config.Lease.SyncReplication.Replicas = 1
+	runMountCommand(t, cmd0)
+	waitForPrimary(t, cmd0)
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.Lease.SyncReplication.Replicas = 1
+	runMountCommand(t, cmd1)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Ensure the replica has the transaction as soon as the commit returns.
+	for i := 0; i < 10; i++ {
+		if _, err := db0.Exec(`INSERT INTO t VALUES (?)`, i); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := cmd1.Store.DB("db").TXID(), cmd0.Store.DB("db").TXID(); got < want {
+			t.Fatalf("replica TXID=%s, want %s", got, want)
+		}
+	}
+}
+
+// Ensure commits that are not acknowledged succeed after the timeout &
+// do not block other connections while waiting.
+func TestSingleNode_SyncReplicationTimeout(t *testing.T) {
+	cmd0 := newMountCommand(t, t.TempDir(), nil)
+	cmd0.Config.Lease.SyncReplication.Replicas = 1
+	cmd0.Config.Lease.SyncReplication.Timeout = 500 * time.Millisecond
+	runMountCommand(t, cmd0)
+	waitForPrimary(t, cmd0)
+
+	dsn := filepath.Join(cmd0.Config.FUSE.Dir, "db")
+	db0 := testingutil.OpenSQLDB(t, dsn)
+	t0 := time.Now()
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if elapsed := time.Since(t0); elapsed < 500*time.Millisecond {
+		t.Fatalf("expected commit to wait for acknowledgment, elapsed=%s", elapsed)
+	}
+
+	// Read from a second connection while the commit waits.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := db0.Exec(`INSERT INTO t VALUES (1)`)
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	db1 := testingutil.OpenSQLDB(t, dsn)
+	t0 = time.Now()
+	var n int
+	if err := db1.QueryRow(`SELECT COUNT(*) FROM t`).Scan(&n); err != nil {
+		t.Fatal(err)
+	} else if elapsed := time.Since(t0); elapsed >= 300*time.Millisecond {
+		t.Fatalf("expected read to not wait on the commit, elapsed=%s", elapsed)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiNode_Backpressure(t *testing.T) {
+	cmd0 := newMountCommand(t, t.TempDir(), nil)
+	cmd0.Config.Lease.Backpressure.MaxLag = 1
+	cmd0.Config.Lease.Backpressure.Timeout = 500 * time.Millisecond
+	runMountCommand(t, cmd0)
+	waitForPrimary(t, cmd0)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Register a subscriber that never receives transactions.
+	sub := cmd0.Store.SubscribeChangeSet(100)
+	defer func() { _ = sub.Close() }()
+
+	// Ensure commits are delayed until the timeout while the replica lags.
+	t0 := time.Now()
+	if _, err := db0.Exec(`INSERT INTO t VALUES (1)`); err != nil {
+		t.Fatal(err)
+	} else if elapsed := time.Since(t0); elapsed < 500*time.Millisecond {
+		t.Fatalf("expected commit to be delayed, elapsed=%s", elapsed)
+	}
+
+	// Ensure commits are not delayed once the replica catches up.
+	sub.SetPosMap(map[string]ltx.Pos{"db": cmd0.Store.DB("db").Pos()})
+	t0 = time.Now()
+	if _, err := db0.Exec(`INSERT INTO t VALUES (2)`); err != nil {
+		t.Fatal(err)
+	} else if elapsed := time.Since(t0); elapsed >= 500*time.Millisecond {
+		t.Fatalf("unexpected commit delay, elapsed=%s", elapsed)
+	}
+}
+
 func TestMultiNode_Drop(t *testing.T) {
 	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
 	waitForPrimary(t, cmd0)
@@ -916,7 +1115,7 @@ func TestMultiNode_Drop(t *testing.T) {
 	if err := os.Remove(filepath.Join(cmd0.Config.FUSE.Dir, "db")); err != nil {
 		t.Fatal(err)
 	}
-	waitForSync(t, "db", cmd0, cmd1)
+	waitForDrop(t, "db", cmd0, cmd1)
 
 	// Ensure primary & replica database has been deleted.
 	if _, err := os.Stat(filepath.Join(cmd0.Config.FUSE.Dir, "db")); !os.IsNotExist(err) {
@@ -1159,6 +1358,45 @@ func TestMultiNode_DropDB(t *testing.T) {
 	}
 }
 
+func TestMultiNode_RenameDB(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+	cmd1 := runMountCommand(t, newMountCommand(t, t.TempDir(), cmd0))
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db0.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	} else if err := db0.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+	txID := cmd1.Store.DB("db").TXID()
+
+	// Rename the database on the primary.
+	if err := os.Rename(filepath.Join(cmd0.Config.FUSE.Dir, "db"), filepath.Join(cmd0.Config.FUSE.Dir, "db2")); err != nil {
+		t.Fatal(err)
+	}
+	waitForDrop(t, "db", cmd0, cmd1)
+	waitForSync(t, "db2", cmd0, cmd1)
+
+	// The replica should move its copy rather than receive a new snapshot.
+	if got, want := cmd1.Store.DB("db2").TXID(), txID; got != want {
+		t.Fatalf("TXID=%s, want %s", got, want)
+	} else if _, err := os.Stat(filepath.Join(cmd1.Config.FUSE.Dir, "db")); !os.IsNotExist(err) {
+		t.Fatalf("expected old database removed on replica, got %v", err)
+	}
+
+	var x int
+	db1 := testingutil.OpenSQLDB(t, filepath.Join(cmd1.Config.FUSE.Dir, "db2"))
+	if err := db1.QueryRow(`SELECT x FROM t`).Scan(&x); err != nil {
+		t.Fatal(err)
+	} else if got, want := x, 100; got != want {
+		t.Fatalf("x=%d, want %d", got, want)
+	}
+}
+
 func TestMultiNode_ForcedReelection(t *testing.T) {
 	dir0, dir1 := t.TempDir(), t.TempDir()
 	cmd0 := runMountCommand(t, newMountCommand(t, dir0, nil))
@@ -1668,6 +1906,46 @@ func TestMultiNode_Halt(t *testing.T) {
 		t.Logf("done")
 	})
 
+	// Ensure that writes on a replica are forwarded to the primary without
+	// the application acquiring the halt lock.
+	t.Run("WriteForwarding", func(t *testing.T) {
+		cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+		waitForPrimary(t, cmd0)
+		cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+		cmd1.Config.FUSE.WriteForwarding = true
+		runMountCommand(t, cmd1)
+		db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+
+		if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+			t.Fatal(err)
+		} else if _, err := db0.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+			t.Fatal(err)
+		}
+
+		waitForSync(t, "db", cmd0, cmd1)
+		db1 := testingutil.OpenSQLDB(t, filepath.Join(cmd1.Config.FUSE.Dir, "db"))
+
+		// Write to the replica directly.
+		if _, err := db1.Exec(`INSERT INTO t VALUES (200)`); err != nil {
+			t.Fatal(err)
+		} else if cmd1.Store.DB("db").HasRemoteHaltLock() {
+			t.Fatal("expected halt lock to be released after commit")
+		}
+
+		// Verify both nodes have been updated.
+		var sum int
+		if err := db1.QueryRow(`SELECT SUM(x) FROM t`).Scan(&sum); err != nil {
+			t.Fatal(err)
+		} else if got, want := sum, 300; got != want {
+			t.Fatalf("sum=%d, want %d", got, want)
+		}
+		if err := db0.QueryRow(`SELECT SUM(x) FROM t`).Scan(&sum); err != nil {
+			t.Fatal(err)
+		} else if got, want := sum, 300; got != want {
+			t.Fatalf("sum=%d, want %d", got, want)
+		}
+	})
+
 	// Ensure that closing a file handle with a halt lock will unhalt the node.
 	t.Run("ImplicitUnhalt", func(t *testing.T) {
 		cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
@@ -2044,6 +2322,107 @@ func TestMultiNode_DatabaseFilter(t *testing.T) {
 	}
 }
 
+func TestMultiNode_ExcludeDatabases(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.Lease.Candidate = false
+	cmd1.Config.Lease.Databases = []string{"*.db"}
+	cmd1.Config.Lease.ExcludeDatabases = []string{"tmp-*"}
+	runMountCommand(t, cmd1)
+
+	for _, name := range []string{"tmp-1.db", "app.db"} {
+		db := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, name))
+		if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+			t.Fatal(err)
+		} else if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitForSync(t, "app.db", cmd0, cmd1)
+
+	// Only the database matching the include pattern should exist.
+	if _, err := os.Stat(filepath.Join(cmd1.Config.FUSE.Dir, "app.db")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cmd1.Config.FUSE.Dir, "tmp-1.db")); !os.IsNotExist(err) {
+		t.Fatal("expected excluded database to not exist on replica")
+	}
+}
+
+func TestMultiNode_Info(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+	cmd1 := runMountCommand(t, newMountCommand(t, t.TempDir(), cmd0))
+
+	db := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Replica should report the primary's URL & no lease.
+	client := cmd0.HTTPServer.Client
+	info, err := client.Info(context.Background(), cmd1.HTTPServer.URL())
+	if err != nil {
+		t.Fatal(err)
+	} else if info.IsPrimary || info.Lease != nil {
+		t.Fatalf("unexpected replica info: %#v", info)
+	} else if got, want := info.Primary.AdvertiseURL, cmd0.Config.Lease.AdvertiseURL; got != want {
+		t.Fatalf("Primary.AdvertiseURL=%q, want %q", got, want)
+	}
+
+	// Primary should report its lease & the connected replica's position.
+	info, err = client.Info(context.Background(), cmd0.HTTPServer.URL())
+	if err != nil {
+		t.Fatal(err)
+	} else if !info.IsPrimary || info.Lease == nil {
+		t.Fatalf("unexpected primary info: %#v", info)
+	} else if got, want := len(info.Replicas), 1; got != want {
+		t.Fatalf("len(Replicas)=%d, want %d", got, want)
+	} else if got, want := info.Replicas[0].ID, litefs.FormatNodeID(cmd1.Store.ID()); got != want {
+		t.Fatalf("Replicas[0].ID=%s, want %s", got, want)
+	} else if got, want := info.Replicas[0].TXIDs["db"], cmd0.Store.DB("db").TXID(); got != want {
+		t.Fatalf("Replicas[0].TXIDs[db]=%s, want %s", got, want)
+	}
+}
+
+func TestMultiNode_ChecksumVerifyResync(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.Data.ChecksumVerifyInterval = 100 * time.Millisecond
+	cmd1.Config.Data.ChecksumVerifyResync = true
+	runMountCommand(t, cmd1)
+
+	db := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Corrupt the replica's copy underneath LiteFS.
+	f, err := os.OpenFile(cmd1.Store.DB("db").DatabasePath(), os.O_RDWR, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := f.WriteAt([]byte{0xFF}, 4096+100); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replica should detect the mismatch & resync from a snapshot.
+	testingutil.RetryUntil(t, 50*time.Millisecond, 10*time.Second, func() error {
+		if _, err := cmd1.Store.DB("db").Verify(context.Background()); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
 func TestMultiNode_StaticLeaser(t *testing.T) {
 	dir0, dir1 := t.TempDir(), t.TempDir()
 	cmd0 := newMountCommand(t, dir0, nil)
@@ -2110,6 +2489,108 @@ func TestMultiNode_StaticLeaser(t *testing.T) {
 	waitForPrimary(t, cmd0)
 }
 
+func TestMultiNode_Ephemeral(t *testing.T) {
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db0.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	}
+
+	dir1 := t.TempDir()
+	cmd1 := newMountCommand(t, dir1, cmd0)
+	cmd1.Config.Data.Ephemeral = true
+	cmd1.Config.Lease.Candidate = false
+	runMountCommand(t, cmd1)
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Verify the replica bootstraps into a temporary data directory.
+	parent := filepath.Join(dir1, "data")
+	if got := cmd1.Store.Path(); filepath.Dir(got) != parent {
+		t.Fatalf("unexpected store path: %s", got)
+	}
+
+	db1 := testingutil.OpenSQLDB(t, filepath.Join(cmd1.Config.FUSE.Dir, "db"))
+	var x int
+	if err := db1.QueryRow(`SELECT x FROM t`).Scan(&x); err != nil {
+		t.Fatal(err)
+	} else if got, want := x, 100; got != want {
+		t.Fatalf("x=%d, want %d", got, want)
+	}
+
+	// Verify no state is left behind after shutdown.
+	if err := db1.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := cmd1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ents, err := os.ReadDir(parent); err != nil {
+		t.Fatal(err)
+	} else if len(ents) != 0 {
+		t.Fatalf("expected empty data directory, found %d entries", len(ents))
+	}
+}
+
+func TestMultiNode_Readiness(t *testing.T) {
+	getStatus := func(tb testing.TB, url string) int {
+		tb.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			tb.Fatal(err)
+		} else if err := resp.Body.Close(); err != nil {
+			tb.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+	waitForPrimary(t, cmd0)
+
+	cmd1 := newMountCommand(t, t.TempDir(), cmd0)
+	cmd1.Config.HTTP.Readiness.RequireConnected = true
+	cmd1.Config.HTTP.Readiness.MaxLag = 5 * time.Second
+	runMountCommand(t, cmd1)
+
+	db0 := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+	if _, err := db0.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+	waitForSync(t, "db", cmd0, cmd1)
+
+	// Ensure both nodes report as healthy & ready.
+	for _, cmd := range []*main.MountCommand{cmd0, cmd1} {
+		if got, want := getStatus(t, cmd.HTTPServer.URL()+"/healthz"), http.StatusOK; got != want {
+			t.Fatalf("healthz: StatusCode=%d, want %d", got, want)
+		} else if got, want := getStatus(t, cmd.HTTPServer.URL()+"/readyz"), http.StatusOK; got != want {
+			t.Fatalf("readyz: StatusCode=%d, want %d", got, want)
+		}
+	}
+
+	// Shutdown the primary & ensure the replica is no longer ready.
+	if err := db0.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := cmd0.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; ; i++ {
+		if getStatus(t, cmd1.HTTPServer.URL()+"/readyz") == http.StatusServiceUnavailable {
+			break
+		} else if i > 50 {
+			t.Fatal("replica still ready after primary shutdown")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The replica is still healthy.
+	if got, want := getStatus(t, cmd1.HTTPServer.URL()+"/healthz"), http.StatusOK; got != want {
+		t.Fatalf("healthz: StatusCode=%d, want %d", got, want)
+	}
+}
+
 func TestMultiNode_EnforceRetention(t *testing.T) {
 	// Ensure files can be removed when they are older than the retention period.
 	t.Run("Expiry", func(t *testing.T) {
@@ -2533,8 +3014,18 @@ func TestEventStream(t *testing.T) {
 
 		dec := json.NewDecoder(resp.Body)
 
+		// Skip database creation events since the replica creates the
+		// database when it receives the first transaction.
+		decode := func(event *litefs.Event) error {
+			for {
+				if err := dec.Decode(event); err != nil || event.Type != litefs.EventTypeCreateDB {
+					return err
+				}
+			}
+		}
+
 		var event litefs.Event
-		if err := dec.Decode(&event); err != nil {
+		if err := decode(&event); err != nil {
 			t.Fatal(err)
 		} else if got, want := event.Type, "init"; got != want {
 			t.Fatalf("type=%s, want %s", got, want)
@@ -2544,7 +3035,7 @@ func TestEventStream(t *testing.T) {
 		if testingutil.IsWALMode() {
 			offset = 1
 
-			if err := dec.Decode(&event); err != nil {
+			if err := decode(&event); err != nil {
 				t.Fatal(err)
 			} else if got, want := event.Type, "tx"; got != want {
 				t.Fatalf("type=%s, want %s", got, want)
@@ -2558,7 +3049,7 @@ func TestEventStream(t *testing.T) {
 		if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
 			t.Fatal(err)
 		}
-		if err := dec.Decode(&event); err != nil {
+		if err := decode(&event); err != nil {
 			t.Fatal(err)
 		} else if got, want := event.Type, "tx"; got != want {
 			t.Fatalf("type=%s, want %s", got, want)
@@ -2571,7 +3062,7 @@ func TestEventStream(t *testing.T) {
 		if _, err := db.Exec(`INSERT INTO t VALUES (100)`); err != nil {
 			t.Fatal(err)
 		}
-		if err := dec.Decode(&event); err != nil {
+		if err := decode(&event); err != nil {
 			t.Fatal(err)
 		} else if got, want := event.Type, "tx"; got != want {
 			t.Fatalf("type=%s, want %s", got, want)
@@ -2581,6 +3072,39 @@ func TestEventStream(t *testing.T) {
 			t.Fatalf("data.txid=%s, want %s", got, want)
 		}
 	})
+
+	t.Run("CreateDB/Replica", func(t *testing.T) {
+		cmd0 := runMountCommand(t, newMountCommand(t, t.TempDir(), nil))
+		waitForPrimary(t, cmd0)
+		cmd1 := runMountCommand(t, newMountCommand(t, t.TempDir(), cmd0))
+
+		resp, err := http.Get(cmd1.HTTPServer.URL() + "/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		dec := json.NewDecoder(resp.Body)
+
+		var event litefs.Event
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		} else if got, want := event.Type, "init"; got != want {
+			t.Fatalf("type=%s, want %s", got, want)
+		}
+
+		db := testingutil.OpenSQLDB(t, filepath.Join(cmd0.Config.FUSE.Dir, "db"))
+		if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		} else if got, want := event.Type, "createDB"; got != want {
+			t.Fatalf("type=%s, want %s", got, want)
+		} else if got, want := event.DB, "db"; got != want {
+			t.Fatalf("db=%s, want %s", got, want)
+		}
+	})
 }
 
 // Ensure multiple nodes can run in a cluster for an extended period of time.
@@ -2723,6 +3247,137 @@ func TestMountCommand_Validate(t *testing.T) {
 			t.Fatalf("unexpected error: %s", err)
 		}
 	})
+	t.Run("ErrSharedDataDir", func(t *testing.T) {
+		cmd := main.NewMountCommand()
+		cmd.Config.FUSE.Dir, cmd.Config.Data.Dir = t.TempDir(), t.TempDir()
+		cmd.Config.Lease.Type = "static"
+		m := main.NewMountCommand()
+		m.Config.FUSE.Dir, m.Config.Data.Dir = t.TempDir(), cmd.Config.Data.Dir
+		m.Config.HTTP.Addr = ":20203"
+		m.Config.Lease.Type = "static"
+		cmd.Mounts = append(cmd.Mounts, m)
+		if err := cmd.Validate(context.Background()); err == nil || err.Error() != `data directory used by multiple mounts: `+m.Config.Data.Dir {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+	t.Run("ErrLogConfigMismatch", func(t *testing.T) {
+		cmd := main.NewMountCommand()
+		cmd.Config.FUSE.Dir, cmd.Config.Data.Dir = t.TempDir(), t.TempDir()
+		cmd.Config.Lease.Type = "static"
+		m := main.NewMountCommand()
+		m.Config.FUSE.Dir, m.Config.Data.Dir = t.TempDir(), t.TempDir()
+		m.Config.HTTP.Addr = ":20203"
+		m.Config.Lease.Type = "static"
+		m.Config.Log.Levels = map[string]string{"store": "debug"}
+		cmd.Mounts = append(cmd.Mounts, m)
+		if err := cmd.Validate(context.Background()); err == nil || !strings.HasPrefix(err.Error(), `log config must be the same for all mounts`) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func TestMountCommand_ParseFlags(t *testing.T) {
+	t.Run("MultipleConfigs", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"a", "b"} {
+			config := fmt.Sprintf("fuse:\n  dir: %q\ndata:\n  dir: %q\nlease:\n  type: static\n", filepath.Join(dir, name, "mnt"), filepath.Join(dir, name, "data"))
+			if err := os.WriteFile(filepath.Join(dir, name+".yml"), []byte(config), 0o666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := main.NewMountCommand()
+		if err := cmd.ParseFlags(context.Background(), []string{"-config", filepath.Join(dir, "a.yml"), "-config", filepath.Join(dir, "b.yml"), "-debug"}); err != nil {
+			t.Fatal(err)
+		} else if got, want := len(cmd.All()), 2; got != want {
+			t.Fatalf("len=%d, want %d", got, want)
+		}
+		if got, want := cmd.Config.Data.Dir, filepath.Join(dir, "a", "data"); got != want {
+			t.Fatalf("Data.Dir=%s, want %s", got, want)
+		} else if got, want := cmd.Mounts[0].Config.Data.Dir, filepath.Join(dir, "b", "data"); got != want {
+			t.Fatalf("Data.Dir=%s, want %s", got, want)
+		} else if !cmd.Mounts[0].Config.Log.Debug {
+			t.Fatal("expected debug flag to apply to all mounts")
+		}
+
+		// Separate mounts share the default HTTP address.
+		if err := cmd.Validate(context.Background()); err == nil || err.Error() != `http address used by multiple mounts: :20202` {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func TestMountCommand_Reload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "litefs.yml")
+	writeConfig := func(tb testing.TB, extra string) {
+		tb.Helper()
+		config := fmt.Sprintf("fuse:\n  dir: %q\ndata:\n  dir: %q\n  retention: 1m\nlog:\n  debug: true\nlease:\n  type: static\n%s", filepath.Join(dir, "mnt"), filepath.Join(dir, "data"), extra)
+		if err := os.WriteFile(path, []byte(config), 0o666); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	writeConfig(t, "  candidate: true\n")
+
+	cmd := main.NewMountCommand()
+	if err := cmd.ParseFlags(context.Background(), []string{"-config", path}); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Store = litefs.NewStore(cmd.Config.Data.Dir, cmd.Config.Lease.Candidate)
+
+	t.Run("OK", func(t *testing.T) {
+		writeConfig(t, "  candidate: false\n  databases: [\"app-*.db\"]\n")
+		if err := cmd.Reload(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Store.Candidate() {
+			t.Fatal("expected store to no longer be a candidate")
+		} else if got, want := cmd.Store.DatabaseFilter, []string{"app-*.db"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("DatabaseFilter=%v, want %v", got, want)
+		} else if retention, _, _ := cmd.Store.RetentionPolicy(); retention != 1*time.Minute {
+			t.Fatalf("Retention=%s, want %s", retention, 1*time.Minute)
+		}
+	})
+
+	// Invalid configs should be rejected without changing the running settings.
+	t.Run("ErrInvalidConfig", func(t *testing.T) {
+		writeConfig(t, "  candidate: false\n  databases: [\"![\"]\n")
+		if err := cmd.Reload(context.Background()); err == nil {
+			t.Fatal("expected error")
+		} else if got, want := cmd.Store.DatabaseFilter, []string{"app-*.db"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("DatabaseFilter=%v, want %v", got, want)
+		}
+	})
+
+	// Additional mounts cannot change the process-wide log levels.
+	t.Run("ErrLogLevelsMismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfig := func(tb testing.TB, name, extra string) {
+			tb.Helper()
+			config := fmt.Sprintf("fuse:\n  dir: %q\ndata:\n  dir: %q\nlease:\n  type: static\nlog:\n  levels:\n%s", filepath.Join(dir, name, "mnt"), filepath.Join(dir, name, "data"), extra)
+			if err := os.WriteFile(filepath.Join(dir, name+".yml"), []byte(config), 0o666); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		writeConfig(t, "a", "    store: warn\n")
+		writeConfig(t, "b", "    store: warn\n")
+
+		cmd := main.NewMountCommand()
+		if err := cmd.ParseFlags(context.Background(), []string{"-config", filepath.Join(dir, "a.yml"), "-config", filepath.Join(dir, "b.yml")}); err != nil {
+			t.Fatal(err)
+		}
+		m := cmd.Mounts[0]
+		if err := m.Reload(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		writeConfig(t, "b", "    store: debug\n")
+		if err := m.Reload(context.Background()); err == nil || !strings.HasPrefix(err.Error(), `log levels must be the same for all mounts`) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := m.Config.Log.Levels["store"], "warn"; got != want {
+			t.Fatalf("Levels[store]=%s, want %s", got, want)
+		}
+	})
 }
 
 //go:embed etc/litefs.yml
@@ -2737,21 +3392,69 @@ func TestUnmarshalConfig(t *testing.T) {
 		if got, want := config.Data.Dir, "/var/lib/litefs"; got != want {
 			t.Fatalf("FUSE.Dir=%s, want %s", got, want)
 		}
+		if got, want := config.Data.StartupIntegrityCheck, "none"; got != want {
+			t.Fatalf("Data.StartupIntegrityCheck=%s, want %s", got, want)
+		}
+		if got, want := config.Data.ChecksumVerifyInterval, time.Duration(0); got != want {
+			t.Fatalf("Data.ChecksumVerifyInterval=%s, want %s", got, want)
+		}
 		if got, want := config.FUSE.Dir, "/litefs"; got != want {
 			t.Fatalf("FUSE.Dir=%s, want %s", got, want)
 		}
 		if got, want := config.FUSE.Debug, false; got != want {
 			t.Fatalf("Debug=%v, want %v", got, want)
 		}
+		if got, want := config.FUSE.CacheInvalidation, "per-page"; got != want {
+			t.Fatalf("CacheInvalidation=%s, want %s", got, want)
+		}
+		if got, want := config.FUSE.WriteForwarding, false; got != want {
+			t.Fatalf("WriteForwarding=%v, want %v", got, want)
+		}
 		if got, want := config.HTTP.Addr, ":20202"; got != want {
 			t.Fatalf("HTTP.Addr=%s, want %s", got, want)
 		}
+		if got, want := config.HTTP.StreamEncoding, ""; got != want {
+			t.Fatalf("HTTP.StreamEncoding=%s, want %s", got, want)
+		}
+		if got, want := config.HTTP.AuthToken, ""; got != want {
+			t.Fatalf("HTTP.AuthToken=%s, want %s", got, want)
+		}
+		if got, want := config.HTTP.TLSCertFile, ""; got != want {
+			t.Fatalf("HTTP.TLSCertFile=%s, want %s", got, want)
+		}
 		if got, want := config.Lease.Type, "consul"; got != want {
 			t.Fatalf("Lease.Type=%s, want %s", got, want)
 		}
 		if got, want := config.Lease.Hostname, "myhost"; got != want {
 			t.Fatalf("Lease.Hostname=%s, want %s", got, want)
 		}
+		if got, want := config.Lease.HeartbeatInterval, 10*time.Second; got != want {
+			t.Fatalf("Lease.HeartbeatInterval=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.ResyncMode, "fail"; got != want {
+			t.Fatalf("Lease.ResyncMode=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.SyncReplication.Replicas, 0; got != want {
+			t.Fatalf("Lease.SyncReplication.Replicas=%d, want %d", got, want)
+		}
+		if got, want := config.Lease.SyncReplication.Timeout, 5*time.Second; got != want {
+			t.Fatalf("Lease.SyncReplication.Timeout=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.Backpressure.MaxLag, 0; got != want {
+			t.Fatalf("Lease.Backpressure.MaxLag=%d, want %d", got, want)
+		}
+		if got, want := config.Lease.Backpressure.Timeout, 5*time.Second; got != want {
+			t.Fatalf("Lease.Backpressure.Timeout=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.Halt.AcquireTimeout, 10*time.Second; got != want {
+			t.Fatalf("Lease.Halt.AcquireTimeout=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.Halt.TTL, 30*time.Second; got != want {
+			t.Fatalf("Lease.Halt.TTL=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.PriorityDelay, 1*time.Second; got != want {
+			t.Fatalf("Lease.PriorityDelay=%s, want %s", got, want)
+		}
 		if got, want := config.Lease.AdvertiseURL, "http://myhost:20202"; got != want {
 			t.Fatalf("Lease.AdvertiseURL=%s, want %s", got, want)
 		}
@@ -2767,9 +3470,24 @@ func TestUnmarshalConfig(t *testing.T) {
 		if got, want := config.Lease.Consul.LockDelay, 1*time.Second; got != want {
 			t.Fatalf("Lease.Consul.LockDelay=%s, want %s", got, want)
 		}
+		if got, want := config.Lease.Etcd.URL, "http://myhost:2379"; got != want {
+			t.Fatalf("Lease.Etcd.URL=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.Etcd.TTL, 10*time.Second; got != want {
+			t.Fatalf("Lease.Etcd.TTL=%s, want %s", got, want)
+		}
+		if got, want := config.Lease.Consul.AcquireDelay, time.Duration(0); got != want {
+			t.Fatalf("Lease.Consul.AcquireDelay=%s, want %s", got, want)
+		}
 		if got, want := config.Lease.Candidate, true; got != want {
 			t.Fatalf("Lease.Candidate=%v, want %v", got, want)
 		}
+		if got, want := config.Log.Format, "text"; got != want {
+			t.Fatalf("Log.Format=%s, want %s", got, want)
+		}
+		if got, want := config.Log.Levels, map[string]string{"store": "debug", "fuse": "warn"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Log.Levels=%v, want %v", got, want)
+		}
 	})
 
 	t.Run("ErrUnknownField", func(t *testing.T) {
@@ -2920,6 +3638,22 @@ func waitForSync(tb testing.TB, name string, cmds ...*main.MountCommand) {
 	})
 }
 
+// waitForDrop waits for a database to be removed from all cmds.
+func waitForDrop(tb testing.TB, name string, cmds ...*main.MountCommand) {
+	tb.Helper()
+
+	testingutil.RetryUntil(tb, 1*time.Millisecond, 5*time.Second, func() error {
+		tb.Helper()
+
+		for i, cmd := range cmds {
+			if cmd.Store.DB(name) != nil {
+				return fmt.Errorf("waiting for drop on db(%d)", i)
+			}
+		}
+		return nil
+	})
+}
+
 // waitForBackupSync waits for the backup state to match the cmd.
 func waitForBackupSync(tb testing.TB, cmd *main.MountCommand) {
 	tb.Helper()
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/superfly/litefs/http"
+)
+
+// NodesCommand represents a command to list the replicas connected to the primary.
+type NodesCommand struct {
+	// Target LiteFS URL. Requests are redirected to the primary.
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewNodesCommand returns a new instance of NodesCommand.
+func NewNodesCommand() *NodesCommand {
+	return &NodesCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *NodesCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-nodes", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The nodes command lists the replicas connected to the primary and how many
+transactions each replica is behind for every database. If the URL refers to a
+replica then the primary is looked up & queried instead.
+
+Usage:
+
+	litefs nodes [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *NodesCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+
+	// Find the primary, if this node is not the primary.
+	primaryURL := c.URL
+	info, err := client.Info(ctx, primaryURL)
+	if err != nil {
+		return err
+	} else if !info.IsPrimary {
+		if primaryURL = info.Primary.AdvertiseURL; primaryURL == "" {
+			return fmt.Errorf("no primary available")
+		}
+		if info, err = client.Info(ctx, primaryURL); err != nil {
+			return fmt.Errorf("primary: %w", err)
+		}
+	}
+
+	posMap, err := client.Pos(ctx, primaryURL)
+	if err != nil {
+		return fmt.Errorf("primary: %w", err)
+	}
+
+	names := make([]string, 0, len(posMap))
+	for name := range posMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tDATABASE\tTXID\tBEHIND")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\tprimary\t%s\t%s\t-\n", info.ID, name, posMap[name].TXID)
+	}
+	for _, replica := range info.Replicas {
+		for _, name := range names {
+			txID, primaryTXID := replica.TXIDs[name], posMap[name].TXID
+			behind := uint64(0)
+			if primaryTXID > txID {
+				behind = uint64(primaryTXID - txID)
+			}
+			fmt.Fprintf(w, "%s\treplica\t%s\t%s\t%d\n", replica.ID, name, txID, behind)
+		}
+	}
+	return w.Flush()
+}
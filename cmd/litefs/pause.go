@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/superfly/litefs/http"
+)
+
+// PauseCommand represents a command to pause replication on a replica.
+type PauseCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewPauseCommand returns a new instance of PauseCommand.
+func NewPauseCommand() *PauseCommand {
+	return &PauseCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *PauseCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-pause", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The pause command stops a replica from applying changes from the primary so its
+databases stay at a fixed position, such as while taking a filesystem backup.
+Changes received while paused are spooled to the data directory & applied once
+"litefs resume" is run. Replicas using synchronous replication cannot be paused
+and a paused replica resumes automatically if it becomes the primary.
+
+Usage:
+
+	litefs pause [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *PauseCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	if err := client.PauseReplication(ctx, c.URL); err != nil {
+		return err
+	}
+
+	fmt.Println("Replication paused")
+	return nil
+}
+
+// ResumeCommand represents a command to resume replication on a paused replica.
+type ResumeCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewResumeCommand returns a new instance of ResumeCommand.
+func NewResumeCommand() *ResumeCommand {
+	return &ResumeCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *ResumeCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-resume", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The resume command resumes replication on a replica paused by "litefs pause".
+
+Usage:
+
+	litefs resume [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *ResumeCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	if err := client.ResumeReplication(ctx, c.URL); err != nil {
+		return err
+	}
+
+	fmt.Println("Replication resumed")
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/superfly/litefs/http"
+)
+
+// ReloadCommand represents a command to reload the config of a running mount.
+type ReloadCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewReloadCommand returns a new instance of ReloadCommand.
+func NewReloadCommand() *ReloadCommand {
+	return &ReloadCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *ReloadCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-reload", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The reload command causes a running mount to re-read its config file and apply
+changes to the lease candidate flag, the replicated databases, the LTX retention
+policy, and the log level without unmounting. This is equivalent to sending a
+SIGHUP to the litefs process.
+
+Usage:
+
+	litefs reload [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *ReloadCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	if err := client.Reload(ctx, c.URL); err != nil {
+		return err
+	}
+
+	fmt.Println("Config reloaded")
+	return nil
+}
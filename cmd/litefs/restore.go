@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/internal"
+	"github.com/superfly/litefs/s3"
+	"github.com/superfly/ltx"
+)
+
+// RestoreCommand represents a command to restore a database to a point in time.
+type RestoreCommand struct {
+	// Name of database to restore.
+	Name string
+
+	// Path to write the restored database to.
+	Path string
+
+	// Source of LTX files. One of these must be set.
+	DataDir    string // LiteFS data directory
+	BackupPath string // path used by a "file" backup
+	S3Bucket   string // bucket used by an "s3" backup
+
+	// Additional S3 settings. Credentials are read from the environment.
+	S3Prefix   string
+	S3Endpoint string
+	S3Region   string
+
+	// Point in time to restore to. Restores latest position if both are zero.
+	TXID      ltx.TXID
+	Timestamp time.Time
+}
+
+// NewRestoreCommand returns a new instance of RestoreCommand.
+func NewRestoreCommand() *RestoreCommand {
+	return &RestoreCommand{}
+}
+
+// ParseFlags parses the command line flags.
+func (c *RestoreCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-restore", flag.ContinueOnError)
+	fs.StringVar(&c.Name, "name", "", "database name")
+	fs.StringVar(&c.DataDir, "data-dir", "", "LiteFS data directory to read LTX files from")
+	fs.StringVar(&c.BackupPath, "backup-path", "", "file backup directory to read LTX files from")
+	fs.StringVar(&c.S3Bucket, "s3-bucket", "", "S3 bucket to read LTX files from")
+	fs.StringVar(&c.S3Prefix, "s3-prefix", "", "S3 key prefix")
+	fs.StringVar(&c.S3Endpoint, "s3-endpoint", "", "S3 endpoint URL")
+	fs.StringVar(&c.S3Region, "s3-region", os.Getenv("AWS_REGION"), "S3 region")
+	txid := fs.String("txid", "", "restore up to and including this transaction ID")
+	timestamp := fs.String("timestamp", "", "restore transactions committed at or before this RFC 3339 time")
+	fs.Usage = func() {
+		fmt.Println(`
+The restore command will reconstruct a database at a specific transaction ID or
+timestamp by replaying LTX files onto the latest snapshot before that point.
+The restored database is written to PATH and the running cluster is not changed.
+
+A data directory only retains a snapshot until retention removes it so restoring
+with -data-dir usually requires a backup to be used instead.
+
+Usage:
+
+	litefs restore [arguments] PATH
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	} else if fs.NArg() > 1 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	// Copy first arg as database path.
+	c.Path = fs.Arg(0)
+
+	if *txid != "" {
+		if c.TXID, err = ltx.ParseTXID(*txid); err != nil {
+			return fmt.Errorf("invalid -txid: %w", err)
+		}
+	}
+	if *timestamp != "" {
+		if c.Timestamp, err = time.Parse(time.RFC3339Nano, *timestamp); err != nil {
+			return fmt.Errorf("invalid -timestamp: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Run executes the command.
+func (c *RestoreCommand) Run(ctx context.Context) (err error) {
+	if c.Name == "" {
+		return fmt.Errorf("database name required")
+	}
+
+	src, err := c.source()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := c.Path + ".tmp"
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	t := time.Now()
+
+	pos, err := litefs.Restore(ctx, src, c.Name, f, litefs.RestoreOptions{
+		TXID:      c.TXID,
+		Timestamp: c.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Sync & close file.
+	if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Clear the related files of any existing database so they are not applied
+	// to the restored one. The database file itself is replaced by the rename.
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(c.Path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Atomically rename & sync parent directory.
+	if err := os.Rename(tmpPath, c.Path); err != nil {
+		return err
+	} else if err := internal.Sync(filepath.Dir(c.Path)); err != nil {
+		return err
+	}
+
+	// Notify user of success and elapsed time.
+	fmt.Printf("Restore of database %q to %s in %s\n", c.Name, pos, time.Since(t))
+
+	return nil
+}
+
+// source returns the restore source specified by the flags.
+func (c *RestoreCommand) source() (litefs.RestoreSource, error) {
+	switch {
+	case c.DataDir != "":
+		return litefs.NewDataDirRestoreSource(c.DataDir), nil
+
+	case c.BackupPath != "":
+		client := litefs.NewFileBackupClient(c.BackupPath)
+		if err := client.Open(); err != nil {
+			return nil, fmt.Errorf("open file backup client: %w", err)
+		}
+		return client, nil
+
+	case c.S3Bucket != "":
+		client := s3.NewBackupClient(c.S3Bucket, c.S3Prefix)
+		client.Endpoint = c.S3Endpoint
+		if c.S3Region != "" {
+			client.Region = c.S3Region
+		}
+		client.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		client.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		client.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		if err := client.Open(); err != nil {
+			return nil, fmt.Errorf("open s3 backup client: %w", err)
+		}
+		return client, nil
+
+	default:
+		return nil, fmt.Errorf("restore source required: -data-dir, -backup-path, or -s3-bucket")
+	}
+}
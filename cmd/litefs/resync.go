@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/superfly/litefs/http"
+)
+
+// ResyncCommand represents a command to resync a replica database from the primary.
+type ResyncCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+
+	// Name of database to resync.
+	Name string
+}
+
+// NewResyncCommand returns a new instance of ResyncCommand.
+func NewResyncCommand() *ResyncCommand {
+	return &ResyncCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *ResyncCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-resync", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.StringVar(&c.Name, "name", "", "database name")
+	fs.Usage = func() {
+		fmt.Println(`
+The resync command discards a replica's copy of a database and replaces it with
+a snapshot from the primary. This also resumes replication to a database that
+was suspended because it diverged from the primary.
+
+Usage:
+
+	litefs resync [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	} else if c.Name == "" {
+		return fmt.Errorf("database name required")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *ResyncCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	if err := client.Resync(ctx, c.URL, c.Name); err != nil {
+		return err
+	}
+
+	fmt.Printf("Resync of database %q requested\n", c.Name)
+	return nil
+}
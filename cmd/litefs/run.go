@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/superfly/litefs"
-	litefsgo "github.com/superfly/litefs-go"
 	"github.com/superfly/litefs/http"
 )
 
@@ -34,6 +33,9 @@ type RunCommand struct {
 	// Target LiteFS URL
 	URL string
 
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+
 	// If true, enables verbose logging.
 	Verbose bool
 }
@@ -50,6 +52,7 @@ func (c *RunCommand) ParseFlags(ctx context.Context, args []string) (err error)
 
 	fs := flag.NewFlagSet("litefs-run", flag.ContinueOnError)
 	fs.StringVar(&c.URL, "url", "http://localhost:20202", "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
 	fs.BoolVar(&c.Promote, "promote", false, "promote node to primary")
 	fs.BoolVar(&c.IfCandidate, "if-candidate", false, "only execute if node is a candidate")
 	fs.StringVar(&c.WithHaltLockOn, "with-halt-lock-on", "", "full database path to halt")
@@ -94,6 +97,7 @@ Arguments:
 // Run executes the command.
 func (c *RunCommand) Run(ctx context.Context) (err error) {
 	client := http.NewClient()
+	client.AuthToken = c.AuthToken
 
 	// It doesn't make any sense to promote the node and then acquire a halt
 	// lock since that is a no-op on the primary.
@@ -152,7 +156,7 @@ func (c *RunCommand) Run(ctx context.Context) (err error) {
 
 		t := time.Now()
 		log.Printf("acquiring halt lock")
-		if err := litefsgo.Halt(f); err != nil {
+		if err := halt(f); err != nil {
 			return err
 		}
 		log.Printf("halt lock acquired in %s", time.Since(t))
@@ -174,7 +178,7 @@ func (c *RunCommand) Run(ctx context.Context) (err error) {
 	if f != nil {
 		t := time.Now()
 		log.Printf("releasing halt lock")
-		if err := litefsgo.Unhalt(f); err != nil {
+		if err := unhalt(f); err != nil {
 			return err
 		}
 		log.Printf("halt lock released in %s", time.Since(t))
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/superfly/litefs/http"
+)
+
+// StatusCommand represents a command to report the replication status of a node.
+type StatusCommand struct {
+	// Target LiteFS URL
+	URL string
+
+	// Bearer token used to authorize with the LiteFS API, if required.
+	AuthToken string
+}
+
+// NewStatusCommand returns a new instance of StatusCommand.
+func NewStatusCommand() *StatusCommand {
+	return &StatusCommand{
+		URL: DefaultURL,
+	}
+}
+
+// ParseFlags parses the command line flags.
+func (c *StatusCommand) ParseFlags(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("litefs-status", flag.ContinueOnError)
+	fs.StringVar(&c.URL, "url", DefaultURL, "LiteFS API URL")
+	fs.StringVar(&c.AuthToken, "auth-token", "", "LiteFS API auth token")
+	fs.Usage = func() {
+		fmt.Println(`
+The status command reports the current primary, the replication lag, and the
+lease state of a LiteFS node.
+
+Usage:
+
+	litefs status [arguments]
+
+Arguments:
+`[1:])
+		fs.PrintDefaults()
+		fmt.Println("")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		return fmt.Errorf("too many arguments")
+	}
+	return nil
+}
+
+// Run executes the command.
+func (c *StatusCommand) Run(ctx context.Context) (err error) {
+	client := http.NewClient()
+	client.AuthToken = c.AuthToken
+	info, err := client.Info(ctx, c.URL)
+	if err != nil {
+		return err
+	}
+
+	role := "replica"
+	if info.IsPrimary {
+		role = "primary"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Node:\t%s (%s)\n", info.ID, role)
+	fmt.Fprintf(w, "Hostname:\t%s\n", info.Hostname)
+	fmt.Fprintf(w, "Cluster:\t%s\n", info.ClusterID)
+	fmt.Fprintf(w, "Candidate:\t%v\n", info.Candidate)
+	fmt.Fprintf(w, "Primary:\t%s\n", info.Primary.Hostname)
+	if !info.IsPrimary {
+		fmt.Fprintf(w, "Lag:\t%s\n", time.Duration(info.Lag)*time.Millisecond)
+		fmt.Fprintf(w, "Paused:\t%v\n", info.Paused)
+	}
+	if info.Lease != nil {
+		if info.Lease.ID == "" {
+			fmt.Fprintf(w, "Lease:\tstatic\n") // static leases never expire
+		} else {
+			fmt.Fprintf(w, "Lease:\t%s (expires in %s)\n", info.Lease.ID, time.Until(info.Lease.ExpiresAt).Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "Epoch:\t%d\n", info.Lease.Epoch)
+		fmt.Fprintf(w, "Replicas:\t%d\n", len(info.Replicas))
+	}
+	return w.Flush()
+}
@@ -3,25 +3,40 @@ package consul
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/consul/api"
+	"github.com/sony/gobreaker"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
+// logger is the logger for the lease subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemLease)
+
 // Default lease settings.
 const (
 	DefaultSessionName = "litefs"
 	DefaultTTL         = 10 * time.Second
 	DefaultLockDelay   = 1 * time.Second
+
+	DefaultCircuitBreakerThreshold        = 5
+	DefaultCircuitBreakerHalfOpenInterval = 5 * time.Second
 )
 
+// ErrConsulUnavailable is returned when requests to Consul are being
+// short-circuited because of repeated failures.
+var ErrConsulUnavailable = errors.New("consul unavailable")
+
 // Leaser represents an API for obtaining a distributed lock on a single key.
 type Leaser struct {
 	consulURL    string
@@ -29,6 +44,11 @@ type Leaser struct {
 	advertiseURL string
 	client       *api.Client
 
+	acquireDelayed atomic.Bool // true after the initial acquire delay
+
+	hooksMu      sync.Mutex
+	renewalHooks []renewalHook
+
 	// SessionName is the name associated with the Consul session.
 	SessionName string
 
@@ -43,6 +63,21 @@ type Leaser struct {
 
 	// LockDefault is the time after the lock expires that a new lock can be acquired.
 	LockDelay time.Duration
+
+	// AcquireDelay is the time to wait before the first acquisition attempt.
+	// This prevents all nodes from hitting Consul at once during a restart.
+	AcquireDelay time.Duration
+
+	// AcquireDelayJitter is the maximum random duration added to AcquireDelay.
+	AcquireDelayJitter time.Duration
+
+	// CircuitBreakerThreshold is the number of consecutive failed requests
+	// before requests to Consul fail immediately. Set to zero to disable.
+	CircuitBreakerThreshold uint32
+
+	// CircuitBreakerHalfOpenInterval is the time the circuit stays open
+	// before a single request is allowed through to check for recovery.
+	CircuitBreakerHalfOpenInterval time.Duration
 }
 
 // NewLeaser returns a new instance of Leaser.
@@ -55,6 +90,9 @@ func NewLeaser(consulURL, key, hostname, advertiseURL string) *Leaser {
 		Key:          key,
 		TTL:          DefaultTTL,
 		LockDelay:    DefaultLockDelay,
+
+		CircuitBreakerThreshold:        DefaultCircuitBreakerThreshold,
+		CircuitBreakerHalfOpenInterval: DefaultCircuitBreakerHalfOpenInterval,
 	}
 }
 
@@ -75,6 +113,9 @@ func (l *Leaser) Open() error {
 
 	config := api.DefaultConfig()
 	config.HttpClient = http.DefaultClient
+	if l.CircuitBreakerThreshold > 0 {
+		config.HttpClient = &http.Client{Transport: l.newBreakerTransport(http.DefaultTransport)}
+	}
 	config.Address = u.Host
 	config.Scheme = u.Scheme
 	if u.User != nil {
@@ -101,6 +142,48 @@ func (l *Leaser) Open() error {
 	return nil
 }
 
+// RenewalHookFunc is a function that is invoked after a lease is renewed.
+type RenewalHookFunc func(ctx context.Context, lease litefs.Lease) error
+
+type renewalHook struct {
+	fn       RenewalHookFunc
+	critical bool
+}
+
+// AddRenewalHook registers fn to be called after each successful lease renewal.
+// Errors returned by fn are logged but do not cause the renewal to fail.
+func (l *Leaser) AddRenewalHook(fn RenewalHookFunc) {
+	l.hooksMu.Lock()
+	defer l.hooksMu.Unlock()
+	l.renewalHooks = append(l.renewalHooks, renewalHook{fn: fn})
+}
+
+// AddCriticalRenewalHook registers fn to be called after each successful lease
+// renewal. If fn returns an error then the renewal returns that error.
+func (l *Leaser) AddCriticalRenewalHook(fn RenewalHookFunc) {
+	l.hooksMu.Lock()
+	defer l.hooksMu.Unlock()
+	l.renewalHooks = append(l.renewalHooks, renewalHook{fn: fn, critical: true})
+}
+
+// runRenewalHooks executes all renewal hooks in the order they were added.
+// Stops at the first critical hook that returns an error.
+func (l *Leaser) runRenewalHooks(ctx context.Context, lease litefs.Lease) error {
+	l.hooksMu.Lock()
+	hooks := make([]renewalHook, len(l.renewalHooks))
+	copy(hooks, l.renewalHooks)
+	l.hooksMu.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook.fn(ctx, lease); err != nil && hook.critical {
+			return fmt.Errorf("critical renewal hook: %w", err)
+		} else if err != nil {
+			logger.Warn("consul renewal hook error", slog.Any("err", err))
+		}
+	}
+	return nil
+}
+
 // Close closes the underlying client.
 func (l *Leaser) Close() (err error) {
 	return nil
@@ -141,6 +224,19 @@ func (l *Leaser) kvValue() ([]byte, error) {
 // Acquire acquires a lock on the key and sets the value.
 // Returns an error if the lease could not be obtained.
 func (l *Leaser) Acquire(ctx context.Context) (_ litefs.Lease, retErr error) {
+	// Wait before the first attempt so that simultaneously started nodes
+	// do not all create sessions. If another node became primary while we
+	// waited then we can skip the session creation entirely.
+	if delayed, err := l.waitAcquireDelay(ctx); err != nil {
+		return nil, err
+	} else if delayed {
+		if _, err := l.PrimaryInfo(ctx); err == nil {
+			return nil, litefs.ErrPrimaryExists
+		} else if err != litefs.ErrNoPrimary {
+			return nil, fmt.Errorf("fetch primary info: %w", err)
+		}
+	}
+
 	// Create session first.
 	sessionID, _, err := l.client.Session().CreateNoChecks(&api.SessionEntry{
 		Node:      l.NodeName(),
@@ -179,9 +275,39 @@ func (l *Leaser) Acquire(ctx context.Context) (_ litefs.Lease, retErr error) {
 	} else if !acquired {
 		return nil, litefs.ErrPrimaryExists
 	}
+
+	if lease.epoch, err = l.epoch(sessionID); err != nil {
+		return nil, err
+	}
 	return lease, nil
 }
 
+// waitAcquireDelay sleeps for the acquire delay plus a random jitter. This only
+// occurs on the first call. Returns true if the leaser waited.
+func (l *Leaser) waitAcquireDelay(ctx context.Context) (bool, error) {
+	if l.acquireDelayed.Swap(true) {
+		return false, nil
+	}
+
+	d := l.AcquireDelay
+	if l.AcquireDelayJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(l.AcquireDelayJitter)))
+	}
+	if d <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, context.Cause(ctx)
+	case <-timer.C:
+		return true, nil
+	}
+}
+
 // AcquireExisting acquires a lock using an existing session ID. This can occur
 // if an existing primary hands off to a replica. Returns an error if the lease
 // could not be renewed.
@@ -209,9 +335,25 @@ func (l *Leaser) AcquireExisting(ctx context.Context, leaseID string) (litefs.Le
 	} else if !acquired {
 		return nil, litefs.ErrPrimaryExists
 	}
+
+	if lease.epoch, err = l.epoch(leaseID); err != nil {
+		return nil, err
+	}
 	return lease, nil
 }
 
+// epoch returns the modify index of the lease key. Consul indexes increase
+// on every write so each acquisition of the key receives a higher index.
+func (l *Leaser) epoch(sessionID string) (uint64, error) {
+	kv, _, err := l.client.KV().Get(l.kvKey(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("fetch consul lease epoch: %w", err)
+	} else if kv == nil || kv.Session != sessionID {
+		return 0, litefs.ErrPrimaryExists
+	}
+	return kv.ModifyIndex, nil
+}
+
 // PrimaryInfo attempts to return the current primary URL.
 func (l *Leaser) PrimaryInfo(ctx context.Context) (info litefs.PrimaryInfo, err error) {
 	kv, _, err := l.client.KV().Get(path.Join(l.KeyPrefix, l.Key), nil)
@@ -269,10 +411,65 @@ func (l *Leaser) SetClusterID(ctx context.Context, clusterID string) error {
 	return nil
 }
 
+// newBreakerTransport returns a transport that stops sending requests after
+// CircuitBreakerThreshold consecutive connection or server errors.
+func (l *Leaser) newBreakerTransport(rt http.RoundTripper) *breakerTransport {
+	threshold := l.CircuitBreakerThreshold
+	return &breakerTransport{
+		rt: rt,
+		cb: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:        "consul",
+			MaxRequests: 1,
+			Timeout:     l.CircuitBreakerHalfOpenInterval,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= threshold
+			},
+			OnStateChange: func(name string, from, to gobreaker.State) {
+				logger.Info("consul circuit breaker state changed", slog.String("from", from.String()), slog.String("to", to.String()))
+			},
+		}),
+	}
+}
+
+// breakerTransport wraps an HTTP transport with a circuit breaker.
+type breakerTransport struct {
+	rt http.RoundTripper
+	cb *gobreaker.CircuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	_, err := t.cb.Execute(func() (_ any, err error) {
+		if resp, err = t.rt.RoundTrip(req); err != nil {
+			return nil, err
+		}
+
+		// Server errors count as a failure but the response is still
+		// returned so the Consul client can report the underlying error.
+		if resp.StatusCode >= 500 {
+			return nil, errConsulServerError
+		}
+		return nil, nil
+	})
+
+	switch err {
+	case nil, errConsulServerError:
+		return resp, nil
+	case gobreaker.ErrOpenState, gobreaker.ErrTooManyRequests:
+		return nil, ErrConsulUnavailable
+	default:
+		return nil, err
+	}
+}
+
+// errConsulServerError is a marker error to record server errors as failures.
+var errConsulServerError = errors.New("consul server error")
+
 // Lease represents a distributed lock obtained by the Leaser.
 type Lease struct {
 	leaser    *Leaser
 	sessionID string
+	epoch     uint64
 	renewedAt time.Time
 	handoffCh chan uint64 // channel of node IDs
 }
@@ -289,6 +486,9 @@ func newLease(leaser *Leaser, sessionID string, renewedAt time.Time) *Lease {
 // ID returns the lease session ID.
 func (l *Lease) ID() string { return l.sessionID }
 
+// Epoch returns the modify index of the lease key when it was acquired.
+func (l *Lease) Epoch() uint64 { return l.epoch }
+
 // TTL returns the time-to-live value the lease was initialized with.
 func (l *Lease) TTL() time.Duration { return l.leaser.TTL }
 
@@ -307,7 +507,8 @@ func (l *Lease) Renew(ctx context.Context) error {
 
 	// Reset the last renewed time.
 	l.renewedAt = time.Now()
-	return nil
+
+	return l.leaser.runRenewalHooks(ctx, l)
 }
 
 // Handoff sends the nodeID to the channel returned by HandoffCh()
@@ -334,9 +535,9 @@ func (l *Lease) Close() error {
 		Key:     kvKey,
 		Session: l.sessionID,
 	}, nil); err != nil {
-		log.Printf("consul key release error: key=%s session=%s", kvKey, l.sessionID)
+		logger.Warn("consul key release error", slog.String("key", kvKey), slog.String("session", l.sessionID))
 	} else if !ok {
-		log.Printf("cannot release consul key: key=%s session=%s", kvKey, l.sessionID)
+		logger.Warn("cannot release consul key", slog.String("key", kvKey), slog.String("session", l.sessionID))
 	}
 
 	_, err := l.leaser.client.Session().Destroy(l.sessionID, nil)
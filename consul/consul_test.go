@@ -0,0 +1,281 @@
+package consul_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/consul"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestLeaser_AcquireDelay(t *testing.T) {
+	t.Run("SingleSession", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		// Simulate five nodes starting simultaneously. Each node looks for an
+		// existing primary and only attempts to acquire if none exists.
+		var g errgroup.Group
+		var primaryN atomic.Int32
+		for i := 0; i < 5; i++ {
+			i := i
+			g.Go(func() error {
+				l := consul.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+				l.AcquireDelay = time.Duration(i) * 100 * time.Millisecond
+				if err := l.Open(); err != nil {
+					return err
+				}
+
+				if _, err := l.PrimaryInfo(context.Background()); err == nil {
+					return nil // primary already exists, become replica
+				} else if err != litefs.ErrNoPrimary {
+					return err
+				}
+
+				if _, err := l.Acquire(context.Background()); err == litefs.ErrPrimaryExists {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				primaryN.Add(1)
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := primaryN.Load(), int32(1); got != want {
+			t.Fatalf("primaryN=%d, want %d", got, want)
+		}
+		if got, want := srv.sessionCreateN.Load(), int32(1); got != want {
+			t.Fatalf("sessionCreateN=%d, want %d", got, want)
+		}
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := consul.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+		l.AcquireDelay = 10 * time.Second
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := l.Acquire(ctx); err != context.Canceled {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := srv.sessionCreateN.Load(), int32(0); got != want {
+			t.Fatalf("sessionCreateN=%d, want %d", got, want)
+		}
+	})
+}
+
+func TestLease_Renew(t *testing.T) {
+	t.Run("RenewalHook", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := consul.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		l.AddRenewalHook(func(ctx context.Context, lease litefs.Lease) error {
+			n++
+			return nil
+		})
+		l.AddRenewalHook(func(ctx context.Context, lease litefs.Lease) error {
+			return fmt.Errorf("marker")
+		})
+
+		lease, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			if err := lease.Renew(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got, want := n, 5; got != want {
+			t.Fatalf("n=%d, want %d", got, want)
+		}
+	})
+
+	t.Run("CriticalRenewalHook", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := consul.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		l.AddCriticalRenewalHook(func(ctx context.Context, lease litefs.Lease) error {
+			return fmt.Errorf("marker")
+		})
+		l.AddRenewalHook(func(ctx context.Context, lease litefs.Lease) error {
+			n++
+			return nil
+		})
+
+		lease, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lease.Renew(context.Background()); err == nil || err.Error() != `critical renewal hook: marker` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := n, 0; got != want {
+			t.Fatalf("n=%d, want %d", got, want)
+		}
+	})
+}
+
+func TestLeaser_CircuitBreaker(t *testing.T) {
+	srv := newFakeServer()
+	defer srv.Close()
+
+	l := consul.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+	l.CircuitBreakerHalfOpenInterval = 100 * time.Millisecond
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	lease, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an outage so that the circuit opens after consecutive failures.
+	srv.fail.Store(true)
+	for i := 0; i < consul.DefaultCircuitBreakerThreshold; i++ {
+		if err := lease.Renew(context.Background()); err == nil {
+			t.Fatal("expected error")
+		} else if errors.Is(err, consul.ErrConsulUnavailable) {
+			t.Fatalf("unexpected short-circuit on request %d", i)
+		}
+	}
+
+	// Requests should now fail immediately without reaching the server.
+	requestN := srv.requestN.Load()
+	if err := lease.Renew(context.Background()); !errors.Is(err, consul.ErrConsulUnavailable) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := srv.requestN.Load(), requestN; got != want {
+		t.Fatalf("requestN=%d, want %d", got, want)
+	}
+
+	// Recover the server & wait for the circuit to half-open.
+	srv.fail.Store(false)
+	time.Sleep(l.CircuitBreakerHalfOpenInterval)
+	if err := lease.Renew(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Circuit should be closed and allow subsequent requests.
+	for i := 0; i < 3; i++ {
+		if err := lease.Renew(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeServer implements the subset of the Consul HTTP API used by the leaser.
+type fakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	kv       map[string][]byte
+	sessions map[string]string // key to session ID
+	indexes  map[string]uint64 // key to modify index
+	index    uint64
+
+	sessionCreateN atomic.Int32
+	requestN       atomic.Int32
+	fail           atomic.Bool // if true, returns server errors
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{
+		kv:       make(map[string][]byte),
+		sessions: make(map[string]string),
+		indexes:  make(map[string]uint64),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requestN.Add(1)
+	if s.fail.Load() {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		n := s.sessionCreateN.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": fmt.Sprintf("session-%d", n)})
+
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"ID": strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")}})
+
+	case strings.HasPrefix(r.URL.Path, "/v1/session/"):
+		_, _ = w.Write([]byte("true"))
+
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		switch r.Method {
+		case http.MethodGet:
+			value, ok := s.kv[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Key": key, "Value": value, "Session": s.sessions[key], "ModifyIndex": s.indexes[key]}})
+
+		case http.MethodPut:
+			q := r.URL.Query()
+			if sessionID := q.Get("acquire"); sessionID != "" {
+				if s.sessions[key] != "" && s.sessions[key] != sessionID {
+					_, _ = w.Write([]byte("false"))
+					return
+				}
+				s.sessions[key] = sessionID
+			} else if sessionID := q.Get("release"); sessionID != "" {
+				delete(s.sessions, key)
+				delete(s.kv, key)
+				_, _ = w.Write([]byte("true"))
+				return
+			}
+
+			value, _ := io.ReadAll(r.Body)
+			s.index++
+			s.kv[key], s.indexes[key] = value, s.index
+			_, _ = w.Write([]byte("true"))
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -1,6 +1,7 @@
 package litefs
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/binary"
@@ -8,7 +9,8 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
+	"math"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sort"
@@ -22,6 +24,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/superfly/litefs/internal"
 	"github.com/superfly/ltx"
+	"golang.org/x/exp/slog"
 )
 
 // WaitInterval is the time between checking if the DB has reached a position in DB.Wait().
@@ -40,21 +43,55 @@ type DB struct {
 	hwm       atomic.Uint64 // high-water mark
 	mode      atomic.Value  // database journaling mode (rollback, wal)
 
+	// Latest TXID received from the primary, including transactions that
+	// have not been applied yet. Only set on replicas.
+	primaryTXID atomic.Uint64
+
+	// Last committed TXID that has not yet waited for synchronous replicas
+	// or backpressure. The wait occurs once SQLite releases its locks.
+	pendingTXID atomic.Uint64
+
 	// Halt lock prevents writes or checkpoints on the primary so that
 	// replica nodes can perform writes and send them back to the primary.
 	//
 	haltLockAndGuard atomic.Value // local halt lock & guard, if currently held
 	remoteHaltLock   atomic.Value // remote halt lock, if currently held
 
+	// Remote halt lock acquired by write forwarding & the lock owner whose
+	// write transaction is forwarded. Only one owner forwards at a time.
+	forwardMu     sync.Mutex
+	forwardLockID int64
+	forwardOwner  uint64
+
+	// Replication is skipped for a database once it is suspended after
+	// too many consecutive errors. See Store.DBErrorThreshold.
+	applyErrorN atomic.Int32 // consecutive replication apply errors
+	suspended   atomic.Bool
+
+	// If set, the replica requests a snapshot of the database the next time
+	// it connects to the primary. See Store.ChecksumVerifyResync.
+	resync atomic.Bool
+
+	// Pages not yet fetched from the primary after the database was
+	// bootstrapped from a lazy snapshot. Nil once every page is present.
+	lazy atomic.Pointer[lazyPageSet]
+
+	// Number of open file handles. The database cannot be dropped or renamed
+	// while in use & is marked as closed once it is removed from the store.
+	handleN atomic.Int64
+	closed  atomic.Bool
+
 	chksums struct { // database page checksums
 		mu     sync.Mutex
 		pages  []ltx.Checksum // individual database page checksums
 		blocks []ltx.Checksum // aggregated database page checksums; grouped by ChecksumBlockSize
 	}
 
-	dirtyPageSet map[uint32]struct{}
+	dirtyPageSet    map[uint32]struct{}
+	txLimitExceeded bool // if true, dirty pages are no longer checked against tx limits
 
 	wal struct {
+		mu               sync.RWMutex              // protects frameOffsets for ReadPage
 		offset           int64                     // offset of the start of the transaction
 		byteOrder        binary.ByteOrder          // determine by WAL header magic
 		salt1, salt2     uint32                    // current WAL header salt values
@@ -117,6 +154,11 @@ func NewDB(store *Store, name string, path string) *DB {
 // Name of the database name.
 func (db *DB) Name() string { return db.name }
 
+// logger returns a store logger that includes the database name.
+func (db *DB) logger() *slog.Logger {
+	return db.store.logger(LogSubsystemStore).With(slog.String("db", db.name))
+}
+
 // Store returns the store that the database is a member of.
 func (db *DB) Store() *Store { return db.store }
 
@@ -126,6 +168,10 @@ func (db *DB) Path() string { return db.path }
 // LTXDir returns the path to the directory of LTX transaction files.
 func (db *DB) LTXDir() string { return filepath.Join(db.path, "ltx") }
 
+// QuarantineDir returns the path to the directory of LTX files that failed
+// verification when the database was opened.
+func (db *DB) QuarantineDir() string { return filepath.Join(db.path, "quarantine") }
+
 // LTXPath returns the path of an LTX file.
 func (db *DB) LTXPath(minTXID, maxTXID ltx.TXID) string {
 	return filepath.Join(db.LTXDir(), ltx.FormatFilename(minTXID, maxTXID))
@@ -164,6 +210,17 @@ func (db *DB) WALPath() string { return filepath.Join(db.path, "wal") }
 // SHMPath returns the path to the underlying shared memory file.
 func (db *DB) SHMPath() string { return filepath.Join(db.path, "shm") }
 
+// PartialSnapshotPath returns the path of a snapshot that is being received
+// from the primary. It is kept after an interrupted transfer so it can be resumed.
+func (db *DB) PartialSnapshotPath() string { return filepath.Join(db.path, "snapshot.partial") }
+
+// LazyPath returns the path of the marker file that exists while the database
+// is missing pages after a lazy snapshot.
+func (db *DB) LazyPath() string { return filepath.Join(db.path, "lazy") }
+
+// PageSize returns the page size of the database. Zero if not yet known.
+func (db *DB) PageSize() uint32 { return db.pageSize }
+
 // PageN returns the number of pages in the database.
 func (db *DB) PageN() uint32 { return db.pageN.Load() }
 
@@ -185,11 +242,36 @@ func (db *DB) setPos(pos ltx.Pos, ts int64) error {
 	}
 
 	// Update metrics.
-	dbTXIDMetricVec.WithLabelValues(db.name).Set(float64(pos.TXID))
+	db.store.dbMetrics.dbTXIDMetricVec.WithLabelValues(db.name).Set(float64(pos.TXID))
+	db.store.dbMetrics.dbReplicaLagTXIDMetricVec.WithLabelValues(db.name).Set(float64(db.ReplicaLag()))
 
 	return nil
 }
 
+// ReplicaLag returns the number of transactions received from the primary
+// that have not been applied to the database yet. This is non-zero while
+// replication is paused or the replica is slow to apply changes. Always zero
+// on the primary.
+func (db *DB) ReplicaLag() uint64 {
+	if txID := uint64(db.TXID()); db.primaryTXID.Load() > txID {
+		return db.primaryTXID.Load() - txID
+	}
+	return 0
+}
+
+// setPrimaryTXID records that the primary has committed up to txID.
+func (db *DB) setPrimaryTXID(txID ltx.TXID) {
+	for {
+		prev := db.primaryTXID.Load()
+		if uint64(txID) <= prev {
+			return
+		} else if db.primaryTXID.CompareAndSwap(prev, uint64(txID)) {
+			break
+		}
+	}
+	db.store.dbMetrics.dbReplicaLagTXIDMetricVec.WithLabelValues(db.name).Set(float64(db.ReplicaLag()))
+}
+
 // Timestamp is the timestamp from the last applied ltx.
 func (db *DB) Timestamp() time.Time {
 	return time.UnixMilli(atomic.LoadInt64(&db.timestamp))
@@ -355,7 +437,7 @@ func (db *DB) AcquireRemoteHaltLock(ctx context.Context, lockID int64) (_ *HaltL
 	defer func() {
 		if retErr != nil {
 			if err := db.store.Client.ReleaseHaltLock(ctx, info.AdvertiseURL, db.store.ID(), db.name, haltLock.ID); err != nil {
-				log.Printf("cannot release remote halt lock after acquisition error: %s", err)
+				db.logger().Warn("cannot release remote halt lock after acquisition error", slog.Any("err", err))
 			}
 		}
 	}()
@@ -469,16 +551,66 @@ func (db *DB) HasRemoteHaltLock() bool {
 }
 
 // Writeable returns true if the node is the primary or if we've acquire the
-// HALT lock from the primary.
+// HALT lock from the primary. Closed databases are never writeable.
 func (db *DB) Writeable() bool {
+	if db.closed.Load() {
+		return false
+	}
 	return db.HasRemoteHaltLock() || db.store.IsPrimary()
 }
 
+// AddHandle records that a file handle to one of the database files has been
+// opened. It must be paired with a call to RemoveHandle() on release.
+func (db *DB) AddHandle() { db.handleN.Add(1) }
+
+// RemoveHandle records that a file handle opened with AddHandle() has been
+// released.
+func (db *DB) RemoveHandle() { db.handleN.Add(-1) }
+
+// InUse returns true if the database has open file handles or if any of its
+// locks, including the HALT lock, are held.
+func (db *DB) InUse() bool {
+	if db.handleN.Load() > 0 || db.HasRemoteHaltLock() || db.haltLockAndGuard.Load().(*haltLockAndGuard) != nil {
+		return true
+	}
+
+	for _, rw := range []*RWMutex{
+		&db.pendingLock, &db.sharedLock, &db.reservedLock,
+		&db.writeLock, &db.ckptLock, &db.recoverLock,
+		&db.read0Lock, &db.read1Lock, &db.read2Lock, &db.read3Lock, &db.read4Lock,
+		&db.dmsLock,
+	} {
+		if rw.State() != RWMutexStateUnlocked {
+			return true
+		}
+	}
+	return false
+}
+
+// close marks the database as closed once it has been removed from the
+// store. Handles still open on a replica continue to read the removed files.
+func (db *DB) close() { db.closed.Store(true) }
+
 // TXID returns the current transaction ID.
 func (db *DB) TXID() ltx.TXID { return db.Pos().TXID }
 
+// Suspended returns true if replication to the database has been suspended
+// because of repeated apply errors.
+func (db *DB) Suspended() bool { return db.suspended.Load() }
+
 // Open initializes the database from files in its data directory.
 func (db *DB) Open() error {
+	// Missing pages are not tracked across restarts so an incomplete lazy
+	// database is removed & a new snapshot is received from the primary.
+	if _, err := db.os.Stat("OPEN", db.LazyPath()); err == nil {
+		db.logger().Info("removing incomplete lazy database")
+		if err := db.clean(); err != nil {
+			return fmt.Errorf("clean lazy database: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat lazy marker: %w", err)
+	}
+
 	// Read page size & page count from database file.
 	if err := db.initFromDatabaseHeader(); err != nil {
 		return fmt.Errorf("init from database header: %w", err)
@@ -549,7 +681,7 @@ func (db *DB) initFromDatabaseHeader() error {
 	if err == io.EOF {
 		return nil
 	} else if err == errInvalidDatabaseHeader { // invalid file
-		log.Printf("invalid database header on %q, clearing data files", db.name)
+		db.logger().Warn("invalid database header, clearing data files")
 		if err := db.clean(); err != nil {
 			return fmt.Errorf("clean: %w", err)
 		}
@@ -800,27 +932,94 @@ func (db *DB) readWALPageOffsets(f *os.File) (_ map[uint32]int64, lastCommit uin
 	return offsets, lastCommit, nil
 }
 
-// maxLTXFile returns the filename of the highest LTX file.
+// maxLTXFile returns the filename of the highest complete LTX file. Partial
+// LTX files from an interrupted write are removed so that the database is
+// recovered from the last complete transaction. Files that are complete but
+// fail verification are moved to the quarantine directory for inspection.
 func (db *DB) maxLTXFile(ctx context.Context) (string, error) {
 	ents, err := db.os.ReadDir("MAXLTX", db.LTXDir())
 	if err != nil {
 		return "", err
 	}
 
-	var max ltx.TXID
-	var filename string
+	type ltxFile struct {
+		maxTXID  ltx.TXID
+		filename string
+	}
+
+	var files []ltxFile
 	for _, ent := range ents {
+		// Temporary files are only left behind if a write was interrupted.
+		if strings.HasSuffix(ent.Name(), ".tmp") {
+			db.logger().Info("removing temporary ltx file", slog.String("file", ent.Name()))
+			if err := db.os.Remove("MAXLTX:TMP", filepath.Join(db.LTXDir(), ent.Name())); err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("remove temporary ltx file: %w", err)
+			}
+			continue
+		}
+
 		_, maxTXID, err := ltx.ParseFilename(ent.Name())
 		if err != nil {
 			continue
-		} else if maxTXID <= max {
+		}
+		files = append(files, ltxFile{maxTXID: maxTXID, filename: filepath.Join(db.LTXDir(), ent.Name())})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].maxTXID > files[j].maxTXID })
+
+	// Return the highest LTX file that is complete.
+	validator := NewLTXFileValidator(db.os)
+	for _, file := range files {
+		err := validator.Validate(file.filename)
+		if err == nil {
+			return file.filename, nil
+		} else if !errors.Is(err, ErrInvalidLTXFile) {
+			return "", err
+		}
+
+		if errors.Is(err, ErrPartialLTXFile) {
+			db.logger().Info("removing partial ltx file", slog.Any("err", err))
+			if err := db.os.Remove("MAXLTX:PARTIAL", file.filename); err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("remove partial ltx file: %w", err)
+			}
 			continue
 		}
 
-		// Save filename with the highest TXID.
-		max, filename = maxTXID, filepath.Join(db.LTXDir(), ent.Name())
+		db.logger().Error("quarantining corrupted ltx file", slog.String("dir", db.QuarantineDir()), slog.Any("err", err))
+		if err := db.os.MkdirAll("MAXLTX:QUARANTINE", db.QuarantineDir(), 0o777); err != nil {
+			return "", fmt.Errorf("create quarantine dir: %w", err)
+		} else if err := db.os.Rename("MAXLTX:QUARANTINE", file.filename, filepath.Join(db.QuarantineDir(), filepath.Base(file.filename))); err != nil {
+			return "", fmt.Errorf("quarantine ltx file: %w", err)
+		}
+	}
+	return "", nil
+}
+
+// LTXFileValidator verifies that LTX files on disk are complete.
+type LTXFileValidator struct {
+	os OS
+}
+
+// NewLTXFileValidator returns a new instance of LTXFileValidator.
+func NewLTXFileValidator(os OS) *LTXFileValidator {
+	return &LTXFileValidator{os: os}
+}
+
+// Validate decodes the entire LTX file at path and verifies its checksum.
+// Returns ErrInvalidLTXFile if the file is truncated or corrupted. Truncated
+// files, including those missing a trailer, also return ErrPartialLTXFile.
+func (v *LTXFileValidator) Validate(path string) error {
+	f, err := v.os.Open("VALIDATELTX", path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := ltx.NewDecoder(f).Verify(); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w: %s: %s", ErrInvalidLTXFile, ErrPartialLTXFile, filepath.Base(path), err)
+	} else if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidLTXFile, filepath.Base(path), err)
 	}
-	return filename, nil
+	return nil
 }
 
 // syncWALToLTX truncates the WAL file to the last LTX file if the WAL info
@@ -845,7 +1044,7 @@ func (db *DB) syncWALToLTX(ctx context.Context, ltxFilename string) error {
 	// Open WAL file, ignore if it doesn't exist.
 	walFile, err := db.os.OpenFile("SYNCWAL:WAL", db.WALPath(), os.O_RDWR, 0o666)
 	if os.IsNotExist(err) {
-		log.Printf("wal-sync: no wal file exists on %q, skipping sync with ltx", db.name)
+		db.logger().Info("wal-sync: no wal file exists, skipping sync with ltx")
 		return nil // no wal file, nothing to do
 	} else if err != nil {
 		return err
@@ -861,7 +1060,7 @@ func (db *DB) syncWALToLTX(ctx context.Context, ltxFilename string) error {
 	// Read WAL header.
 	hdr := make([]byte, WALHeaderSize)
 	if _, err := internal.ReadFullAt(walFile, hdr, 0); err == io.EOF || err == io.ErrUnexpectedEOF {
-		log.Printf("wal-sync: short wal file exists on %q, skipping sync with ltx", db.name)
+		db.logger().Info("wal-sync: short wal file exists, skipping sync with ltx")
 		return nil // short WAL header, skip
 	} else if err != nil {
 		return err
@@ -873,7 +1072,7 @@ func (db *DB) syncWALToLTX(ctx context.Context, ltxFilename string) error {
 	salt1 := binary.BigEndian.Uint32(hdr[16:])
 	salt2 := binary.BigEndian.Uint32(hdr[20:])
 	if salt1 != dec.Header().WALSalt1 || salt2 != dec.Header().WALSalt2 {
-		log.Printf("wal-sync: wal salt mismatch on %q, removing wal", db.name)
+		db.logger().Warn("wal-sync: wal salt mismatch, removing wal")
 		if err := db.os.Rename("SYNCWAL", db.WALPath(), db.WALPath()+".removed"); err != nil {
 			return fmt.Errorf("wal-sync: rename wal file with salt mismatch: %w", err)
 		}
@@ -888,14 +1087,14 @@ func (db *DB) syncWALToLTX(ctx context.Context, ltxFilename string) error {
 
 	// Resize WAL back to size in the LTX file.
 	if fi.Size() > ltxWALSize {
-		log.Printf("wal-sync: truncating wal of %q from %d bytes to %d bytes to match ltx", db.name, fi.Size(), ltxWALSize)
+		db.logger().Warn("wal-sync: truncating wal to match ltx", slog.Int64("size", fi.Size()), slog.Int64("ltx-wal-size", ltxWALSize))
 		if err := walFile.Truncate(ltxWALSize); err != nil {
 			return fmt.Errorf("truncate wal: %w", err)
 		}
 		return nil
 	}
 
-	log.Printf("wal-sync: database %q has wal size of %d bytes within range of ltx file (@%d, %d bytes)", db.name, fi.Size(), dec.Header().WALOffset, dec.Header().WALSize)
+	db.logger().Info("wal-sync: wal size within range of ltx file", slog.Int64("size", fi.Size()), slog.Int64("ltx-wal-offset", dec.Header().WALOffset), slog.Int64("ltx-wal-size", dec.Header().WALSize))
 	return nil
 }
 
@@ -905,7 +1104,7 @@ func (db *DB) syncWALToLTX(ctx context.Context, ltxFilename string) error {
 func (db *DB) initDatabaseFile() error {
 	f, err := db.os.Open("INITDBFILE", db.DatabasePath())
 	if os.IsNotExist(err) {
-		log.Printf("database file does not exist on initialization: %s", db.DatabasePath())
+		db.logger().Info("database file does not exist on initialization", slog.String("path", db.DatabasePath()))
 		return nil // no database file yet
 	} else if err != nil {
 		return err
@@ -914,7 +1113,7 @@ func (db *DB) initDatabaseFile() error {
 
 	hdr, _, err := readSQLiteDatabaseHeader(f)
 	if err == io.EOF {
-		log.Printf("database file is zero length on initialization: %s", db.DatabasePath())
+		db.logger().Info("database file is zero length on initialization", slog.String("path", db.DatabasePath()))
 		return nil // no contents yet
 	} else if err != nil {
 		return fmt.Errorf("cannot read database header: %w", err)
@@ -936,7 +1135,7 @@ func (db *DB) initDatabaseFile() error {
 	for pgno := uint32(1); pgno <= db.PageN(); pgno++ {
 		offset := int64(pgno-1) * int64(db.pageSize)
 		if _, err := internal.ReadFullAt(f, buf, offset); err == io.EOF || err == io.ErrUnexpectedEOF {
-			log.Printf("database checksum ending early at page %d of %d ", pgno-1, db.PageN())
+			db.logger().Warn("database checksum ending early", slog.Uint64("pgno", uint64(pgno-1)), slog.Uint64("page-n", uint64(db.PageN())))
 			break
 		} else if err != nil {
 			return fmt.Errorf("read database page %d: %w", pgno, err)
@@ -1022,6 +1221,16 @@ func (db *DB) truncateDatabase(f *os.File, pageN uint32) (err error) {
 	db.resetDatabasePageChecksumsAfter(pageN)
 	db.chksums.mu.Unlock()
 
+	// Pages past the end of the database no longer need to be fetched.
+	if lazy := db.lazy.Load(); lazy != nil {
+		lazy.mu.Lock()
+		for pgno := pageN + 1; pgno <= uint32(len(lazy.missing))*64; pgno++ {
+			lazy.remove(pgno)
+		}
+		db.completeLazy(lazy)
+		lazy.mu.Unlock()
+	}
+
 	return nil
 }
 
@@ -1043,6 +1252,14 @@ func (db *DB) SyncDatabase(ctx context.Context) (err error) {
 
 // ReadDatabaseAt reads from the database at the specified index.
 func (db *DB) ReadDatabaseAt(ctx context.Context, f *os.File, data []byte, offset int64, owner uint64) (int, error) {
+	// Fetch pages from the primary that have not been received yet.
+	if lazy := db.lazy.Load(); lazy != nil {
+		if err := db.fetchLazyPages(ctx, lazy, f, offset, len(data), owner); err != nil {
+			TraceLog.Printf("[ReadDatabaseAt(%s)]: offset=%d size=%d owner=%d %s", db.name, offset, len(data), owner, errorKeyValue(err))
+			return 0, err
+		}
+	}
+
 	n, err := f.ReadAt(data, offset)
 
 	// Compute checksum if page aligned.
@@ -1091,6 +1308,14 @@ func (db *DB) WriteDatabaseAt(ctx context.Context, f *os.File, data []byte, offs
 	// instead of overwritten. We can determine the dirty set at commit-time.
 	pgno := uint32(offset/int64(db.pageSize)) + 1
 	if db.Mode() == DBModeRollback {
+		if _, ok := db.dirtyPageSet[pgno]; !ok && !db.txLimitExceeded {
+			// Once the limit is exceeded, SQLite rolls back by writing the
+			// original pages from the journal so those writes must succeed.
+			if err := db.checkTxLimit(len(db.dirtyPageSet) + 1); err != nil {
+				db.txLimitExceeded = true
+				return err
+			}
+		}
 		db.dirtyPageSet[pgno] = struct{}{}
 	}
 
@@ -1114,12 +1339,23 @@ func (db *DB) writeDatabasePage(f *os.File, pgno uint32, data []byte, invalidate
 		return fmt.Errorf("database write (%d bytes) must be a single page (%d bytes)", len(data), db.pageSize)
 	}
 
+	// Prevent a concurrent fetch from overwriting the page & mark it present.
+	if lazy := db.lazy.Load(); lazy != nil {
+		lazy.mu.Lock()
+		defer lazy.mu.Unlock()
+		defer func() {
+			if err == nil {
+				db.removeLazyPage(lazy, pgno)
+			}
+		}()
+	}
+
 	// Issue write to database.
 	offset := (int64(pgno) - 1) * int64(db.pageSize)
 	if _, err := f.WriteAt(data, offset); err != nil {
 		return err
 	}
-	dbDatabaseWriteCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbDatabaseWriteCountMetricVec.WithLabelValues(db.name).Inc()
 
 	// Update in-memory checksum.
 	newChksum = ltx.ChecksumPage(pgno, data)
@@ -1236,7 +1472,7 @@ func (db *DB) WriteJournalAt(ctx context.Context, f *os.File, data []byte, offse
 		db.pageSize = binary.BigEndian.Uint32(data[24:])
 	}
 
-	dbJournalWriteCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbJournalWriteCountMetricVec.WithLabelValues(db.name).Inc()
 
 	// Assume this is a PERSIST commit if the initial header bytes are cleared.
 	if offset == 0 && len(data) == SQLITE_JOURNAL_HEADER_SIZE && isByteSliceZero(data) {
@@ -1285,7 +1521,9 @@ func (db *DB) TruncateWAL(ctx context.Context, size int64) (err error) {
 	}
 
 	// Clear all per-page checksums for the WAL.
+	db.wal.mu.Lock()
 	db.wal.frameOffsets = make(map[uint32]int64)
+	db.wal.mu.Unlock()
 	db.wal.chksums = make(map[uint32][]ltx.Checksum)
 
 	return nil
@@ -1300,7 +1538,9 @@ func (db *DB) RemoveWAL(ctx context.Context) (err error) {
 	}
 
 	// Clear all per-page checksums for the WAL.
+	db.wal.mu.Lock()
 	db.wal.frameOffsets = make(map[uint32]int64)
+	db.wal.mu.Unlock()
 	db.wal.chksums = make(map[uint32][]ltx.Checksum)
 
 	return nil
@@ -1322,6 +1562,42 @@ func (db *DB) SyncWAL(ctx context.Context) (err error) {
 	return f.Close()
 }
 
+// WALFileSize returns the current size of the WAL file, in bytes, without
+// acquiring any locks. Returns zero if the WAL file does not exist.
+func (db *DB) WALFileSize() (int64, error) {
+	fi, err := db.os.Stat("WALFILESIZE", db.WALPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// DBStats represents point-in-time statistics about a database.
+type DBStats struct {
+	Pos          ltx.Pos // current replication position
+	PageSize     uint32  // database page size, in bytes
+	PageN        uint32  // number of pages in the database
+	WALSizeBytes int64   // size of the WAL file, in bytes
+}
+
+// Stats returns statistics about the database. Like WALFileSize(), no locks
+// are acquired so the values may change while they are being read.
+func (db *DB) Stats() (DBStats, error) {
+	walSize, err := db.WALFileSize()
+	if err != nil {
+		return DBStats{}, fmt.Errorf("wal file size: %w", err)
+	}
+
+	return DBStats{
+		Pos:          db.Pos(),
+		PageSize:     db.PageSize(),
+		PageN:        db.PageN(),
+		WALSizeBytes: walSize,
+	}, nil
+}
+
 // ReadWALAt reads from the WAL at the specified index.
 func (db *DB) ReadWALAt(ctx context.Context, f *os.File, data []byte, offset int64, owner uint64) (int, error) {
 	n, err := f.ReadAt(data, offset)
@@ -1343,7 +1619,7 @@ func (db *DB) WriteWALAt(ctx context.Context, f *os.File, data []byte, offset in
 
 	assert(db.pageSize != 0, "page size cannot be zero for wal write")
 
-	dbWALWriteCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbWALWriteCountMetricVec.WithLabelValues(db.name).Inc()
 
 	// WAL header writes always start at a zero offset and are 32 bytes in size.
 	frameSize := WALFrameHeaderSize + int64(db.pageSize)
@@ -1395,7 +1671,9 @@ func (db *DB) writeWALHeader(ctx context.Context, f *os.File, data []byte, offse
 	db.wal.salt2 = binary.BigEndian.Uint32(data[20:])
 	db.wal.chksum1 = binary.BigEndian.Uint32(data[24:])
 	db.wal.chksum2 = binary.BigEndian.Uint32(data[28:])
+	db.wal.mu.Lock()
 	db.wal.frameOffsets = make(map[uint32]int64)
+	db.wal.mu.Unlock()
 	db.wal.chksums = make(map[uint32][]ltx.Checksum)
 
 	// Passthrough write to underlying WAL file.
@@ -1432,6 +1710,12 @@ func (db *DB) writeWALFrameHeader(ctx context.Context, f *os.File, data []byte,
 		return fmt.Errorf("cannot write wal frame header @%d before current WAL position @%d", offset, db.wal.offset)
 	}
 
+	// Reject frames past the transaction limits. SQLite rolls back the
+	// transaction and the frames are overwritten by the next transaction.
+	if err := db.checkTxLimit(int((offset-db.wal.offset)/(WALFrameHeaderSize+int64(db.pageSize))) + 1); err != nil {
+		return err
+	}
+
 	// Passthrough write to underlying WAL file.
 	_, err = f.WriteAt(data, offset)
 	return err
@@ -1452,6 +1736,30 @@ func (db *DB) writeWALFrameData(ctx context.Context, f *os.File, data []byte, of
 	return err
 }
 
+// checkTxLimit returns ErrTxTooLarge if a transaction with pageN pages exceeds
+// the store's per-transaction page count or size limits.
+func (db *DB) checkTxLimit(pageN int) error {
+	maxPageN, maxSize := db.store.MaxTxPageN, db.store.MaxTxSize
+	size := int64(pageN) * int64(db.pageSize)
+	if (maxPageN <= 0 || pageN <= maxPageN) && (maxSize <= 0 || size <= maxSize) {
+		return nil
+	}
+
+	db.logger().Warn("transaction exceeds limits, rejecting", slog.Int("pages", pageN), slog.Int64("size", size))
+	db.store.dbMetrics.dbTxLimitExceededCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.NotifyEvent(Event{
+		Type: EventTypeTxLimitExceeded,
+		DB:   db.name,
+		Data: TxLimitExceededEventData{
+			PageN:    pageN,
+			Size:     size,
+			MaxPageN: maxPageN,
+			MaxSize:  maxSize,
+		},
+	})
+	return ErrTxTooLarge
+}
+
 func (db *DB) buildTxFrameOffsets(walFile *os.File) (_ map[uint32]int64, commit, chksum1, chksum2 uint32, endOffset int64, err error) {
 	m := make(map[uint32]int64)
 
@@ -1533,7 +1841,7 @@ func (db *DB) CommitWAL(ctx context.Context) (err error) {
 	defer func() {
 		if err != nil {
 			TraceLog.Printf("[FATAL(%s)]: err=%d\n", db.name, err)
-			log.Printf("fatal error occurred while committing WAL to %q: %s\n", db.name, err)
+			db.logger().Error("fatal error occurred while committing WAL", slog.Any("err", err))
 			db.store.Exit(99)
 		}
 	}()
@@ -1567,7 +1875,10 @@ func (db *DB) CommitWAL(ctx context.Context) (err error) {
 	defer func() { _ = dbFile.Close() }()
 
 	// Determine transaction ID of the in-process transaction.
-	txID := prevPos.TXID + 1
+	txID, err := nextTXID(prevPos)
+	if err != nil {
+		return err
+	}
 
 	// Open file descriptors for the header & page blocks for new LTX file.
 	ltxPath := db.LTXPath(txID, txID)
@@ -1707,7 +2018,9 @@ func (db *DB) CommitWAL(ctx context.Context) (err error) {
 		return fmt.Errorf("sync ltx dir: %w", err)
 	}
 
-	// Copy page offsets on commit.
+	// Copy page offsets on commit. The lock is held until the position is
+	// updated so ReadPage sees the offsets & TXID of the same transaction.
+	db.wal.mu.Lock()
 	for pgno, off := range txFrameOffsets {
 		db.wal.frameOffsets[pgno] = off
 	}
@@ -1729,14 +2042,16 @@ func (db *DB) CommitWAL(ctx context.Context) (err error) {
 		PostApplyChecksum: enc.Trailer().PostApplyChecksum,
 	}
 	if err := db.setPos(pos, enc.Header().Timestamp); err != nil {
+		db.wal.mu.Unlock()
 		return fmt.Errorf("set pos: %w", err)
 	}
+	db.wal.mu.Unlock()
 
 	// Update metrics
-	dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
-	dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
+	db.store.dbMetrics.dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
+	db.store.dbMetrics.dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
 
 	// Notify store of database change.
 	db.store.MarkDirty(db.name)
@@ -1763,6 +2078,10 @@ func (db *DB) CommitWAL(ctx context.Context) (err error) {
 		}
 	}
 
+	// Wait for replicas to receive the transaction, or to catch up, once the
+	// write lock is released, if required.
+	db.pendingTXID.Store(uint64(pos.TXID))
+
 	return nil
 }
 
@@ -1850,12 +2169,14 @@ func (db *DB) UnlockSHM(ctx context.Context, owner uint64) {
 	// Process WAL if we have an exclusive lock on WAL_WRITE_LOCK.
 	if guardSet.Write().State() == RWMutexStateExclusive {
 		if err := db.CommitWAL(ctx); err != nil {
-			log.Printf("commit wal error(1): %s", err)
+			db.logger().Error("commit wal error", slog.Any("err", err))
 		}
 	}
 
 	guardSet.UnlockSHM()
 	TraceLog.Printf("[UnlockSHM(%s)]: owner=%d", db.name, owner)
+
+	db.waitForReplicas(ctx)
 }
 
 // ReadSHMAt reads from the shared memory at the specified offset.
@@ -1874,7 +2195,7 @@ func (db *DB) WriteSHMAt(ctx context.Context, f *os.File, data []byte, offset in
 		return len(data), nil
 	}
 
-	dbSHMWriteCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbSHMWriteCountMetricVec.WithLabelValues(db.name).Inc()
 	n, err := f.WriteAt(data, offset)
 	TraceLog.Printf("[WriteSHMAt(%s)]: offset=%d size=%d owner=%d %s", db.name, offset, len(data), owner, errorKeyValue(err))
 	return n, err
@@ -1922,7 +2243,10 @@ func (db *DB) CommitJournal(ctx context.Context, mode JournalMode) (err error) {
 	}
 
 	// Determine transaction ID of the in-process transaction.
-	txID := prevPos.TXID + 1
+	txID, err := nextTXID(prevPos)
+	if err != nil {
+		return err
+	}
 
 	dbFile, err := db.os.Open("COMMITJOURNAL:DB", db.DatabasePath())
 	if err != nil {
@@ -2041,6 +2365,14 @@ func (db *DB) CommitJournal(ctx context.Context, mode JournalMode) (err error) {
 	if err != nil {
 		return fmt.Errorf("compute checksum: %w", err)
 	}
+
+	// A transaction rejected by the transaction limits is rolled back by
+	// SQLite so skip the LTX file if the database is back to its prior state.
+	if db.txLimitExceeded && commit == prevPageN && postApplyChecksum == prevPos.PostApplyChecksum {
+		_ = ltxFile.Close()
+		_ = db.os.Remove("COMMITJOURNAL:LTX", tmpPath)
+		return db.invalidateJournal(mode)
+	}
 	enc.SetPostApplyChecksum(postApplyChecksum)
 
 	// Finish page block to compute checksum and then finish header block.
@@ -2099,10 +2431,10 @@ func (db *DB) CommitJournal(ctx context.Context, mode JournalMode) (err error) {
 	}
 
 	// Update metrics
-	dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
-	dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
+	db.store.dbMetrics.dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
+	db.store.dbMetrics.dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
 
 	// Notify store of database change.
 	db.store.MarkDirty(db.name)
@@ -2129,6 +2461,10 @@ func (db *DB) CommitJournal(ctx context.Context, mode JournalMode) (err error) {
 		}
 	}
 
+	// Wait for replicas to receive the transaction, or to catch up, once
+	// SQLite releases its locks, if required.
+	db.pendingTXID.Store(uint64(pos.TXID))
+
 	return nil
 }
 
@@ -2140,12 +2476,16 @@ func (db *DB) Drop(ctx context.Context) (err error) {
 	var pos ltx.Pos
 	prevPos := db.Pos()
 	prevPageN := db.PageN()
-	txID := prevPos.TXID + 1
 	defer func() {
 		TraceLog.Printf("[Drop(%s)]: pos=%s prevPos=%s pages=%d commit=%d prevPageN=%d pageSize=%d msg=%q %s\n\n",
 			db.name, pos, prevPos, txPageCount, commit, prevPageN, db.pageSize, msg, errorKeyValue(err))
 	}()
 
+	txID, err := nextTXID(prevPos)
+	if err != nil {
+		return err
+	}
+
 	// Open file descriptors for the header & page blocks for new LTX file.
 	ltxPath := db.LTXPath(txID, txID)
 	tmpPath := ltxPath + ".tmp"
@@ -2230,7 +2570,9 @@ func (db *DB) Drop(ctx context.Context) (err error) {
 	db.wal.offset = 0
 	db.wal.chksum1 = 0
 	db.wal.chksum2 = 0
+	db.wal.mu.Lock()
 	db.wal.frameOffsets = make(map[uint32]int64)
+	db.wal.mu.Unlock()
 	db.wal.chksums = make(map[uint32][]ltx.Checksum)
 
 	// Update transaction for database.
@@ -2240,10 +2582,10 @@ func (db *DB) Drop(ctx context.Context) (err error) {
 	}
 
 	// Update metrics
-	dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
-	dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
-	dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
+	db.store.dbMetrics.dbCommitCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
+	db.store.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(enc.N()))
+	db.store.dbMetrics.dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(0.0)
 
 	// Notify store of database change.
 	db.store.MarkDirty(db.name)
@@ -2353,6 +2695,7 @@ func (db *DB) invalidateJournal(mode JournalMode) error {
 	}
 
 	db.dirtyPageSet = make(map[uint32]struct{})
+	db.txLimitExceeded = false
 
 	return nil
 }
@@ -2412,7 +2755,7 @@ func (db *DB) WriteLTXFileAt(ctx context.Context, r io.Reader) (string, error) {
 	// If this is a snapshot, remove all other files before rename.
 	if hdr.IsSnapshot() {
 		dir, file := filepath.Split(tmpPath)
-		log.Printf("snapshot received for %q, removing other ltx files except: %s", db.Name(), file)
+		db.logger().Info("snapshot received, removing other ltx files", slog.String("txid", hdr.MaxTXID.String()), slog.String("file", file))
 		if err := removeFilesExcept(db.os, dir, file); err != nil {
 			return "", fmt.Errorf("remove ltx except snapshot: %w", err)
 		}
@@ -2428,148 +2771,491 @@ func (db *DB) WriteLTXFileAt(ctx context.Context, r io.Reader) (string, error) {
 }
 
 // ApplyLTXNoLock applies an LTX file to the database.
-func (db *DB) ApplyLTXNoLock(path string, fatalOnError bool) (retErr error) {
-	var hdr ltx.Header
-	var trailer ltx.Trailer
-	prevDBMode := db.Mode()
-	defer func() {
-		TraceLog.Printf("[ApplyLTX(%s)]: txid=%s-%s chksum=%s-%s commit=%d pageSize=%d timestamp=%s mode=(%s→%s) path=%s",
-			db.name, hdr.MinTXID.String(), hdr.MaxTXID.String(), hdr.PreApplyChecksum, trailer.PostApplyChecksum, hdr.Commit, db.pageSize,
-			time.UnixMilli(hdr.Timestamp).UTC().Format(time.RFC3339), prevDBMode, db.Mode(), filepath.Base(path))
-	}()
-
-	// Open LTX header reader.
-	hf, err := db.os.Open("APPLYLTX:LTX", path)
+func (db *DB) ApplyLTXNoLock(path string, fatalOnError bool) error {
+	f, err := db.os.Open("APPLYLTX:LTX", path)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
-	defer func() { _ = hf.Close() }()
+	defer func() { _ = f.Close() }()
 
-	dec := ltx.NewDecoder(hf)
-	if err := dec.DecodeHeader(); err != nil {
-		return fmt.Errorf("decode ltx header: %s", err)
-	}
-	hdr = dec.Header()
-	if db.pageSize == 0 {
-		db.pageSize = dec.Header().PageSize
-	}
+	return db.applyLTXNoLock(f, filepath.Base(path), fatalOnError, nil, nil)
+}
 
-	// Delete database files if this has a zero "commit" field.
-	dbMode := db.Mode()
-	var dbFile *os.File
-	if dec.Header().Commit > 0 {
-		if dbFile, err = db.os.OpenFile("APPLYLTX:DB", db.DatabasePath(), os.O_RDWR|os.O_CREATE, 0o666); err != nil {
-			return fmt.Errorf("open database file: %w", err)
-		}
-		defer func() { _ = dbFile.Close() }()
+// ApplyLTXStream applies LTX data from r to the database in a single pass.
+// Pages are written to the database as they are decoded & the stream is
+// copied to a temporary file in the LTX directory at the same time so it can
+// be kept as the LTX file for the transaction & sent to other nodes.
+//
+// The previous contents of overwritten pages are saved to an undo log. If the
+// stream is truncated or fails its checksum then the pages are restored so
+// the database is left at its previous position.
+func (db *DB) ApplyLTXStream(ctx context.Context, r io.Reader) (err error) {
+	hdr, data, err := ltx.DecodeHeader(r)
+	if err != nil {
+		return fmt.Errorf("peek ltx header: %w", err)
 	}
+	r = io.MultiReader(bytes.NewReader(data), r)
 
-	// After this point, a partial failure will result in a partially written
-	// database. We don't have the ability to signal to the client that a failure
-	// occurred so we need to exit.
+	TraceLog.Printf("[ApplyLTXStream.Begin(%s)]: txid=%s-%s, preApplyChecksum=%s", db.name, hdr.MinTXID.String(), hdr.MaxTXID.String(), hdr.PreApplyChecksum)
 	defer func() {
-		if fatalOnError && retErr != nil {
-			TraceLog.Printf("[FATAL(%s)]: err=%d\n", db.name, retErr)
-			log.Printf("fatal error occurred while applying ltx to %q, exiting: %s\n", db.name, retErr)
-			db.store.Exit(99)
-		}
+		TraceLog.Printf("[ApplyLTXStream.End(%s)]: %s", db.name, errorKeyValue(err))
 	}()
 
-	pageBuf := make([]byte, dec.Header().PageSize)
-	for i := 0; ; i++ {
-		// Read pgno & page data from LTX file.
-		var phdr ltx.PageHeader
-		if err := dec.DecodePage(&phdr, pageBuf); err == io.EOF {
-			break
-		} else if err != nil {
-			return fmt.Errorf("decode ltx page[%d]: %w", i, err)
-		}
+	guardSet, err := db.AcquireWriteLock(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer guardSet.Unlock()
 
-		// Update the mode if this is the first page and the write/read versions as set to WAL (2).
-		if phdr.Pgno == 1 && pageBuf[18] == 2 && pageBuf[19] == 2 {
-			dbMode = DBModeWAL
+	// Verify LTX pre-apply checksum matches the current database position
+	// unless this is a snapshot, which will overwrite all data.
+	if !hdr.IsSnapshot() {
+		if pos := db.Pos(); pos != hdr.PreApplyPos() {
+			return fmt.Errorf("position mismatch on db %q: %s <> %s", db.name, pos, hdr.PreApplyPos())
 		}
+	}
 
-		// Copy to database file.
-		if err := db.writeDatabasePage(dbFile, phdr.Pgno, pageBuf, true); err != nil {
-			return fmt.Errorf("write to database file: %w", err)
-		}
+	// Copy the stream to a temporary LTX file while it is applied.
+	path := db.LTXPath(hdr.MinTXID, hdr.MaxTXID)
+	tmpPath := path + ".tmp"
+	defer func() { _ = db.os.Remove("APPLYLTXSTREAM", tmpPath) }()
+
+	f, err := db.os.Create("APPLYLTXSTREAM", tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot create temp ltx file: %w", err)
 	}
+	defer func() { _ = f.Close() }()
 
-	// Close the reader so we can verify file integrity.
-	if err := dec.Close(); err != nil {
-		return fmt.Errorf("close ltx decode: %w", err)
+	undo, err := db.newUndoLog()
+	if err != nil {
+		return fmt.Errorf("create undo log: %w", err)
 	}
+	defer func() { _ = undo.Close() }()
 
-	// Truncate database file to size after LTX file.
-	// If this is zero-length database then delete all the database files.
-	if dec.Header().Commit > 0 {
-		if err := db.truncateDatabase(dbFile, dec.Header().Commit); err != nil {
-			return fmt.Errorf("truncate database file: %w", err)
+	return db.applyLTXNoLock(io.TeeReader(r, f), filepath.Base(tmpPath), false, undo, func() error {
+		n, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("seek ltx file: %w", err)
+		} else if err := f.Sync(); err != nil {
+			return fmt.Errorf("fsync ltx file: %w", err)
 		}
-	} else {
-		dbMode = DBModeRollback
 
-		// If the database has been deleted, ensure the local files are removed.
-		if err := db.os.Remove("APPLYLTX:DROP:DB", db.DatabasePath()); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("delete database file: %w", err)
-		}
-		if err := db.os.Remove("APPLYLTX:DROP:JOURNAL", db.JournalPath()); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("delete journal file: %w", err)
-		}
-		if err := db.os.Remove("APPLYLTX:DROP:WAL", db.WALPath()); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("delete wal file: %w", err)
-		}
-		if err := db.os.Remove("APPLYLTX:DROP:SHM", db.SHMPath()); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("delete shm file: %w", err)
+		if hdr.IsSnapshot() {
+			dir, file := filepath.Split(tmpPath)
+			if err := removeFilesExcept(db.os, dir, file); err != nil {
+				return fmt.Errorf("remove ltx except snapshot: %w", err)
+			}
 		}
 
-		if invalidator := db.store.Invalidator; invalidator != nil {
-			_ = invalidator.InvalidateEntry(db.Name())
-			_ = invalidator.InvalidateEntry(db.Name() + "-journal")
-			_ = invalidator.InvalidateEntry(db.Name() + "-wal")
-			_ = invalidator.InvalidateEntry(db.Name() + "-shm")
+		if err := db.os.Rename("APPLYLTXSTREAM", tmpPath, path); err != nil {
+			return fmt.Errorf("rename ltx file: %w", err)
+		} else if err := internal.Sync(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("sync ltx dir: %w", err)
 		}
-	}
 
-	trailer = dec.Trailer()
-	db.pageN.Store(dec.Header().Commit)
-	db.mode.Store(dbMode)
+		db.store.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.name).Inc()
+		db.store.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(n))
+		return nil
+	})
+}
 
-	// Ensure checksum matches the post-apply checksum.
-	if chksum, err := db.checksum(dec.Header().Commit, nil); err != nil {
-		return fmt.Errorf("compute checksum: %w", err)
-	} else if chksum != dec.Trailer().PostApplyChecksum {
-		return fmt.Errorf("database checksum %s on TXID %s does not match LTX post-apply checksum %s",
-			chksum, dec.Header().MaxTXID.String(), dec.Trailer().PostApplyChecksum)
+// undoLog records the previous contents of database pages that are
+// overwritten while an LTX stream is applied so they can be restored if the
+// stream turns out to be invalid.
+type undoLog struct {
+	f       *os.File
+	path    string
+	pageN   uint32                  // page count before the apply
+	mode    DBMode                  // database mode before the apply
+	offsets map[uint32]int64        // offset of each saved page in f
+	missing map[uint32]ltx.Checksum // checksums of pages not yet fetched from the primary
+}
+
+// newUndoLog creates an undo log for the current state of the database.
+func (db *DB) newUndoLog() (*undoLog, error) {
+	path := filepath.Join(db.path, "undo")
+	f, err := db.os.OpenFile("UNDO", path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	return &undoLog{
+		f:       f,
+		path:    path,
+		pageN:   db.PageN(),
+		mode:    db.Mode(),
+		offsets: make(map[uint32]int64),
+		missing: make(map[uint32]ltx.Checksum),
+	}, nil
+}
+
+// Close closes & removes the undo log.
+func (u *undoLog) Close() error {
+	_ = u.f.Close()
+	if err := os.Remove(u.path); err != nil && !os.IsNotExist(err) {
+		return err
 	}
+	return nil
+}
 
-	// Update transaction for database.
-	pos := ltx.Pos{
-		TXID:              dec.Header().MaxTXID,
-		PostApplyChecksum: dec.Trailer().PostApplyChecksum,
+// saveUndoPage copies the current contents of pgno from dbFile to the undo log, if it
+// exists & has not already been saved.
+func (db *DB) saveUndoPage(u *undoLog, dbFile *os.File, pgno uint32, buf []byte) error {
+	if _, ok := u.offsets[pgno]; ok || pgno > u.pageN {
+		return nil
 	}
-	if err := db.setPos(pos, dec.Header().Timestamp); err != nil {
-		return fmt.Errorf("set pos: %w", err)
+
+	if lazy := db.lazy.Load(); lazy != nil {
+		lazy.mu.Lock()
+		missing := lazy.has(pgno)
+		lazy.mu.Unlock()
+		if missing {
+			db.chksums.mu.Lock()
+			u.missing[pgno] = db.databasePageChecksum(pgno)
+			db.chksums.mu.Unlock()
+			u.offsets[pgno] = -1
+			return nil
+		}
 	}
 
-	// Rewrite SHM so that the transaction is visible.
-	if err := db.updateSHM(); err != nil {
-		return fmt.Errorf("update shm: %w", err)
+	if _, err := internal.ReadFullAt(dbFile, buf, int64(pgno-1)*int64(db.pageSize)); err != nil {
+		return fmt.Errorf("read database page %d: %w", pgno, err)
+	}
+	offset := int64(len(u.offsets)-len(u.missing)) * int64(db.pageSize)
+	if _, err := u.f.WriteAt(buf, offset); err != nil {
+		return err
 	}
+	u.offsets[pgno] = offset
+	return nil
+}
 
-	// Invalidate entire database if this was a snapshot.
-	if invalidator := db.store.Invalidator; invalidator != nil && hdr.IsSnapshot() {
-		if err := invalidator.InvalidateDB(db); err != nil {
-			return fmt.Errorf("invalidate db: %w", err)
+// rollback restores the pages saved in the undo log & the previous size and
+// mode of the database. If the database was lazy & pages that were not yet
+// fetched were overwritten, a snapshot is requested on the next connection.
+func (db *DB) rollback(u *undoLog, dbFile *os.File) error {
+	buf := make([]byte, db.pageSize)
+	for pgno, offset := range u.offsets {
+		if offset < 0 {
+			continue
+		}
+		if _, err := internal.ReadFullAt(u.f, buf, offset); err != nil {
+			return fmt.Errorf("read undo page %d: %w", pgno, err)
+		} else if err := db.writeDatabasePage(dbFile, pgno, buf, db.store.CacheInvalidationStrategy == InvalidatePerPage); err != nil {
+			return fmt.Errorf("restore page %d: %w", pgno, err)
 		}
 	}
 
-	// Notify store of database change.
-	db.store.MarkDirty(db.name)
+	if err := db.truncateDatabase(dbFile, u.pageN); err != nil {
+		return fmt.Errorf("truncate database file: %w", err)
+	}
+	db.pageN.Store(u.pageN)
+	db.mode.Store(u.mode)
 
-	// Notify event stream subscribers of new transaction.
-	db.store.NotifyEvent(Event{
+	if len(u.missing) > 0 {
+		lazy := db.lazy.Load()
+		if lazy == nil {
+			db.resync.Store(true)
+			return nil
+		}
+		db.chksums.mu.Lock()
+		lazy.mu.Lock()
+		for pgno, chksum := range u.missing {
+			lazy.add(pgno)
+			db.setDatabasePageChecksum(pgno, chksum)
+		}
+		lazy.mu.Unlock()
+		db.chksums.mu.Unlock()
+	}
+	return nil
+}
+
+// nextTXID returns the transaction ID following pos.
+// Returns ErrTXIDOverflow if pos is at the maximum transaction ID.
+func nextTXID(pos ltx.Pos) (ltx.TXID, error) {
+	if pos.TXID == math.MaxUint64 {
+		return 0, ErrTXIDOverflow
+	}
+	return pos.TXID + 1, nil
+}
+
+// ApplyResult represents the outcome of a dry-run LTX apply.
+type ApplyResult struct {
+	TXID              ltx.TXID      // max TXID of the LTX file
+	PagesModified     int           // number of pages in the LTX file
+	EstimatedDuration time.Duration // time taken to decode & verify the file
+}
+
+// DryRunApplyLTX validates that the LTX file at filename can be applied to the
+// database without modifying any state. The position, file checksum & the
+// resulting database checksum are all verified. The result is returned with
+// any validation error so partially decoded files can still be inspected.
+func (db *DB) DryRunApplyLTX(ctx context.Context, filename string) (_ *ApplyResult, err error) {
+	var result ApplyResult
+	defer func() {
+		TraceLog.Printf("[DryRunApplyLTX(%s)]: txid=%s pages=%d path=%s %s",
+			db.name, result.TXID.String(), result.PagesModified, filename, errorKeyValue(err))
+	}()
+
+	f, err := db.os.Open("DRYRUNAPPLYLTX", filename)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// Hold the write lock so the position & page checksums do not change.
+	guardSet, err := db.AcquireWriteLock(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer guardSet.Unlock()
+
+	t := time.Now()
+	defer func() { result.EstimatedDuration = time.Since(t) }()
+
+	dec := ltx.NewDecoder(f)
+	if err := dec.DecodeHeader(); err != nil {
+		return &result, fmt.Errorf("decode ltx header: %w", err)
+	}
+	hdr := dec.Header()
+	result.TXID = hdr.MaxTXID
+
+	if db.pageSize != 0 && hdr.PageSize != db.pageSize {
+		return &result, fmt.Errorf("page size mismatch on db %q: %d <> %d", db.name, db.pageSize, hdr.PageSize)
+	}
+	if !hdr.IsSnapshot() {
+		if pos := db.Pos(); pos != hdr.PreApplyPos() {
+			return &result, fmt.Errorf("position mismatch on db %q: %s <> %s", db.name, pos, hdr.PreApplyPos())
+		}
+	}
+
+	// Compute checksums of the new pages instead of writing them to the database.
+	newChksums := make(map[uint32]ltx.Checksum)
+	pageBuf := make([]byte, hdr.PageSize)
+	for i := 0; ; i++ {
+		var phdr ltx.PageHeader
+		if err := dec.DecodePage(&phdr, pageBuf); err == io.EOF {
+			break
+		} else if err != nil {
+			return &result, fmt.Errorf("decode ltx page[%d]: %w", i, err)
+		}
+		newChksums[phdr.Pgno] = ltx.ChecksumPage(phdr.Pgno, pageBuf)
+		result.PagesModified++
+	}
+
+	if err := dec.Close(); err != nil {
+		return &result, fmt.Errorf("close ltx decode: %w", err)
+	}
+
+	// Snapshots replace every page so the checksum is computed only from the
+	// LTX pages. Otherwise, new pages are combined with the existing pages.
+	chksum := ltx.ChecksumFlag
+	if hdr.IsSnapshot() {
+		for pgno := uint32(1); pgno <= hdr.Commit; pgno++ {
+			if pgno == ltx.LockPgno(hdr.PageSize) {
+				continue
+			}
+			pageChksum, ok := newChksums[pgno]
+			if !ok {
+				return &result, fmt.Errorf("snapshot missing page %d", pgno)
+			}
+			chksum = ltx.ChecksumFlag | (chksum ^ pageChksum)
+		}
+	} else if chksum, err = db.checksum(hdr.Commit, newChksums); err != nil {
+		return &result, fmt.Errorf("compute checksum: %w", err)
+	}
+
+	if chksum != dec.Trailer().PostApplyChecksum {
+		return &result, fmt.Errorf("database checksum %s on TXID %s does not match LTX post-apply checksum %s",
+			chksum, hdr.MaxTXID.String(), dec.Trailer().PostApplyChecksum)
+	}
+
+	return &result, nil
+}
+
+// applyLTXNoLock decodes LTX data from r and writes its pages to the database.
+// If onVerify is specified, it is called after the LTX data has been fully
+// read & verified but before the database position is updated.
+//
+// If undo is specified, overwritten & truncated pages are saved to it and
+// restored if the data fails to apply before the position is updated.
+func (db *DB) applyLTXNoLock(r io.Reader, filename string, fatalOnError bool, undo *undoLog, onVerify func() error) (retErr error) {
+	var hdr ltx.Header
+	var trailer ltx.Trailer
+	prevDBMode := db.Mode()
+	defer func() {
+		TraceLog.Printf("[ApplyLTX(%s)]: txid=%s-%s chksum=%s-%s commit=%d pageSize=%d timestamp=%s mode=(%s→%s) path=%s",
+			db.name, hdr.MinTXID.String(), hdr.MaxTXID.String(), hdr.PreApplyChecksum, trailer.PostApplyChecksum, hdr.Commit, db.pageSize,
+			time.UnixMilli(hdr.Timestamp).UTC().Format(time.RFC3339), prevDBMode, db.Mode(), filename)
+	}()
+
+	dec := ltx.NewDecoder(r)
+	if err := dec.DecodeHeader(); err != nil {
+		return fmt.Errorf("decode ltx header: %s", err)
+	}
+	hdr = dec.Header()
+	if db.pageSize == 0 {
+		db.pageSize = dec.Header().PageSize
+	}
+
+	// Delete database files if this has a zero "commit" field.
+	dbMode := db.Mode()
+	var dbFile *os.File
+	if dec.Header().Commit > 0 {
+		var err error
+		if dbFile, err = db.os.OpenFile("APPLYLTX:DB", db.DatabasePath(), os.O_RDWR|os.O_CREATE, 0o666); err != nil {
+			return fmt.Errorf("open database file: %w", err)
+		}
+		defer func() { _ = dbFile.Close() }()
+	}
+
+	// Restore the previous pages if the transaction fails before it is
+	// committed & an undo log is available.
+	var committed bool
+	if undo != nil {
+		defer func() {
+			if retErr == nil || committed {
+				return
+			}
+			if dbFile == nil {
+				db.resync.Store(true)
+				return
+			}
+			if err := db.rollback(undo, dbFile); err != nil {
+				db.logger().Error("cannot roll back ltx, exiting", slog.String("txid", hdr.MaxTXID.String()), slog.Any("err", err))
+				db.store.Exit(99)
+			}
+		}()
+	}
+
+	// After this point, a partial failure will result in a partially written
+	// database. We don't have the ability to signal to the client that a failure
+	// occurred so we need to exit.
+	defer func() {
+		if fatalOnError && retErr != nil {
+			TraceLog.Printf("[FATAL(%s)]: err=%d\n", db.name, retErr)
+			db.logger().Error("fatal error occurred while applying ltx, exiting", slog.String("txid", hdr.MaxTXID.String()), slog.Any("err", retErr))
+			db.store.Exit(99)
+		}
+	}()
+
+	strategy := db.store.CacheInvalidationStrategy
+	pageBuf := make([]byte, dec.Header().PageSize)
+	undoBuf := make([]byte, dec.Header().PageSize)
+	for i := 0; ; i++ {
+		// Read pgno & page data from LTX file.
+		var phdr ltx.PageHeader
+		if err := dec.DecodePage(&phdr, pageBuf); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("decode ltx page[%d]: %w", i, err)
+		}
+
+		// Update the mode if this is the first page and the write/read versions as set to WAL (2).
+		if phdr.Pgno == 1 && pageBuf[18] == 2 && pageBuf[19] == 2 {
+			dbMode = DBModeWAL
+		}
+
+		// Save the previous page so it can be restored on failure.
+		if undo != nil {
+			if err := db.saveUndoPage(undo, dbFile, phdr.Pgno, undoBuf); err != nil {
+				return fmt.Errorf("save undo page: %w", err)
+			}
+		}
+
+		// Copy to database file.
+		if err := db.writeDatabasePage(dbFile, phdr.Pgno, pageBuf, strategy == InvalidatePerPage); err != nil {
+			return fmt.Errorf("write to database file: %w", err)
+		}
+	}
+
+	// Close the reader so we can verify file integrity.
+	if err := dec.Close(); err != nil {
+		return fmt.Errorf("close ltx decode: %w", err)
+	}
+
+	// Truncate database file to size after LTX file.
+	// If this is zero-length database then delete all the database files.
+	if dec.Header().Commit > 0 {
+		if undo != nil {
+			for pgno := dec.Header().Commit + 1; pgno <= undo.pageN; pgno++ {
+				if err := db.saveUndoPage(undo, dbFile, pgno, undoBuf); err != nil {
+					return fmt.Errorf("save undo page: %w", err)
+				}
+			}
+		}
+		if err := db.truncateDatabase(dbFile, dec.Header().Commit); err != nil {
+			return fmt.Errorf("truncate database file: %w", err)
+		}
+	} else {
+		dbMode = DBModeRollback
+
+		// If the database has been deleted, ensure the local files are removed.
+		if err := db.os.Remove("APPLYLTX:DROP:DB", db.DatabasePath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("delete database file: %w", err)
+		}
+		if err := db.os.Remove("APPLYLTX:DROP:JOURNAL", db.JournalPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("delete journal file: %w", err)
+		}
+		if err := db.os.Remove("APPLYLTX:DROP:WAL", db.WALPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("delete wal file: %w", err)
+		}
+		if err := db.os.Remove("APPLYLTX:DROP:SHM", db.SHMPath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("delete shm file: %w", err)
+		}
+
+		if invalidator := db.store.Invalidator; invalidator != nil {
+			_ = invalidator.InvalidateEntry(db.Name())
+			_ = invalidator.InvalidateEntry(db.Name() + "-journal")
+			_ = invalidator.InvalidateEntry(db.Name() + "-wal")
+			_ = invalidator.InvalidateEntry(db.Name() + "-shm")
+		}
+	}
+
+	trailer = dec.Trailer()
+	db.pageN.Store(dec.Header().Commit)
+	db.mode.Store(dbMode)
+
+	// Ensure checksum matches the post-apply checksum.
+	if chksum, err := db.checksum(dec.Header().Commit, nil); err != nil {
+		return fmt.Errorf("compute checksum: %w", err)
+	} else if chksum != dec.Trailer().PostApplyChecksum {
+		return fmt.Errorf("database checksum %s on TXID %s does not match LTX post-apply checksum %s",
+			chksum, dec.Header().MaxTXID.String(), dec.Trailer().PostApplyChecksum)
+	}
+
+	if onVerify != nil {
+		if err := onVerify(); err != nil {
+			return err
+		}
+	}
+
+	// Update transaction for database.
+	pos := ltx.Pos{
+		TXID:              dec.Header().MaxTXID,
+		PostApplyChecksum: dec.Trailer().PostApplyChecksum,
+	}
+	if err := db.setPos(pos, dec.Header().Timestamp); err != nil {
+		return fmt.Errorf("set pos: %w", err)
+	}
+	committed = true
+
+	// Rewrite SHM so that the transaction is visible.
+	if err := db.updateSHM(); err != nil {
+		return fmt.Errorf("update shm: %w", err)
+	}
+
+	// Invalidate entire database if this was a snapshot or if pages were not
+	// invalidated individually.
+	if invalidator := db.store.Invalidator; invalidator != nil && strategy != NoInvalidate && (hdr.IsSnapshot() || strategy == InvalidateAll) {
+		if err := invalidator.InvalidateDB(db); err != nil {
+			return fmt.Errorf("invalidate db: %w", err)
+		}
+	}
+
+	// Notify store of database change.
+	db.store.MarkDirty(db.name)
+
+	// Notify event stream subscribers of new transaction.
+	db.store.NotifyEvent(Event{
 		Type: EventTypeTx,
 		DB:   db.name,
 		Data: TxEventData{
@@ -2583,7 +3269,7 @@ func (db *DB) ApplyLTXNoLock(path string, fatalOnError bool) (retErr error) {
 
 	// Calculate latency since LTX file was written.
 	latency := float64(time.Now().UnixMilli()-dec.Header().Timestamp) / 1000
-	dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(latency)
+	db.store.dbMetrics.dbLatencySecondsMetricVec.WithLabelValues(db.name).Set(latency)
 
 	return nil
 }
@@ -2659,6 +3345,10 @@ func (db *DB) updateSHM() error {
 // Export writes the contents of the database to dst.
 // Returns the current replication position.
 func (db *DB) Export(ctx context.Context, dst io.Writer) (ltx.Pos, error) {
+	if db.IsLazy() {
+		return db.Pos(), ErrDBIncomplete
+	}
+
 	gs := db.newGuardSet(0) // TODO(fsm): Track internal owners?
 	defer gs.Unlock()
 
@@ -2755,6 +3445,84 @@ func (db *DB) Export(ctx context.Context, dst io.Writer) (ltx.Pos, error) {
 	return pos, nil
 }
 
+// Verify recomputes the checksum of the database from its contents and
+// compares it to the checksum of the current position. Returns the position
+// and ErrChecksumMismatch if they differ.
+func (db *DB) Verify(ctx context.Context) (ltx.Pos, error) {
+	if db.PageN() == 0 || db.pageSize == 0 {
+		return db.Pos(), nil // empty database
+	} else if db.IsLazy() {
+		return db.Pos(), nil // fetched pages are verified individually
+	}
+
+	w := newChecksumWriter(db.pageSize)
+	pos, err := db.Export(ctx, w)
+	if err != nil {
+		return pos, err
+	} else if chksum := w.Checksum(); chksum != pos.PostApplyChecksum {
+		return pos, fmt.Errorf("%w: txid=%s checksum=%s, expected %s", ErrChecksumMismatch, pos.TXID, chksum, pos.PostApplyChecksum)
+	}
+	return pos, nil
+}
+
+// checksumWriter computes the LTX checksum of a database written to it
+// sequentially, page by page.
+type checksumWriter struct {
+	buf      []byte
+	n        int
+	pgno     uint32
+	lockPgno uint32
+	chksum   ltx.Checksum
+}
+
+func newChecksumWriter(pageSize uint32) *checksumWriter {
+	return &checksumWriter{
+		buf:      make([]byte, pageSize),
+		pgno:     1,
+		lockPgno: ltx.LockPgno(pageSize),
+	}
+}
+
+func (w *checksumWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		sz := copy(w.buf[w.n:], p)
+		w.n, p, n = w.n+sz, p[sz:], n+sz
+
+		if w.n < len(w.buf) {
+			continue
+		}
+
+		// Add the page to the rolling checksum, skipping the lock page.
+		if w.pgno != w.lockPgno {
+			w.chksum = ltx.ChecksumFlag | (w.chksum ^ ltx.ChecksumPage(w.pgno, w.buf))
+		}
+		w.n = 0
+		w.pgno++
+	}
+	return n, nil
+}
+
+// Checksum returns the checksum of all complete pages written.
+func (w *checksumWriter) Checksum() ltx.Checksum { return w.chksum }
+
+// skipWriter discards the first n bytes written before passing writes through.
+type skipWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *skipWriter) Write(p []byte) (int, error) {
+	if w.n >= int64(len(p)) {
+		w.n -= int64(len(p))
+		return len(p), nil
+	}
+
+	skip := w.n
+	w.n = 0
+	n, err := w.w.Write(p[skip:])
+	return int(skip) + n, err
+}
+
 // Import replaces the contents of the database with the contents from the r.
 // NOTE: LiteFS does not validate the integrity of the imported database!
 func (db *DB) Import(ctx context.Context, r io.Reader) error {
@@ -3067,6 +3835,21 @@ func (db *DB) TryLocks(ctx context.Context, owner uint64, lockTypes []LockType)
 			return false, nil
 		}
 
+		// Starting a write transaction on a replica acquires the remote halt
+		// lock so the transaction can be forwarded to the primary on commit.
+		// This occurs before taking the guard so other owners are not blocked
+		// on the local lock while the request to the primary is in flight.
+		var forwarded bool
+		if lockType == LockTypeReserved || lockType == LockTypeWrite {
+			var err error
+			if forwarded, err = db.acquireForwardLock(ctx, owner); err == errForwardLockBusy {
+				TraceLog.Printf("[TryLock(%s)]: type=%s owner=%d status=FORWARD-BUSY", db.name, lockType, owner)
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+		}
+
 		ok := guard.TryLock()
 
 		status := "OK"
@@ -3076,22 +3859,69 @@ func (db *DB) TryLocks(ctx context.Context, owner uint64, lockTypes []LockType)
 		TraceLog.Printf("[TryLock(%s)]: type=%s owner=%d status=%s", db.name, lockType, owner, status)
 
 		if !ok {
+			if forwarded {
+				if err := db.releaseForwardLock(ctx, owner); err != nil {
+					db.logger().Warn("release forward lock error", slog.Any("err", err))
+				}
+			}
 			return false, nil
 		}
+	}
+	return true, nil
+}
+
+// errForwardLockBusy is returned when another owner is forwarding a write.
+var errForwardLockBusy = errors.New("write forwarding: lock held by another owner")
+
+// acquireForwardLock acquires the remote halt lock for owner's write
+// transaction if write forwarding is enabled and the node is a replica.
+// Returns true if the lock was acquired by this call. This is a no-op if the
+// node is the primary, if owner already holds the lock, or if a remote halt
+// lock was acquired outside of write forwarding. Returns errForwardLockBusy
+// if another owner holds the lock.
+func (db *DB) acquireForwardLock(ctx context.Context, owner uint64) (bool, error) {
+	if !db.store.WriteForwarding || db.store.IsPrimary() {
+		return false, nil
+	}
+
+	db.forwardMu.Lock()
+	defer db.forwardMu.Unlock()
 
-		// TODO(fwd): Move remote lock to lock byte on database.
+	if db.forwardLockID != 0 {
+		if db.forwardOwner != owner {
+			return false, errForwardLockBusy
+		}
+		return false, nil
+	} else if db.HasRemoteHaltLock() {
+		return false, nil
+	}
 
-		// Start a new transaction on the database. This may start a remote transaction.
-		//if lockType == LockTypeWrite {
-		//	if err := db.store.Begin(context.Background(), db.name); err != nil {
-		//		guard.Unlock()
-		//		return false, err
-		//	}
-		//}
+	lockID := rand.Int63()
+	if _, err := db.AcquireRemoteHaltLock(ctx, lockID); err != nil {
+		return false, fmt.Errorf("write forwarding: %w", err)
 	}
+	db.forwardLockID, db.forwardOwner = lockID, owner
 	return true, nil
 }
 
+// releaseForwardLock releases the remote halt lock acquired by write
+// forwarding, if it is held by owner.
+func (db *DB) releaseForwardLock(ctx context.Context, owner uint64) error {
+	db.forwardMu.Lock()
+	defer db.forwardMu.Unlock()
+
+	if db.forwardLockID == 0 || db.forwardOwner != owner {
+		return nil
+	}
+	lockID := db.forwardLockID
+	db.forwardLockID, db.forwardOwner = 0, 0
+
+	if err := db.ReleaseRemoteHaltLock(ctx, lockID); err != nil {
+		return fmt.Errorf("write forwarding: %w", err)
+	}
+	return nil
+}
+
 // CanLock returns true if all locks can acquire a write lock.
 // If false, also returns the mutex state of the blocking lock.
 func (db *DB) CanLock(ctx context.Context, owner uint64, lockTypes []LockType) (bool, RWMutexState) {
@@ -3146,20 +3976,55 @@ func (db *DB) Unlock(ctx context.Context, owner uint64, lockTypes []LockType) er
 	// Process WAL if we have an exclusive lock on WAL_WRITE_LOCK.
 	if ContainsLockType(lockTypes, LockTypeWrite) && guardSet.Write().State() == RWMutexStateExclusive {
 		if err := db.CommitWAL(ctx); err != nil {
-			log.Printf("commit wal error(2): %s", err)
+			db.logger().Error("commit wal error", slog.Any("err", err))
 		}
 	}
 
+	// Release the forwarded write transaction once the owner gives up its
+	// RESERVED or WAL_WRITE_LOCK lock. Any commit has already been sent.
+	endTx := (ContainsLockType(lockTypes, LockTypeReserved) && guardSet.Reserved().State() == RWMutexStateExclusive) ||
+		(ContainsLockType(lockTypes, LockTypeWrite) && guardSet.Write().State() == RWMutexStateExclusive)
+
 	for _, lockType := range lockTypes {
 		TraceLog.Printf("[Unlock(%s)]: type=%s owner=%d", db.name, lockType, owner)
 		guardSet.Guard(lockType).Unlock()
 	}
 
+	if endTx {
+		if err := db.releaseForwardLock(ctx, owner); err != nil {
+			db.logger().Warn("release forward lock error", slog.Any("err", err))
+		}
+		db.waitForReplicas(ctx)
+	}
+
 	// TODO: Release guard set if completely unlocked.
 
 	return nil
 }
 
+// waitForReplicas blocks until synchronous replicas acknowledge the last
+// committed transaction & while replicas are too far behind, if required.
+// This is called after SQLite releases its locks so that other connections
+// are not blocked while the committing connection waits. The commit is
+// already durable on the primary at this point so a timeout is logged
+// instead of being returned as an error.
+func (db *DB) waitForReplicas(ctx context.Context) {
+	txID := ltx.TXID(db.pendingTXID.Swap(0))
+	if txID == 0 {
+		return
+	}
+
+	if err := db.store.waitForSyncReplicas(ctx, db.name, txID); err != nil {
+		db.logger().Warn("transaction not acknowledged by sync replicas", slog.String("txid", txID.String()), slog.Any("err", err))
+		return
+	}
+
+	// Slow down writes while replicas are too far behind.
+	if err := db.store.waitForBackpressure(ctx, db.name, txID); err != nil {
+		db.logger().Warn("backpressure wait canceled", slog.String("txid", txID.String()), slog.Any("err", err))
+	}
+}
+
 // InWriteTx returns true if the RESERVED lock has an exclusive lock.
 func (db *DB) InWriteTx() bool {
 	return db.reservedLock.State() == RWMutexStateExclusive
@@ -3321,8 +4186,575 @@ func (db *DB) resetDatabasePageChecksumsAfter(commit uint32) {
 	}
 }
 
+// lazyPageFetchTimeout is the maximum time to wait for the primary when
+// fetching a single page that was not included in a lazy snapshot.
+const lazyPageFetchTimeout = 10 * time.Second
+
+// lazySnapshotHeader is written at the beginning of a lazy snapshot. It is
+// followed by the checksum of every page & the contents of the first page.
+type lazySnapshotHeader struct {
+	PageSize          uint32
+	Commit            uint32
+	TXID              ltx.TXID
+	PostApplyChecksum ltx.Checksum
+	Timestamp         int64
+}
+
+// lazyPageSet tracks the pages of a database that have not been fetched from
+// the primary since it was bootstrapped from a lazy snapshot.
+type lazyPageSet struct {
+	mu       sync.Mutex
+	missing  []uint64                 // bitset of missing pages, indexed by pgno-1
+	n        int                      // number of missing pages
+	fetching map[uint32]chan struct{} // in-flight fetches, closed when done
+}
+
+// newLazyPageSet returns a set with every page up to commit marked as missing
+// except for the first page & the lock page.
+func newLazyPageSet(pageSize, commit uint32) *lazyPageSet {
+	s := &lazyPageSet{
+		missing:  make([]uint64, (commit+63)/64),
+		fetching: make(map[uint32]chan struct{}),
+	}
+	lockPgno := ltx.LockPgno(pageSize)
+	for pgno := uint32(2); pgno <= commit; pgno++ {
+		if pgno != lockPgno {
+			s.missing[(pgno-1)/64] |= 1 << ((pgno - 1) % 64)
+			s.n++
+		}
+	}
+	return s
+}
+
+// has returns true if pgno is missing. Must hold mu.
+func (s *lazyPageSet) has(pgno uint32) bool {
+	i := (pgno - 1) / 64
+	return i < uint32(len(s.missing)) && s.missing[i]&(1<<((pgno-1)%64)) != 0
+}
+
+// add marks pgno as missing. Must hold mu.
+func (s *lazyPageSet) add(pgno uint32) {
+	if !s.has(pgno) {
+		s.missing[(pgno-1)/64] |= 1 << ((pgno - 1) % 64)
+		s.n++
+	}
+}
+
+// remove marks pgno as no longer missing. Must hold mu.
+func (s *lazyPageSet) remove(pgno uint32) {
+	if s.has(pgno) {
+		s.missing[(pgno-1)/64] &^= 1 << ((pgno - 1) % 64)
+		s.n--
+	}
+}
+
+// IsLazy returns true if the database was bootstrapped from a lazy snapshot
+// & some of its pages have not been fetched from the primary yet.
+func (db *DB) IsLazy() bool { return db.lazy.Load() != nil }
+
+// WriteLazySnapshotTo writes the current position, the checksum of every page
+// & the contents of the first page to dst. A replica applies this with
+// ApplyLazySnapshot & fetches the remaining pages on demand with ReadPage.
+func (db *DB) WriteLazySnapshotTo(ctx context.Context, dst io.Writer) (pos ltx.Pos, err error) {
+	hdr, chksums, page1, err := db.lazySnapshot(ctx)
+	if err != nil {
+		return pos, err
+	}
+
+	// Write checksums in batches so the entire list is not encoded at once.
+	bw := bufio.NewWriter(dst)
+	if err := binary.Write(bw, binary.BigEndian, hdr); err != nil {
+		return pos, fmt.Errorf("write lazy snapshot header: %w", err)
+	}
+	buf := make([]byte, 0, 8*1024)
+	for i, chksum := range chksums {
+		if buf = binary.BigEndian.AppendUint64(buf, uint64(chksum)); len(buf) < cap(buf) && i < len(chksums)-1 {
+			continue
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return pos, fmt.Errorf("write lazy snapshot checksums: %w", err)
+		}
+		buf = buf[:0]
+	}
+	if _, err := bw.Write(page1); err != nil {
+		return pos, fmt.Errorf("write lazy snapshot page: %w", err)
+	} else if err := bw.Flush(); err != nil {
+		return pos, fmt.Errorf("flush lazy snapshot: %w", err)
+	}
+
+	return ltx.Pos{TXID: hdr.TXID, PostApplyChecksum: hdr.PostApplyChecksum}, nil
+}
+
+// lazySnapshot returns a consistent copy of the position, page checksums &
+// the first page of the database.
+func (db *DB) lazySnapshot(ctx context.Context) (hdr lazySnapshotHeader, chksums []ltx.Checksum, page1 []byte, err error) {
+	if db.IsLazy() {
+		return hdr, nil, nil, ErrDBIncomplete
+	}
+
+	gs := db.newGuardSet(0)
+	defer gs.Unlock()
+
+	// Acquire PENDING then SHARED. Release PENDING immediately afterward.
+	if err := gs.pending.RLock(ctx); err != nil {
+		return hdr, nil, nil, fmt.Errorf("acquire PENDING read lock: %w", err)
+	}
+	if err := gs.shared.RLock(ctx); err != nil {
+		return hdr, nil, nil, fmt.Errorf("acquire SHARED read lock: %w", err)
+	}
+	gs.pending.Unlock()
+
+	// Hold the write lock in WAL mode so the checksums of WAL pages are stable.
+	if db.Mode() == DBModeWAL {
+		if err := gs.write.Lock(ctx); err != nil {
+			return hdr, nil, nil, fmt.Errorf("acquire exclusive WAL_WRITE_LOCK: %w", err)
+		}
+	}
+
+	pos, pageN := db.Pos(), db.PageN()
+	if pageN == 0 {
+		return hdr, nil, nil, fmt.Errorf("cannot write lazy snapshot of empty database")
+	}
+	hdr = lazySnapshotHeader{
+		PageSize:          db.pageSize,
+		Commit:            pageN,
+		TXID:              pos.TXID,
+		PostApplyChecksum: pos.PostApplyChecksum,
+		Timestamp:         atomic.LoadInt64(&db.timestamp),
+	}
+
+	chksums = make([]ltx.Checksum, pageN)
+	chksum := ltx.ChecksumFlag
+	db.chksums.mu.Lock()
+	for pgno := uint32(1); pgno <= pageN; pgno++ {
+		pageChksum, ok := db.pageChecksum(pgno, pageN, nil)
+		if !ok {
+			db.chksums.mu.Unlock()
+			return hdr, nil, nil, fmt.Errorf("missing checksum for page %d", pgno)
+		}
+		chksums[pgno-1] = pageChksum
+		chksum = ltx.ChecksumFlag | (chksum ^ pageChksum)
+	}
+	db.chksums.mu.Unlock()
+
+	if chksum != pos.PostApplyChecksum {
+		return hdr, nil, nil, fmt.Errorf("lazy snapshot checksum mismatch at tx %s: %s <> %s", pos.TXID.String(), chksum, pos.PostApplyChecksum)
+	}
+
+	if page1, err = db.readCommittedPage(1, db.wal.frameOffsets[1]); err != nil {
+		return hdr, nil, nil, err
+	}
+	return hdr, chksums, page1, nil
+}
+
+// ReadPage returns the latest committed contents of a page & the TXID it was
+// read at. This is used by replicas to fetch pages that were not included in
+// a lazy snapshot. Returns ErrPositionMismatch if the database is behind txID.
+//
+// Only read locks are held so writers are not blocked in WAL mode. Holding
+// the CKPT & READ locks prevents committed pages from being checkpointed or
+// overwritten by a WAL restart while the page is read.
+func (db *DB) ReadPage(ctx context.Context, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error) {
+	if db.IsLazy() {
+		return nil, 0, ErrDBIncomplete
+	}
+
+	gs := db.newGuardSet(0)
+	defer gs.Unlock()
+
+	// Acquire PENDING then SHARED. Release PENDING immediately afterward.
+	if err := gs.pending.RLock(ctx); err != nil {
+		return nil, 0, fmt.Errorf("acquire PENDING read lock: %w", err)
+	}
+	if err := gs.shared.RLock(ctx); err != nil {
+		return nil, 0, fmt.Errorf("acquire SHARED read lock: %w", err)
+	}
+	gs.pending.Unlock()
+
+	if db.Mode() == DBModeWAL {
+		if err := gs.ckpt.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire CKPT read lock: %w", err)
+		}
+		if err := gs.recover.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire RECOVER read lock: %w", err)
+		}
+		if err := gs.read0.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire READ0 read lock: %w", err)
+		}
+		if err := gs.read1.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire READ1 read lock: %w", err)
+		}
+		if err := gs.read2.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire READ2 read lock: %w", err)
+		}
+		if err := gs.read3.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire READ3 read lock: %w", err)
+		}
+		if err := gs.read4.RLock(ctx); err != nil {
+			return nil, 0, fmt.Errorf("acquire READ4 read lock: %w", err)
+		}
+	}
+
+	// Determine the location of the page as of the current position.
+	db.wal.mu.RLock()
+	walOffset := db.wal.frameOffsets[pgno]
+	pos, pageN := db.Pos(), db.PageN()
+	db.wal.mu.RUnlock()
+
+	if pos.TXID < txID {
+		return nil, pos.TXID, fmt.Errorf("%w: database at tx %s, requested %s", ErrPositionMismatch, pos.TXID.String(), txID.String())
+	} else if pgno == 0 || pgno > pageN {
+		return nil, pos.TXID, fmt.Errorf("page %d out of range", pgno)
+	}
+
+	data, err := db.readCommittedPage(pgno, walOffset)
+	if err != nil {
+		return nil, pos.TXID, err
+	}
+	return data, pos.TXID, nil
+}
+
+// readCommittedPage reads a page from the WAL frame at walOffset or, if
+// walOffset is zero, from the database file.
+func (db *DB) readCommittedPage(pgno uint32, walOffset int64) ([]byte, error) {
+	buf := make([]byte, db.pageSize)
+	if walOffset == 0 {
+		dbFile, err := db.os.Open("READPAGE:DB", db.DatabasePath())
+		if err != nil {
+			return nil, fmt.Errorf("open database file: %w", err)
+		}
+		defer func() { _ = dbFile.Close() }()
+
+		if _, err := internal.ReadFullAt(dbFile, buf, int64(pgno-1)*int64(db.pageSize)); err != nil {
+			return nil, fmt.Errorf("read database page: %w", err)
+		}
+		return buf, nil
+	}
+
+	walFile, err := db.os.Open("READPAGE:WAL", db.WALPath())
+	if err != nil {
+		return nil, fmt.Errorf("open wal file: %w", err)
+	}
+	defer func() { _ = walFile.Close() }()
+
+	if _, err := internal.ReadFullAt(walFile, buf, walOffset+WALFrameHeaderSize); err != nil {
+		return nil, fmt.Errorf("read wal page: %w", err)
+	}
+	return buf, nil
+}
+
+// ApplyLazySnapshot bootstraps the database from a lazy snapshot written by
+// WriteLazySnapshotTo. Only the first page is written to the database file &
+// the remaining pages are fetched from the primary when they are first read.
+// Each fetched page is verified against the checksum in the snapshot.
+//
+// An incomplete database cannot be recovered after a restart so it is removed
+// & a new snapshot is requested when the database is next opened.
+func (db *DB) ApplyLazySnapshot(ctx context.Context, r io.Reader) (err error) {
+	var hdr lazySnapshotHeader
+	defer func() {
+		TraceLog.Printf("[ApplyLazySnapshot(%s)]: txid=%s chksum=%s commit=%d pageSize=%d %s",
+			db.name, hdr.TXID.String(), hdr.PostApplyChecksum, hdr.Commit, hdr.PageSize, errorKeyValue(err))
+	}()
+
+	br := bufio.NewReader(r)
+	if err := binary.Read(br, binary.BigEndian, &hdr); err != nil {
+		return fmt.Errorf("read lazy snapshot header: %w", err)
+	} else if !ltx.IsValidPageSize(hdr.PageSize) {
+		return fmt.Errorf("invalid lazy snapshot page size: %d", hdr.PageSize)
+	} else if hdr.Commit == 0 {
+		return fmt.Errorf("lazy snapshot page count required")
+	}
+
+	// Read page checksums & verify that they match the database checksum.
+	chksums := make([]ltx.Checksum, hdr.Commit)
+	buf := make([]byte, 8)
+	chksum := ltx.ChecksumFlag
+	for i := range chksums {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return fmt.Errorf("read lazy snapshot checksum: %w", err)
+		}
+		chksums[i] = ltx.Checksum(binary.BigEndian.Uint64(buf))
+		chksum = ltx.ChecksumFlag | (chksum ^ chksums[i])
+	}
+	if chksum != hdr.PostApplyChecksum {
+		return fmt.Errorf("lazy snapshot checksum mismatch: %s <> %s", chksum, hdr.PostApplyChecksum)
+	}
+
+	page1 := make([]byte, hdr.PageSize)
+	if _, err := io.ReadFull(br, page1); err != nil {
+		return fmt.Errorf("read lazy snapshot page: %w", err)
+	} else if ltx.ChecksumPage(1, page1) != chksums[0] {
+		return fmt.Errorf("lazy snapshot page 1 checksum mismatch")
+	}
+
+	guardSet, err := db.AcquireWriteLock(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer guardSet.Unlock()
+
+	// Mark the database as incomplete before changing any data.
+	if err := db.os.WriteFile("APPLYLAZYSNAPSHOT", db.LazyPath(), nil, 0o666); err != nil {
+		return fmt.Errorf("write lazy marker: %w", err)
+	} else if err := internal.Sync(db.path); err != nil {
+		return fmt.Errorf("sync db dir: %w", err)
+	}
+
+	// Existing LTX files do not apply to the new database state.
+	if err := removeFilesExcept(db.os, db.LTXDir(), ""); err != nil {
+		return fmt.Errorf("remove ltx files: %w", err)
+	} else if err := db.os.Remove("APPLYLAZYSNAPSHOT", db.PartialSnapshotPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove partial snapshot: %w", err)
+	}
+
+	// Replace the database with a sparse file holding only the first page.
+	f, err := db.os.OpenFile("APPLYLAZYSNAPSHOT", db.DatabasePath(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return fmt.Errorf("open database file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := f.Truncate(int64(hdr.Commit) * int64(hdr.PageSize)); err != nil {
+		return fmt.Errorf("truncate database file: %w", err)
+	} else if _, err := f.WriteAt(page1, 0); err != nil {
+		return fmt.Errorf("write database page: %w", err)
+	} else if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync database file: %w", err)
+	}
+
+	db.pageSize = hdr.PageSize
+	db.lazy.Store(newLazyPageSet(hdr.PageSize, hdr.Commit))
+
+	db.chksums.mu.Lock()
+	db.chksums.pages = chksums
+	db.chksums.blocks = make([]ltx.Checksum, pageChksumBlock(hdr.Commit))
+	db.chksums.mu.Unlock()
+
+	dbMode := DBModeRollback
+	if page1[18] == 2 && page1[19] == 2 {
+		dbMode = DBModeWAL
+	}
+	db.pageN.Store(hdr.Commit)
+	db.mode.Store(dbMode)
+
+	pos := ltx.Pos{TXID: hdr.TXID, PostApplyChecksum: hdr.PostApplyChecksum}
+	if err := db.setPos(pos, hdr.Timestamp); err != nil {
+		return fmt.Errorf("set pos: %w", err)
+	}
+
+	// Rewrite SHM so that the transaction is visible.
+	if err := db.updateSHM(); err != nil {
+		return fmt.Errorf("update shm: %w", err)
+	}
+
+	if invalidator := db.store.Invalidator; invalidator != nil && db.store.CacheInvalidationStrategy != NoInvalidate {
+		if err := invalidator.InvalidateDB(db); err != nil {
+			return fmt.Errorf("invalidate db: %w", err)
+		}
+	}
+
+	db.logger().Info("lazy snapshot applied, pages will be fetched on demand", slog.String("txid", pos.TXID.String()), slog.Uint64("page-n", uint64(hdr.Commit)))
+
+	db.store.MarkDirty(db.name)
+
+	db.store.NotifyEvent(Event{
+		Type: EventTypeTx,
+		DB:   db.name,
+		Data: TxEventData{
+			TXID:              pos.TXID,
+			PostApplyChecksum: pos.PostApplyChecksum,
+			PageSize:          hdr.PageSize,
+			Commit:            hdr.Commit,
+			Timestamp:         time.UnixMilli(hdr.Timestamp).UTC(),
+		},
+	})
+
+	return nil
+}
+
+// fetchLazyPages fetches pages within the byte range from the primary if they
+// have not been received since the lazy snapshot & writes them to f.
+func (db *DB) fetchLazyPages(ctx context.Context, lazy *lazyPageSet, f *os.File, offset int64, n int, owner uint64) error {
+	if n == 0 || db.pageSize == 0 {
+		return nil
+	}
+	first := uint32(offset/int64(db.pageSize)) + 1
+	last := uint32((offset+int64(n)-1)/int64(db.pageSize)) + 1
+
+	for pgno := first; pgno <= last; pgno++ {
+		if err := db.fetchLazyPage(ctx, lazy, f, pgno, owner); err != nil {
+			return fmt.Errorf("fetch page %d: %w", pgno, err)
+		}
+	}
+	return nil
+}
+
+// fetchLazyPage fetches a single page from the primary, if it is still
+// missing, & writes it to f. Only one fetch runs for each page at a time and
+// concurrent reads of the same page wait for it to finish. The lazy.mu lock
+// is not held during the fetch so other pages can be read & written.
+//
+// If the page has changed on the primary since the replica's position then
+// the new version is received with a later LTX file. The fetch waits for it to
+// be applied & tries again. The LTX file cannot be applied while the reader
+// holds database locks so, in that case, the fetch fails with
+// ErrChecksumMismatch instead & the read can be retried once they are released.
+func (db *DB) fetchLazyPage(ctx context.Context, lazy *lazyPageSet, f *os.File, pgno uint32, owner uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, lazyPageFetchTimeout)
+	defer cancel()
+
+	for {
+		lazy.mu.Lock()
+		if !lazy.has(pgno) {
+			lazy.mu.Unlock()
+			return nil
+		}
+
+		// Wait for an in-flight fetch of the same page & check again.
+		if ch := lazy.fetching[pgno]; ch != nil {
+			lazy.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			case <-ch:
+				continue
+			}
+		}
+		ch := make(chan struct{})
+		lazy.fetching[pgno] = ch
+		lazy.mu.Unlock()
+
+		primaryTXID, err := db.fetchLazyPageOnce(ctx, lazy, f, pgno)
+
+		lazy.mu.Lock()
+		delete(lazy.fetching, pgno)
+		close(ch)
+		lazy.mu.Unlock()
+
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, ErrChecksumMismatch) || primaryTXID <= db.TXID() {
+			return err
+		} else if gs := db.GuardSet(owner); gs != nil && gs.Locked() {
+			return err
+		}
+
+		// Wait for the replica to reach the position the page was read at.
+		if err := db.waitTXID(ctx, primaryTXID); err != nil {
+			return fmt.Errorf("wait for tx %s: %w", primaryTXID.String(), err)
+		}
+	}
+}
+
+// fetchLazyPageOnce fetches a single page from the primary, verifies it
+// against the expected page checksum & writes it to f. Returns the TXID the
+// page was read at on the primary.
+func (db *DB) fetchLazyPageOnce(ctx context.Context, lazy *lazyPageSet, f *os.File, pgno uint32) (ltx.TXID, error) {
+	_, info := db.store.PrimaryInfo()
+	if info == nil {
+		return 0, fmt.Errorf("no primary available")
+	}
+
+	data, primaryTXID, err := db.store.Client.FetchPage(ctx, info.AdvertiseURL, db.store.ID(), db.name, pgno, db.TXID())
+	db.setPrimaryTXID(primaryTXID)
+	if err != nil {
+		return primaryTXID, err
+	} else if len(data) != int(db.pageSize) {
+		return primaryTXID, fmt.Errorf("unexpected page size: %d", len(data))
+	}
+
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+
+	// The page may have been received with an LTX file during the fetch.
+	if !lazy.has(pgno) {
+		return primaryTXID, nil
+	}
+
+	db.chksums.mu.Lock()
+	expected := db.databasePageChecksum(pgno)
+	db.chksums.mu.Unlock()
+
+	if chksum := ltx.ChecksumPage(pgno, data); chksum != expected {
+		db.store.dbMetrics.dbLazyPageFetchCountMetricVec.WithLabelValues(db.name, "mismatch").Inc()
+		return primaryTXID, fmt.Errorf("%w: page checksum %s, expected %s", ErrChecksumMismatch, chksum, expected)
+	}
+
+	if _, err := f.WriteAt(data, int64(pgno-1)*int64(db.pageSize)); err != nil {
+		return primaryTXID, err
+	}
+	db.store.dbMetrics.dbLazyPageFetchCountMetricVec.WithLabelValues(db.name, "ok").Inc()
+
+	db.removeLazyPage(lazy, pgno)
+	return primaryTXID, nil
+}
+
+// waitTXID returns once the database has reached txID or ctx is done.
+func (db *DB) waitTXID(ctx context.Context, txID ltx.TXID) error {
+	ticker := time.NewTicker(WaitInterval)
+	defer ticker.Stop()
+
+	for db.TXID() < txID {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// removeLazyPage marks a page as present & completes the database once every
+// page is present. Must hold lazy.mu.
+func (db *DB) removeLazyPage(lazy *lazyPageSet, pgno uint32) {
+	lazy.remove(pgno)
+	db.completeLazy(lazy)
+}
+
+// completeLazy removes the lazy state once every page is present.
+// Must hold lazy.mu.
+func (db *DB) completeLazy(lazy *lazyPageSet) {
+	if lazy.n > 0 || !db.lazy.CompareAndSwap(lazy, nil) {
+		return
+	}
+
+	if err := db.os.Remove("COMPLETELAZY", db.LazyPath()); err != nil && !os.IsNotExist(err) {
+		db.logger().Warn("cannot remove lazy marker", slog.Any("err", err))
+	}
+	db.logger().Info("all pages fetched, database is complete")
+}
+
 // WriteSnapshotTo writes an LTX snapshot to dst.
 func (db *DB) WriteSnapshotTo(ctx context.Context, dst io.Writer) (header ltx.Header, trailer ltx.Trailer, err error) {
+	return db.writeSnapshotTo(ctx, dst, 0, db.Now().UnixMilli())
+}
+
+// ResumeSnapshotTo regenerates the snapshot described by partial & writes it
+// to dst, skipping the bytes the replica has already received. Returns an
+// error before writing any data if the database has moved past the snapshot's
+// TXID or the snapshot was generated by a different node.
+func (db *DB) ResumeSnapshotTo(ctx context.Context, dst io.Writer, partial PartialSnapshot) (header ltx.Header, trailer ltx.Trailer, err error) {
+	if partial.NodeID != db.store.ID() {
+		return header, trailer, fmt.Errorf("snapshot generated by different node: %s", FormatNodeID(partial.NodeID))
+	}
+
+	w := &skipWriter{w: dst, n: partial.Size}
+	if header, trailer, err = db.writeSnapshotTo(ctx, w, partial.TXID, partial.Timestamp); err != nil {
+		return header, trailer, err
+	} else if w.n > 0 {
+		return header, trailer, fmt.Errorf("partial snapshot larger than snapshot")
+	}
+	return header, trailer, nil
+}
+
+// writeSnapshotTo writes an LTX snapshot with the given header timestamp to
+// dst. If txID is non-zero, the current position must match it.
+func (db *DB) writeSnapshotTo(ctx context.Context, dst io.Writer, txID ltx.TXID, timestamp int64) (header ltx.Header, trailer ltx.Trailer, err error) {
+	if db.IsLazy() {
+		return header, trailer, ErrDBIncomplete
+	}
+
 	gs := db.newGuardSet(0) // TODO(fsm): Track internal owners?
 	defer gs.Unlock()
 
@@ -3345,6 +4777,9 @@ func (db *DB) WriteSnapshotTo(ctx context.Context, dst io.Writer) (header ltx.He
 
 	// Determine current position & snapshot overriding WAL frames.
 	pos := db.Pos()
+	if txID != 0 && pos.TXID != txID {
+		return header, trailer, fmt.Errorf("snapshot position changed: %s <> %s", pos.TXID.String(), txID.String())
+	}
 	pageSize, pageN := db.pageSize, db.PageN()
 	walFrameOffsets := make(map[uint32]int64, len(db.wal.frameOffsets))
 	for k, v := range db.wal.frameOffsets {
@@ -3384,7 +4819,7 @@ func (db *DB) WriteSnapshotTo(ctx context.Context, dst io.Writer) (header ltx.He
 	gs.recover.Unlock()
 
 	// Log transaction ID for the snapshot.
-	log.Printf("writing snapshot %q @ %s", db.name, pos.TXID.String())
+	db.logger().Info("writing snapshot", slog.String("txid", pos.TXID.String()))
 
 	// Open database file. File may not exist if the database has been deleted.
 	var dbFile *os.File
@@ -3412,7 +4847,7 @@ func (db *DB) WriteSnapshotTo(ctx context.Context, dst io.Writer) (header ltx.He
 		Commit:    pageN,
 		MinTXID:   1,
 		MaxTXID:   pos.TXID,
-		Timestamp: db.Now().UnixMilli(),
+		Timestamp: timestamp,
 		NodeID:    db.store.ID(),
 	}); err != nil {
 		return header, trailer, fmt.Errorf("encode ltx header: %w", err)
@@ -3482,11 +4917,16 @@ func (db *DB) EnforceRetention(ctx context.Context, minTime time.Time) error {
 		return nil // no LTX files, exit
 	}
 
-	// Delete all files that are before the minimum time.
-	var totalN int
-	var totalSize int64
-	for i, ent := range ents {
-		// Check if file qualifies for deletion.
+	// Collect LTX file info so count & size limits can be computed.
+	type ltxFileInfo struct {
+		name    string
+		maxTXID ltx.TXID
+		size    int64
+		modTime time.Time
+	}
+	infos := make([]ltxFileInfo, 0, len(ents))
+	var remainingSize int64
+	for _, ent := range ents {
 		fi, err := ent.Info()
 		if err != nil {
 			return fmt.Errorf("info: %w", err)
@@ -3498,41 +4938,56 @@ func (db *DB) EnforceRetention(ctx context.Context, minTime time.Time) error {
 			continue // unknown file, skip
 		}
 
-		// File should be marked for removal if it is older than the retention period.
-		shouldRemove := fi.ModTime().Before(minTime)
+		infos = append(infos, ltxFileInfo{name: ent.Name(), maxTXID: maxTXID, size: fi.Size(), modTime: fi.ModTime()})
+		remainingSize += fi.Size()
+	}
+	remainingN := len(infos)
+	_, maxCount, maxSize := db.store.RetentionPolicy()
+
+	// Delete all files that are before the minimum time or that exceed the
+	// count or size limits, starting with the oldest files.
+	var totalN int
+	var totalSize int64
+	for i, info := range infos {
+		// File should be marked for removal if it is older than the retention
+		// period or if the remaining files exceed the retention limits.
+		shouldRemove := info.modTime.Before(minTime) ||
+			(maxCount > 0 && remainingN > maxCount) ||
+			(maxSize > 0 && remainingSize > maxSize)
 
 		// If a backup service is enabled, ensure the LTX file has been persisted
 		// to long-term storage. This is typically something like S3 which has
 		// very high durability.
 		if db.store.BackupClient != nil {
-			shouldRemove = shouldRemove && maxTXID < hwm
+			shouldRemove = shouldRemove && info.maxTXID < hwm
 		}
 
 		// Ensure the latest LTX file is never deleted.
-		if i == len(ents)-1 {
+		if i == len(infos)-1 {
 			shouldRemove = false
 		}
 
 		// If we aren't removing the file, just track its metrics and skip.
 		if !shouldRemove {
 			totalN++
-			totalSize += fi.Size()
+			totalSize += info.size
 			continue
 		}
 
 		// Remove file if it passes all the checks.
-		filename := filepath.Join(db.LTXDir(), ent.Name())
+		filename := filepath.Join(db.LTXDir(), info.name)
 		if err := db.os.Remove("ENFORCERETENTION", filename); err != nil {
 			return err
 		}
+		remainingN, remainingSize = remainingN-1, remainingSize-info.size
 
 		// Update metrics.
-		dbLTXReapCountMetricVec.WithLabelValues(db.name).Inc()
+		db.store.dbMetrics.dbLTXReapCountMetricVec.WithLabelValues(db.name).Inc()
 	}
 
 	// Reset metrics for LTX disk usage.
-	dbLTXCountMetricVec.WithLabelValues(db.name).Set(float64(totalN))
-	dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(totalSize))
+	db.store.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.name).Set(float64(totalN))
+	db.store.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.name).Set(float64(totalSize))
 
 	return nil
 }
@@ -3541,6 +4996,7 @@ type dbVarJSON struct {
 	Name     string `json:"name"`
 	TXID     string `json:"txid"`
 	Checksum string `json:"checksum"`
+	WALSize  int64  `json:"walSize"`
 
 	Locks struct {
 		Pending  string `json:"pending"`
@@ -3753,55 +5209,113 @@ func pageChksumBlock(pgno uint32) uint32 {
 	return (pgno - 1) / ChecksumBlockSize
 }
 
-// Database metrics.
-var (
-	dbTXIDMetricVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "litefs_db_txid",
-		Help: "Current transaction ID.",
-	}, []string{"db"})
-
-	dbDatabaseWriteCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_db_database_write_count",
-		Help: "Number of writes to the database file.",
-	}, []string{"db"})
-
-	dbJournalWriteCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_db_journal_write_count",
-		Help: "Number of writes to the journal file.",
-	}, []string{"db"})
-
-	dbWALWriteCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_db_wal_write_count",
-		Help: "Number of writes to the WAL file.",
-	}, []string{"db"})
-
-	dbSHMWriteCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_db_shm_write_count",
-		Help: "Number of writes to the shared memory file.",
-	}, []string{"db"})
-
-	dbCommitCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_db_commit_count",
-		Help: "Number of database commits.",
-	}, []string{"db"})
-
-	dbLTXCountMetricVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "litefs_db_ltx_count",
-		Help: "Number of LTX files on disk.",
-	}, []string{"db"})
-
-	dbLTXBytesMetricVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "litefs_db_ltx_bytes",
-		Help: "Number of bytes used by LTX files on disk.",
-	}, []string{"db"})
-
-	dbLTXReapCountMetricVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "litefs_db_ltx_reap_count",
-		Help: "Number of LTX files removed by retention.",
-	}, []string{"db"})
-
-	dbLatencySecondsMetricVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "litefs_db_lag_seconds",
-		Help: "Latency between generating an LTX file and consuming it.",
-	}, []string{"db"})
-)
+// dbMetrics holds the per-database collectors for a single store.
+type dbMetrics struct {
+	dbTXIDMetricVec                  *prometheus.GaugeVec
+	dbDatabaseWriteCountMetricVec    *prometheus.CounterVec
+	dbJournalWriteCountMetricVec     *prometheus.CounterVec
+	dbWALWriteCountMetricVec         *prometheus.CounterVec
+	dbSHMWriteCountMetricVec         *prometheus.CounterVec
+	dbCommitCountMetricVec           *prometheus.CounterVec
+	dbLTXCountMetricVec              *prometheus.GaugeVec
+	dbLTXBytesMetricVec              *prometheus.GaugeVec
+	dbLTXRecvBytesMetricVec          *prometheus.CounterVec
+	dbReplicaLagTXIDMetricVec        *prometheus.GaugeVec
+	dbLTXReapCountMetricVec          *prometheus.GaugeVec
+	dbLatencySecondsMetricVec        *prometheus.GaugeVec
+	dbWALSizeBytesMetricVec          *prometheus.GaugeVec
+	dbSuspendedCountMetricVec        *prometheus.CounterVec
+	dbChecksumMismatchCountMetricVec *prometheus.CounterVec
+	dbTxLimitExceededCountMetricVec  *prometheus.CounterVec
+	dbLazyPageFetchCountMetricVec    *prometheus.CounterVec
+}
+
+func newDBMetrics(reg prometheus.Registerer) *dbMetrics {
+	f := promauto.With(reg)
+	return &dbMetrics{
+		dbTXIDMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_txid",
+			Help: "Current transaction ID.",
+		}, []string{"db"}),
+
+		dbDatabaseWriteCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_database_write_count",
+			Help: "Number of writes to the database file.",
+		}, []string{"db"}),
+
+		dbJournalWriteCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_journal_write_count",
+			Help: "Number of writes to the journal file.",
+		}, []string{"db"}),
+
+		dbWALWriteCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_wal_write_count",
+			Help: "Number of writes to the WAL file.",
+		}, []string{"db"}),
+
+		dbSHMWriteCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_shm_write_count",
+			Help: "Number of writes to the shared memory file.",
+		}, []string{"db"}),
+
+		dbCommitCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_commit_count",
+			Help: "Number of database commits.",
+		}, []string{"db"}),
+
+		dbLTXCountMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_ltx_count",
+			Help: "Number of LTX files on disk.",
+		}, []string{"db"}),
+
+		dbLTXBytesMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_ltx_bytes",
+			Help: "Number of bytes used by LTX files on disk.",
+		}, []string{"db"}),
+
+		dbLTXRecvBytesMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_ltx_recv_bytes",
+			Help: "Number of LTX bytes received from the primary.",
+		}, []string{"db"}),
+
+		dbReplicaLagTXIDMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_replica_lag_txid",
+			Help: "Number of transactions received from the primary but not yet applied.",
+		}, []string{"db"}),
+
+		dbLTXReapCountMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_ltx_reap_count",
+			Help: "Number of LTX files removed by retention.",
+		}, []string{"db"}),
+
+		dbLatencySecondsMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_lag_seconds",
+			Help: "Latency between generating an LTX file and consuming it.",
+		}, []string{"db"}),
+
+		dbWALSizeBytesMetricVec: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "litefs_db_wal_size_bytes",
+			Help: "Size of the WAL file, in bytes.",
+		}, []string{"db"}),
+
+		dbSuspendedCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_suspended_total",
+			Help: "Number of times replication was suspended on the database.",
+		}, []string{"db"}),
+
+		dbChecksumMismatchCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_checksum_mismatch_total",
+			Help: "Number of times checksum verification failed on the database.",
+		}, []string{"db"}),
+
+		dbTxLimitExceededCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_tx_limit_exceeded_total",
+			Help: "Number of transactions rejected for exceeding the transaction limits.",
+		}, []string{"db"}),
+
+		dbLazyPageFetchCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_db_lazy_page_fetch_total",
+			Help: "Number of pages fetched from the primary after a lazy snapshot.",
+		}, []string{"db", "result"}),
+	}
+}
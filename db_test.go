@@ -0,0 +1,576 @@
+package litefs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/mock"
+	"github.com/superfly/ltx"
+)
+
+func TestDB_ApplyLTXStream(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Stream a snapshot through a pipe so the data is only available as it is written.
+		pages := [][]byte{
+			bytes.Repeat([]byte{1}, 4096),
+			bytes.Repeat([]byte{2}, 4096),
+			bytes.Repeat([]byte{3}, 4096),
+		}
+		pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: pages[0], 2: pages[1], 3: pages[2]}, 3)
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		}
+		if got, want := mustReadFile(t, db.DatabasePath()), bytes.Join(pages, nil); !bytes.Equal(got, want) {
+			t.Fatal("database content mismatch after snapshot")
+		}
+
+		// Stream an incremental transaction that updates a single page.
+		pages[1] = bytes.Repeat([]byte{4}, 4096)
+		pos = applyLTXStream(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{2: pages[1]}, 3)
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		}
+		if got, want := mustReadFile(t, db.DatabasePath()), bytes.Join(pages, nil); !bytes.Equal(got, want) {
+			t.Fatal("database content mismatch after transaction")
+		}
+
+		// Ensure the LTX file was persisted for the transaction.
+		if _, err := os.Stat(db.LTXPath(2, 2)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrPositionMismatch", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		enc := ltx.NewEncoder(&buf)
+		if err := enc.EncodeHeader(ltx.Header{Version: ltx.Version, PageSize: 4096, Commit: 1, MinTXID: 2, MaxTXID: 2, Timestamp: 1000, PreApplyChecksum: ltx.ChecksumFlag | 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.ApplyLTXStream(context.Background(), &buf); err == nil || err.Error() != `position mismatch on db "test.db": 0000000000000000/0000000000000000 <> 0000000000000001/8000000000000001` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrTruncated", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		store.Exit = func(code int) { t.Fatalf("unexpected exit: %d", code) }
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		page := bytes.Repeat([]byte{1}, 4096)
+		pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: page}, 1)
+
+		// Send a transaction that is cut off after its first page.
+		var buf bytes.Buffer
+		enc := ltx.NewEncoder(&buf)
+		if err := enc.EncodeHeader(ltx.Header{Version: ltx.Version, PageSize: 4096, Commit: 1, MinTXID: 2, MaxTXID: 2, Timestamp: 1000, PreApplyChecksum: pos.PostApplyChecksum}); err != nil {
+			t.Fatal(err)
+		} else if err := enc.EncodePage(ltx.PageHeader{Pgno: 1}, bytes.Repeat([]byte{2}, 4096)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.ApplyLTXStream(context.Background(), &buf); err == nil {
+			t.Fatal("expected error")
+		}
+
+		// Ensure the database & position were not changed.
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		} else if got, want := mustReadFile(t, db.DatabasePath()), page; !bytes.Equal(got, want) {
+			t.Fatal("database content mismatch")
+		} else if _, err := os.Stat(db.LTXPath(2, 2)); !os.IsNotExist(err) {
+			t.Fatalf("expected no ltx file: %v", err)
+		}
+	})
+
+	t.Run("ErrChecksumMismatch", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		store.Exit = func(code int) { t.Fatalf("unexpected exit: %d", code) }
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		pages := [][]byte{bytes.Repeat([]byte{1}, 4096), bytes.Repeat([]byte{2}, 4096)}
+		pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: pages[0], 2: pages[1]}, 2)
+
+		// Send a complete transaction that shrinks the database but has an
+		// invalid post-apply checksum.
+		var buf bytes.Buffer
+		enc := ltx.NewEncoder(&buf)
+		if err := enc.EncodeHeader(ltx.Header{Version: ltx.Version, PageSize: 4096, Commit: 1, MinTXID: 2, MaxTXID: 2, Timestamp: 1000, PreApplyChecksum: pos.PostApplyChecksum}); err != nil {
+			t.Fatal(err)
+		} else if err := enc.EncodePage(ltx.PageHeader{Pgno: 1}, bytes.Repeat([]byte{3}, 4096)); err != nil {
+			t.Fatal(err)
+		}
+		enc.SetPostApplyChecksum(ltx.ChecksumFlag | 1)
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.ApplyLTXStream(context.Background(), &buf); err == nil || !strings.Contains(err.Error(), "does not match LTX post-apply checksum") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Ensure the database was rolled back to its previous state.
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		} else if got, want := db.PageN(), uint32(2); got != want {
+			t.Fatalf("PageN=%d, want %d", got, want)
+		} else if got, want := mustReadFile(t, db.DatabasePath()), bytes.Join(pages, nil); !bytes.Equal(got, want) {
+			t.Fatal("database content mismatch")
+		} else if _, err := os.Stat(db.LTXPath(2, 2)); !os.IsNotExist(err) {
+			t.Fatalf("expected no ltx file: %v", err)
+		}
+
+		// Ensure the next valid transaction applies on top of the previous state.
+		pages[1] = bytes.Repeat([]byte{4}, 4096)
+		pos = applyLTXStream(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{2: pages[1]}, 2)
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		} else if got, want := mustReadFile(t, db.DatabasePath()), bytes.Join(pages, nil); !bytes.Equal(got, want) {
+			t.Fatal("database content mismatch after transaction")
+		}
+	})
+}
+
+func TestDB_WALFileSize(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := store.CreateDB("test.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing WAL files should be reported as empty.
+	if sz, err := db.WALFileSize(); err != nil {
+		t.Fatal(err)
+	} else if got, want := sz, int64(0); got != want {
+		t.Fatalf("WALFileSize=%d, want %d", got, want)
+	}
+
+	if err := os.WriteFile(db.WALPath(), make([]byte, 1000), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if sz, err := db.WALFileSize(); err != nil {
+		t.Fatal(err)
+	} else if got, want := sz, int64(1000); got != want {
+		t.Fatalf("WALFileSize=%d, want %d", got, want)
+	}
+
+	// Remove invalid WAL so it is not recovered on close.
+	if err := os.Remove(db.WALPath()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_Stats(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := store.CreateDB("test.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: newSQLitePage1()}, 1)
+	if stats, err := db.Stats(); err != nil {
+		t.Fatal(err)
+	} else if got, want := stats, (litefs.DBStats{Pos: pos, PageSize: 4096, PageN: 1}); got != want {
+		t.Fatalf("Stats=%#v, want %#v", got, want)
+	}
+
+	// The WAL size is read from the WAL file.
+	if err := os.WriteFile(db.WALPath(), make([]byte, 1000), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := db.Stats(); err != nil {
+		t.Fatal(err)
+	} else if got, want := stats.WALSizeBytes, int64(1000); got != want {
+		t.Fatalf("WALSizeBytes=%d, want %d", got, want)
+	}
+
+	// Remove invalid WAL so it is not recovered on close.
+	if err := os.Remove(db.WALPath()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_DryRunApplyLTX(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		pages := map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096), 2: bytes.Repeat([]byte{2}, 4096)}
+		pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, pages, 2)
+		data := mustReadFile(t, db.DatabasePath())
+
+		filename := filepath.Join(t.TempDir(), "ltx")
+		encodeLTXFile(t, db, filename, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{2: bytes.Repeat([]byte{3}, 4096)}, 2)
+
+		result, err := db.DryRunApplyLTX(context.Background(), filename)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := result.TXID, ltx.TXID(2); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		} else if got, want := result.PagesModified, 1; got != want {
+			t.Fatalf("PagesModified=%d, want %d", got, want)
+		} else if result.EstimatedDuration <= 0 {
+			t.Fatalf("unexpected duration: %s", result.EstimatedDuration)
+		}
+
+		// Ensure no state was modified.
+		if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		} else if !bytes.Equal(mustReadFile(t, db.DatabasePath()), data) {
+			t.Fatal("database modified by dry run")
+		}
+	})
+
+	t.Run("ErrCorrupt", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		filename := filepath.Join(t.TempDir(), "ltx")
+		encodeLTXFile(t, db, filename, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096)}, 1)
+
+		// Flip a byte within the page data.
+		buf := mustReadFile(t, filename)
+		buf[ltx.HeaderSize+ltx.PageHeaderSize+100] ^= 0xFF
+		if err := os.WriteFile(filename, buf, 0o666); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.DryRunApplyLTX(context.Background(), filename); err == nil {
+			t.Fatal("expected error")
+		}
+		if got, want := db.Pos(), (ltx.Pos{}); got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("ErrPositionMismatch", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096)}, 1)
+
+		filename := filepath.Join(t.TempDir(), "ltx")
+		encodeLTXFile(t, db, filename, ltx.Header{MinTXID: 3, MaxTXID: 3, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{1: bytes.Repeat([]byte{2}, 4096)}, 1)
+
+		result, err := db.DryRunApplyLTX(context.Background(), filename)
+		if err == nil || !strings.Contains(err.Error(), "position mismatch") {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := result.TXID, ltx.TXID(3); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+	})
+}
+
+// Ensure new transactions cannot be committed past the maximum TXID.
+func TestDB_ErrTXIDOverflow(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := store.CreateDB("test.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Seed the database with a snapshot near the end of the TXID range.
+	applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: math.MaxUint64 - 10}, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096)}, 1)
+
+	for i := 0; i < 10; i++ {
+		if err := db.Drop(context.Background()); err != nil {
+			t.Fatalf("drop %d: %s", i, err)
+		}
+	}
+	if got, want := db.TXID(), ltx.TXID(math.MaxUint64); got != want {
+		t.Fatalf("TXID=%s, want %s", got, want)
+	}
+
+	if err := db.Drop(context.Background()); err != litefs.ErrTXIDOverflow {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := db.TXID(), ltx.TXID(math.MaxUint64); got != want {
+		t.Fatalf("TXID=%s, want %s", got, want)
+	}
+}
+
+// Ensure a forwarded write acquires the remote halt lock before the local
+// lock & that only one owner forwards at a time.
+func TestDB_TryLocks_WriteForwarding(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+
+	var db *litefs.DB
+	var acquireN, releaseN int
+	leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			return &mock.Stream{
+				ReadCloser:    pr,
+				ClusterIDFunc: func() string { return "" },
+				EpochFunc:     func() uint64 { return 0 },
+			}, nil
+		},
+		AcquireHaltLockFunc: func(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) (*litefs.HaltLock, error) {
+			acquireN++
+			if ok, _ := db.CanLock(ctx, 2, []litefs.LockType{litefs.LockTypeReserved}); !ok {
+				t.Fatal("expected local lock to be available while acquiring remote lock")
+			}
+			return &litefs.HaltLock{ID: lockID, Pos: db.Pos()}, nil
+		},
+		ReleaseHaltLockFunc: func(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) error {
+			releaseN++
+			return nil
+		},
+	}
+	store := newStore(t, leaser, &client)
+	store.WriteForwarding = true
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLTXStreamFramePage(t, pw, "test.db", ltx.Header{MinTXID: 1, MaxTXID: 1}, newSQLitePage1())
+	if err := litefs.WriteStreamFrame(pw, &litefs.ReadyStreamFrame{}); err != nil {
+		t.Fatal(err)
+	}
+	<-store.ReadyCh()
+	if db = store.DB("test.db"); db == nil {
+		t.Fatal("expected database")
+	}
+
+	lockTypes := []litefs.LockType{litefs.LockTypeReserved}
+	if ok, err := db.TryLocks(context.Background(), 1, lockTypes); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected lock")
+	} else if got, want := acquireN, 1; got != want {
+		t.Fatalf("acquire=%d, want %d", got, want)
+	}
+
+	// Another owner cannot write while the first owner is forwarding.
+	if ok, err := db.TryLocks(context.Background(), 2, lockTypes); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected lock failure")
+	} else if got, want := acquireN, 1; got != want {
+		t.Fatalf("acquire=%d, want %d", got, want)
+	}
+
+	if err := db.Unlock(context.Background(), 1, lockTypes); err != nil {
+		t.Fatal(err)
+	} else if got, want := releaseN, 1; got != want {
+		t.Fatalf("release=%d, want %d", got, want)
+	}
+
+	// The second owner can forward once the first owner has finished.
+	if ok, err := db.TryLocks(context.Background(), 2, lockTypes); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected lock")
+	} else if got, want := acquireN, 2; got != want {
+		t.Fatalf("acquire=%d, want %d", got, want)
+	}
+	if err := db.Unlock(context.Background(), 2, lockTypes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_EnforceRetention(t *testing.T) {
+	// Creates a database with five single-page transactions.
+	newDB := func(tb testing.TB, store *litefs.Store) *litefs.DB {
+		db, f, err := store.CreateDB("test.db")
+		if err != nil {
+			tb.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			tb.Fatal(err)
+		}
+
+		pos := applyLTXStream(tb, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096)}, 1)
+		for txID := ltx.TXID(2); txID <= 5; txID++ {
+			pos = applyLTXStream(tb, db, ltx.Header{MinTXID: txID, MaxTXID: txID, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{1: bytes.Repeat([]byte{byte(txID)}, 4096)}, 1)
+		}
+		return db
+	}
+
+	ltxNames := func(tb testing.TB, db *litefs.DB) (a []string) {
+		ents, err := db.ReadLTXDir()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		for _, ent := range ents {
+			a = append(a, ent.Name())
+		}
+		return a
+	}
+
+	t.Run("MaxCount", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		store.RetentionMaxCount = 2
+		db := newDB(t, store)
+
+		if err := db.EnforceRetention(context.Background(), time.Time{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := ltxNames(t, db), []string{ltx.FormatFilename(4, 4), ltx.FormatFilename(5, 5)}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("files=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("MaxSize", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		store.RetentionMaxSize = 1
+		db := newDB(t, store)
+
+		// The latest file is always kept.
+		if err := db.EnforceRetention(context.Background(), time.Time{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := ltxNames(t, db), []string{ltx.FormatFilename(5, 5)}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("files=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("NoLimits", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db := newDB(t, store)
+
+		if err := db.EnforceRetention(context.Background(), time.Time{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := len(ltxNames(t, db)), 5; got != want {
+			t.Fatalf("n=%d, want %d", got, want)
+		}
+	})
+}
+
+// applyLTXStream encodes pages from a separate goroutine into a pipe and
+// applies them to db. Returns the expected position after the transaction.
+func applyLTXStream(tb testing.TB, db *litefs.DB, hdr ltx.Header, pages map[uint32][]byte, commit uint32) ltx.Pos {
+	tb.Helper()
+
+	hdr.Version, hdr.PageSize, hdr.Commit = ltx.Version, 4096, commit
+	hdr.Timestamp = time.Now().UnixMilli()
+	chksum := postApplyChecksum(tb, db, hdr, pages)
+
+	pr, pw := io.Pipe()
+	go func() {
+		enc := ltx.NewEncoder(pw)
+		if err := enc.EncodeHeader(hdr); err != nil {
+			_ = pw.CloseWithError(err)
+			return
+		}
+		for pgno := uint32(1); pgno <= commit; pgno++ {
+			if data, ok := pages[pgno]; ok {
+				if err := enc.EncodePage(ltx.PageHeader{Pgno: pgno}, data); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+			}
+		}
+		enc.SetPostApplyChecksum(chksum)
+		_ = pw.CloseWithError(enc.Close())
+	}()
+
+	if err := db.ApplyLTXStream(context.Background(), pr); err != nil {
+		tb.Fatal(err)
+	}
+	return ltx.Pos{TXID: hdr.MaxTXID, PostApplyChecksum: chksum}
+}
+
+// encodeLTXFile writes an LTX file with the given pages to filename. Returns
+// the expected position after the transaction is applied to db.
+func encodeLTXFile(tb testing.TB, db *litefs.DB, filename string, hdr ltx.Header, pages map[uint32][]byte, commit uint32) ltx.Pos {
+	tb.Helper()
+
+	hdr.Version, hdr.PageSize, hdr.Commit = ltx.Version, 4096, commit
+	hdr.Timestamp = time.Now().UnixMilli()
+	chksum := postApplyChecksum(tb, db, hdr, pages)
+
+	var buf bytes.Buffer
+	enc := ltx.NewEncoder(&buf)
+	if err := enc.EncodeHeader(hdr); err != nil {
+		tb.Fatal(err)
+	}
+	for pgno := uint32(1); pgno <= commit; pgno++ {
+		if data, ok := pages[pgno]; ok {
+			if err := enc.EncodePage(ltx.PageHeader{Pgno: pgno}, data); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+	enc.SetPostApplyChecksum(chksum)
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	} else if err := os.WriteFile(filename, buf.Bytes(), 0o666); err != nil {
+		tb.Fatal(err)
+	}
+	return ltx.Pos{TXID: hdr.MaxTXID, PostApplyChecksum: chksum}
+}
+
+// postApplyChecksum computes the checksum of db after pages are applied.
+func postApplyChecksum(tb testing.TB, db *litefs.DB, hdr ltx.Header, pages map[uint32][]byte) ltx.Checksum {
+	tb.Helper()
+
+	chksum := hdr.PreApplyChecksum
+	buf := mustReadFile(tb, db.DatabasePath())
+	for pgno, data := range pages {
+		// Remove the previous page checksum if the page already exists.
+		if offset := int64(pgno-1) * int64(hdr.PageSize); !hdr.IsSnapshot() && offset < int64(len(buf)) {
+			chksum ^= ltx.ChecksumPage(pgno, buf[offset:offset+int64(hdr.PageSize)])
+		}
+		chksum = ltx.ChecksumFlag | (chksum ^ ltx.ChecksumPage(pgno, data))
+	}
+	return chksum
+}
+
+func mustReadFile(tb testing.TB, filename string) []byte {
+	tb.Helper()
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return buf
+}
@@ -0,0 +1,198 @@
+package litefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default settings for DNSLeaser.
+const (
+	DefaultDNSLeaserScheme          = "http"
+	DefaultDNSLeaserRefreshInterval = 30 * time.Second
+)
+
+var (
+	_ Leaser            = (*DNSLeaser)(nil)
+	_ PrimaryInfoCacher = (*DNSLeaser)(nil)
+)
+
+// DNSResolver represents the DNS lookups used by DNSLeaser.
+// It is implemented by *net.Resolver.
+type DNSResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSLeaser is a static leaser where replicas discover the primary's address
+// by resolving a DNS name instead of using a fixed advertise URL. This allows
+// the primary's address to change without reconfiguring the replicas.
+//
+// If Port is set then the name is resolved as an A/AAAA record & the first
+// address is used with that port. Otherwise the name is resolved as an SRV
+// record, such as "_litefs._tcp.primary.internal", & the target & port of
+// the highest priority record are used.
+type DNSLeaser struct {
+	mu       sync.Mutex
+	info     *PrimaryInfo // cached primary info
+	cachedAt time.Time
+
+	isPrimary    bool
+	hostname     string
+	advertiseURL string
+	name         string
+
+	// Port of the primary's API. If zero, an SRV lookup is performed.
+	Port int
+
+	// URL scheme of the primary's API. Defaults to "http".
+	Scheme string
+
+	// Time to cache the resolved primary before resolving it again.
+	RefreshInterval time.Duration
+
+	// Resolver used for DNS lookups. Defaults to net.DefaultResolver.
+	Resolver DNSResolver
+
+	// Returns the current time. Used for testing.
+	Now func() time.Time
+}
+
+// NewDNSLeaser returns a new instance of DNSLeaser. The primary is specified
+// with isPrimary & replicas resolve its address from name.
+func NewDNSLeaser(isPrimary bool, name, hostname, advertiseURL string) *DNSLeaser {
+	return &DNSLeaser{
+		isPrimary:    isPrimary,
+		hostname:     hostname,
+		advertiseURL: advertiseURL,
+		name:         name,
+
+		Scheme:          DefaultDNSLeaserScheme,
+		RefreshInterval: DefaultDNSLeaserRefreshInterval,
+		Resolver:        net.DefaultResolver,
+		Now:             time.Now,
+	}
+}
+
+// Close is a no-op.
+func (l *DNSLeaser) Close() (err error) { return nil }
+
+// Type returns "dns".
+func (l *DNSLeaser) Type() string { return "dns" }
+
+// Name returns the DNS name used to resolve the primary.
+func (l *DNSLeaser) Name() string { return l.name }
+
+func (l *DNSLeaser) Hostname() string {
+	return l.hostname
+}
+
+// AdvertiseURL returns the primary URL if this is the primary.
+// Otherwise returns blank.
+func (l *DNSLeaser) AdvertiseURL() string {
+	if l.isPrimary {
+		return l.advertiseURL
+	}
+	return ""
+}
+
+// IsPrimary returns true if the current node is the primary.
+func (l *DNSLeaser) IsPrimary() bool {
+	return l.isPrimary
+}
+
+// Acquire returns a lease if this node is the primary.
+// Otherwise returns ErrPrimaryExists.
+func (l *DNSLeaser) Acquire(ctx context.Context) (Lease, error) {
+	if !l.isPrimary {
+		return nil, ErrPrimaryExists
+	}
+	return &StaticLease{}, nil
+}
+
+// AcquireExisting always returns an error. DNS leasing does not support handoff.
+func (l *DNSLeaser) AcquireExisting(ctx context.Context, leaseID string) (Lease, error) {
+	return nil, fmt.Errorf("dns lease handoff not supported")
+}
+
+// PrimaryInfo returns the primary's info resolved from DNS. The result is
+// cached for RefreshInterval. Returns ErrNoPrimary if the node is the primary
+// or if the name does not resolve to any records.
+func (l *DNSLeaser) PrimaryInfo(ctx context.Context) (PrimaryInfo, error) {
+	if l.isPrimary {
+		return PrimaryInfo{}, ErrNoPrimary
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	if l.info != nil && now.Sub(l.cachedAt) < l.RefreshInterval {
+		return *l.info, nil
+	}
+
+	info, err := l.resolve(ctx)
+	if err != nil {
+		return PrimaryInfo{}, err
+	}
+	l.info, l.cachedAt = &info, now
+	return info, nil
+}
+
+// InvalidatePrimaryInfo clears the cached primary info so the next call to
+// PrimaryInfo() resolves the name again.
+func (l *DNSLeaser) InvalidatePrimaryInfo() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.info = nil
+}
+
+// resolve looks up the primary's address from DNS.
+func (l *DNSLeaser) resolve(ctx context.Context) (PrimaryInfo, error) {
+	var host string
+	var port int
+	if l.Port != 0 {
+		addrs, err := l.Resolver.LookupHost(ctx, l.name)
+		if isDNSNotFound(err) || (err == nil && len(addrs) == 0) {
+			return PrimaryInfo{}, ErrNoPrimary
+		} else if err != nil {
+			return PrimaryInfo{}, fmt.Errorf("lookup host: %w", err)
+		}
+		host, port = addrs[0], l.Port
+	} else {
+		// Records are returned sorted by priority & randomized by weight.
+		_, addrs, err := l.Resolver.LookupSRV(ctx, "", "", l.name)
+		if isDNSNotFound(err) || (err == nil && len(addrs) == 0) {
+			return PrimaryInfo{}, ErrNoPrimary
+		} else if err != nil {
+			return PrimaryInfo{}, fmt.Errorf("lookup srv: %w", err)
+		}
+		host, port = strings.TrimSuffix(addrs[0].Target, "."), int(addrs[0].Port)
+	}
+
+	return PrimaryInfo{
+		Hostname:     host,
+		AdvertiseURL: l.Scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)),
+	}, nil
+}
+
+// ClusterID always returns a blank string for the DNS leaser.
+func (l *DNSLeaser) ClusterID(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+// SetClusterID is always a no-op for the DNS leaser.
+func (l *DNSLeaser) SetClusterID(ctx context.Context, clusterID string) error {
+	return nil
+}
+
+// isDNSNotFound returns true if err is a DNS error for a missing name.
+func isDNSNotFound(err error) bool {
+	var e *net.DNSError
+	return errors.As(err, &e) && e.IsNotFound
+}
@@ -0,0 +1,169 @@
+package litefs_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+)
+
+func TestDNSLeaser(t *testing.T) {
+	t.Run("Primary", func(t *testing.T) {
+		l := litefs.NewDNSLeaser(true, "primary.internal", "localhost", "http://localhost:20202")
+		if got, want := l.AdvertiseURL(), "http://localhost:20202"; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if lease, err := l.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if lease == nil {
+			t.Fatal("expected lease")
+		}
+	})
+
+	t.Run("A", func(t *testing.T) {
+		var resolver mockDNSResolver
+		resolver.LookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+			if got, want := host, "primary.internal"; got != want {
+				t.Fatalf("host=%q, want %q", got, want)
+			}
+			return []string{"fdaa::2", "fdaa::3"}, nil
+		}
+
+		l := litefs.NewDNSLeaser(false, "primary.internal", "localhost", "http://localhost:20202")
+		l.Port = 20202
+		l.Resolver = &resolver
+		if got, want := l.AdvertiseURL(), ""; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "fdaa::2"; got != want {
+			t.Fatalf("Hostname=%q, want %q", got, want)
+		} else if got, want := info.AdvertiseURL, "http://[fdaa::2]:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+
+		if lease, err := l.Acquire(context.Background()); err != litefs.ErrPrimaryExists {
+			t.Fatalf("unexpected error: %v", err)
+		} else if lease != nil {
+			t.Fatal("expected no lease")
+		}
+	})
+
+	t.Run("SRV", func(t *testing.T) {
+		var resolver mockDNSResolver
+		resolver.LookupSRVFunc = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if got, want := name, "_litefs._tcp.primary.internal"; got != want {
+				t.Fatalf("name=%q, want %q", got, want)
+			}
+			return name, []*net.SRV{{Target: "node1.internal.", Port: 30303}}, nil
+		}
+
+		l := litefs.NewDNSLeaser(false, "_litefs._tcp.primary.internal", "localhost", "")
+		l.Scheme = "https"
+		l.Resolver = &resolver
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "node1.internal"; got != want {
+			t.Fatalf("Hostname=%q, want %q", got, want)
+		} else if got, want := info.AdvertiseURL, "https://node1.internal:30303"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		addr := "10.0.0.1"
+		var resolver mockDNSResolver
+		resolver.LookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+			return []string{addr}, nil
+		}
+
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		l := litefs.NewDNSLeaser(false, "primary.internal", "localhost", "")
+		l.Port = 20202
+		l.RefreshInterval = 10 * time.Second
+		l.Resolver = &resolver
+		l.Now = func() time.Time { return now }
+
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.AdvertiseURL, "http://10.0.0.1:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+
+		// Cached result is used until the refresh interval elapses.
+		addr, now = "10.0.0.2", now.Add(5*time.Second)
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.AdvertiseURL, "http://10.0.0.1:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+
+		now = now.Add(5 * time.Second)
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.AdvertiseURL, "http://10.0.0.2:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+	})
+
+	// Ensure an invalidated result is resolved again before the interval ends.
+	t.Run("InvalidatePrimaryInfo", func(t *testing.T) {
+		addr := "10.0.0.1"
+		var resolver mockDNSResolver
+		resolver.LookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+			return []string{addr}, nil
+		}
+
+		l := litefs.NewDNSLeaser(false, "primary.internal", "localhost", "")
+		l.Port = 20202
+		l.RefreshInterval = 1 * time.Hour
+		l.Resolver = &resolver
+		if _, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		addr = "10.0.0.2"
+		l.InvalidatePrimaryInfo()
+		if info, err := l.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.AdvertiseURL, "http://10.0.0.2:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("ErrNoPrimary", func(t *testing.T) {
+		var resolver mockDNSResolver
+		resolver.LookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+
+		l := litefs.NewDNSLeaser(false, "primary.internal", "localhost", "")
+		l.Port = 20202
+		l.Resolver = &resolver
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+type mockDNSResolver struct {
+	LookupHostFunc func(ctx context.Context, host string) ([]string, error)
+	LookupSRVFunc  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+func (r *mockDNSResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return r.LookupHostFunc(ctx, host)
+}
+
+func (r *mockDNSResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return r.LookupSRVFunc(ctx, service, proto, name)
+}
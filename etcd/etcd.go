@@ -0,0 +1,502 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
+)
+
+// logger is the logger for the lease subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemLease)
+
+// Default lease settings.
+const (
+	DefaultTTL = 10 * time.Second
+)
+
+// Leaser represents an API for obtaining a distributed lock on a single key.
+// It communicates with etcd through the v3 JSON gateway.
+type Leaser struct {
+	etcdURL      string
+	hostname     string
+	advertiseURL string
+	baseURL      string // endpoint without key prefix path
+
+	acquireDelayed atomic.Bool // true after the initial acquire delay
+
+	// HTTPClient is the client used to communicate with etcd.
+	HTTPClient *http.Client
+
+	// Key is the etcd key use to acquire the lock.
+	Key string
+
+	// Prefix that is prepended to the key. Automatically set if the URL contains a path.
+	KeyPrefix string
+
+	// TTL is the time until the lease expires.
+	TTL time.Duration
+
+	// AcquireDelay is the time to wait before the first acquisition attempt.
+	// This prevents all nodes from hitting etcd at once during a restart.
+	AcquireDelay time.Duration
+
+	// AcquireDelayJitter is the maximum random duration added to AcquireDelay.
+	AcquireDelayJitter time.Duration
+}
+
+// NewLeaser returns a new instance of Leaser.
+func NewLeaser(etcdURL, key, hostname, advertiseURL string) *Leaser {
+	return &Leaser{
+		etcdURL:      etcdURL,
+		hostname:     hostname,
+		advertiseURL: advertiseURL,
+		HTTPClient:   http.DefaultClient,
+		Key:          key,
+		TTL:          DefaultTTL,
+	}
+}
+
+// Open validates the configuration & parses the etcd URL.
+func (l *Leaser) Open() error {
+	u, err := url.Parse(l.etcdURL)
+	if err != nil {
+		return err
+	}
+
+	if l.Key == "" {
+		return fmt.Errorf("must specify an etcd key")
+	} else if l.hostname == "" {
+		return fmt.Errorf("must specify a hostname for this node")
+	} else if l.advertiseURL == "" {
+		return fmt.Errorf("must specify an advertise URL for this node")
+	} else if l.TTL < time.Second {
+		return fmt.Errorf("etcd lease ttl must be at least one second")
+	}
+
+	if v := strings.TrimPrefix(u.Path, "/"); v != "" {
+		l.KeyPrefix = v
+	}
+	l.baseURL = (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+
+	return nil
+}
+
+// Close is a no-op.
+func (l *Leaser) Close() (err error) {
+	return nil
+}
+
+// Type returns "etcd".
+func (l *Leaser) Type() string { return "etcd" }
+
+// Hostname returns the hostname for this node.
+func (l *Leaser) Hostname() string {
+	return l.hostname
+}
+
+// AdvertiseURL returns the URL being advertised to nodes when primary.
+func (l *Leaser) AdvertiseURL() string {
+	return l.advertiseURL
+}
+
+func (l *Leaser) kvKey() string {
+	return path.Join(l.KeyPrefix, l.Key)
+}
+
+func (l *Leaser) kvValue() ([]byte, error) {
+	return json.Marshal(litefs.PrimaryInfo{
+		Hostname:     l.hostname,
+		AdvertiseURL: l.advertiseURL,
+	})
+}
+
+// Acquire grants a new etcd lease and attaches it to the key if the key does
+// not exist. Returns ErrPrimaryExists if another node holds the key.
+func (l *Leaser) Acquire(ctx context.Context) (_ litefs.Lease, retErr error) {
+	// Wait before the first attempt so that simultaneously started nodes
+	// do not all grant leases. If another node became primary while we
+	// waited then we can skip the lease grant entirely.
+	if delayed, err := l.waitAcquireDelay(ctx); err != nil {
+		return nil, err
+	} else if delayed {
+		if _, err := l.PrimaryInfo(ctx); err == nil {
+			return nil, litefs.ErrPrimaryExists
+		} else if err != litefs.ErrNoPrimary {
+			return nil, fmt.Errorf("fetch primary info: %w", err)
+		}
+	}
+
+	// Grant lease first.
+	var grantResp leaseGrantResponse
+	if err := l.do(ctx, "/v3/lease/grant", leaseGrantRequest{
+		TTL: int64String(l.TTL / time.Second),
+	}, &grantResp); err != nil {
+		return nil, fmt.Errorf("grant etcd lease: %w", err)
+	}
+	lease := newLease(l, int64(grantResp.ID), time.Now())
+
+	// Attempt to revoke lease. It'll be removed via TTL eventually anyway though.
+	defer func() {
+		if retErr != nil {
+			_ = lease.Close()
+		}
+	}()
+
+	// Marshal information about the primary node.
+	kvValue, err := l.kvValue()
+	if err != nil {
+		return nil, fmt.Errorf("marshal lease info: %w", err)
+	}
+
+	// Set key with lease only if the key does not exist yet.
+	kvKey := []byte(l.kvKey())
+	var txnResp txnResponse
+	if err := l.do(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: kvKey, Target: "CREATE", Result: "EQUAL", CreateRevision: new(int64String)}},
+		Success: []requestOp{{RequestPut: &putRequest{Key: kvKey, Value: kvValue, Lease: grantResp.ID}}},
+	}, &txnResp); err != nil {
+		return nil, fmt.Errorf("put etcd key/value: %w", err)
+	} else if !txnResp.Succeeded {
+		return nil, litefs.ErrPrimaryExists
+	}
+	lease.epoch = uint64(txnResp.Header.Revision)
+	return lease, nil
+}
+
+// waitAcquireDelay sleeps for the acquire delay plus a random jitter. This only
+// occurs on the first call. Returns true if the leaser waited.
+func (l *Leaser) waitAcquireDelay(ctx context.Context) (bool, error) {
+	if l.acquireDelayed.Swap(true) {
+		return false, nil
+	}
+
+	d := l.AcquireDelay
+	if l.AcquireDelayJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(l.AcquireDelayJitter)))
+	}
+	if d <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, context.Cause(ctx)
+	case <-timer.C:
+		return true, nil
+	}
+}
+
+// AcquireExisting acquires the key using an existing lease ID. This can occur
+// if an existing primary hands off to a replica. Returns an error if the lease
+// could not be renewed or if the key is not attached to the lease.
+func (l *Leaser) AcquireExisting(ctx context.Context, leaseID string) (litefs.Lease, error) {
+	id, err := strconv.ParseInt(leaseID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid etcd lease id: %q", leaseID)
+	}
+
+	lease := newLease(l, id, time.Now())
+	if err := lease.Renew(ctx); err != nil {
+		return nil, err
+	}
+
+	// Marshal information about the primary node.
+	kvValue, err := l.kvValue()
+	if err != nil {
+		return nil, fmt.Errorf("marshal lease info: %w", err)
+	}
+
+	// Replace value only if the key is still attached to the lease.
+	kvKey := []byte(l.kvKey())
+	var txnResp txnResponse
+	if err := l.do(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: kvKey, Target: "LEASE", Result: "EQUAL", Lease: int64String(id)}},
+		Success: []requestOp{{RequestPut: &putRequest{Key: kvKey, Value: kvValue, Lease: int64String(id)}}},
+	}, &txnResp); err != nil {
+		return nil, fmt.Errorf("replace etcd key/value: %w", err)
+	} else if !txnResp.Succeeded {
+		return nil, litefs.ErrPrimaryExists
+	}
+	lease.epoch = uint64(txnResp.Header.Revision)
+	return lease, nil
+}
+
+// PrimaryInfo attempts to return the current primary URL.
+func (l *Leaser) PrimaryInfo(ctx context.Context) (info litefs.PrimaryInfo, err error) {
+	value, err := l.get(ctx, l.kvKey())
+	if err != nil {
+		return info, err
+	} else if len(value) == 0 {
+		return info, litefs.ErrNoPrimary
+	}
+
+	if err := json.Unmarshal(value, &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// ClusterIDKey returns the key used to store the cluster ID.
+func (l *Leaser) ClusterIDKey() string {
+	return path.Join(l.KeyPrefix, l.Key, "clusterid")
+}
+
+// ClusterID returns the current cluster ID from etcd.
+// Returns a blank string if no cluster ID has been set yet.
+func (l *Leaser) ClusterID(ctx context.Context) (string, error) {
+	value, err := l.get(ctx, l.ClusterIDKey())
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// SetClusterID sets the cluster ID on etcd. The cluster ID can only be set
+// once and it will return an error if attemping to reassign the cluster ID.
+func (l *Leaser) SetClusterID(ctx context.Context, clusterID string) error {
+	key := []byte(l.ClusterIDKey())
+
+	var txnResp txnResponse
+	if err := l.do(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: key, Target: "CREATE", Result: "EQUAL", CreateRevision: new(int64String)}},
+		Success: []requestOp{{RequestPut: &putRequest{Key: key, Value: []byte(clusterID)}}},
+	}, &txnResp); err != nil {
+		return err
+	} else if !txnResp.Succeeded {
+		return fmt.Errorf("cluster already initialized, cannot set cluster id")
+	}
+	return nil
+}
+
+// get returns the value for key. Returns nil if the key does not exist.
+func (l *Leaser) get(ctx context.Context, key string) ([]byte, error) {
+	var resp rangeResponse
+	if err := l.do(ctx, "/v3/kv/range", rangeRequest{Key: []byte(key)}, &resp); err != nil {
+		return nil, err
+	} else if len(resp.KVs) == 0 {
+		return nil, nil
+	}
+	return resp.KVs[0].Value, nil
+}
+
+// do sends a JSON request to the etcd gateway and decodes the response.
+func (l *Leaser) do(ctx context.Context, path string, reqBody, respBody any) error {
+	buf, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+path, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		body, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(body, &e); err == nil && e.Message != "" {
+			return fmt.Errorf("etcd error (%d): %s", resp.StatusCode, e.Message)
+		}
+		return fmt.Errorf("etcd error (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Streaming endpoints may return multiple objects so only decode the first.
+	return json.NewDecoder(resp.Body).Decode(respBody)
+}
+
+// Lease represents a distributed lock obtained by the Leaser.
+type Lease struct {
+	leaser    *Leaser
+	id        int64
+	epoch     uint64
+	renewedAt time.Time
+	handoffCh chan uint64 // channel of node IDs
+}
+
+func newLease(leaser *Leaser, id int64, renewedAt time.Time) *Lease {
+	return &Lease{
+		leaser:    leaser,
+		id:        id,
+		renewedAt: renewedAt,
+		handoffCh: make(chan uint64),
+	}
+}
+
+// ID returns the etcd lease ID.
+func (l *Lease) ID() string { return strconv.FormatInt(l.id, 10) }
+
+// Epoch returns the etcd revision at which the lease key was written.
+func (l *Lease) Epoch() uint64 { return l.epoch }
+
+// TTL returns the time-to-live value the lease was initialized with.
+func (l *Lease) TTL() time.Duration { return l.leaser.TTL }
+
+// RenewedAt returns the time that the lease was created or renewed.
+func (l *Lease) RenewedAt() time.Time { return l.renewedAt }
+
+// Renew attempts to reset the TTL on the lease by sending a keep alive.
+// Returns ErrLeaseExpired if lease no longer exists.
+func (l *Lease) Renew(ctx context.Context) error {
+	var resp leaseKeepAliveResponse
+	if err := l.leaser.do(ctx, "/v3/lease/keepalive", leaseKeepAliveRequest{
+		ID: int64String(l.id),
+	}, &resp); err != nil {
+		return err
+	} else if resp.Error != nil {
+		return fmt.Errorf("etcd keepalive error: %s", resp.Error.Message)
+	} else if resp.Result.TTL <= 0 {
+		return litefs.ErrLeaseExpired
+	}
+
+	// Reset the last renewed time.
+	l.renewedAt = time.Now()
+
+	return nil
+}
+
+// Handoff sends the nodeID to the channel returned by HandoffCh()
+func (l *Lease) Handoff(ctx context.Context, nodeID uint64) error {
+	ctx, cancel := context.WithTimeoutCause(ctx, 5*time.Second, fmt.Errorf("etcd handoff timeout"))
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case l.handoffCh <- nodeID:
+		return nil
+	}
+}
+
+// HandoffCh returns the handoff channel.
+func (l *Lease) HandoffCh() <-chan uint64 { return l.handoffCh }
+
+// Close revokes the underlying lease which deletes any attached keys.
+func (l *Lease) Close() error {
+	var resp struct{}
+	if err := l.leaser.do(context.Background(), "/v3/lease/revoke", leaseRevokeRequest{
+		ID: int64String(l.id),
+	}, &resp); err != nil {
+		logger.Warn("etcd lease revoke error", slog.String("key", l.leaser.kvKey()), slog.Int64("lease", int64(l.id)))
+		return err
+	}
+	return nil
+}
+
+// int64String is an integer that is encoded as a JSON string as required by
+// the etcd gateway for 64-bit fields. It decodes from strings or numbers.
+type int64String int64
+
+func (i int64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(i), 10))
+}
+
+func (i *int64String) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = int64String(v)
+	return nil
+}
+
+// etcd gateway request & response types. Byte slices are base64 encoded.
+type leaseGrantRequest struct {
+	TTL int64String `json:"TTL"`
+}
+
+type leaseGrantResponse struct {
+	ID  int64String `json:"ID"`
+	TTL int64String `json:"TTL"`
+}
+
+type leaseKeepAliveRequest struct {
+	ID int64String `json:"ID"`
+}
+
+type leaseKeepAliveResponse struct {
+	Result struct {
+		ID  int64String `json:"ID"`
+		TTL int64String `json:"TTL"`
+	} `json:"result"`
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+type leaseRevokeRequest struct {
+	ID int64String `json:"ID"`
+}
+
+type rangeRequest struct {
+	Key []byte `json:"key"`
+}
+
+type rangeResponse struct {
+	KVs []struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	} `json:"kvs"`
+}
+
+type txnRequest struct {
+	Compare []compare   `json:"compare"`
+	Success []requestOp `json:"success"`
+}
+
+type compare struct {
+	Key            []byte       `json:"key"`
+	Target         string       `json:"target"`
+	Result         string       `json:"result"`
+	CreateRevision *int64String `json:"create_revision,omitempty"`
+	Lease          int64String  `json:"lease,omitempty"`
+}
+
+type requestOp struct {
+	RequestPut *putRequest `json:"request_put,omitempty"`
+}
+
+type putRequest struct {
+	Key   []byte      `json:"key"`
+	Value []byte      `json:"value"`
+	Lease int64String `json:"lease,omitempty"`
+}
+
+type txnResponse struct {
+	Header    responseHeader `json:"header"`
+	Succeeded bool           `json:"succeeded"`
+}
+
+type responseHeader struct {
+	Revision int64String `json:"revision"`
+}
+
+type errorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
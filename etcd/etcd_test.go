@@ -0,0 +1,308 @@
+package etcd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/etcd"
+)
+
+func TestLeaser_Acquire(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := etcd.NewLeaser(srv.URL+"/prefix", "primary", "node1", "http://node1:20202")
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lease, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := l.PrimaryInfo(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "node1"; got != want {
+			t.Fatalf("Hostname=%s, want %s", got, want)
+		} else if got, want := info.AdvertiseURL, "http://node1:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%s, want %s", got, want)
+		}
+
+		if err := lease.Renew(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Closing the lease should revoke it & remove the primary key.
+		if err := lease.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrPrimaryExists", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l0 := etcd.NewLeaser(srv.URL, "primary", "node0", "http://node0:20202")
+		if err := l0.Open(); err != nil {
+			t.Fatal(err)
+		} else if _, err := l0.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		l1 := etcd.NewLeaser(srv.URL, "primary", "node1", "http://node1:20202")
+		if err := l1.Open(); err != nil {
+			t.Fatal(err)
+		} else if _, err := l1.Acquire(context.Background()); err != litefs.ErrPrimaryExists {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if info, err := l1.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "node0"; got != want {
+			t.Fatalf("Hostname=%s, want %s", got, want)
+		}
+	})
+}
+
+func TestLeaser_AcquireExisting(t *testing.T) {
+	srv := newFakeServer()
+	defer srv.Close()
+
+	l0 := etcd.NewLeaser(srv.URL, "primary", "node0", "http://node0:20202")
+	if err := l0.Open(); err != nil {
+		t.Fatal(err)
+	}
+	lease0, err := l0.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hand off the lease to another node.
+	l1 := etcd.NewLeaser(srv.URL, "primary", "node1", "http://node1:20202")
+	if err := l1.Open(); err != nil {
+		t.Fatal(err)
+	}
+	lease1, err := l1.AcquireExisting(context.Background(), lease0.ID())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := lease1.ID(), lease0.ID(); got != want {
+		t.Fatalf("ID=%s, want %s", got, want)
+	} else if lease1.Epoch() <= lease0.Epoch() {
+		t.Fatalf("Epoch=%d, want greater than %d", lease1.Epoch(), lease0.Epoch())
+	}
+
+	if info, err := l1.PrimaryInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := info.Hostname, "node1"; got != want {
+		t.Fatalf("Hostname=%s, want %s", got, want)
+	}
+
+	// Acquiring with an unknown lease should fail.
+	if _, err := l1.AcquireExisting(context.Background(), "1000"); err != litefs.ErrLeaseExpired {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLease_Renew(t *testing.T) {
+	t.Run("ErrLeaseExpired", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := etcd.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+		if err := l.Open(); err != nil {
+			t.Fatal(err)
+		}
+		lease, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Simulate expiration of the lease on the server.
+		srv.expire(lease.ID())
+		if err := lease.Renew(context.Background()); err != litefs.ErrLeaseExpired {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestLeaser_ClusterID(t *testing.T) {
+	srv := newFakeServer()
+	defer srv.Close()
+
+	l := etcd.NewLeaser(srv.URL, "primary", "node", "http://node:20202")
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := l.ClusterID(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if id != "" {
+		t.Fatalf("unexpected cluster id: %q", id)
+	}
+
+	if err := l.SetClusterID(context.Background(), "LFSC0000000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := l.ClusterID(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := id, "LFSC0000000000000001"; got != want {
+		t.Fatalf("ClusterID=%s, want %s", got, want)
+	}
+
+	// Cluster ID cannot be reassigned.
+	if err := l.SetClusterID(context.Background(), "LFSC0000000000000002"); err == nil || err.Error() != `cluster already initialized, cannot set cluster id` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// fakeServer implements the subset of the etcd v3 JSON gateway used by the leaser.
+type fakeServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	kv     map[string]fakeKV
+	leases map[int64]struct{}
+	nextID int64
+	rev    int64
+}
+
+type fakeKV struct {
+	value          []byte
+	lease          int64
+	createRevision int64
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{
+		kv:     make(map[string]fakeKV),
+		leases: make(map[int64]struct{}),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// expire removes a lease and its attached keys.
+func (s *fakeServer) expire(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, _ := strconv.ParseInt(id, 10, 64)
+	s.revoke(v)
+}
+
+func (s *fakeServer) revoke(id int64) {
+	delete(s.leases, id)
+	for k, kv := range s.kv {
+		if kv.lease == id {
+			delete(s.kv, k)
+		}
+	}
+}
+
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var req struct {
+		ID      string `json:"ID"`
+		TTL     string `json:"TTL"`
+		Key     []byte `json:"key"`
+		Compare []struct {
+			Key            []byte  `json:"key"`
+			Target         string  `json:"target"`
+			CreateRevision *string `json:"create_revision"`
+			Lease          string  `json:"lease"`
+		} `json:"compare"`
+		Success []struct {
+			RequestPut struct {
+				Key   []byte `json:"key"`
+				Value []byte `json:"value"`
+				Lease string `json:"lease"`
+			} `json:"request_put"`
+		} `json:"success"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, _ := strconv.ParseInt(req.ID, 10, 64)
+
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		s.nextID++
+		s.leases[s.nextID] = struct{}{}
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": strconv.FormatInt(s.nextID, 10), "TTL": req.TTL})
+
+	case "/v3/lease/keepalive":
+		result := map[string]string{"ID": req.ID}
+		if _, ok := s.leases[id]; ok {
+			result["TTL"] = "10"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": result})
+
+	case "/v3/lease/revoke":
+		s.revoke(id)
+		_ = json.NewEncoder(w).Encode(map[string]any{})
+
+	case "/v3/kv/range":
+		kv, ok := s.kv[string(req.Key)]
+		if !ok {
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"kvs": []map[string][]byte{{"key": req.Key, "value": kv.value}}})
+
+	case "/v3/kv/txn":
+		succeeded := true
+		for _, cmp := range req.Compare {
+			kv := s.kv[string(cmp.Key)]
+			switch cmp.Target {
+			case "CREATE":
+				succeeded = succeeded && strconv.FormatInt(kv.createRevision, 10) == *cmp.CreateRevision
+			case "LEASE":
+				succeeded = succeeded && strconv.FormatInt(kv.lease, 10) == cmp.Lease
+			}
+		}
+
+		if succeeded {
+			for _, op := range req.Success {
+				s.rev++
+				lease, _ := strconv.ParseInt(op.RequestPut.Lease, 10, 64)
+				kv, ok := s.kv[string(op.RequestPut.Key)]
+				if !ok {
+					kv.createRevision = s.rev
+				}
+				kv.value, kv.lease = op.RequestPut.Value, lease
+				s.kv[string(op.RequestPut.Key)] = kv
+			}
+		}
+
+		// The gateway omits false values.
+		resp := map[string]any{"header": map[string]string{"revision": strconv.FormatInt(s.rev, 10)}}
+		if succeeded {
+			resp["succeeded"] = true
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
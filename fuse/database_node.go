@@ -3,13 +3,13 @@ package fuse
 import (
 	"context"
 	"io"
-	"log"
 	"os"
 	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 var _ fs.Node = (*DatabaseNode)(nil)
@@ -117,6 +117,7 @@ type DatabaseHandle struct {
 }
 
 func newDatabaseHandle(node *DatabaseNode, file *os.File) *DatabaseHandle {
+	node.db.AddHandle()
 	return &DatabaseHandle{
 		node: node,
 		file: file,
@@ -134,8 +135,8 @@ func (h *DatabaseHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *
 
 func (h *DatabaseHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
 	if err := h.node.db.WriteDatabaseAt(ctx, h.file, req.Data, req.Offset, uint64(req.LockOwner)); err != nil {
-		log.Printf("fuse: write(): database error: %s", err)
-		return err
+		logger.Error("write(): database error", slog.String("db", h.node.db.Name()), slog.Any("err", err))
+		return ToError(err)
 	}
 	resp.Size = len(req.Data)
 	return nil
@@ -147,6 +148,7 @@ func (h *DatabaseHandle) Flush(ctx context.Context, req *fuse.FlushRequest) erro
 }
 
 func (h *DatabaseHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	defer h.node.db.RemoveHandle()
 	return h.node.db.CloseDatabase(ctx, h.file, uint64(req.LockOwner))
 }
 
@@ -177,7 +179,7 @@ func lock(ctx context.Context, req *fuse.LockRequest, db *litefs.DB, lockTypes [
 
 	case fuse.LockWrite:
 		if ok, err := db.TryLocks(ctx, uint64(req.LockOwner), lockTypes); err != nil {
-			log.Printf("fuse lock error: %s", err)
+			logger.Error("lock error", slog.String("db", db.Name()), slog.Any("err", err))
 			return err
 		} else if !ok {
 			return syscall.EAGAIN
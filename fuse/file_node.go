@@ -0,0 +1,135 @@
+package fuse
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+var _ fs.Node = (*FileNode)(nil)
+var _ fs.NodeOpener = (*FileNode)(nil)
+var _ fs.NodeFsyncer = (*FileNode)(nil)
+var _ fs.NodeForgetter = (*FileNode)(nil)
+var _ fs.NodeSetattrer = (*FileNode)(nil)
+
+// FileNode represents a regular file that is not a SQLite database. These
+// files are stored locally in the data directory and are not replicated.
+type FileNode struct {
+	fsys *FileSystem
+	name string
+}
+
+func newFileNode(fsys *FileSystem, name string) *FileNode {
+	return &FileNode{
+		fsys: fsys,
+		name: name,
+	}
+}
+
+// Path returns the path to the underlying file in the data directory.
+func (n *FileNode) Path() string {
+	return filepath.Join(n.fsys.store.FilesDir(), n.name)
+}
+
+func (n *FileNode) Attr(ctx context.Context, attr *fuse.Attr) error {
+	fi, err := os.Stat(n.Path())
+	if os.IsNotExist(err) {
+		return syscall.ENOENT
+	} else if err != nil {
+		return err
+	}
+
+	attr.Mode = fi.Mode().Perm()
+	attr.Size = uint64(fi.Size())
+	attr.Mtime = fi.ModTime()
+	attr.Uid = uint32(n.fsys.Uid)
+	attr.Gid = uint32(n.fsys.Gid)
+	attr.Valid = 0
+	return nil
+}
+
+func (n *FileNode) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	if req.Valid.Size() {
+		if err := os.Truncate(n.Path(), int64(req.Size)); err != nil {
+			return err
+		}
+	}
+	if req.Valid.Mode() {
+		if err := os.Chmod(n.Path(), req.Mode.Perm()); err != nil {
+			return err
+		}
+	}
+	return n.Attr(ctx, &resp.Attr)
+}
+
+func (n *FileNode) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	f, err := os.OpenFile(n.Path(), int(req.Flags&fuse.OpenAccessModeMask), 0o666)
+	if err != nil {
+		return nil, err
+	}
+	return newFileHandle(n, f, req.Flags&fuse.OpenAppend != 0), nil
+}
+
+func (n *FileNode) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	f, err := os.Open(n.Path())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return f.Sync()
+}
+
+func (n *FileNode) Forget() { n.fsys.root.ForgetNode(n) }
+
+var _ fs.Handle = (*FileHandle)(nil)
+var _ fs.HandleReader = (*FileHandle)(nil)
+var _ fs.HandleWriter = (*FileHandle)(nil)
+var _ fs.HandleReleaser = (*FileHandle)(nil)
+
+// FileHandle represents a file handle to a regular file.
+type FileHandle struct {
+	node   *FileNode
+	file   *os.File
+	append bool // if true, writes are always appended to the end of the file
+}
+
+func newFileHandle(node *FileNode, file *os.File, append bool) *FileHandle {
+	return &FileHandle{
+		node:   node,
+		file:   file,
+		append: append,
+	}
+}
+
+func (h *FileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	n, err := h.file.ReadAt(resp.Data[:req.Size], req.Offset)
+	if err == io.EOF {
+		err = nil
+	}
+	resp.Data = resp.Data[:n]
+	return err
+}
+
+func (h *FileHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	offset := req.Offset
+	if h.append {
+		fi, err := h.file.Stat()
+		if err != nil {
+			return err
+		}
+		offset = fi.Size()
+	}
+
+	n, err := h.file.WriteAt(req.Data, offset)
+	resp.Size = n
+	return err
+}
+
+func (h *FileHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	return h.file.Close()
+}
@@ -2,15 +2,19 @@ package fuse
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"os"
 	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
+// logger is the logger for the fuse subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemFUSE)
+
 var _ fs.FS = (*FileSystem)(nil)
 var _ fs.FSStatfser = (*FileSystem)(nil)
 var _ litefs.Invalidator = (*FileSystem)(nil)
@@ -89,7 +93,7 @@ func (fsys *FileSystem) Mount() (err error) {
 
 	go func() {
 		if err := fsys.server.Serve(fsys); err != nil {
-			log.Printf("fuse serve error: %s", err)
+			logger.Error("serve error", slog.Any("err", err))
 		}
 	}()
 
@@ -185,8 +189,9 @@ func (fsys *FileSystem) InvalidatePos(db *litefs.DB) error {
 	return nil
 }
 
-// InvalidateEntry removes the file from the cache.
+// InvalidateEntry removes the file from the kernel cache & the node cache.
 func (fsys *FileSystem) InvalidateEntry(name string) error {
+	fsys.root.ForgetNodeByName(name)
 	if err := fsys.server.InvalidateEntry(fsys.root, name); err != nil && err != fuse.ErrNotCached {
 		return err
 	}
@@ -211,5 +216,5 @@ func (fsys *FileSystem) debugFn(msg any) {
 	if fsys.store.IsPrimary() {
 		status = "p"
 	}
-	log.Printf("%s [%s]: %s", litefs.FormatNodeID(fsys.store.ID()), status, msg)
+	logger.Info(fmt.Sprint(msg), slog.String("node", litefs.FormatNodeID(fsys.store.ID())), slog.String("status", status))
 }
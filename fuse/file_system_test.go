@@ -645,6 +645,47 @@ func testFileSystem_Checkpoint(t *testing.T, mode string) {
 	}
 }
 
+// Ensure the WAL size grows with each transaction and is reset on checkpoint.
+func TestFileSystem_WALFileSize(t *testing.T) {
+	if !testingutil.IsWALMode() {
+		t.Skip("wal size does not apply to the rollback journal, skipping")
+	}
+
+	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+	dsn := filepath.Join(fs.Path(), "db")
+	db := testingutil.OpenSQLDB(t, dsn)
+
+	if _, err := db.Exec(`PRAGMA wal_autocheckpoint = 0`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+
+	var prev int64
+	for i := 0; i < 100; i++ {
+		if _, err := db.Exec(`INSERT INTO t VALUES (?)`, strings.Repeat("x", 100)); err != nil {
+			t.Fatal(err)
+		}
+
+		sz, err := fs.Store().DB("db").WALFileSize()
+		if err != nil {
+			t.Fatal(err)
+		} else if sz < prev {
+			t.Fatalf("wal size decreased: %d < %d", sz, prev)
+		}
+		prev = sz
+	}
+
+	if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		t.Fatal(err)
+	}
+	if sz, err := fs.Store().DB("db").WALFileSize(); err != nil {
+		t.Fatal(err)
+	} else if sz > litefs.WALHeaderSize {
+		t.Fatalf("wal size=%d, want <= %d", sz, litefs.WALHeaderSize)
+	}
+}
+
 func TestFileSystem_ConcurrentWriteAndSnapshot(t *testing.T) {
 	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
 	dsn := filepath.Join(fs.Path(), "db")
@@ -904,6 +945,351 @@ func TestFileSystem_Lag(t *testing.T) {
 	}
 }
 
+// Ensure database lifecycle hooks are called after file system operations.
+func TestFileSystem_DBHooks(t *testing.T) {
+	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+
+	var created, deleted []string
+	var renamed [][2]string
+	fs.Store().SetDBCreateHook(func(dbName string) { created = append(created, dbName) })
+	fs.Store().SetDBDeleteHook(func(dbName string) { deleted = append(deleted, dbName) })
+	fs.Store().SetDBRenameHook(func(oldName, newName string) { renamed = append(renamed, [2]string{oldName, newName}) })
+
+	dsn := filepath.Join(fs.Path(), "db")
+	db := testingutil.OpenSQLDB(t, dsn)
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := created, []string{"db"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("created=%v, want %v", got, want)
+	}
+
+	if err := os.Rename(dsn, dsn+"2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := renamed, [][2]string{{"db", "db2"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("renamed=%v, want %v", got, want)
+	}
+
+	if err := os.Remove(dsn + "2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := deleted, []string{"db2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("deleted=%v, want %v", got, want)
+	}
+	if got, want := created, []string{"db"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("created=%v, want %v", got, want)
+	}
+}
+
+// Ensure a database can be renamed through the file system.
+func TestFileSystem_Rename(t *testing.T) {
+	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+
+	var renamed [][2]string
+	fs.Store().SetDBRenameHook(func(oldName, newName string) { renamed = append(renamed, [2]string{oldName, newName}) })
+
+	dsn := filepath.Join(fs.Path(), "db")
+	db := testingutil.OpenSQLDB(t, dsn)
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(dsn, dsn+"2"); err != nil {
+		t.Fatal(err)
+	} else if got, want := renamed, [][2]string{{"db", "db2"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("renamed=%v, want %v", got, want)
+	} else if _, err := os.Stat(dsn); !os.IsNotExist(err) {
+		t.Fatalf("expected old database to not exist, got %v", err)
+	}
+
+	var x int
+	db = testingutil.OpenSQLDB(t, dsn+"2")
+	if err := db.QueryRow(`SELECT x FROM t`).Scan(&x); err != nil {
+		t.Fatal(err)
+	} else if got, want := x, 100; got != want {
+		t.Fatalf("x=%d, want %d", got, want)
+	}
+}
+
+// Ensure a database cannot be removed or renamed while it is open.
+func TestFileSystem_DBBusy(t *testing.T) {
+	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+
+	dsn := filepath.Join(fs.Path(), "db")
+	db := testingutil.OpenSQLDB(t, dsn)
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(dsn); !errors.Is(err, syscall.EBUSY) {
+		t.Fatalf("unexpected remove error: %v", err)
+	} else if err := os.Rename(dsn, dsn+"2"); !errors.Is(err, syscall.EBUSY) {
+		t.Fatalf("unexpected rename error: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := os.Remove(dsn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure transactions that exceed the page limit fail with SQLITE_FULL without
+// writing an LTX file & that the next transaction succeeds.
+func TestFileSystem_TxLimit(t *testing.T) {
+	for _, mode := range []string{"DELETE", "WAL"} {
+		t.Run(mode, func(t *testing.T) {
+			fs := newFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+			fs.Store().MaxTxPageN = 5
+			if err := fs.Mount(); err != nil {
+				t.Fatalf("cannot open file system: %s", err)
+			}
+			t.Cleanup(func() { _ = fs.Unmount() })
+			waitForPrimary(t, fs)
+
+			sqldb := testingutil.OpenSQLDB(t, filepath.Join(fs.Path(), "db"))
+			if _, err := sqldb.Exec(`PRAGMA journal_mode = ` + mode); err != nil {
+				t.Fatal(err)
+			} else if _, err := sqldb.Exec(`CREATE TABLE t (x)`); err != nil {
+				t.Fatal(err)
+			}
+
+			db := fs.Store().DB("db")
+			pos := db.Pos()
+			ents, err := db.ReadLTXDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Insert enough data to exceed the page limit in a single transaction.
+			var sqliteErr sqlite3.Error
+			_, err = sqldb.Exec(`WITH RECURSIVE c(n) AS (SELECT 1 UNION ALL SELECT n+1 FROM c WHERE n < 100) INSERT INTO t SELECT randomblob(4000) FROM c`)
+			if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrFull {
+				t.Fatalf("expected SQLITE_FULL, got %v", err)
+			}
+
+			if got, want := db.Pos(), pos; got != want {
+				t.Fatalf("Pos=%s, want %s", got, want)
+			} else if other, err := db.ReadLTXDir(); err != nil {
+				t.Fatal(err)
+			} else if got, want := len(other), len(ents); got != want {
+				t.Fatalf("ltx file count=%d, want %d", got, want)
+			}
+
+			// Ensure the next transaction within the limit is committed.
+			var n int
+			if _, err := sqldb.Exec(`INSERT INTO t VALUES (100)`); err != nil {
+				t.Fatal(err)
+			} else if got, want := db.TXID(), pos.TXID+1; got != want {
+				t.Fatalf("TXID=%s, want %s", got, want)
+			} else if err := sqldb.QueryRow(`SELECT COUNT(*) FROM t`).Scan(&n); err != nil {
+				t.Fatal(err)
+			} else if got, want := n, 1; got != want {
+				t.Fatalf("n=%d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// Ensure only files matching the database extensions are replicated.
+func TestFileSystem_DBExtensions(t *testing.T) {
+	fs := newFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+	fs.Store().DBExtensions = []string{".db", ".sqlite"}
+	if err := fs.Mount(); err != nil {
+		t.Fatalf("cannot open file system: %s", err)
+	}
+	t.Cleanup(func() { _ = fs.Unmount() })
+	waitForPrimary(t, fs)
+
+	db := testingutil.OpenSQLDB(t, filepath.Join(fs.Path(), "test.db"))
+	if _, err := db.Exec(`CREATE TABLE t (x)`); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write & append to a regular file.
+	txtPath := filepath.Join(fs.Path(), "notes.txt")
+	if err := os.WriteFile(txtPath, []byte("foo"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(txtPath, os.O_WRONLY|os.O_APPEND, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := f.Write([]byte("bar")); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := os.ReadFile(txtPath); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(buf), "foobar"; got != want {
+		t.Fatalf("content=%q, want %q", got, want)
+	}
+
+	// Only the database should produce LTX files.
+	if fs.Store().DB("notes.txt") != nil {
+		t.Fatal("expected regular file to not be a database")
+	}
+	if ents, err := os.ReadDir(fs.Store().DB("test.db").LTXDir()); err != nil {
+		t.Fatal(err)
+	} else if len(ents) == 0 {
+		t.Fatal("expected ltx files for database")
+	}
+
+	// Regular files should be listed & removable.
+	if ents, err := os.ReadDir(fs.Path()); err != nil {
+		t.Fatal(err)
+	} else if !containsDirEntry(ents, "notes.txt") || !containsDirEntry(ents, "test.db") {
+		t.Fatalf("unexpected entries: %v", ents)
+	}
+	if err := os.Remove(txtPath); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(txtPath); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed: %v", err)
+	}
+}
+
+func containsDirEntry(ents []os.DirEntry, name string) bool {
+	for _, ent := range ents {
+		if ent.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure the cache invalidation strategy controls whether readers of the
+// mount see pages written by an applied LTX file.
+func TestFileSystem_CacheInvalidationStrategy(t *testing.T) {
+	t.Run("NoInvalidate", func(t *testing.T) {
+		if got, want := testFileSystem_CacheInvalidationStrategy(t, litefs.NoInvalidate), byte(1); got != want {
+			t.Fatalf("read %d, want stale %d", got, want)
+		}
+	})
+	t.Run("InvalidatePerPage", func(t *testing.T) {
+		if got, want := testFileSystem_CacheInvalidationStrategy(t, litefs.InvalidatePerPage), byte(2); got != want {
+			t.Fatalf("read %d, want %d", got, want)
+		}
+	})
+	t.Run("InvalidateAll", func(t *testing.T) {
+		if got, want := testFileSystem_CacheInvalidationStrategy(t, litefs.InvalidateAll), byte(2); got != want {
+			t.Fatalf("read %d, want %d", got, want)
+		}
+	})
+}
+
+// testFileSystem_CacheInvalidationStrategy reads a database through the mount
+// to populate the kernel cache, applies a transaction that overwrites every
+// page, and returns the first byte of the last page as seen through the mount.
+func testFileSystem_CacheInvalidationStrategy(t *testing.T, strategy litefs.CacheInvalidationStrategy) byte {
+	fs := newOpenFileSystem(t, t.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+	fs.Store().CacheInvalidationStrategy = strategy
+
+	db, f, err := fs.Store().CreateDB("db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pos := applyLTXPages(t, db, ltx.Pos{}, 4, 1)
+
+	dsn := filepath.Join(fs.Path(), "db")
+	if buf, err := os.ReadFile(dsn); err != nil {
+		t.Fatal(err)
+	} else if got, want := buf[len(buf)-1], byte(1); got != want {
+		t.Fatalf("read %d, want %d", got, want)
+	}
+
+	applyLTXPages(t, db, pos, 4, 2)
+
+	buf, err := os.ReadFile(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[len(buf)-1]
+}
+
+func BenchmarkFileSystem_CacheInvalidationStrategy(b *testing.B) {
+	for _, strategy := range []litefs.CacheInvalidationStrategy{litefs.InvalidatePerPage, litefs.InvalidateAll, litefs.NoInvalidate} {
+		b.Run(strategy.String(), func(b *testing.B) {
+			const pageN = 10000
+
+			fs := newOpenFileSystem(b, b.TempDir(), litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202"))
+			fs.Store().CacheInvalidationStrategy = strategy
+
+			db, f, err := fs.Store().CreateDB("db")
+			if err != nil {
+				b.Fatal(err)
+			} else if err := f.Close(); err != nil {
+				b.Fatal(err)
+			}
+			pos := applyLTXPages(b, db, ltx.Pos{}, pageN, 1)
+
+			// Read through the mount so the kernel cache is populated.
+			if _, err := os.ReadFile(filepath.Join(fs.Path(), "db")); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pos = applyLTXPages(b, db, pos, pageN, byte(i%2)+2)
+			}
+		})
+	}
+}
+
+// applyLTXPages applies a transaction to db that overwrites pages 1 through
+// pageN with the value v. Applies a snapshot if pos is zero. Returns the new position.
+func applyLTXPages(tb testing.TB, db *litefs.DB, pos ltx.Pos, pageN uint32, v byte) ltx.Pos {
+	tb.Helper()
+
+	hdr := ltx.Header{
+		Version:          ltx.Version,
+		PageSize:         4096,
+		Commit:           pageN,
+		MinTXID:          pos.TXID + 1,
+		MaxTXID:          pos.TXID + 1,
+		Timestamp:        time.Now().UnixMilli(),
+		PreApplyChecksum: pos.PostApplyChecksum,
+	}
+
+	var buf bytes.Buffer
+	enc := ltx.NewEncoder(&buf)
+	if err := enc.EncodeHeader(hdr); err != nil {
+		tb.Fatal(err)
+	}
+
+	// Every page is overwritten so the checksum only depends on the new pages.
+	chksum := ltx.ChecksumFlag
+	data := bytes.Repeat([]byte{v}, int(hdr.PageSize))
+	for pgno := uint32(1); pgno <= pageN; pgno++ {
+		if pgno == ltx.LockPgno(hdr.PageSize) {
+			continue
+		}
+		if err := enc.EncodePage(ltx.PageHeader{Pgno: pgno}, data); err != nil {
+			tb.Fatal(err)
+		}
+		chksum = ltx.ChecksumFlag | (chksum ^ ltx.ChecksumPage(pgno, data))
+	}
+	enc.SetPostApplyChecksum(chksum)
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	}
+
+	if err := db.ApplyLTXStream(context.Background(), &buf); err != nil {
+		tb.Fatal(err)
+	}
+	return ltx.Pos{TXID: hdr.MaxTXID, PostApplyChecksum: chksum}
+}
+
 func newFileSystem(tb testing.TB, path string, leaser litefs.Leaser) *fuse.FileSystem {
 	tb.Helper()
 
@@ -1,6 +1,7 @@
 package fuse
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -52,6 +53,16 @@ func ToError(err error) error {
 		return &Error{err: err, errno: fuse.ToErrno(syscall.ENOENT)}
 	} else if err == litefs.ErrReadOnlyReplica {
 		return &Error{err: err, errno: fuse.ToErrno(syscall.EACCES)}
+	} else if err == litefs.ErrDatabaseNotFound {
+		return &Error{err: err, errno: fuse.ToErrno(syscall.ENOENT)}
+	} else if err == litefs.ErrDatabaseExists {
+		return &Error{err: err, errno: fuse.ToErrno(syscall.EEXIST)}
+	} else if err == litefs.ErrDatabaseBusy {
+		return &Error{err: err, errno: fuse.ToErrno(syscall.EBUSY)}
+	} else if err == litefs.ErrRenameNotSupported {
+		return &Error{err: err, errno: fuse.ToErrno(syscall.ENOTSUP)}
+	} else if errors.Is(err, litefs.ErrTxTooLarge) {
+		return &Error{err: err, errno: fuse.ToErrno(syscall.ENOSPC)} // SQLITE_FULL
 	}
 	return err
 }
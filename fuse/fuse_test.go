@@ -3,6 +3,7 @@ package fuse_test
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"syscall"
@@ -80,6 +81,24 @@ func TestToErrno(t *testing.T) {
 		}
 	})
 
+	t.Run("EBUSY", func(t *testing.T) {
+		err := fuse.ToError(litefs.ErrDatabaseBusy).(*fuse.Error)
+		if got, want := err.Error(), `database in use`; got != want {
+			t.Fatalf("Error()=%q, want %q", got, want)
+		} else if got, want := syscall.Errno(err.Errno()), syscall.EBUSY; got != want {
+			t.Fatalf("Errno()=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("ENOSPC", func(t *testing.T) {
+		err := fuse.ToError(fmt.Errorf("wal frame header: %w", litefs.ErrTxTooLarge)).(*fuse.Error)
+		if got, want := err.Error(), `wal frame header: transaction exceeds size limit`; got != want {
+			t.Fatalf("Error()=%q, want %q", got, want)
+		} else if got, want := syscall.Errno(err.Errno()), syscall.ENOSPC; got != want {
+			t.Fatalf("Errno()=%v, want %v", got, want)
+		}
+	})
+
 	t.Run("Passthrough", func(t *testing.T) {
 		if _, ok := fuse.ToError(errors.New("marker")).(*fuse.Error); ok {
 			t.Fatal("expected original error")
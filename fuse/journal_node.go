@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 var _ fs.Node = (*JournalNode)(nil)
@@ -115,6 +115,7 @@ type JournalHandle struct {
 }
 
 func newJournalHandle(node *JournalNode, file *os.File) *JournalHandle {
+	node.db.AddHandle()
 	return &JournalHandle{node: node, file: file}
 }
 
@@ -129,7 +130,7 @@ func (h *JournalHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *f
 
 func (h *JournalHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
 	if err := h.node.db.WriteJournalAt(ctx, h.file, req.Data, req.Offset, uint64(req.LockOwner)); err != nil {
-		log.Printf("fuse: write(): journal error: %s", err)
+		logger.Error("write(): journal error", slog.String("db", h.node.db.Name()), slog.Any("err", err))
 		return ToError(err)
 	}
 	resp.Size = len(req.Data)
@@ -137,6 +138,7 @@ func (h *JournalHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp
 }
 
 func (h *JournalHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	defer h.node.db.RemoveHandle()
 	_ = h.node.db.CloseJournal(ctx, h.file, uint64(req.LockOwner))
 	return nil
 }
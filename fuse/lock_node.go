@@ -3,7 +3,6 @@ package fuse
 import (
 	"context"
 	"errors"
-	"log"
 	"math/rand"
 	"sync"
 	"sync/atomic"
@@ -12,6 +11,7 @@ import (
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 var _ fs.Node = (*LockNode)(nil)
@@ -107,7 +107,7 @@ func (h *LockHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse
 }
 
 func (h *LockHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
-	log.Printf("fuse write error: cannot write to lock file")
+	logger.Warn("write error: cannot write to lock file")
 	return syscall.EIO
 }
 
@@ -125,7 +125,7 @@ func (h *LockHandle) Lock(ctx context.Context, req *fuse.LockRequest) error {
 
 func (h *LockHandle) LockWait(ctx context.Context, req *fuse.LockWaitRequest) (err error) {
 	if req.Lock.Start != req.Lock.End {
-		log.Printf("fuse lock error: only one lock can be acquired on the lock file at a time (%d..%d)", req.Lock.Start, req.Lock.End)
+		logger.Warn("lock error: only one lock can be acquired on the lock file at a time", slog.Uint64("start", req.Lock.Start), slog.Uint64("end", req.Lock.End))
 		return syscall.EINVAL
 	}
 
@@ -133,7 +133,7 @@ func (h *LockHandle) LockWait(ctx context.Context, req *fuse.LockWaitRequest) (e
 	case uint64(litefs.LockTypeHalt):
 		return h.lockWaitHalt(ctx, req)
 	default:
-		log.Printf("fuse lock error: invalid lock file byte: %d", req.Lock.Start)
+		logger.Warn("lock error: invalid lock file byte", slog.Uint64("start", req.Lock.Start))
 		return syscall.EINVAL
 	}
 }
@@ -141,14 +141,14 @@ func (h *LockHandle) LockWait(ctx context.Context, req *fuse.LockWaitRequest) (e
 func (h *LockHandle) lockWaitHalt(ctx context.Context, req *fuse.LockWaitRequest) (err error) {
 	// Return an error this handle is already waiting for a halt lock.
 	if !h.haltLockMu.TryLock() {
-		log.Printf("lock wait error: handle is already waiting for halt lock")
+		logger.Warn("lock wait error: handle is already waiting for halt lock")
 		return syscall.ENOLCK
 	}
 	defer h.haltLockMu.Unlock()
 
 	// Return an error if this handle is already holding a halt lock.
 	if h.haltLock != nil {
-		log.Printf("lock wait error: handle already acquired halt lock")
+		logger.Warn("lock wait error: handle already acquired halt lock")
 		return syscall.ENOLCK
 	}
 
@@ -185,7 +185,7 @@ func (h *LockHandle) lockWaitHalt(ctx context.Context, req *fuse.LockWaitRequest
 
 func (h *LockHandle) Unlock(ctx context.Context, req *fuse.UnlockRequest) error {
 	if req.Lock.Start != req.Lock.End {
-		log.Printf("fuse unlock error: only one lock can be released on the lock file at a time (%d..%d)", req.Lock.Start, req.Lock.End)
+		logger.Warn("unlock error: only one lock can be released on the lock file at a time", slog.Uint64("start", req.Lock.Start), slog.Uint64("end", req.Lock.End))
 		return syscall.EINVAL
 	}
 
@@ -193,7 +193,7 @@ func (h *LockHandle) Unlock(ctx context.Context, req *fuse.UnlockRequest) error
 	case uint64(litefs.LockTypeHalt):
 		return h.unlockHalt(ctx)
 	default:
-		log.Printf("fuse unlock error: invalid lock file byte: %d", req.Lock.Start)
+		logger.Warn("unlock error: invalid lock file byte", slog.Uint64("start", req.Lock.Start))
 		return syscall.EINVAL
 	}
 }
@@ -220,7 +220,7 @@ func (h *LockHandle) unlockHalt(ctx context.Context) error {
 
 func (h *LockHandle) QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) error {
 	if req.Lock.Start != req.Lock.End {
-		log.Printf("fuse query lock error: only one lock can be queried on the lock file at a time (%d..%d)", req.Lock.Start, req.Lock.End)
+		logger.Warn("query lock error: only one lock can be queried on the lock file at a time", slog.Uint64("start", req.Lock.Start), slog.Uint64("end", req.Lock.End))
 		return syscall.EINVAL
 	}
 
@@ -236,7 +236,7 @@ func (h *LockHandle) QueryLock(ctx context.Context, req *fuse.QueryLockRequest,
 		}
 		return nil
 	default:
-		log.Printf("fuse query lock error: invalid lock file byte: %d", req.Lock.Start)
+		logger.Warn("query lock error: invalid lock file byte", slog.Uint64("start", req.Lock.Start))
 		return syscall.EINVAL
 	}
 }
@@ -3,15 +3,16 @@ package fuse
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"sort"
 	"sync"
 	"syscall"
+	"time"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 const RootInode = 1
@@ -22,6 +23,7 @@ var (
 	_ fs.NodeOpener         = (*RootNode)(nil)
 	_ fs.NodeCreater        = (*RootNode)(nil)
 	_ fs.NodeRemover        = (*RootNode)(nil)
+	_ fs.NodeRenamer        = (*RootNode)(nil)
 	_ fs.NodeFsyncer        = (*RootNode)(nil)
 	_ fs.NodeListxattrer    = (*RootNode)(nil)
 	_ fs.NodeGetxattrer     = (*RootNode)(nil)
@@ -85,7 +87,11 @@ func (n *RootNode) Lookup(ctx context.Context, name string) (node fs.Node, err e
 	case LagFilename:
 		node = newLagNode(n.fsys)
 	default:
-		if node, err = n.lookupDBNode(ctx, name); err != nil {
+		if dbName, _ := ParseFilename(name); !n.fsys.store.IsDBName(dbName) {
+			if node, err = n.lookupFileNode(ctx, name); err != nil {
+				return nil, err
+			}
+		} else if node, err = n.lookupDBNode(ctx, name); err != nil {
 			return nil, err
 		}
 	}
@@ -104,12 +110,25 @@ func (n *RootNode) lookupPrimaryNode(ctx context.Context) (fs.Node, error) {
 	return newPrimaryNode(n.fsys), nil
 }
 
+func (n *RootNode) lookupFileNode(ctx context.Context, name string) (fs.Node, error) {
+	node := newFileNode(n.fsys, name)
+	if _, err := os.Stat(node.Path()); os.IsNotExist(err) {
+		return nil, syscall.ENOENT
+	} else if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
 func (n *RootNode) lookupDBNode(ctx context.Context, name string) (fs.Node, error) {
 	dbName, fileType := ParseFilename(name)
 
-	db := n.fsys.store.DB(dbName)
-	if db == nil {
+	db, err := n.fsys.store.OpenDB(dbName)
+	if err == litefs.ErrDatabaseNotFound {
 		return nil, fuse.ToErrno(syscall.ENOENT)
+	} else if err != nil {
+		logger.Error("lookup(): cannot open database", slog.Any("err", err))
+		return nil, ToError(err)
 	}
 
 	switch fileType {
@@ -158,8 +177,15 @@ func (n *RootNode) Create(ctx context.Context, req *fuse.CreateRequest, resp *fu
 	resp.Flags |= fuse.OpenKeepCache
 
 	dbName, fileType := ParseFilename(req.Name)
+	if !n.fsys.store.IsDBName(dbName) {
+		fileType = litefs.FileTypeNone
+	}
 
 	switch fileType {
+	case litefs.FileTypeNone:
+		if node, h, err = n.createFile(ctx, req, resp); err != nil {
+			return nil, nil, err
+		}
 	case litefs.FileTypeDatabase:
 		if node, h, err = n.createDatabase(ctx, dbName, req, resp); err != nil {
 			return nil, nil, err
@@ -186,15 +212,32 @@ func (n *RootNode) Create(ctx context.Context, req *fuse.CreateRequest, resp *fu
 	return node, h, nil
 }
 
+func (n *RootNode) createFile(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	if err := os.MkdirAll(n.fsys.store.FilesDir(), 0o777); err != nil {
+		return nil, nil, err
+	}
+
+	node := newFileNode(n.fsys, req.Name)
+	flags := req.Flags & (fuse.OpenAccessModeMask | fuse.OpenExclusive | fuse.OpenTruncate)
+	file, err := os.OpenFile(node.Path(), int(flags)|os.O_CREATE, req.Mode.Perm())
+	if err != nil {
+		return nil, nil, err
+	}
+	return node, newFileHandle(node, file, req.Flags&fuse.OpenAppend != 0), nil
+}
+
 func (n *RootNode) createDatabase(ctx context.Context, dbName string, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
 	db, file, err := n.fsys.store.CreateDB(dbName)
 	if err == litefs.ErrDatabaseExists {
 		return nil, nil, fuse.Errno(syscall.EEXIST)
 	} else if err != nil {
-		log.Printf("fuse: create(): cannot create database: %s", err)
+		logger.Error("create(): cannot create database", slog.Any("err", err))
 		return nil, nil, ToError(err)
 	}
 
+	// Notify embedding application of the new database.
+	n.fsys.store.NotifyDBCreate(dbName)
+
 	node := newDatabaseNode(n.fsys, db)
 	return node, newDatabaseHandle(node, file), nil
 }
@@ -202,13 +245,13 @@ func (n *RootNode) createDatabase(ctx context.Context, dbName string, req *fuse.
 func (n *RootNode) createJournal(ctx context.Context, dbName string, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
 	db := n.fsys.store.DB(dbName)
 	if db == nil {
-		log.Printf("fuse: create(): cannot create journal, database not found: %s", dbName)
+		logger.Warn("create(): cannot create journal, database not found", slog.String("db", dbName))
 		return nil, nil, fuse.Errno(syscall.ENOENT)
 	}
 
 	file, err := db.CreateJournal()
 	if err != nil {
-		log.Printf("fuse: create(): cannot create journal: %s", err)
+		logger.Error("create(): cannot create journal", slog.String("db", dbName), slog.Any("err", err))
 		return nil, nil, ToError(err)
 	}
 
@@ -219,13 +262,13 @@ func (n *RootNode) createJournal(ctx context.Context, dbName string, req *fuse.C
 func (n *RootNode) createWAL(ctx context.Context, dbName string, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
 	db := n.fsys.store.DB(dbName)
 	if db == nil {
-		log.Printf("fuse: create(): cannot create wal, database not found: %s", dbName)
+		logger.Warn("create(): cannot create wal, database not found", slog.String("db", dbName))
 		return nil, nil, fuse.Errno(syscall.ENOENT)
 	}
 
 	file, err := db.CreateWAL()
 	if err != nil {
-		log.Printf("fuse: create(): cannot create wal: %s", err)
+		logger.Error("create(): cannot create wal", slog.String("db", dbName), slog.Any("err", err))
 		return nil, nil, ToError(err)
 	}
 
@@ -236,13 +279,13 @@ func (n *RootNode) createWAL(ctx context.Context, dbName string, req *fuse.Creat
 func (n *RootNode) createSHM(ctx context.Context, dbName string, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
 	db := n.fsys.store.DB(dbName)
 	if db == nil {
-		log.Printf("fuse: create(): cannot create shm, database not found: %s", dbName)
+		logger.Warn("create(): cannot create shm, database not found", slog.String("db", dbName))
 		return nil, nil, fuse.Errno(syscall.ENOENT)
 	}
 
 	file, err := db.CreateSHM()
 	if err != nil {
-		log.Printf("fuse: create(): cannot create shm: %s", err)
+		logger.Error("create(): cannot create shm", slog.String("db", dbName), slog.Any("err", err))
 		return nil, nil, ToError(err)
 	}
 
@@ -265,19 +308,27 @@ func (n *RootNode) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.O
 func (n *RootNode) Remove(ctx context.Context, req *fuse.RemoveRequest) (err error) {
 	dbName, fileType := ParseFilename(req.Name)
 
+	// Regular files are removed directly from the data directory.
+	if !n.fsys.store.IsDBName(dbName) {
+		if err := os.Remove(newFileNode(n.fsys, req.Name).Path()); os.IsNotExist(err) {
+			return syscall.ENOENT
+		} else if err != nil {
+			return err
+		}
+		return nil
+	}
+
 	if fileType == litefs.FileTypeDatabase {
 		// Only allow deletion from the primary itself.
 		if !n.fsys.store.IsPrimary() {
 			return ToError(litefs.ErrReadOnlyReplica)
 		}
 
-		db := n.fsys.store.DB(dbName)
-		if db == nil {
-			return syscall.ENOENT
-		}
-		if err := db.Drop(ctx); err != nil {
-			return err
+		if err := retryDBBusy(ctx, func() error { return n.fsys.store.DropDB(ctx, dbName) }); err != nil {
+			return ToError(err)
 		}
+		n.fsys.store.NotifyDBDelete(dbName)
+		n.forgetDBNodes(dbName)
 
 		// Notify the file system that the associated files have been deleted.
 		// We have to put this in a goroutine otherwise it locks the system.
@@ -297,7 +348,7 @@ func (n *RootNode) Remove(ctx context.Context, req *fuse.RemoveRequest) (err err
 	switch fileType {
 	case litefs.FileTypeJournal:
 		if err := db.RemoveJournal(ctx); err != nil {
-			log.Printf("fuse: commit error: %s", err)
+			logger.Error("commit error", slog.Any("err", err))
 			return err
 		}
 		return nil
@@ -313,6 +364,99 @@ func (n *RootNode) Remove(ctx context.Context, req *fuse.RemoveRequest) (err err
 	}
 }
 
+// Rename moves a file within the root directory. Databases are renamed by the
+// store so that the rename is replicated. Only the database file itself can be
+// renamed; its journal, WAL, and SHM files move with it.
+func (n *RootNode) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	if newDir != n {
+		return fuse.ToErrno(syscall.EXDEV)
+	}
+
+	oldDBName, oldFileType := ParseFilename(req.OldName)
+	newDBName, newFileType := ParseFilename(req.NewName)
+	oldIsDB, newIsDB := n.fsys.store.IsDBName(oldDBName), n.fsys.store.IsDBName(newDBName)
+
+	// Regular files are renamed directly in the data directory.
+	if !oldIsDB && !newIsDB {
+		if err := os.Rename(newFileNode(n.fsys, req.OldName).Path(), newFileNode(n.fsys, req.NewName).Path()); err != nil {
+			return ToError(err)
+		}
+		n.ForgetNodeByName(req.OldName)
+		n.ForgetNodeByName(req.NewName)
+		return nil
+	}
+
+	// Files cannot be moved between regular files & databases.
+	if oldIsDB != newIsDB {
+		return fuse.ToErrno(syscall.EXDEV)
+	} else if oldFileType != litefs.FileTypeDatabase || newFileType != litefs.FileTypeDatabase {
+		return fuse.ToErrno(syscall.EPERM)
+	}
+
+	if err := retryDBBusy(ctx, func() error { return n.fsys.store.RenameDB(ctx, oldDBName, newDBName) }); err != nil {
+		logger.Error("rename(): cannot rename database", slog.Any("err", err))
+		return ToError(err)
+	}
+	n.fsys.store.NotifyDBRename(oldDBName, newDBName)
+	n.forgetDBNodes(oldDBName)
+	n.forgetDBNodes(newDBName)
+
+	// The kernel moves the existing node to the new name but that node still
+	// references the database under its old name. Invalidate the entries so
+	// the next lookup returns new nodes. This blocks on the directory lock
+	// until the rename completes so it must run in a goroutine.
+	go func() {
+		for _, dbName := range []string{oldDBName, newDBName} {
+			for _, suffix := range dbFileSuffixes {
+				_ = n.fsys.server.InvalidateEntry(n, dbName+suffix)
+			}
+		}
+	}()
+	return nil
+}
+
+// DBBusyRetryTimeout is the time a drop or rename waits for a database to no
+// longer be in use before returning EBUSY. The kernel releases file handles
+// asynchronously so a database may still be open right after close().
+const DBBusyRetryTimeout = 100 * time.Millisecond
+
+// retryDBBusy executes fn until it no longer returns ErrDatabaseBusy or until
+// DBBusyRetryTimeout elapses.
+func retryDBBusy(ctx context.Context, fn func() error) error {
+	timer := time.NewTimer(DBBusyRetryTimeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(DBBusyRetryTimeout / 20)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(); err != litefs.ErrDatabaseBusy {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return litefs.ErrDatabaseBusy
+		case <-timer.C:
+			return litefs.ErrDatabaseBusy
+		case <-ticker.C:
+		}
+	}
+}
+
+// dbFileSuffixes are the suffixes of the files associated with a database.
+var dbFileSuffixes = []string{"", "-journal", "-wal", "-shm", "-pos", "-lock"}
+
+// forgetDBNodes removes the cached nodes for all files of a database after
+// it is dropped or renamed.
+func (n *RootNode) forgetDBNodes(dbName string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, suffix := range dbFileSuffixes {
+		delete(n.nodes, dbName+suffix)
+	}
+}
+
 // ForgetNode removes the node from the node map.
 func (n *RootNode) ForgetNode(node fs.Node) {
 	n.mu.Lock()
@@ -384,6 +528,30 @@ func (h *RootHandle) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
 		})
 	}
 
+	// Return a list of regular files that are not treated as databases.
+	fis, err := os.ReadDir(h.node.fsys.store.FilesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, fi := range fis {
+		ents = append(ents, fuse.Dirent{
+			Name: fi.Name(),
+			Type: fuse.DT_File,
+		})
+	}
+
+	// Return a list of databases that have not been lazily opened yet.
+	names, err := h.node.fsys.store.UnopenedDBNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		ents = append(ents, fuse.Dirent{
+			Name: name,
+			Type: fuse.DT_File,
+		})
+	}
+
 	// Return a list of database files.
 	dbs := h.node.fsys.store.DBs()
 	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name() < dbs[j].Name() })
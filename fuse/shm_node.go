@@ -3,13 +3,13 @@ package fuse
 import (
 	"context"
 	"io"
-	"log"
 	"os"
 	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 var (
@@ -118,6 +118,7 @@ type SHMHandle struct {
 }
 
 func newSHMHandle(node *SHMNode, file *os.File) *SHMHandle {
+	node.db.AddHandle()
 	return &SHMHandle{node: node, file: file}
 }
 
@@ -134,7 +135,7 @@ func (h *SHMHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fus
 	n, err := h.node.db.WriteSHMAt(ctx, h.file, req.Data, req.Offset, uint64(req.LockOwner))
 	resp.Size = n
 	if err != nil {
-		log.Printf("fuse: write(): shm error: %s", err)
+		logger.Error("write(): shm error", slog.String("db", h.node.db.Name()), slog.Any("err", err))
 		return err
 	}
 	return nil
@@ -146,6 +147,7 @@ func (h *SHMHandle) Flush(ctx context.Context, req *fuse.FlushRequest) error {
 }
 
 func (h *SHMHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	defer h.node.db.RemoveHandle()
 	return h.node.db.CloseSHM(ctx, h.file, uint64(req.LockOwner))
 }
 
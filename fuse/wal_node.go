@@ -3,13 +3,13 @@ package fuse
 import (
 	"context"
 	"io"
-	"log"
 	"os"
 	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
 )
 
 var _ fs.Node = (*WALNode)(nil)
@@ -111,6 +111,7 @@ type WALHandle struct {
 }
 
 func newWALHandle(node *WALNode, file *os.File) *WALHandle {
+	node.db.AddHandle()
 	return &WALHandle{node: node, file: file}
 }
 
@@ -126,7 +127,7 @@ func (h *WALHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.
 func (h *WALHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
 	// TODO(wal): Generate SQLITE_READONLY for WAL.
 	if err := h.node.db.WriteWALAt(ctx, h.file, req.Data, req.Offset, uint64(req.LockOwner)); err != nil {
-		log.Printf("fuse: write(): wal error: %s", err)
+		logger.Error("write(): wal error", slog.String("db", h.node.db.Name()), slog.Any("err", err))
 		return ToError(err)
 	}
 	resp.Size = len(req.Data)
@@ -134,5 +135,6 @@ func (h *WALHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fus
 }
 
 func (h *WALHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	defer h.node.db.RemoveHandle()
 	return h.node.db.CloseWAL(ctx, h.file, uint64(req.LockOwner))
 }
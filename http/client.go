@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pierrec/lz4/v4"
 	"github.com/superfly/litefs"
 	"github.com/superfly/litefs/internal/chunk"
 	"github.com/superfly/ltx"
@@ -26,20 +27,60 @@ var _ litefs.Client = (*Client)(nil)
 type Client struct {
 	// Underlying HTTP client
 	HTTPClient *http.Client
+
+	// Bearer token sent with every request, if set.
+	AuthToken string
+
+	// If set, connections are made over TLS instead of h2c. Certificates are
+	// presented to the server for mutual TLS, if required.
+	TLSConfig *tls.Config
+
+	// Encoding requested for the replication stream, if any. The primary may
+	// ignore the request if it does not support the encoding.
+	StreamEncoding string
+
+	// If non-zero, the primary is asked to send a lazy snapshot instead of a
+	// full snapshot for databases of at least this many bytes that do not
+	// exist locally. Pages are then fetched from the primary on demand.
+	LazySnapshotMinSize int64
 }
 
 // NewClient returns an instance of Client.
 func NewClient() *Client {
-	return &Client{
-		HTTPClient: &http.Client{
-			Transport: &http2.Transport{
-				AllowHTTP: true,
-				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-					return net.Dial(network, addr) // h2c-only right now
-				},
-			},
+	c := &Client{}
+	c.HTTPClient = &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS:   c.dial,
 		},
 	}
+	return c
+}
+
+// dial connects to addr over h2c, or over TLS if a TLS config is set.
+func (c *Client) dial(network, addr string, _ *tls.Config) (net.Conn, error) {
+	if c.TLSConfig == nil {
+		return net.Dial(network, addr)
+	}
+
+	cfg := c.TLSConfig.Clone()
+	cfg.NextProtos = []string{http2.NextProtoTLS}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ServerName = host
+	}
+	return tls.Dial(network, addr, cfg)
+}
+
+// do sets the authorization header, if required, and executes the request.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+	return c.HTTPClient.Do(req)
 }
 
 // Promote attempts to promote the current node to be the primary.
@@ -60,7 +101,7 @@ func (c *Client) Promote(ctx context.Context, baseURL string) error {
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -74,6 +115,117 @@ func (c *Client) Promote(ctx context.Context, baseURL string) error {
 	return nil
 }
 
+// Resync requests that a replica discards its copy of a database and
+// resyncs it from a snapshot of the primary.
+func (c *Client) Resync(ctx context.Context, baseURL, name string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid client URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return fmt.Errorf("URL host required")
+	}
+	*u = url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/resync"}
+	u.RawQuery = (url.Values{"name": {name}}).Encode()
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return litefs.ErrDatabaseNotFound
+	default:
+		return fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+}
+
+// PauseReplication requests that a replica stops applying changes from the
+// primary until ResumeReplication is called.
+func (c *Client) PauseReplication(ctx context.Context, baseURL string) error {
+	return c.doPause(ctx, "POST", baseURL)
+}
+
+// ResumeReplication requests that a paused replica resumes replication.
+func (c *Client) ResumeReplication(ctx context.Context, baseURL string) error {
+	return c.doPause(ctx, "DELETE", baseURL)
+}
+
+func (c *Client) doPause(ctx context.Context, method, baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid client URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return fmt.Errorf("URL host required")
+	}
+	*u = url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/pause"}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusConflict {
+		if body, _ := io.ReadAll(resp.Body); strings.TrimSpace(string(body)) == litefs.ErrPauseSyncReplica.Error() {
+			return litefs.ErrPauseSyncReplica
+		}
+		return litefs.ErrPausePrimary
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Reload requests that a node reloads its configuration file.
+func (c *Client) Reload(ctx context.Context, baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid client URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return fmt.Errorf("URL host required")
+	}
+	*u = url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/reload"}
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+	return nil
+}
+
 // Handoff requests that the current primary handoff leadership to a specific node.
 func (c *Client) Handoff(ctx context.Context, primaryURL string, nodeID uint64) error {
 	u, err := url.Parse(primaryURL)
@@ -93,7 +245,7 @@ func (c *Client) Handoff(ctx context.Context, primaryURL string, nodeID uint64)
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -134,7 +286,7 @@ func (c *Client) Import(ctx context.Context, primaryURL, name string, r io.Reade
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -174,7 +326,7 @@ func (c *Client) Export(ctx context.Context, primaryURL, name string) (io.ReadCl
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -209,7 +361,7 @@ func (c *Client) Info(ctx context.Context, baseURL string) (info litefs.NodeInfo
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return info, err
 	}
@@ -226,6 +378,46 @@ func (c *Client) Info(ctx context.Context, baseURL string) (info litefs.NodeInfo
 	return info, nil
 }
 
+// Pos returns the replication position of each database on the node.
+func (c *Client) Pos(ctx context.Context, baseURL string) (map[string]ltx.Pos, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return nil, fmt.Errorf("URL host required")
+	}
+	*u = url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/pos"}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+
+	var m map[string]posJSON
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode body: %w", err)
+	}
+
+	posMap := make(map[string]ltx.Pos, len(m))
+	for name, pos := range m {
+		posMap[name] = ltx.Pos{TXID: pos.TXID, PostApplyChecksum: pos.PostApplyChecksum}
+	}
+	return posMap, nil
+}
+
 func (c *Client) AcquireHaltLock(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) (_ *litefs.HaltLock, retErr error) {
 	u, err := url.Parse(primaryURL)
 	if err != nil {
@@ -254,7 +446,7 @@ func (c *Client) AcquireHaltLock(ctx context.Context, primaryURL string, nodeID
 	req = req.WithContext(ctx)
 	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -300,7 +492,7 @@ func (c *Client) ReleaseHaltLock(ctx context.Context, primaryURL string, nodeID
 	req = req.WithContext(ctx)
 	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -341,7 +533,7 @@ func (c *Client) Commit(ctx context.Context, primaryURL string, nodeID uint64, n
 	req = req.WithContext(ctx)
 	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -354,8 +546,108 @@ func (c *Client) Commit(ctx context.Context, primaryURL string, nodeID uint64, n
 	return nil
 }
 
+// Ack notifies the primary that the replica has applied a database up to txID.
+func (c *Client) Ack(ctx context.Context, primaryURL string, nodeID uint64, name string, txID ltx.TXID) error {
+	u, err := url.Parse(primaryURL)
+	if err != nil {
+		return fmt.Errorf("invalid primary URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return fmt.Errorf("URL host required")
+	}
+
+	// Strip off everything but the scheme & host.
+	*u = url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   "/ack",
+		RawQuery: (url.Values{
+			"name": []string{name},
+			"txid": []string{txID.String()},
+		}).Encode(),
+	}
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FetchPage returns the current contents of a single database page from the
+// primary & the TXID it was read at.
+func (c *Client) FetchPage(ctx context.Context, primaryURL string, nodeID uint64, name string, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error) {
+	u, err := url.Parse(primaryURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid primary URL: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, 0, fmt.Errorf("invalid URL scheme")
+	} else if u.Host == "" {
+		return nil, 0, fmt.Errorf("URL host required")
+	}
+
+	// Strip off everything but the scheme & host.
+	*u = url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   "/page",
+		RawQuery: (url.Values{
+			"name": []string{name},
+			"pgno": []string{strconv.FormatUint(uint64(pgno), 10)},
+			"txid": []string{txID.String()},
+		}).Encode(),
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, 0, litefs.ErrDatabaseNotFound
+	case http.StatusConflict:
+		return nil, 0, litefs.ErrPositionMismatch
+	default:
+		return nil, 0, fmt.Errorf("invalid response: code=%d", resp.StatusCode)
+	}
+
+	pageTXID, err := ltx.ParseTXID(resp.Header.Get(HeaderTXID))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid txid header: %w", err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, pageTXID, nil
+}
+
 // Stream returns a snapshot and continuous stream of WAL updates.
-func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (litefs.Stream, error) {
+func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
 	u, err := url.Parse(primaryURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid client URL: %w", err)
@@ -369,6 +661,12 @@ func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, p
 	if len(filter) > 0 {
 		q.Set("filter", strings.Join(filter, ","))
 	}
+	for _, partial := range partials {
+		q.Add("resume", partial.String())
+	}
+	if c.LazySnapshotMinSize > 0 {
+		q.Set("lazy", strconv.FormatInt(c.LazySnapshotMinSize, 10))
+	}
 
 	// Strip off everything but the scheme & host.
 	*u = url.URL{
@@ -390,8 +688,12 @@ func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, p
 	req = req.WithContext(ctx)
 
 	req.Header.Set(HeaderNodeID, litefs.FormatNodeID(nodeID))
+	req.Header.Set(HeaderFeatures, StreamFeatureRenameDB)
+	if c.StreamEncoding != "" {
+		req.Header.Set("Accept-Encoding", c.StreamEncoding)
+	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
@@ -399,10 +701,47 @@ func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, p
 		return nil, fmt.Errorf("invalid response: code=%d", resp.StatusCode)
 	}
 
-	return &Stream{
+	// Older primaries only match exact names so patterns would silently
+	// replicate nothing instead of the intended databases.
+	if hasFilterPatterns(filter) && !hasFeature(resp.Header, StreamFeatureFilterPatterns) {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("primary does not support database filter patterns, upgrade the primary or use exact names: %s", strings.Join(filter, ","))
+	}
+
+	stream := &Stream{
 		ReadCloser: resp.Body,
 		clusterID:  resp.Header.Get(HeaderClusterID),
-	}, nil
+	}
+	if v := resp.Header.Get(HeaderEpoch); v != "" {
+		if stream.epoch, err = strconv.ParseUint(v, 10, 64); err != nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("invalid epoch header: %q", v)
+		}
+	}
+
+	switch encoding := resp.Header.Get("Content-Encoding"); encoding {
+	case "":
+	case StreamEncodingLZ4:
+		stream.ReadCloser = struct {
+			io.Reader
+			io.Closer
+		}{lz4.NewReader(resp.Body), resp.Body}
+	default:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unsupported stream encoding: %q", encoding)
+	}
+
+	return stream, nil
+}
+
+// hasFilterPatterns returns true if filter contains glob or exclude patterns.
+func hasFilterPatterns(filter []string) bool {
+	for _, pattern := range filter {
+		if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, `*?[\`) {
+			return true
+		}
+	}
+	return false
 }
 
 var _ litefs.Stream = (*Stream)(nil)
@@ -412,11 +751,15 @@ type Stream struct {
 	io.ReadCloser
 
 	clusterID string
+	epoch     uint64
 }
 
 // ClusterID returns the cluster ID found in the response header.
 func (s *Stream) ClusterID() string { return s.clusterID }
 
+// Epoch returns the primary's lease epoch found in the response header.
+func (s *Stream) Epoch() uint64 { return s.epoch }
+
 // RemoteTx represents a remote transaction created by Client.Begin().
 type RemoteTx struct {
 	id               uint64
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"regexp"
@@ -14,6 +13,7 @@ import (
 
 	"github.com/superfly/litefs"
 	"github.com/superfly/ltx"
+	"golang.org/x/exp/slog"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -325,7 +325,7 @@ func (s *ProxyServer) proxyToTarget(w http.ResponseWriter, r *http.Request, pass
 	// Set response code and copy the body.
 	w.WriteHeader(resp.StatusCode)
 	if err := copyAndFlush(w, resp.Body); err != nil {
-		log.Printf("http: proxy response error: %s", err)
+		logger.Warn("proxy response error", slog.Any("err", err))
 		return
 	}
 }
@@ -379,7 +379,7 @@ func (s *ProxyServer) isAlwaysForwarded(r *http.Request) bool {
 // logf logs if debug logging is enabled.
 func (s *ProxyServer) logf(format string, v ...any) {
 	if s.Debug {
-		log.Printf(format, v...)
+		logger.Info(fmt.Sprintf(format, v...))
 	}
 }
 
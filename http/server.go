@@ -2,11 +2,13 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"expvar"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"net"
 	"net/http"
@@ -15,8 +17,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pierrec/lz4/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -24,6 +28,7 @@ import (
 	"github.com/superfly/litefs/internal"
 	"github.com/superfly/litefs/internal/chunk"
 	"github.com/superfly/ltx"
+	"golang.org/x/exp/slog"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"golang.org/x/sync/errgroup"
@@ -32,12 +37,36 @@ import (
 // Default settings
 const (
 	DefaultAddr = ":20202"
+
+	// Time to wait for a database to reach a TXID when requesting its position.
+	DefaultWaitTimeout = 5 * time.Second
+
+	// Time to wait for the lease backend when checking readiness.
+	DefaultReadinessLeaseTimeout = 2 * time.Second
 )
 
 // HTTP headers
 const (
 	HeaderNodeID    = "Litefs-Id"
 	HeaderClusterID = "Litefs-Cluster-Id"
+	HeaderEpoch     = "Litefs-Epoch"
+	HeaderTXID      = "Litefs-Txid"
+	HeaderFeatures  = "Litefs-Features"
+)
+
+// Stream features advertised via the Litefs-Features header so that newer
+// frames are only sent to nodes that understand them.
+const (
+	// The replica applies RenameDBStreamFrame.
+	StreamFeatureRenameDB = "rename-db"
+
+	// The primary matches glob & "!" exclude patterns in database filters.
+	StreamFeatureFilterPatterns = "filter-patterns"
+)
+
+// Stream encodings negotiated via the Accept-Encoding header.
+const (
+	StreamEncodingLZ4 = "lz4"
 )
 
 const (
@@ -46,6 +75,9 @@ const (
 
 var ErrServerClosed = fmt.Errorf("canceled, http server closed")
 
+// logger is the logger for the http subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemHTTP)
+
 // Server represents an HTTP API server for LiteFS.
 type Server struct {
 	ln net.Listener
@@ -53,6 +85,7 @@ type Server struct {
 	httpServer  *http.Server
 	http2Server *http2.Server
 	promHandler http.Handler
+	metrics     *serverMetrics
 
 	addr  string
 	store *litefs.Store
@@ -64,16 +97,61 @@ type Server struct {
 	// Time allowed to write a single LTX snapshot in the stream.
 	// This is meant to prevent slow snapshot downloads backing up the primary.
 	SnapshotTimeout time.Duration
+
+	// Maximum number of bytes per second sent across all replication
+	// streams. Unlimited if zero.
+	StreamRateLimit int64
+
+	rateLimiterOnce sync.Once
+	rateLimiter     *internal.RateLimiter
+
+	// If set, requests must include this value as a bearer token.
+	// Metrics are still served without authorization.
+	AuthToken string
+
+	// If set, the server only accepts TLS connections. Set ClientAuth on the
+	// config to require client certificates for mutual TLS.
+	TLSConfig *tls.Config
+
+	// Client used to connect to other nodes during promotion.
+	Client *Client
+
+	// Conditions that must hold for GET /readyz to report the node as ready.
+	Readiness ReadinessCriteria
+
+	// Reloads the node's configuration when POST /reload is requested.
+	// The endpoint returns an error if this is not set.
+	ReloadFunc func(ctx context.Context) error
+}
+
+// ReadinessCriteria represents the conditions checked by GET /readyz in
+// addition to the store having found or become the primary.
+type ReadinessCriteria struct {
+	// If set, replicas are not ready while lagging further than this
+	// behind the primary.
+	MaxLag time.Duration
+
+	// If true, replicas are not ready unless they are currently connected
+	// to the primary's replication stream.
+	RequireConnected bool
+
+	// If true, the node is not ready unless the lease backend responds
+	// within LeaseTimeout.
+	RequireLease bool
+	LeaseTimeout time.Duration
 }
 
 func NewServer(store *litefs.Store, addr string) *Server {
 	s := &Server{
-		addr:  addr,
-		store: store,
+		addr:   addr,
+		store:  store,
+		Client: NewClient(),
 	}
 	s.ctx, s.cancel = context.WithCancelCause(context.Background())
 
-	s.promHandler = promhttp.Handler()
+	// Serve the store's metrics along with the process-wide default collectors.
+	s.metrics = newServerMetrics(store.Registry())
+	s.promHandler = promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, store.Registry()}, promhttp.HandlerOpts{})
 	s.http2Server = &http2.Server{}
 	s.httpServer = &http.Server{
 		Handler: h2c.NewHandler(http.HandlerFunc(s.serveHTTP), s.http2Server),
@@ -88,6 +166,19 @@ func (s *Server) Listen() (err error) {
 	if s.ln, err = net.Listen("tcp", s.addr); err != nil {
 		return err
 	}
+
+	// Serve HTTP/2 over TLS instead of h2c, if enabled.
+	if s.TLSConfig != nil {
+		if err := http2.ConfigureServer(s.httpServer, s.http2Server); err != nil {
+			_ = s.ln.Close()
+			return fmt.Errorf("configure http2 server: %w", err)
+		}
+
+		cfg := s.TLSConfig.Clone()
+		cfg.NextProtos = []string{http2.NextProtoTLS}
+		s.ln = tls.NewListener(s.ln, cfg)
+	}
+
 	return nil
 }
 
@@ -128,10 +219,40 @@ func (s *Server) URL() string {
 	if host == "" {
 		host = "localhost"
 	}
-	return fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprint(s.Port())))
+	scheme := "http"
+	if s.TLSConfig != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, fmt.Sprint(s.Port())))
+}
+
+// authorized returns true if the request contains the server's auth token.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.AuthToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.AuthToken)) == 1
 }
 
 func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/metrics":
+		s.promHandler.ServeHTTP(w, r)
+		return
+	case "/healthz":
+		s.handleGetHealthz(w, r)
+		return
+	case "/readyz":
+		s.handleGetReadyz(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		Error(w, r, fmt.Errorf("unauthorized"), http.StatusUnauthorized)
+		return
+	}
+
 	if strings.HasPrefix(r.URL.Path, "/debug/pprof") {
 		switch r.URL.Path {
 		case "/debug/pprof/cmdline":
@@ -155,9 +276,6 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	case "/debug/rand":
 		s.handleDebugRand(w, r)
 		return
-	case "/metrics":
-		s.promHandler.ServeHTTP(w, r)
-		return
 	}
 
 	// Inject standard headers.
@@ -167,6 +285,14 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	switch r.URL.Path {
+	case "/ack":
+		switch r.Method {
+		case http.MethodPost:
+			s.handlePostAck(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
 	case "/export":
 		switch r.Method {
 		case http.MethodGet:
@@ -209,6 +335,32 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
 		}
 
+	case "/page":
+		switch r.Method {
+		case http.MethodGet:
+			s.handleGetPage(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
+	case "/pause":
+		switch r.Method {
+		case http.MethodPost:
+			s.handlePostPause(w, r)
+		case http.MethodDelete:
+			s.handleDeletePause(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
+	case "/pos":
+		switch r.Method {
+		case http.MethodGet:
+			s.handleGetPos(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
 	case "/promote":
 		switch r.Method {
 		case http.MethodPost:
@@ -217,6 +369,22 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
 		}
 
+	case "/reload":
+		switch r.Method {
+		case http.MethodPost:
+			s.handlePostReload(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
+	case "/resync":
+		switch r.Method {
+		case http.MethodPost:
+			s.handlePostResync(w, r)
+		default:
+			Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		}
+
 	case "/stream":
 		switch r.Method {
 		case http.MethodPost:
@@ -247,8 +415,8 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleDebugRand(w http.ResponseWriter, r *http.Request) {
-	log.Printf("/debug/rand: connected")
-	defer log.Printf("/debug/rand: disconnected")
+	s.logger().Info("/debug/rand: connected")
+	defer s.logger().Info("/debug/rand: disconnected")
 
 	ctx, cancel := context.WithTimeout(r.Context(), 1*time.Minute)
 	defer cancel()
@@ -270,15 +438,32 @@ func (s *Server) handleDebugRand(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetInfo(w http.ResponseWriter, r *http.Request) {
 	var info litefs.NodeInfo
+	info.ID = litefs.FormatNodeID(s.store.ID())
 	info.ClusterID = s.store.ClusterID()
 	info.IsPrimary = s.store.IsPrimary()
 	info.Candidate = s.store.Candidate()
 	info.Path = s.store.Path()
+	info.Lag = s.store.Lag().Milliseconds()
+	info.Paused = s.store.ReplicationPaused()
+	if s.store.Leaser != nil {
+		info.Hostname = s.store.Leaser.Hostname()
+	}
 
 	if isPrimary, primaryInfo := s.store.PrimaryInfo(); isPrimary {
 		info.Primary.Hostname = s.store.Leaser.Hostname()
+		info.Primary.AdvertiseURL = s.store.Leaser.AdvertiseURL()
 	} else if primaryInfo != nil {
 		info.Primary.Hostname = primaryInfo.Hostname
+		info.Primary.AdvertiseURL = primaryInfo.AdvertiseURL
+	}
+
+	if info.Lease = s.store.LeaseInfo(); info.Lease != nil {
+		for _, sub := range s.store.ChangeSetSubscribers() {
+			info.Replicas = append(info.Replicas, litefs.ReplicaInfo{
+				ID:    litefs.FormatNodeID(sub.NodeID()),
+				TXIDs: sub.TXIDs(),
+			})
+		}
 	}
 
 	if buf, err := json.MarshalIndent(info, "", "  "); err != nil {
@@ -291,6 +476,127 @@ func (s *Server) handleGetInfo(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("\n"))
 }
 
+// handleGetHealthz reports that the server is running. It does not check the
+// state of the store so that orchestrators do not restart a node that is
+// still catching up.
+func (s *Server) handleGetHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// handleGetReadyz reports whether the node should receive traffic based on
+// the server's readiness criteria. Failures are not logged as probes are
+// expected to fail while a node starts up.
+func (s *Server) handleGetReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		Error(w, r, fmt.Errorf("method not allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.checkReadiness(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// checkReadiness returns an error describing the first readiness criterion
+// which the node does not meet.
+func (s *Server) checkReadiness(ctx context.Context) error {
+	if !s.store.IsReady() {
+		return fmt.Errorf("not ready: primary not found")
+	}
+
+	if !s.store.IsPrimary() {
+		if s.Readiness.RequireConnected && !s.store.IsConnected() {
+			return fmt.Errorf("not ready: not connected to primary")
+		}
+		if maxLag := s.Readiness.MaxLag; maxLag > 0 {
+			if lag := s.store.Lag(); lag > maxLag {
+				return fmt.Errorf("not ready: replication lag of %s exceeds %s", lag.Truncate(time.Millisecond), maxLag)
+			}
+		}
+	}
+
+	if s.Readiness.RequireLease && s.store.Leaser != nil {
+		if timeout := s.Readiness.LeaseTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if _, err := s.store.Leaser.PrimaryInfo(ctx); err != nil && err != litefs.ErrNoPrimary {
+			return fmt.Errorf("not ready: lease unreachable: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) handleGetPos(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := q.Get("name")
+
+	// If a TXID is specified, wait for the database to reach it before returning.
+	if v := q.Get("txid"); v != "" {
+		txID, err := ltx.ParseTXID(v)
+		if err != nil {
+			Error(w, r, fmt.Errorf("invalid txid: %q", v), http.StatusBadRequest)
+			return
+		} else if name == "" {
+			Error(w, r, fmt.Errorf("name required"), http.StatusBadRequest)
+			return
+		}
+
+		timeout := DefaultWaitTimeout
+		if v := q.Get("timeout"); v != "" {
+			if timeout, err = time.ParseDuration(v); err != nil {
+				Error(w, r, fmt.Errorf("invalid timeout: %q", v), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		if err := s.store.WaitForTX(ctx, name, txID); err != nil {
+			Error(w, r, fmt.Errorf("wait for txid: %w", err), http.StatusGatewayTimeout)
+			return
+		}
+	}
+
+	// Return all positions or only the requested database.
+	posMap := s.store.PosMap()
+	if name != "" {
+		pos, ok := posMap[name]
+		if !ok {
+			Error(w, r, litefs.ErrDatabaseNotFound, http.StatusNotFound)
+			return
+		}
+		posMap = map[string]ltx.Pos{name: pos}
+	}
+
+	resp := make(map[string]posJSON, len(posMap))
+	for name, pos := range posMap {
+		resp[name] = posJSON{TXID: pos.TXID, PostApplyChecksum: pos.PostApplyChecksum}
+	}
+
+	if buf, err := json.MarshalIndent(resp, "", "  "); err != nil {
+		Error(w, r, err, http.StatusInternalServerError)
+		return
+	} else if _, err := w.Write(buf); err != nil {
+		Error(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	_, _ = w.Write([]byte("\n"))
+}
+
+// posJSON is the JSON representation of a database position.
+type posJSON struct {
+	TXID              ltx.TXID     `json:"txID"`
+	PostApplyChecksum ltx.Checksum `json:"postApplyChecksum"`
+}
+
 func (s *Server) handlePostImport(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
@@ -342,7 +648,43 @@ func (s *Server) handleGetExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("%s: snapshot successfully exported @ %s", litefs.FormatNodeID(s.store.ID()), pos.String())
+	s.logger().Info("snapshot successfully exported", slog.String("db", name), slog.String("txid", pos.TXID.String()))
+}
+
+// handleGetPage returns the current contents of a single database page.
+// Replicas bootstrapped from a lazy snapshot fetch missing pages from here.
+// The TXID the page was read at is returned in the Litefs-Txid header.
+func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pgno, err := strconv.ParseUint(q.Get("pgno"), 10, 32)
+	if err != nil || pgno == 0 {
+		Error(w, r, fmt.Errorf("invalid pgno: %q", q.Get("pgno")), http.StatusBadRequest)
+		return
+	}
+	txID, err := ltx.ParseTXID(q.Get("txid"))
+	if err != nil {
+		Error(w, r, fmt.Errorf("invalid txid: %q", q.Get("txid")), http.StatusBadRequest)
+		return
+	}
+
+	db := s.store.DB(q.Get("name"))
+	if db == nil {
+		Error(w, r, litefs.ErrDatabaseNotFound, http.StatusNotFound)
+		return
+	}
+
+	data, pageTXID, err := db.ReadPage(r.Context(), uint32(pgno), txID)
+	if errors.Is(err, litefs.ErrPositionMismatch) {
+		Error(w, r, err, http.StatusConflict)
+		return
+	} else if err != nil {
+		Error(w, r, fmt.Errorf("read page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(HeaderTXID, pageTXID.String())
+	_, _ = w.Write(data)
 }
 
 func (s *Server) handlePostHalt(w http.ResponseWriter, r *http.Request) {
@@ -406,6 +748,68 @@ func (s *Server) handleDeleteHalt(w http.ResponseWriter, r *http.Request) {
 	db.ReleaseHaltLock(r.Context(), lockID)
 }
 
+func (s *Server) handlePostAck(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	txID, err := ltx.ParseTXID(q.Get("txid"))
+	if err != nil {
+		Error(w, r, fmt.Errorf("invalid txid: %q", q.Get("txid")), http.StatusBadRequest)
+		return
+	}
+
+	id, err := litefs.ParseNodeID(r.Header.Get(HeaderNodeID))
+	if err != nil {
+		Error(w, r, fmt.Errorf("invalid node id"), http.StatusBadRequest)
+		return
+	}
+
+	s.store.Ack(id, q.Get("name"), txID)
+}
+
+func (s *Server) handlePostReload(w http.ResponseWriter, r *http.Request) {
+	if s.ReloadFunc == nil {
+		Error(w, r, fmt.Errorf("config reload not supported"), http.StatusNotImplemented)
+		return
+	}
+
+	if err := s.ReloadFunc(r.Context()); err != nil {
+		Error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handlePostResync(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		Error(w, r, fmt.Errorf("name required"), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.ResyncDB(name); err == litefs.ErrDatabaseNotFound {
+		Error(w, r, err, http.StatusNotFound)
+		return
+	} else if err != nil {
+		Error(w, r, err, http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlePostPause pauses replication on a replica.
+func (s *Server) handlePostPause(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.PauseReplication(); err != nil {
+		Error(w, r, err, http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDeletePause resumes replication on a paused replica.
+func (s *Server) handleDeletePause(w http.ResponseWriter, r *http.Request) {
+	s.store.ResumeReplication()
+	w.WriteHeader(http.StatusOK)
+}
+
 func (s *Server) handlePostPromote(w http.ResponseWriter, r *http.Request) {
 	// Return an error if current node is not eligible to become primary.
 	if !s.store.Candidate() {
@@ -416,7 +820,7 @@ func (s *Server) handlePostPromote(w http.ResponseWriter, r *http.Request) {
 	// Skip if the node is already the primary.
 	isPrimary, info := s.store.PrimaryInfo()
 	if isPrimary {
-		log.Printf("node is already primary, skipping promotion")
+		s.logger().Info("node is already primary, skipping promotion")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -432,13 +836,12 @@ func (s *Server) handlePostPromote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Request that the current primary hands off to this node.
-	log.Printf("requesting primary handoff from: %s", info.AdvertiseURL)
-	client := NewClient()
-	if err := client.Handoff(r.Context(), info.AdvertiseURL, s.store.ID()); err != nil {
+	s.logger().Info("requesting primary handoff", slog.String("url", info.AdvertiseURL))
+	if err := s.Client.Handoff(r.Context(), info.AdvertiseURL, s.store.ID()); err != nil {
 		Error(w, r, fmt.Errorf("handoff failed: %w", err), http.StatusInternalServerError)
 		return
 	}
-	log.Printf("primary handoff request successful")
+	s.logger().Info("primary handoff request successful")
 }
 
 func (s *Server) handlePostHandoff(w http.ResponseWriter, r *http.Request) {
@@ -513,11 +916,11 @@ func (s *Server) handlePostStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("%s: stream connected (%s)", litefs.FormatNodeID(s.store.ID()), r.RemoteAddr)
-	defer log.Printf("%s: stream disconnected (%s)", litefs.FormatNodeID(s.store.ID()), r.RemoteAddr)
+	s.logger().Info("stream connected", slog.String("replica", litefs.FormatNodeID(id)), slog.String("addr", r.RemoteAddr))
+	defer s.logger().Info("stream disconnected", slog.String("replica", litefs.FormatNodeID(id)), slog.String("addr", r.RemoteAddr))
 
-	serverStreamCountMetric.Inc()
-	defer serverStreamCountMetric.Dec()
+	s.metrics.serverStreamCountMetric.Inc()
+	defer s.metrics.serverStreamCountMetric.Dec()
 
 	// Subscribe to store changes
 	subscription := s.store.SubscribeChangeSet(id)
@@ -542,14 +945,68 @@ func (s *Server) handlePostStream(w http.ResponseWriter, r *http.Request) {
 		dirtySet[db.Name()] = struct{}{}
 	}
 
-	// Determine filtered set of databases, if any.
-	filterSet := make(map[string]struct{})
-	if filter := q.Get("filter"); filter != "" {
-		for _, name := range strings.Split(filter, ",") {
-			filterSet[name] = struct{}{}
+	// Determine database filter patterns, if any.
+	var filter []string
+	if v := q.Get("filter"); v != "" {
+		filter = strings.Split(v, ",")
+	}
+	if err := litefs.ValidateDatabaseFilter(filter); err != nil {
+		Error(w, r, err, http.StatusBadRequest)
+		return
+	}
+	subscription.SetDatabaseFilter(filter)
+
+	// Parse snapshots partially received by the replica on a previous stream.
+	partials := make(map[string]litefs.PartialSnapshot)
+	for _, v := range q["resume"] {
+		partial, err := litefs.ParsePartialSnapshot(v)
+		if err != nil {
+			Error(w, r, err, http.StatusBadRequest)
+			return
+		}
+		partials[partial.Name] = partial
+	}
+
+	// Older replicas cannot decode rename frames so they receive a drop of the
+	// old name & a snapshot of the new name instead.
+	renameSupported := hasFeature(r.Header, StreamFeatureRenameDB)
+
+	// Replicas may request lazy snapshots for databases of at least this size.
+	var lazyMinSize int64
+	if v := q.Get("lazy"); v != "" {
+		if lazyMinSize, err = strconv.ParseInt(v, 10, 64); err != nil || lazyMinSize < 0 {
+			Error(w, r, fmt.Errorf("invalid lazy snapshot size: %q", v), http.StatusBadRequest)
+			return
 		}
 	}
 
+	// Advertise features so replicas can detect an older primary.
+	w.Header().Set(HeaderFeatures, StreamFeatureFilterPatterns)
+
+	// Send the lease epoch so replicas can reject streams from a stale primary.
+	if info := s.store.LeaseInfo(); info != nil && info.Epoch != 0 {
+		w.Header().Set(HeaderEpoch, strconv.FormatUint(info.Epoch, 10))
+	}
+
+	// Limit the bandwidth used by replication, if enabled. This is applied
+	// beneath compression so that it limits the bytes sent over the network.
+	if limiter := s.streamRateLimiter(); limiter != nil {
+		w = newRateLimitedResponseWriter(r.Context(), w, limiter)
+	}
+
+	// Compress the stream if the client supports it. Older clients do not
+	// send an Accept-Encoding header and receive an uncompressed stream.
+	if acceptsEncoding(r, StreamEncodingLZ4) {
+		zw, err := newLZ4ResponseWriter(w)
+		if err != nil {
+			Error(w, r, fmt.Errorf("cannot create lz4 writer: %s", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Encoding", StreamEncodingLZ4)
+		defer func() { _ = zw.Close() }()
+		w = zw
+	}
+
 	// Flush header so client can resume control.
 	w.WriteHeader(http.StatusOK)
 	w.(http.Flusher).Flush()
@@ -561,17 +1018,47 @@ func (s *Server) handlePostStream(w http.ResponseWriter, r *http.Request) {
 		w.(http.Flusher).Flush()
 	}()
 
-	tkr := time.NewTicker(HeartbeatInterval)
+	// Send heartbeats at least as often as the store's replication heartbeat
+	// interval so replicas do not time out an idle stream.
+	heartbeatInterval := HeartbeatInterval
+	if v := s.store.ReplicationHeartbeatInterval; v > 0 {
+		heartbeatInterval = min(heartbeatInterval, v)
+	}
+
+	tkr := time.NewTicker(heartbeatInterval)
 	defer tkr.Stop()
 
 	// Continually iterate by writing dirty changes and then waiting for new changes.
 	var readySent bool
 	var handoffLeaseID string
 	for {
-		// Restrict dirty set to only databases in the filter set.
-		if len(filterSet) > 0 && len(dirtySet) > 0 {
+		// Send renames before any changes so the replica moves its local copy
+		// instead of receiving a snapshot under the new name. Renames that the
+		// replica cannot apply fall back to a drop & snapshot via the dirty set.
+		for _, rename := range subscription.Renames() {
+			if !renameSupported {
+				continue
+			} else if _, ok := posMap[rename.OldName]; !ok {
+				continue
+			} else if len(filter) > 0 && (!litefs.MatchDatabaseFilter(filter, rename.OldName) || !litefs.MatchDatabaseFilter(filter, rename.NewName)) {
+				continue
+			}
+
+			if err := litefs.WriteStreamFrame(w, &litefs.RenameDBStreamFrame{OldName: rename.OldName, NewName: rename.NewName}); err != nil {
+				Error(w, r, fmt.Errorf("stream error: write rename db frame: %s", err), http.StatusInternalServerError)
+				return
+			}
+			w.(http.Flusher).Flush()
+
+			posMap[rename.NewName] = posMap[rename.OldName]
+			delete(posMap, rename.OldName)
+			delete(dirtySet, rename.OldName)
+		}
+
+		// Restrict dirty set to only databases that pass the filter.
+		if len(filter) > 0 && len(dirtySet) > 0 {
 			for name := range dirtySet {
-				if _, ok := filterSet[name]; !ok {
+				if !litefs.MatchDatabaseFilter(filter, name) {
 					delete(dirtySet, name)
 				}
 			}
@@ -579,11 +1066,12 @@ func (s *Server) handlePostStream(w http.ResponseWriter, r *http.Request) {
 
 		// Send pending transactions for each database.
 		for name := range dirtySet {
-			if err := s.streamDB(r.Context(), w, name, posMap); err != nil {
+			if err := s.streamDB(r.Context(), w, name, posMap, partials, lazyMinSize); err != nil {
 				Error(w, r, fmt.Errorf("stream error: db=%q err=%s", name, err), http.StatusInternalServerError)
 				return
 			}
 		}
+		subscription.SetPosMap(posMap)
 
 		sendHeartbeat := !readySent || len(dirtySet) == 0
 
@@ -632,15 +1120,15 @@ func (s *Server) handlePostStream(w http.ResponseWriter, r *http.Request) {
 			dirtySet = nil
 		}
 
-		tkr.Reset(HeartbeatInterval)
+		tkr.Reset(heartbeatInterval)
 	}
 }
 
-func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name string, posMap map[string]ltx.Pos) error {
-	db := s.store.DB(name)
-
-	// If the replica has a database that doesn't exist on the primary, skip it.
-	if db == nil {
+func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name string, posMap map[string]ltx.Pos, partials map[string]litefs.PartialSnapshot, lazyMinSize int64) error {
+	// If the replica has a database that doesn't exist on the primary, drop it.
+	// Databases that have not been lazily opened yet are opened here.
+	db, err := s.store.OpenDB(name)
+	if err == litefs.ErrDatabaseNotFound {
 		if err := litefs.WriteStreamFrame(w, &litefs.DropDBStreamFrame{Name: name}); err != nil {
 			return fmt.Errorf("write drop db frame: %w", err)
 		}
@@ -648,6 +1136,8 @@ func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name strin
 
 		delete(posMap, name)
 		return nil
+	} else if err != nil {
+		return fmt.Errorf("open database: %w", err)
 	}
 
 	for {
@@ -659,14 +1149,14 @@ func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name strin
 		// then loses its primary status and reconnects. By invalidating, we
 		// will cause a snapshot to occur.
 		if clientPos.TXID > dbPos.TXID {
-			log.Printf("client transaction id (%s) exceeds primary transaction id (%s), clearing client position", clientPos.TXID.String(), dbPos.TXID.String())
+			s.logger().Warn("client transaction id exceeds primary transaction id, clearing client position", slog.String("db", name), slog.String("txid", dbPos.TXID.String()), slog.String("client-txid", clientPos.TXID.String()))
 			clientPos = ltx.Pos{}
 		}
 
 		// Invalidate client position if the TXID matches but the checksum does not.
 		// This can also occur if an old primary has unreplicated transactions.
 		if clientPos.TXID == dbPos.TXID && clientPos.PostApplyChecksum != dbPos.PostApplyChecksum {
-			log.Printf("client transaction id (%s) caught up but checksum is mismatched (%s <> %s), clearing client position", clientPos.TXID.String(), clientPos.PostApplyChecksum, dbPos.PostApplyChecksum)
+			s.logger().Warn("client transaction id caught up but checksum is mismatched, clearing client position", slog.String("db", name), slog.String("txid", dbPos.TXID.String()), slog.String("chksum", dbPos.PostApplyChecksum.String()), slog.String("client-chksum", clientPos.PostApplyChecksum.String()))
 			clientPos = ltx.Pos{}
 		}
 
@@ -675,7 +1165,26 @@ func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name strin
 			return nil
 		}
 
-		newPos, err := s.streamLTX(ctx, w, db, clientPos.TXID+1, clientPos.PostApplyChecksum)
+		// A partial snapshot can only be resumed by the next frame sent as the
+		// replica discards it after receiving any other data for the database.
+		var partial *litefs.PartialSnapshot
+		if p, ok := partials[name]; ok {
+			partial = &p
+			delete(partials, name)
+		}
+
+		// Bootstrap a replica without a local copy of a large database from a
+		// lazy snapshot so it can serve reads before receiving every page.
+		if clientPos.TXID == 0 && partial == nil && lazyMinSize > 0 && int64(db.PageN())*int64(db.PageSize()) >= lazyMinSize {
+			newPos, err := s.streamLazySnapshot(ctx, w, db)
+			if err != nil {
+				return fmt.Errorf("stream lazy snapshot: %w", err)
+			}
+			posMap[name] = newPos
+			continue
+		}
+
+		newPos, err := s.streamLTX(ctx, w, db, clientPos.TXID+1, clientPos.PostApplyChecksum, partial)
 		if err != nil {
 			return fmt.Errorf("stream ltx (%s): %w", ltx.TXID(clientPos.TXID+1).String(), err)
 		}
@@ -683,20 +1192,20 @@ func (s *Server) streamDB(ctx context.Context, w http.ResponseWriter, name strin
 	}
 }
 
-func (s *Server) streamLTX(ctx context.Context, w http.ResponseWriter, db *litefs.DB, txID ltx.TXID, preApplyChecksum ltx.Checksum) (newPos ltx.Pos, err error) {
+func (s *Server) streamLTX(ctx context.Context, w http.ResponseWriter, db *litefs.DB, txID ltx.TXID, preApplyChecksum ltx.Checksum, partial *litefs.PartialSnapshot) (newPos ltx.Pos, err error) {
 	// Always stream snapshot if we are starting from the first transaction.
 	// There's an edge case where LTX files originated on the client and that
 	// client will skip them if they're seen again (because of write forwarding).
 	if txID == 1 {
-		log.Printf("starting from txid %s, writing snapshot", txID.String())
-		return s.streamLTXSnapshot(ctx, w, db)
+		s.logger().Info("starting from first transaction, writing snapshot", slog.String("db", db.Name()), slog.String("txid", txID.String()))
+		return s.streamLTXSnapshot(ctx, w, db, partial)
 	}
 
 	// Open LTX file, read header.
 	f, err := db.OpenLTXFile(txID)
 	if os.IsNotExist(err) {
-		log.Printf("transaction file for txid %s no longer available, writing snapshot", txID.String())
-		return s.streamLTXSnapshot(ctx, w, db)
+		s.logger().Info("transaction file no longer available, writing snapshot", slog.String("db", db.Name()), slog.String("txid", txID.String()))
+		return s.streamLTXSnapshot(ctx, w, db, partial)
 	} else if err != nil {
 		return ltx.Pos{}, fmt.Errorf("open ltx file: %w", err)
 	}
@@ -713,8 +1222,8 @@ func (s *Server) streamLTX(ctx context.Context, w http.ResponseWriter, db *litef
 
 	// If previous checksum on client does not match, return snapshot instead.
 	if dec.Header().PreApplyChecksum != preApplyChecksum {
-		log.Printf("client preapply checksum mismatch for txid %s, writing snapshot", txID.String())
-		return s.streamLTXSnapshot(ctx, w, db)
+		s.logger().Warn("client preapply checksum mismatch, writing snapshot", slog.String("db", db.Name()), slog.String("txid", txID.String()))
+		return s.streamLTXSnapshot(ctx, w, db, partial)
 	}
 
 	// Write frame.
@@ -725,14 +1234,16 @@ func (s *Server) streamLTX(ctx context.Context, w http.ResponseWriter, db *litef
 
 	// Write LTX file as a chunked byte stream.
 	cw := chunk.NewWriter(w)
-	if _, err := io.Copy(cw, f); err != nil {
+	n, err := io.Copy(cw, f)
+	if err != nil {
 		return ltx.Pos{}, fmt.Errorf("write ltx chunked stream: %w", err)
 	}
 	if err := cw.Close(); err != nil {
 		return ltx.Pos{}, fmt.Errorf("close ltx chunked stream: %w", err)
 	}
 
-	serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx")
+	s.metrics.serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx").Inc()
+	s.metrics.serverLTXSendBytesMetricVec.WithLabelValues(db.Name()).Add(float64(n))
 
 	// Send current HWM as a separate frame.
 	// OPTIMIZE: Only send this when it's been updated or periodically.
@@ -745,17 +1256,26 @@ func (s *Server) streamLTX(ctx context.Context, w http.ResponseWriter, db *litef
 	return ltx.Pos{TXID: dec.Header().MaxTXID, PostApplyChecksum: dec.Trailer().PostApplyChecksum}, nil
 }
 
-func (s *Server) streamLTXSnapshot(ctx context.Context, w http.ResponseWriter, db *litefs.DB) (newPos ltx.Pos, err error) {
+func (s *Server) streamLTXSnapshot(ctx context.Context, w http.ResponseWriter, db *litefs.DB, partial *litefs.PartialSnapshot) (newPos ltx.Pos, err error) {
 	// Default the timeout to the retention period if not explicitly set.
 	// If a LTX file takes longer than this to download then the next LTX file
 	// will be gone before the download is complete.
 	timeout := s.SnapshotTimeout
 	if timeout == 0 {
-		timeout = s.store.Retention
+		timeout, _, _ = s.store.RetentionPolicy()
 	}
 	ctx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("snapshot timeout exceeded (%s)", timeout))
 	defer cancel()
 
+	// Send only the remainder of a snapshot the replica partially received.
+	if partial != nil {
+		if newPos, ok, err := s.resumeLTXSnapshot(ctx, w, db, *partial); err != nil {
+			return ltx.Pos{}, err
+		} else if ok {
+			return newPos, nil
+		}
+	}
+
 	// Write frame.
 	if err := litefs.WriteStreamFrame(w, &litefs.LTXStreamFrame{Name: db.Name()}); err != nil {
 		return ltx.Pos{}, fmt.Errorf("write ltx snapshot stream frame: %w", err)
@@ -771,11 +1291,70 @@ func (s *Server) streamLTXSnapshot(ctx context.Context, w http.ResponseWriter, d
 	}
 	w.(http.Flusher).Flush()
 
-	serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx:snapshot")
+	s.metrics.serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx:snapshot").Inc()
 
 	return ltx.Pos{TXID: header.MaxTXID, PostApplyChecksum: trailer.PostApplyChecksum}, nil
 }
 
+// streamLazySnapshot writes the page checksums & first page of the database.
+// The replica fetches the remaining pages from the /page endpoint on demand.
+func (s *Server) streamLazySnapshot(ctx context.Context, w http.ResponseWriter, db *litefs.DB) (newPos ltx.Pos, err error) {
+	if err := litefs.WriteStreamFrame(w, &litefs.LazySnapshotStreamFrame{Name: db.Name()}); err != nil {
+		return ltx.Pos{}, fmt.Errorf("write lazy snapshot stream frame: %w", err)
+	}
+
+	cw := chunk.NewWriter(w)
+	if newPos, err = db.WriteLazySnapshotTo(ctx, cw); err != nil {
+		return ltx.Pos{}, fmt.Errorf("write lazy snapshot to chunked stream: %w", err)
+	} else if err := cw.Close(); err != nil {
+		return ltx.Pos{}, fmt.Errorf("close lazy snapshot chunked stream: %w", err)
+	}
+	w.(http.Flusher).Flush()
+
+	s.logger().Info("sent lazy snapshot", slog.String("db", db.Name()), slog.String("txid", newPos.TXID.String()))
+	s.metrics.serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx:lazy-snapshot").Inc()
+
+	return newPos, nil
+}
+
+// resumeLTXSnapshot regenerates a snapshot partially received by the replica
+// & writes the remaining bytes. Returns false if the snapshot could not be
+// regenerated, in which case nothing has been written to w.
+func (s *Server) resumeLTXSnapshot(ctx context.Context, w http.ResponseWriter, db *litefs.DB, partial litefs.PartialSnapshot) (newPos ltx.Pos, ok bool, err error) {
+	// The frame is only written once snapshot data is available so that
+	// we can fall back to a full snapshot if the database has changed.
+	fw := &lazyFrameWriter{w: w, frame: &litefs.LTXResumeStreamFrame{Name: db.Name(), Offset: partial.Size}}
+	cw := chunk.NewWriter(fw)
+
+	header, trailer, err := db.ResumeSnapshotTo(ctx, cw, partial)
+	if err == nil {
+		err = cw.Close()
+	}
+	if err != nil && !fw.written {
+		s.logger().Info("cannot resume snapshot, writing full snapshot", slog.String("db", db.Name()), slog.Any("err", err))
+		return ltx.Pos{}, false, nil
+	} else if err != nil {
+		return ltx.Pos{}, false, fmt.Errorf("resume ltx snapshot: %w", err)
+	}
+	w.(http.Flusher).Flush()
+
+	s.logger().Info("resumed snapshot", slog.String("db", db.Name()), slog.String("txid", header.MaxTXID.String()), slog.Int64("offset", partial.Size))
+	s.metrics.serverFrameSendCountMetricVec.WithLabelValues(db.Name(), "ltx:snapshot-resume").Inc()
+
+	return ltx.Pos{TXID: header.MaxTXID, PostApplyChecksum: trailer.PostApplyChecksum}, true, nil
+}
+
+// streamRateLimiter returns the limiter shared by all streams.
+// Returns nil if the stream rate is unlimited.
+func (s *Server) streamRateLimiter() *internal.RateLimiter {
+	s.rateLimiterOnce.Do(func() {
+		if s.StreamRateLimit > 0 {
+			s.rateLimiter = internal.NewRateLimiter(s.StreamRateLimit)
+		}
+	})
+	return s.rateLimiter
+}
+
 func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	subscription := s.store.SubscribeEvents()
 	defer func() { subscription.Stop() }()
@@ -790,11 +1369,11 @@ func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 			return
 		case event, ok := <-subscription.C():
 			if !ok {
-				log.Printf("http: event stream buffer exceeded, disconnecting")
+				s.logger().Warn("event stream buffer exceeded, disconnecting")
 				return
 			}
 			if err := enc.Encode(event); err != nil {
-				log.Printf("http: event stream error: %s", err)
+				s.logger().Warn("event stream error", slog.Any("err", err))
 				return
 			}
 			w.(http.Flusher).Flush()
@@ -802,20 +1381,138 @@ func (s *Server) handleGetEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// logger returns an http logger that includes the node ID.
+func (s *Server) logger() *slog.Logger {
+	return logger.With(slog.String("node", litefs.FormatNodeID(s.store.ID())))
+}
+
 func Error(w http.ResponseWriter, r *http.Request, err error, code int) {
-	log.Printf("http: %s %s: error: %s", r.Method, r.URL.Path, err)
+	logger.Warn("request error", slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.Any("err", err))
 	http.Error(w, err.Error(), code)
 }
 
-// HTTP server metrics.
-var (
-	serverStreamCountMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "litefs_http_stream_count",
-		Help: "Number of streams currently connected.",
-	})
+// acceptsEncoding returns true if encoding is listed in the request's
+// Accept-Encoding header.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if v, _, _ := strings.Cut(v, ";"); strings.TrimSpace(v) == encoding {
+			return true
+		}
+	}
+	return false
+}
 
-	serverFrameSendCountMetricVec = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "litefs_http_frame_send_count",
-		Help: "Number of frames sent.",
-	}, []string{"db", "type"})
-)
+// hasFeature returns true if feature is listed in the Litefs-Features header.
+func hasFeature(h http.Header, feature string) bool {
+	for _, v := range strings.Split(h.Get(HeaderFeatures), ",") {
+		if strings.TrimSpace(v) == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// lz4ResponseWriter wraps a response writer to compress the response body.
+// Each flush writes out a complete LZ4 block so the client can decode frames
+// as they arrive.
+type lz4ResponseWriter struct {
+	http.ResponseWriter
+	zw *lz4.Writer
+}
+
+// lz4BlockSize is the block size used to compress replication streams. Each
+// stream buffers a full block so the 4MB default would use a lot of memory
+// with many replicas. Small blocks still hold a batch of LTX pages.
+const lz4BlockSize = lz4.Block64Kb
+
+func newLZ4ResponseWriter(w http.ResponseWriter) (*lz4ResponseWriter, error) {
+	zw := lz4.NewWriter(w)
+	if err := zw.Apply(lz4.BlockSizeOption(lz4BlockSize)); err != nil {
+		return nil, err
+	}
+	return &lz4ResponseWriter{ResponseWriter: w, zw: zw}, nil
+}
+
+func (w *lz4ResponseWriter) Write(p []byte) (int, error) {
+	return w.zw.Write(p)
+}
+
+// Flush writes any buffered data to the underlying response writer.
+func (w *lz4ResponseWriter) Flush() {
+	if err := w.zw.Flush(); err != nil {
+		return
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// Close writes the LZ4 end frame and flushes the underlying response writer.
+func (w *lz4ResponseWriter) Close() error {
+	if err := w.zw.Close(); err != nil {
+		return err
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+	return nil
+}
+
+// rateLimitedResponseWriter wraps a response writer to limit its bandwidth.
+type rateLimitedResponseWriter struct {
+	http.ResponseWriter
+	w *internal.RateLimitedWriter
+}
+
+func newRateLimitedResponseWriter(ctx context.Context, w http.ResponseWriter, limiter *internal.RateLimiter) *rateLimitedResponseWriter {
+	return &rateLimitedResponseWriter{ResponseWriter: w, w: internal.NewRateLimitedWriter(ctx, w, limiter)}
+}
+
+func (w *rateLimitedResponseWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Flush flushes the underlying response writer.
+func (w *rateLimitedResponseWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// lazyFrameWriter writes a stream frame to w before the first write.
+type lazyFrameWriter struct {
+	w       io.Writer
+	frame   litefs.StreamFrame
+	written bool
+}
+
+func (w *lazyFrameWriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		if err := litefs.WriteStreamFrame(w.w, w.frame); err != nil {
+			return 0, err
+		}
+	}
+	return w.w.Write(p)
+}
+
+// serverMetrics holds the collectors for a single server.
+type serverMetrics struct {
+	serverStreamCountMetric       prometheus.Gauge
+	serverFrameSendCountMetricVec *prometheus.CounterVec
+	serverLTXSendBytesMetricVec   *prometheus.CounterVec
+}
+
+func newServerMetrics(reg prometheus.Registerer) *serverMetrics {
+	f := promauto.With(reg)
+	return &serverMetrics{
+		serverStreamCountMetric: f.NewGauge(prometheus.GaugeOpts{
+			Name: "litefs_http_stream_count",
+			Help: "Number of streams currently connected.",
+		}),
+
+		serverFrameSendCountMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_http_frame_send_count",
+			Help: "Number of frames sent.",
+		}, []string{"db", "type"}),
+
+		serverLTXSendBytesMetricVec: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "litefs_http_ltx_send_bytes",
+			Help: "Number of LTX bytes sent to replicas.",
+		}, []string{"db"}),
+	}
+}
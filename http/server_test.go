@@ -0,0 +1,335 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	gohttp "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/http"
+	"github.com/superfly/litefs/internal/chunk"
+	"github.com/superfly/litefs/internal/testingutil"
+	"github.com/superfly/ltx"
+)
+
+// Ensure the number of LTX bytes streamed to replicas is reported.
+func TestServer_Metrics(t *testing.T) {
+	store := litefs.NewStore(t.TempDir(), true)
+	store.Leaser = litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202")
+	store.Client = http.NewClient()
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	<-store.ReadyCh()
+
+	server := http.NewServer(store, "127.0.0.1:0")
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	server.Serve()
+	defer func() { _ = server.Close() }()
+
+	db, f, err := store.CreateDB("metrics.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a snapshot & a transaction that replaces the only page.
+	page1, page2 := bytes.Repeat([]byte{1}, 4096), bytes.Repeat([]byte{2}, 4096)
+	pos1 := ltx.Pos{TXID: 1, PostApplyChecksum: ltx.ChecksumFlag | ltx.ChecksumPage(1, page1)}
+	applyLTX(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, page1, pos1.PostApplyChecksum)
+	applyLTX(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos1.PostApplyChecksum}, page2, ltx.ChecksumFlag|ltx.ChecksumPage(1, page2))
+
+	sendN0 := metricValue(t, store.Registry(), "litefs_http_ltx_send_bytes", "db", "metrics.db")
+
+	// Stream from the first transaction so the second is sent as an LTX file.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := http.NewClient().Stream(ctx, server.URL(), 1, map[string]ltx.Pos{"metrics.db": pos1}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = st.Close() }()
+
+	for {
+		frame, err := litefs.ReadStreamFrame(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := frame.(*litefs.LTXStreamFrame); ok {
+			if _, err := io.Copy(io.Discard, chunk.NewReader(st)); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if got := metricValue(t, store.Registry(), "litefs_http_ltx_send_bytes", "db", "metrics.db") - sendN0; got <= 0 {
+			return fmt.Errorf("send bytes=%v, want > 0", got)
+		}
+		return nil
+	})
+}
+
+// Ensure renames are only sent as rename frames to replicas that support them.
+func TestServer_Stream_RenameDB(t *testing.T) {
+	t.Run("Supported", func(t *testing.T) {
+		store, server, pos := newStreamServer(t)
+		st := newStream(t, server, http.NewClient(), pos)
+
+		if err := store.RenameDB(context.Background(), "old.db", "new.db"); err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			switch frame := readStreamFrame(t, st).(type) {
+			case *litefs.RenameDBStreamFrame:
+				if frame.OldName != "old.db" || frame.NewName != "new.db" {
+					t.Fatalf("unexpected rename frame: %#v", frame)
+				}
+				return
+			case *litefs.DropDBStreamFrame, *litefs.LTXStreamFrame:
+				t.Fatalf("unexpected frame: %#v", frame)
+			}
+		}
+	})
+
+	// Replicas that do not advertise the feature receive a drop & snapshot.
+	t.Run("NotSupported", func(t *testing.T) {
+		store, server, pos := newStreamServer(t)
+
+		client := http.NewClient()
+		transport := client.HTTPClient.Transport
+		client.HTTPClient.Transport = roundTripperFunc(func(req *gohttp.Request) (*gohttp.Response, error) {
+			req.Header.Del(http.HeaderFeatures)
+			return transport.RoundTrip(req)
+		})
+		st := newStream(t, server, client, pos)
+
+		if err := store.RenameDB(context.Background(), "old.db", "new.db"); err != nil {
+			t.Fatal(err)
+		}
+
+		var dropped, snapshotted bool
+		for !dropped || !snapshotted {
+			switch frame := readStreamFrame(t, st).(type) {
+			case *litefs.RenameDBStreamFrame:
+				t.Fatalf("unexpected rename frame: %#v", frame)
+			case *litefs.DropDBStreamFrame:
+				if frame.Name != "old.db" {
+					t.Fatalf("unexpected drop frame: %#v", frame)
+				}
+				dropped = true
+			case *litefs.LTXStreamFrame:
+				if frame.Name != "new.db" {
+					t.Fatalf("unexpected ltx frame: %#v", frame)
+				}
+				snapshotted = true
+			}
+		}
+	})
+}
+
+// Ensure the stream is decoded by replicas when compressed with LZ4.
+func TestServer_Stream_LZ4(t *testing.T) {
+	_, server, _ := newStreamServer(t)
+
+	client := http.NewClient()
+	client.StreamEncoding = http.StreamEncodingLZ4
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := client.Stream(ctx, server.URL(), 1, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = st.Close() }()
+
+	for {
+		if frame, ok := readStreamFrame(t, st).(*litefs.LTXStreamFrame); ok {
+			if frame.Name != "old.db" {
+				t.Fatalf("unexpected ltx frame: %#v", frame)
+			}
+			return
+		}
+	}
+}
+
+// Ensure replicas refuse filter patterns from a primary that does not match them.
+func TestClient_Stream_FilterPatterns(t *testing.T) {
+	_, server, pos := newStreamServer(t)
+
+	// Emulate an older primary by removing the features header.
+	client := http.NewClient()
+	transport := client.HTTPClient.Transport
+	client.HTTPClient.Transport = roundTripperFunc(func(req *gohttp.Request) (*gohttp.Response, error) {
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Header.Del(http.HeaderFeatures)
+		}
+		return resp, err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	posMap := map[string]ltx.Pos{"old.db": pos}
+
+	if _, err := client.Stream(ctx, server.URL(), 1, posMap, []string{"*.db", "!tmp.db"}, nil); err == nil || !strings.Contains(err.Error(), "primary does not support database filter patterns") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Exact names are still supported by older primaries.
+	st, err := client.Stream(ctx, server.URL(), 1, posMap, []string{"old.db"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = st.Close()
+}
+
+// newStreamServer returns a primary store & server with a single
+// database named "old.db". Returns the position of the database.
+func newStreamServer(tb testing.TB) (*litefs.Store, *http.Server, ltx.Pos) {
+	tb.Helper()
+
+	store := litefs.NewStore(tb.TempDir(), true)
+	store.Leaser = litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202")
+	store.Client = http.NewClient()
+	if err := store.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = store.Close() })
+	<-store.ReadyCh()
+
+	server := http.NewServer(store, "127.0.0.1:0")
+	if err := server.Listen(); err != nil {
+		tb.Fatal(err)
+	}
+	server.Serve()
+	tb.Cleanup(func() { _ = server.Close() })
+
+	db, f, err := store.CreateDB("old.db")
+	if err != nil {
+		tb.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		tb.Fatal(err)
+	}
+
+	page := make([]byte, 4096)
+	copy(page, "SQLite format 3\x00\x10\x00\x01\x01")
+	binary.BigEndian.PutUint32(page[28:], 1) // page count
+	pos := ltx.Pos{TXID: 1, PostApplyChecksum: ltx.ChecksumFlag | ltx.ChecksumPage(1, page)}
+	applyLTX(tb, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, page, pos.PostApplyChecksum)
+	return store, server, pos
+}
+
+// newStream connects to server as an up-to-date replica of "old.db" &
+// reads until the initial ready frame.
+func newStream(tb testing.TB, server *http.Server, client *http.Client, pos ltx.Pos) litefs.Stream {
+	tb.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+
+	st, err := client.Stream(ctx, server.URL(), 1, map[string]ltx.Pos{"old.db": pos}, nil, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = st.Close() })
+
+	for {
+		if _, ok := readStreamFrame(tb, st).(*litefs.ReadyStreamFrame); ok {
+			return st
+		}
+	}
+}
+
+// readStreamFrame reads the next frame from st. The payload of LTX frames is
+// discarded.
+func readStreamFrame(tb testing.TB, st litefs.Stream) litefs.StreamFrame {
+	tb.Helper()
+
+	frame, err := litefs.ReadStreamFrame(st)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, ok := frame.(*litefs.LTXStreamFrame); ok {
+		if _, err := io.Copy(io.Discard, chunk.NewReader(st)); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return frame
+}
+
+type roundTripperFunc func(*gohttp.Request) (*gohttp.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *gohttp.Request) (*gohttp.Response, error) { return fn(req) }
+
+// applyLTX applies a single page transaction to db.
+func applyLTX(tb testing.TB, db *litefs.DB, hdr ltx.Header, page []byte, postApplyChecksum ltx.Checksum) {
+	tb.Helper()
+
+	hdr.Version, hdr.PageSize, hdr.Commit = ltx.Version, uint32(len(page)), 1
+	hdr.Timestamp = time.Now().UnixMilli()
+
+	var buf bytes.Buffer
+	enc := ltx.NewEncoder(&buf)
+	if err := enc.EncodeHeader(hdr); err != nil {
+		tb.Fatal(err)
+	} else if err := enc.EncodePage(ltx.PageHeader{Pgno: 1}, page); err != nil {
+		tb.Fatal(err)
+	}
+	enc.SetPostApplyChecksum(postApplyChecksum)
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.ApplyLTXStream(context.Background(), &buf); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// metricValue returns the value of the counter or gauge gathered from g under
+// name & the given label name/value pairs. Returns zero if no value has been
+// recorded yet.
+func metricValue(tb testing.TB, g prometheus.Gatherer, name string, labelPairs ...string) float64 {
+	tb.Helper()
+
+	mfs, err := g.Gather()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+
+	METRICS:
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			for i := 0; i+1 < len(labelPairs); i += 2 {
+				if labels[labelPairs[i]] != labelPairs[i+1] {
+					continue METRICS
+				}
+			}
+			if c := m.GetCounter(); c != nil {
+				return c.GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+		return 0
+	}
+	return 0
+}
@@ -0,0 +1,496 @@
+package litefs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/superfly/litefs/internal"
+	"github.com/superfly/ltx"
+)
+
+// IntegrityCheckLevel represents the amount of verification performed on
+// existing database files when the store is opened.
+type IntegrityCheckLevel int
+
+// Integrity check levels. Each level includes the checks of the levels below it.
+const (
+	// CheckNone skips verification of the database file.
+	CheckNone = IntegrityCheckLevel(0)
+
+	// CheckHeader verifies the magic bytes, page size & format versions in
+	// the SQLite database header.
+	CheckHeader = IntegrityCheckLevel(1)
+
+	// CheckPageCount verifies that the file size matches the page count in
+	// the header and that every root page in the schema is a b-tree page.
+	CheckPageCount = IntegrityCheckLevel(2)
+
+	// CheckFull walks every b-tree in the database and verifies that each
+	// page is a valid b-tree page that is only referenced once. This is a
+	// structural check similar to "PRAGMA quick_check" but it does not verify
+	// the contents of individual records.
+	CheckFull = IntegrityCheckLevel(3)
+)
+
+// ParseIntegrityCheckLevel returns the level for its string representation.
+func ParseIntegrityCheckLevel(s string) (IntegrityCheckLevel, error) {
+	switch s {
+	case "none":
+		return CheckNone, nil
+	case "header":
+		return CheckHeader, nil
+	case "page-count":
+		return CheckPageCount, nil
+	case "full":
+		return CheckFull, nil
+	default:
+		return 0, fmt.Errorf("invalid integrity check level: %q", s)
+	}
+}
+
+// String returns the string representation of v.
+func (v IntegrityCheckLevel) String() string {
+	switch v {
+	case CheckNone:
+		return "none"
+	case CheckHeader:
+		return "header"
+	case CheckPageCount:
+		return "page-count"
+	case CheckFull:
+		return "full"
+	default:
+		return fmt.Sprintf("IntegrityCheckLevel<%d>", v)
+	}
+}
+
+// SQLite b-tree page types.
+const (
+	btreeIndexInteriorPage = 2
+	btreeTableInteriorPage = 5
+	btreeIndexLeafPage     = 10
+	btreeTableLeafPage     = 13
+)
+
+// checkDatabaseHeader verifies the SQLite header of the database file.
+// Missing & empty database files are skipped as they have not been written yet.
+func (db *DB) checkDatabaseHeader() error {
+	f, err := db.os.Open("CHECKDBHDR", db.DatabasePath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	b := make([]byte, databaseHeaderSize)
+	if n, err := io.ReadFull(f, b); err == io.EOF {
+		return nil
+	} else if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: short database header (%d bytes)", ErrDBCorrupted, n)
+	} else if err != nil {
+		return err
+	}
+	return validateSQLiteDatabaseHeader(b)
+}
+
+// validateSQLiteDatabaseHeader returns ErrDBCorrupted if b is not a valid header.
+func validateSQLiteDatabaseHeader(b []byte) error {
+	if string(b[:len(SQLITE_DATABASE_HEADER_STRING)]) != SQLITE_DATABASE_HEADER_STRING {
+		return fmt.Errorf("%w: invalid header magic", ErrDBCorrupted)
+	}
+
+	pageSize := uint32(binary.BigEndian.Uint16(b[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return fmt.Errorf("%w: invalid page size %d", ErrDBCorrupted, pageSize)
+	}
+
+	if v := b[18]; v != 1 && v != 2 {
+		return fmt.Errorf("%w: invalid file format write version %d", ErrDBCorrupted, v)
+	} else if v := b[19]; v != 1 && v != 2 {
+		return fmt.Errorf("%w: invalid file format read version %d", ErrDBCorrupted, v)
+	}
+
+	if usableSize := pageSize - uint32(b[20]); usableSize < 480 {
+		return fmt.Errorf("%w: invalid reserved space %d", ErrDBCorrupted, b[20])
+	}
+	if b[21] != 64 || b[22] != 32 || b[23] != 32 {
+		return fmt.Errorf("%w: invalid payload fractions %d/%d/%d", ErrDBCorrupted, b[21], b[22], b[23])
+	}
+	return nil
+}
+
+// checkDatabaseBTrees verifies the page count & b-tree pages of the database
+// file. If full is false, only the schema root pages are verified.
+func (db *DB) checkDatabaseBTrees(full bool) error {
+	f, err := db.os.Open("CHECKDBBTREES", db.DatabasePath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	} else if fi.Size() == 0 {
+		return nil
+	}
+
+	hdr := make([]byte, databaseHeaderSize)
+	if _, err := internal.ReadFullAt(f, hdr, 0); err != nil {
+		return fmt.Errorf("read database header: %w", err)
+	} else if err := validateSQLiteDatabaseHeader(hdr); err != nil {
+		return err
+	}
+
+	c := &btreeChecker{
+		f:        f,
+		pageSize: uint32(binary.BigEndian.Uint16(hdr[16:])),
+		full:     full,
+		visited:  make(map[uint32]struct{}),
+	}
+	if c.pageSize == 1 {
+		c.pageSize = 65536
+	}
+	c.usableSize = c.pageSize - uint32(hdr[20])
+
+	// The in-header page count is only valid if the version-valid-for number
+	// matches the change counter. Otherwise, SQLite uses the file size.
+	if fi.Size()%int64(c.pageSize) != 0 {
+		return fmt.Errorf("%w: database size %d is not a multiple of page size %d", ErrDBCorrupted, fi.Size(), c.pageSize)
+	}
+	c.pageN = uint32(fi.Size() / int64(c.pageSize))
+	if binary.BigEndian.Uint32(hdr[92:]) == binary.BigEndian.Uint32(hdr[24:]) {
+		if pageN := binary.BigEndian.Uint32(hdr[28:]); pageN != c.pageN {
+			return fmt.Errorf("%w: header page count %d does not match database size of %d pages", ErrDBCorrupted, pageN, c.pageN)
+		}
+	}
+
+	roots, err := c.schemaRootPages()
+	if err != nil {
+		return err
+	}
+
+	// Reset visited pages so the schema tree is included in the full check.
+	c.visited = make(map[uint32]struct{})
+	for _, pgno := range append([]uint32{1}, roots...) {
+		if err := c.check(pgno, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// btreeChecker verifies the b-tree structure of a SQLite database file.
+type btreeChecker struct {
+	f          *os.File
+	pageSize   uint32
+	usableSize uint32
+	pageN      uint32
+	full       bool
+	visited    map[uint32]struct{}
+}
+
+// readPage returns the contents of the page at pgno.
+func (c *btreeChecker) readPage(pgno uint32) ([]byte, error) {
+	if pgno < 1 || pgno > c.pageN || pgno == ltx.LockPgno(c.pageSize) {
+		return nil, fmt.Errorf("%w: invalid page number %d", ErrDBCorrupted, pgno)
+	}
+
+	buf := make([]byte, c.pageSize)
+	if _, err := internal.ReadFullAt(c.f, buf, int64(pgno-1)*int64(c.pageSize)); err != nil {
+		return nil, fmt.Errorf("read page %d: %w", pgno, err)
+	}
+	return buf, nil
+}
+
+// btreePage represents a decoded b-tree page header & cell pointers.
+type btreePage struct {
+	data  []byte
+	typ   byte
+	cells []uint16 // cell offsets within data
+	right uint32   // right-most child pointer; interior pages only
+}
+
+func (p *btreePage) isInterior() bool {
+	return p.typ == btreeIndexInteriorPage || p.typ == btreeTableInteriorPage
+}
+
+func (p *btreePage) isIndex() bool {
+	return p.typ == btreeIndexInteriorPage || p.typ == btreeIndexLeafPage
+}
+
+// readBTreePage reads & validates the header of the b-tree page at pgno.
+func (c *btreeChecker) readBTreePage(pgno uint32) (*btreePage, error) {
+	data, err := c.readPage(pgno)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first page contains the database header before the b-tree header.
+	offset := 0
+	if pgno == 1 {
+		offset = databaseHeaderSize
+	}
+
+	p := &btreePage{data: data, typ: data[offset]}
+	hdrSize := 8
+	switch p.typ {
+	case btreeIndexInteriorPage, btreeTableInteriorPage:
+		hdrSize = 12
+		p.right = binary.BigEndian.Uint32(data[offset+8:])
+	case btreeIndexLeafPage, btreeTableLeafPage:
+	default:
+		return nil, fmt.Errorf("%w: invalid b-tree page type %d on page %d", ErrDBCorrupted, p.typ, pgno)
+	}
+
+	cellN := int(binary.BigEndian.Uint16(data[offset+3:]))
+	cellPtrEnd := offset + hdrSize + (2 * cellN)
+	if cellPtrEnd > int(c.usableSize) {
+		return nil, fmt.Errorf("%w: cell count %d overflows page %d", ErrDBCorrupted, cellN, pgno)
+	}
+
+	p.cells = make([]uint16, cellN)
+	for i := range p.cells {
+		ptr := binary.BigEndian.Uint16(data[offset+hdrSize+(2*i):])
+		if int(ptr) < cellPtrEnd || uint32(ptr) >= c.usableSize {
+			return nil, fmt.Errorf("%w: cell pointer %d out of bounds on page %d", ErrDBCorrupted, ptr, pgno)
+		}
+		p.cells[i] = ptr
+	}
+	return p, nil
+}
+
+// check verifies the b-tree page at pgno. If index is -1, the page may be
+// either a table or index page. Children are only checked in full mode.
+func (c *btreeChecker) check(pgno uint32, index int) error {
+	if _, ok := c.visited[pgno]; ok {
+		return fmt.Errorf("%w: page %d referenced multiple times", ErrDBCorrupted, pgno)
+	}
+	c.visited[pgno] = struct{}{}
+
+	p, err := c.readBTreePage(pgno)
+	if err != nil {
+		return err
+	}
+
+	isIndex := 0
+	if p.isIndex() {
+		isIndex = 1
+	}
+	if index != -1 && index != isIndex {
+		return fmt.Errorf("%w: unexpected b-tree page type %d on page %d", ErrDBCorrupted, p.typ, pgno)
+	}
+
+	if !c.full || !p.isInterior() {
+		return nil
+	}
+
+	for _, ptr := range p.cells {
+		if int(ptr)+4 > len(p.data) {
+			return fmt.Errorf("%w: cell pointer %d out of bounds on page %d", ErrDBCorrupted, ptr, pgno)
+		}
+		if err := c.check(binary.BigEndian.Uint32(p.data[ptr:]), isIndex); err != nil {
+			return err
+		}
+	}
+	return c.check(p.right, isIndex)
+}
+
+// schemaRootPages returns the root page numbers of all tables & indexes.
+func (c *btreeChecker) schemaRootPages() ([]uint32, error) {
+	var roots []uint32
+	if err := c.walkTableLeafCells(1, func(payload []byte) error {
+		rootpage, err := schemaRecordRootPage(payload)
+		if err != nil {
+			return err
+		} else if rootpage != 0 { // views & triggers have no root page
+			roots = append(roots, rootpage)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// walkTableLeafCells calls fn with the payload of each cell in the table b-tree at pgno.
+func (c *btreeChecker) walkTableLeafCells(pgno uint32, fn func(payload []byte) error) error {
+	if _, ok := c.visited[pgno]; ok {
+		return fmt.Errorf("%w: page %d referenced multiple times", ErrDBCorrupted, pgno)
+	}
+	c.visited[pgno] = struct{}{}
+
+	p, err := c.readBTreePage(pgno)
+	if err != nil {
+		return err
+	} else if p.isIndex() {
+		return fmt.Errorf("%w: unexpected b-tree page type %d on page %d", ErrDBCorrupted, p.typ, pgno)
+	}
+
+	if p.isInterior() {
+		for _, ptr := range p.cells {
+			if int(ptr)+4 > len(p.data) {
+				return fmt.Errorf("%w: cell pointer %d out of bounds on page %d", ErrDBCorrupted, ptr, pgno)
+			}
+			if err := c.walkTableLeafCells(binary.BigEndian.Uint32(p.data[ptr:]), fn); err != nil {
+				return err
+			}
+		}
+		return c.walkTableLeafCells(p.right, fn)
+	}
+
+	for _, ptr := range p.cells {
+		payload, err := c.readTableLeafPayload(pgno, p.data[ptr:c.usableSize])
+		if err != nil {
+			return err
+		} else if err := fn(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTableLeafPayload returns the full payload of a table leaf cell,
+// including any data stored on overflow pages.
+func (c *btreeChecker) readTableLeafPayload(pgno uint32, cell []byte) ([]byte, error) {
+	payloadN, n := readVarint(cell)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: invalid cell payload size on page %d", ErrDBCorrupted, pgno)
+	}
+	cell = cell[n:]
+	if _, n = readVarint(cell); n == 0 {
+		return nil, fmt.Errorf("%w: invalid cell rowid on page %d", ErrDBCorrupted, pgno)
+	}
+	cell = cell[n:]
+
+	// Determine the amount of payload stored locally on the page.
+	u := uint64(c.usableSize)
+	x, m := u-35, ((u-12)*32/255)-23
+	localN := payloadN
+	if payloadN > x {
+		if localN = m + ((payloadN - m) % (u - 4)); localN > x {
+			localN = m
+		}
+	}
+
+	if localN > uint64(len(cell)) || (localN < payloadN && localN+4 > uint64(len(cell))) {
+		return nil, fmt.Errorf("%w: cell payload overflows page %d", ErrDBCorrupted, pgno)
+	}
+	payload := append(make([]byte, 0, payloadN), cell[:localN]...)
+	if localN == payloadN {
+		return payload, nil
+	}
+
+	// Read the remaining payload from the overflow page chain.
+	next := binary.BigEndian.Uint32(cell[localN:])
+	for i := uint32(0); uint64(len(payload)) < payloadN; i++ {
+		if i >= c.pageN {
+			return nil, fmt.Errorf("%w: overflow page cycle from page %d", ErrDBCorrupted, pgno)
+		}
+
+		buf, err := c.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(buf)
+
+		sz := min(payloadN-uint64(len(payload)), u-4)
+		payload = append(payload, buf[4:4+sz]...)
+	}
+	return payload, nil
+}
+
+// schemaRecordRootPage returns the "rootpage" column from a sqlite_schema record.
+func schemaRecordRootPage(record []byte) (uint32, error) {
+	const rootpageColumn = 3
+
+	hdrSize, n := readVarint(record)
+	if n == 0 || hdrSize < uint64(n) || hdrSize > uint64(len(record)) {
+		return 0, fmt.Errorf("%w: invalid schema record header", ErrDBCorrupted)
+	}
+
+	// Skip over the values of the columns before the rootpage.
+	hdr, offset := record[n:hdrSize], hdrSize
+	for i := 0; ; i++ {
+		typ, n := readVarint(hdr)
+		if n == 0 {
+			return 0, fmt.Errorf("%w: invalid schema record serial type", ErrDBCorrupted)
+		}
+		hdr = hdr[n:]
+
+		sz := serialTypeSize(typ)
+		if sz > uint64(len(record))-offset {
+			return 0, fmt.Errorf("%w: schema record value out of bounds", ErrDBCorrupted)
+		}
+		if i < rootpageColumn {
+			offset += sz
+			continue
+		}
+
+		// Decode the big-endian signed integer.
+		switch typ {
+		case 0, 8: // NULL, zero
+			return 0, nil
+		case 9: // one
+			return 1, nil
+		case 1, 2, 3, 4, 5, 6:
+			var v int64
+			for _, b := range record[offset : offset+sz] {
+				v = (v << 8) | int64(b)
+			}
+			if shift := 64 - (8 * sz); shift > 0 {
+				v = (v << shift) >> shift // sign extend
+			}
+			if v < 0 || v > int64(^uint32(0)) {
+				return 0, fmt.Errorf("%w: invalid schema root page %d", ErrDBCorrupted, v)
+			}
+			return uint32(v), nil
+		default:
+			return 0, fmt.Errorf("%w: invalid schema root page serial type %d", ErrDBCorrupted, typ)
+		}
+	}
+}
+
+// serialTypeSize returns the size, in bytes, of a record value with serial type typ.
+func serialTypeSize(typ uint64) uint64 {
+	switch typ {
+	case 0, 8, 9:
+		return 0
+	case 1, 2, 3, 4:
+		return typ
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	case 10, 11: // reserved
+		return 0
+	default:
+		return (typ - 12) / 2
+	}
+}
+
+// readVarint decodes a SQLite variable-length integer from b. Returns the
+// value & the number of bytes read. Returns zero bytes if b is too short.
+func readVarint(b []byte) (v uint64, n int) {
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return (v << 8) | uint64(b[i]), 9
+		}
+		v = (v << 7) | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the number of bytes per second across one or more
+// writers. It allows bursts of up to one second worth of bytes.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a new instance of RateLimiter.
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// WaitN blocks until n bytes can be sent or ctx is done. Waiting callers
+// reserve their bytes up front so concurrent writers share the rate evenly.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	tokens := l.tokens
+	l.mu.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-tokens / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimitedWriter wraps a writer & limits its throughput with a RateLimiter.
+type RateLimitedWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *RateLimiter
+}
+
+// NewRateLimitedWriter returns a new instance of RateLimitedWriter.
+func NewRateLimitedWriter(ctx context.Context, w io.Writer, limiter *RateLimiter) *RateLimitedWriter {
+	return &RateLimitedWriter{ctx: ctx, w: w, limiter: limiter}
+}
+
+// Write writes p to the underlying writer in pieces no larger than the
+// burst size of the limiter.
+func (w *RateLimitedWriter) Write(p []byte) (n int, err error) {
+	burst := max(int(w.limiter.rate), 1)
+	for len(p) > 0 {
+		buf := p[:min(len(p), burst, 32*1024)]
+		if err := w.limiter.WaitN(w.ctx, len(buf)); err != nil {
+			return n, err
+		}
+
+		nn, err := w.w.Write(buf)
+		if n += nn; err != nil {
+			return n, err
+		}
+		p = p[len(buf):]
+	}
+	return n, nil
+}
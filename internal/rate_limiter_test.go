@@ -0,0 +1,45 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs/internal"
+)
+
+func TestRateLimitedWriter(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		// Initial burst is one second worth of bytes so the remaining 500
+		// bytes should take roughly half a second.
+		var buf bytes.Buffer
+		w := internal.NewRateLimitedWriter(context.Background(), &buf, internal.NewRateLimiter(1000))
+
+		start := time.Now()
+		if n, err := w.Write(make([]byte, 1500)); err != nil {
+			t.Fatal(err)
+		} else if got, want := n, 1500; got != want {
+			t.Fatalf("n=%d, want %d", got, want)
+		} else if got, want := buf.Len(), 1500; got != want {
+			t.Fatalf("len=%d, want %d", got, want)
+		}
+
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Fatalf("write not limited: %s", elapsed)
+		}
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var buf bytes.Buffer
+		w := internal.NewRateLimitedWriter(ctx, &buf, internal.NewRateLimiter(100))
+		if _, err := w.Write(make([]byte, 500)); err != context.Canceled {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := buf.Len(), 100; got != want {
+			t.Fatalf("len=%d, want %d", got, want)
+		}
+	})
+}
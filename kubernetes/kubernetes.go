@@ -0,0 +1,562 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
+)
+
+// logger is the logger for the lease subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemLease)
+
+// Default lease settings.
+const (
+	DefaultTTL = 10 * time.Second
+)
+
+// Default in-cluster service account paths.
+const (
+	ServiceAccountTokenPath     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	ServiceAccountCACertPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	ServiceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Annotations stored on the Lease object.
+const (
+	PrimaryInfoAnnotation = "litefs.fly.io/primary-info"
+	ClusterIDAnnotation   = "litefs.fly.io/cluster-id"
+)
+
+// microTimeFormat is the format of Kubernetes MicroTime fields.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// errConflict is returned when an update fails because the Lease object was
+// modified concurrently.
+var errConflict = errors.New("kubernetes lease conflict")
+
+// Leaser represents an API for obtaining a distributed lock using a
+// Kubernetes coordination.k8s.io/v1 Lease object.
+type Leaser struct {
+	name         string
+	hostname     string
+	advertiseURL string
+
+	// The last lease record seen & the local time it was first seen. Expiry
+	// is measured from the observed time rather than the holder's renew
+	// time so clock skew between nodes does not affect the lease.
+	mu           sync.Mutex
+	observedSpec leaseSpec
+	observedTime time.Time
+
+	// HTTPClient is the client used to communicate with the API server.
+	// Automatically configured with the service account CA when in-cluster.
+	HTTPClient *http.Client
+
+	// APIURL is the base URL of the Kubernetes API server. If blank, the
+	// in-cluster configuration is used.
+	APIURL string
+
+	// Namespace of the Lease object. If blank, the service account
+	// namespace is used.
+	Namespace string
+
+	// Token is the bearer token used for authentication. If blank, the token
+	// is read from TokenPath on every request so rotated tokens are used.
+	Token     string
+	TokenPath string
+
+	// TTL is the time until the lease expires.
+	TTL time.Duration
+}
+
+// NewLeaser returns a new instance of Leaser for the named Lease object.
+func NewLeaser(name, hostname, advertiseURL string) *Leaser {
+	return &Leaser{
+		name:         name,
+		hostname:     hostname,
+		advertiseURL: advertiseURL,
+		TTL:          DefaultTTL,
+	}
+}
+
+// Open initializes the API server configuration. Missing settings are read
+// from the in-cluster service account.
+func (l *Leaser) Open() error {
+	if l.name == "" {
+		return fmt.Errorf("must specify a kubernetes lease name")
+	} else if l.hostname == "" {
+		return fmt.Errorf("must specify a hostname for this node")
+	} else if l.advertiseURL == "" {
+		return fmt.Errorf("must specify an advertise URL for this node")
+	} else if l.TTL < time.Second {
+		return fmt.Errorf("kubernetes lease ttl must be at least one second")
+	}
+
+	if l.APIURL == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return fmt.Errorf("kubernetes api url not specified and not running in-cluster")
+		}
+		l.APIURL = "https://" + net.JoinHostPort(host, port)
+
+		if l.HTTPClient == nil {
+			client, err := newInClusterHTTPClient(ServiceAccountCACertPath)
+			if err != nil {
+				return err
+			}
+			l.HTTPClient = client
+		}
+		if l.Token == "" && l.TokenPath == "" {
+			l.TokenPath = ServiceAccountTokenPath
+		}
+	}
+	l.APIURL = strings.TrimSuffix(l.APIURL, "/")
+
+	if l.HTTPClient == nil {
+		l.HTTPClient = http.DefaultClient
+	}
+
+	if l.Namespace == "" {
+		buf, err := os.ReadFile(ServiceAccountNamespacePath)
+		if err != nil {
+			return fmt.Errorf("read service account namespace: %w", err)
+		}
+		l.Namespace = strings.TrimSpace(string(buf))
+	}
+
+	return nil
+}
+
+// newInClusterHTTPClient returns an HTTP client that trusts the cluster CA.
+func newInClusterHTTPClient(caPath string) (*http.Client, error) {
+	buf, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("read service account ca: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("invalid service account ca")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Close is a no-op.
+func (l *Leaser) Close() (err error) {
+	return nil
+}
+
+// Type returns "kubernetes".
+func (l *Leaser) Type() string { return "kubernetes" }
+
+// Hostname returns the hostname for this node.
+func (l *Leaser) Hostname() string {
+	return l.hostname
+}
+
+// AdvertiseURL returns the URL being advertised to nodes when primary.
+func (l *Leaser) AdvertiseURL() string {
+	return l.advertiseURL
+}
+
+// Acquire takes ownership of the Lease object if it is not held or if the
+// current holder's lease has expired. Returns ErrPrimaryExists otherwise.
+func (l *Leaser) Acquire(ctx context.Context) (litefs.Lease, error) {
+	infoValue, err := l.primaryInfoValue()
+	if err != nil {
+		return nil, fmt.Errorf("marshal lease info: %w", err)
+	}
+
+	leaseID, err := newLeaseID()
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := l.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if obj == nil {
+		obj = &leaseObject{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"}
+		obj.Metadata.Name, obj.Metadata.Namespace = l.name, l.Namespace
+	} else if l.held(obj, now) {
+		return nil, litefs.ErrPrimaryExists
+	} else {
+		obj.Spec.LeaseTransitions++
+	}
+
+	obj.setAnnotation(PrimaryInfoAnnotation, infoValue)
+	obj.Spec.HolderIdentity = leaseID
+	obj.Spec.LeaseDurationSeconds = int(l.TTL / time.Second)
+	obj.Spec.AcquireTime = now.UTC().Format(microTimeFormat)
+	obj.Spec.RenewTime = obj.Spec.AcquireTime
+
+	if err := l.put(ctx, obj); errors.Is(err, errConflict) {
+		return nil, litefs.ErrPrimaryExists
+	} else if err != nil {
+		return nil, fmt.Errorf("update kubernetes lease: %w", err)
+	}
+
+	lease := newLease(l, leaseID, now)
+	lease.epoch = uint64(obj.Spec.LeaseTransitions) + 1
+	return lease, nil
+}
+
+// AcquireExisting takes over an existing lease ID. This can occur if an
+// existing primary hands off to a replica. Returns ErrLeaseExpired if the
+// Lease object is no longer held by leaseID.
+func (l *Leaser) AcquireExisting(ctx context.Context, leaseID string) (litefs.Lease, error) {
+	infoValue, err := l.primaryInfoValue()
+	if err != nil {
+		return nil, fmt.Errorf("marshal lease info: %w", err)
+	}
+
+	// A handoff counts as a transition so the new primary has a higher epoch.
+	lease := newLease(l, leaseID, time.Now())
+	if err := lease.renew(ctx, func(obj *leaseObject) {
+		obj.setAnnotation(PrimaryInfoAnnotation, infoValue)
+		obj.Spec.LeaseTransitions++
+		lease.epoch = uint64(obj.Spec.LeaseTransitions) + 1
+	}); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// PrimaryInfo returns the primary info stored on the Lease object.
+// Returns ErrNoPrimary if the lease is not currently held.
+func (l *Leaser) PrimaryInfo(ctx context.Context) (info litefs.PrimaryInfo, err error) {
+	obj, err := l.get(ctx)
+	if err != nil {
+		return info, err
+	} else if obj == nil || !l.held(obj, time.Now()) {
+		return info, litefs.ErrNoPrimary
+	}
+
+	value := obj.Metadata.Annotations[PrimaryInfoAnnotation]
+	if value == "" {
+		return info, litefs.ErrNoPrimary
+	}
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// ClusterID returns the cluster ID stored on the Lease object.
+// Returns a blank string if no cluster ID has been set yet.
+func (l *Leaser) ClusterID(ctx context.Context) (string, error) {
+	obj, err := l.get(ctx)
+	if err != nil || obj == nil {
+		return "", err
+	}
+	return obj.Metadata.Annotations[ClusterIDAnnotation], nil
+}
+
+// SetClusterID sets the cluster ID on the Lease object. The cluster ID can
+// only be set once and it will return an error if attemping to reassign it.
+func (l *Leaser) SetClusterID(ctx context.Context, clusterID string) error {
+	obj, err := l.get(ctx)
+	if err != nil {
+		return err
+	} else if obj == nil {
+		obj = &leaseObject{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"}
+		obj.Metadata.Name, obj.Metadata.Namespace = l.name, l.Namespace
+	}
+
+	if obj.Metadata.Annotations[ClusterIDAnnotation] != "" {
+		return fmt.Errorf("cluster already initialized, cannot set cluster id")
+	}
+	obj.setAnnotation(ClusterIDAnnotation, clusterID)
+
+	if err := l.put(ctx, obj); errors.Is(err, errConflict) {
+		return fmt.Errorf("cluster id concurrently modified, cannot set cluster id")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *Leaser) primaryInfoValue() (string, error) {
+	buf, err := json.Marshal(litefs.PrimaryInfo{
+		Hostname:     l.hostname,
+		AdvertiseURL: l.advertiseURL,
+	})
+	return string(buf), err
+}
+
+func (l *Leaser) leasesURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.APIURL, l.Namespace)
+}
+
+// get returns the Lease object. Returns nil if it does not exist.
+func (l *Leaser) get(ctx context.Context) (*leaseObject, error) {
+	var obj leaseObject
+	if err := l.do(ctx, http.MethodGet, l.leasesURL()+"/"+l.name, nil, &obj); err == errNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	l.observe(&obj, time.Now())
+	return &obj, nil
+}
+
+// put creates the Lease object if it has no resource version. Otherwise it
+// is replaced. Returns errConflict if the object was concurrently modified.
+func (l *Leaser) put(ctx context.Context, obj *leaseObject) (err error) {
+	if obj.Metadata.ResourceVersion == "" {
+		err = l.do(ctx, http.MethodPost, l.leasesURL(), obj, obj)
+	} else {
+		err = l.do(ctx, http.MethodPut, l.leasesURL()+"/"+l.name, obj, obj)
+	}
+	if err != nil {
+		return err
+	}
+	l.observe(obj, time.Now())
+	return nil
+}
+
+// observe records the local time that the lease record last changed.
+func (l *Leaser) observe(obj *leaseObject, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if obj.Spec != l.observedSpec || l.observedTime.IsZero() {
+		l.observedSpec, l.observedTime = obj.Spec, now
+	}
+}
+
+// held returns true if the lease has a holder and the record has changed
+// within the lease duration, as measured by the local clock. This matches
+// the expiry used by the Kubernetes leader election client.
+func (l *Leaser) held(obj *leaseObject, now time.Time) bool {
+	if obj.Spec.HolderIdentity == "" {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if obj.Spec != l.observedSpec {
+		return true // record not observed yet, assume held
+	}
+	return now.Before(l.observedTime.Add(time.Duration(obj.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// errNotFound is returned when the Lease object does not exist.
+var errNotFound = errors.New("kubernetes lease not found")
+
+// do sends a JSON request to the API server and decodes the response.
+func (l *Leaser) do(ctx context.Context, method, rawurl string, reqBody, respBody any) error {
+	var body io.Reader
+	if reqBody != nil {
+		buf, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token := l.Token
+	if token == "" && l.TokenPath != "" {
+		buf, err := os.ReadFile(l.TokenPath)
+		if err != nil {
+			return fmt.Errorf("read service account token: %w", err)
+		}
+		token = strings.TrimSpace(string(buf))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return json.NewDecoder(resp.Body).Decode(respBody)
+	case http.StatusNotFound:
+		return errNotFound
+	case http.StatusConflict:
+		return errConflict
+	default:
+		var status struct {
+			Message string `json:"message"`
+		}
+		buf, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(buf, &status); err == nil && status.Message != "" {
+			return fmt.Errorf("kubernetes error (%d): %s", resp.StatusCode, status.Message)
+		}
+		return fmt.Errorf("kubernetes error (%d): %s", resp.StatusCode, strings.TrimSpace(string(buf)))
+	}
+}
+
+// newLeaseID returns a random identifier used as the lease holder identity.
+func newLeaseID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Lease represents a distributed lock obtained by the Leaser.
+type Lease struct {
+	leaser    *Leaser
+	id        string
+	epoch     uint64
+	renewedAt time.Time
+	handoffCh chan uint64 // channel of node IDs
+}
+
+func newLease(leaser *Leaser, id string, renewedAt time.Time) *Lease {
+	return &Lease{
+		leaser:    leaser,
+		id:        id,
+		renewedAt: renewedAt,
+		handoffCh: make(chan uint64),
+	}
+}
+
+// ID returns the lease holder identity.
+func (l *Lease) ID() string { return l.id }
+
+// Epoch returns the number of lease transitions, including this acquisition.
+func (l *Lease) Epoch() uint64 { return l.epoch }
+
+// TTL returns the time-to-live value the lease was initialized with.
+func (l *Lease) TTL() time.Duration { return l.leaser.TTL }
+
+// RenewedAt returns the time that the lease was created or renewed.
+func (l *Lease) RenewedAt() time.Time { return l.renewedAt }
+
+// Renew attempts to reset the TTL on the lease by updating its renew time.
+// Returns ErrLeaseExpired if the lease is held by another holder.
+func (l *Lease) Renew(ctx context.Context) error {
+	return l.renew(ctx, nil)
+}
+
+func (l *Lease) renew(ctx context.Context, fn func(*leaseObject)) error {
+	obj, err := l.leaser.get(ctx)
+	if err != nil {
+		return err
+	} else if obj == nil || obj.Spec.HolderIdentity != l.id {
+		return litefs.ErrLeaseExpired
+	}
+
+	if fn != nil {
+		fn(obj)
+	}
+
+	now := time.Now()
+	obj.Spec.RenewTime = now.UTC().Format(microTimeFormat)
+	obj.Spec.LeaseDurationSeconds = int(l.leaser.TTL / time.Second)
+
+	// A conflict means another node may have taken over the lease.
+	if err := l.leaser.put(ctx, obj); errors.Is(err, errConflict) {
+		return litefs.ErrLeaseExpired
+	} else if err != nil {
+		return err
+	}
+
+	// Reset the last renewed time.
+	l.renewedAt = now
+
+	return nil
+}
+
+// Handoff sends the nodeID to the channel returned by HandoffCh()
+func (l *Lease) Handoff(ctx context.Context, nodeID uint64) error {
+	ctx, cancel := context.WithTimeoutCause(ctx, 5*time.Second, fmt.Errorf("kubernetes handoff timeout"))
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case l.handoffCh <- nodeID:
+		return nil
+	}
+}
+
+// HandoffCh returns the handoff channel.
+func (l *Lease) HandoffCh() <-chan uint64 { return l.handoffCh }
+
+// Close releases the Lease object so another node can acquire it immediately.
+func (l *Lease) Close() error {
+	ctx := context.Background()
+	obj, err := l.leaser.get(ctx)
+	if err != nil {
+		return err
+	} else if obj == nil || obj.Spec.HolderIdentity != l.id {
+		return nil // already released
+	}
+
+	obj.Spec.HolderIdentity = ""
+	delete(obj.Metadata.Annotations, PrimaryInfoAnnotation)
+	if err := l.leaser.put(ctx, obj); err != nil {
+		logger.Warn("kubernetes lease release error", slog.String("lease", l.leaser.name), slog.String("holder", l.id))
+		return err
+	}
+	return nil
+}
+
+// leaseObject is the subset of the coordination.k8s.io/v1 Lease used by LiteFS.
+type leaseObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace,omitempty"`
+		ResourceVersion string            `json:"resourceVersion,omitempty"`
+		Annotations     map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+	Spec leaseSpec `json:"spec"`
+}
+
+// leaseSpec is the spec of a Lease object. It is comparable so changes to the
+// record can be detected.
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+func (obj *leaseObject) setAnnotation(key, value string) {
+	if obj.Metadata.Annotations == nil {
+		obj.Metadata.Annotations = make(map[string]string)
+	}
+	obj.Metadata.Annotations[key] = value
+}
@@ -0,0 +1,287 @@
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/kubernetes"
+)
+
+func TestLeaser_Acquire(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l := newLeaser(t, srv, "node1")
+		if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		lease, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := l.PrimaryInfo(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "node1"; got != want {
+			t.Fatalf("Hostname=%s, want %s", got, want)
+		} else if got, want := info.AdvertiseURL, "http://node1:20202"; got != want {
+			t.Fatalf("AdvertiseURL=%s, want %s", got, want)
+		}
+
+		if err := lease.Renew(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := srv.token(), "Bearer TOKEN"; got != want {
+			t.Fatalf("Authorization=%q, want %q", got, want)
+		}
+
+		// Closing the lease should release it for other nodes.
+		if err := lease.Close(); err != nil {
+			t.Fatal(err)
+		} else if _, err := l.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := newLeaser(t, srv, "node2").Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrPrimaryExists", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		if _, err := newLeaser(t, srv, "node0").Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		l1 := newLeaser(t, srv, "node1")
+		if _, err := l1.Acquire(context.Background()); err != litefs.ErrPrimaryExists {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info, err := l1.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := info.Hostname, "node0"; got != want {
+			t.Fatalf("Hostname=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l0 := newLeaser(t, srv, "node0")
+		l0.TTL = 1 * time.Second
+		lease0, err := l0.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Expiry is measured from when a node first observes the record.
+		l1 := newLeaser(t, srv, "node1")
+		if _, err := l1.PrimaryInfo(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the primary's lease to expire & take over.
+		time.Sleep(l0.TTL + 100*time.Millisecond)
+		if _, err := l0.PrimaryInfo(context.Background()); err != litefs.ErrNoPrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := l1.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Original lease can no longer be renewed.
+		if err := lease0.Renew(context.Background()); err != litefs.ErrLeaseExpired {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	// Ensure the holder's clock does not affect expiry on other nodes.
+	t.Run("ClockSkew", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+
+		l0 := newLeaser(t, srv, "node0")
+		l0.TTL = 1 * time.Second
+		if _, err := l0.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Holder's clock is behind so its renew time is far in the past.
+		srv.setRenewTime(time.Now().Add(-1 * time.Hour))
+
+		l1 := newLeaser(t, srv, "node1")
+		if _, err := l1.Acquire(context.Background()); err != litefs.ErrPrimaryExists {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The lease expires once the record is unchanged for the duration.
+		time.Sleep(l0.TTL + 100*time.Millisecond)
+		if _, err := l1.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestLeaser_AcquireExisting(t *testing.T) {
+	srv := newFakeServer()
+	defer srv.Close()
+
+	lease0, err := newLeaser(t, srv, "node0").Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hand off the lease to another node.
+	l1 := newLeaser(t, srv, "node1")
+	if lease1, err := l1.AcquireExisting(context.Background(), lease0.ID()); err != nil {
+		t.Fatal(err)
+	} else if got, want := lease1.ID(), lease0.ID(); got != want {
+		t.Fatalf("ID=%s, want %s", got, want)
+	} else if got, want := lease1.Epoch(), lease0.Epoch()+1; got != want {
+		t.Fatalf("Epoch=%d, want %d", got, want)
+	}
+	if info, err := l1.PrimaryInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := info.Hostname, "node1"; got != want {
+		t.Fatalf("Hostname=%s, want %s", got, want)
+	}
+
+	if _, err := l1.AcquireExisting(context.Background(), "unknown"); err != litefs.ErrLeaseExpired {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLeaser_ClusterID(t *testing.T) {
+	srv := newFakeServer()
+	defer srv.Close()
+
+	l := newLeaser(t, srv, "node")
+	if id, err := l.ClusterID(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if id != "" {
+		t.Fatalf("unexpected cluster id: %q", id)
+	}
+
+	if err := l.SetClusterID(context.Background(), "LFSC0000000000000001"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := l.ClusterID(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got, want := id, "LFSC0000000000000001"; got != want {
+		t.Fatalf("ClusterID=%s, want %s", got, want)
+	}
+
+	// Cluster ID is preserved when the lease is acquired.
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := l.SetClusterID(context.Background(), "LFSC0000000000000002"); err == nil || err.Error() != `cluster already initialized, cannot set cluster id` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func newLeaser(tb testing.TB, srv *fakeServer, hostname string) *kubernetes.Leaser {
+	tb.Helper()
+	l := kubernetes.NewLeaser("litefs", hostname, "http://"+hostname+":20202")
+	l.APIURL = srv.URL
+	l.Namespace = "default"
+	l.Token = "TOKEN"
+	if err := l.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	return l
+}
+
+// fakeServer implements the subset of the Kubernetes Lease API used by the leaser.
+type fakeServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	obj     map[string]any // stored Lease object, if any
+	version int
+	auth    string // last authorization header
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *fakeServer) token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.auth
+}
+
+// setRenewTime overwrites the renew time on the stored Lease object.
+func (s *fakeServer) setRenewTime(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	spec, _ := s.obj["spec"].(map[string]any)
+	spec["renewTime"] = t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	s.version++
+	s.obj["metadata"].(map[string]any)["resourceVersion"] = strconv.Itoa(s.version)
+}
+
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auth = r.Header.Get("Authorization")
+
+	const prefix = "/apis/coordination.k8s.io/v1/namespaces/default/leases"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if s.obj == nil {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.obj)
+
+	case http.MethodPost, http.MethodPut:
+		var obj map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metadata, _ := obj["metadata"].(map[string]any)
+		if r.Method == http.MethodPost && s.obj != nil {
+			http.Error(w, `{"message":"already exists"}`, http.StatusConflict)
+			return
+		} else if r.Method == http.MethodPut && (s.obj == nil || metadata["resourceVersion"] != strconv.Itoa(s.version)) {
+			http.Error(w, `{"message":"conflict"}`, http.StatusConflict)
+			return
+		}
+
+		s.version++
+		metadata["resourceVersion"] = strconv.Itoa(s.version)
+		s.obj = obj
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(s.obj)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
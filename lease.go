@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -36,9 +38,21 @@ type Leaser interface {
 	SetClusterID(ctx context.Context, clusterID string) error
 }
 
+// PrimaryInfoCacher is implemented by leasers that cache the primary's info.
+// The store calls InvalidatePrimaryInfo() when it cannot connect to the
+// primary so that the next call to PrimaryInfo() looks it up again.
+type PrimaryInfoCacher interface {
+	InvalidatePrimaryInfo()
+}
+
 // Lease represents an acquired lease from a Leaser.
 type Lease interface {
 	ID() string
+
+	// Epoch returns a fencing token that is greater than the epoch of any
+	// lease previously acquired from the leaser, including handoffs.
+	Epoch() uint64
+
 	RenewedAt() time.Time
 	TTL() time.Duration
 
@@ -70,6 +84,87 @@ func (info *PrimaryInfo) Clone() *PrimaryInfo {
 	return &other
 }
 
+// LeaserFactory creates a Leaser for a custom lease type. Factories are
+// registered by name with RegisterLeaser and selected by setting "lease.type"
+// in the config to that name.
+type LeaserFactory interface {
+	// NewLeaser returns an opened leaser for the given config.
+	NewLeaser(ctx context.Context, config LeaserConfig) (Leaser, error)
+}
+
+// LeaserFactoryFunc is a function that implements LeaserFactory.
+type LeaserFactoryFunc func(ctx context.Context, config LeaserConfig) (Leaser, error)
+
+// NewLeaser calls fn(ctx, config).
+func (fn LeaserFactoryFunc) NewLeaser(ctx context.Context, config LeaserConfig) (Leaser, error) {
+	return fn(ctx, config)
+}
+
+// LeaserConfig holds the settings passed to a LeaserFactory.
+type LeaserConfig struct {
+	// Lease type the factory was registered under.
+	Type string
+
+	// Hostname & API URL of the local node to advertise when primary.
+	Hostname     string
+	AdvertiseURL string
+
+	// Specifies if this node can become primary.
+	Candidate bool
+
+	// Decode unmarshals the backend-specific settings from "lease.options"
+	// into v. It is a no-op if no options were specified.
+	Decode func(v any) error
+}
+
+var leaserRegistry = struct {
+	mu        sync.RWMutex
+	factories map[string]LeaserFactory
+}{factories: make(map[string]LeaserFactory)}
+
+// RegisterLeaser makes a leaser factory available by the given lease type.
+// It is typically called from the init() function of the package that
+// implements the leaser. Panics if factory is nil or if a factory has
+// already been registered under the same type.
+//
+// The built-in lease types ("consul", "dns", "etcd", "kubernetes", & "static")
+// are always checked first so registering a factory under those names has no
+// effect.
+func RegisterLeaser(typ string, factory LeaserFactory) {
+	if factory == nil {
+		panic("litefs: register leaser factory is nil")
+	}
+
+	leaserRegistry.mu.Lock()
+	defer leaserRegistry.mu.Unlock()
+
+	if _, ok := leaserRegistry.factories[typ]; ok {
+		panic("litefs: register leaser called twice for type: " + typ)
+	}
+	leaserRegistry.factories[typ] = factory
+}
+
+// LookupLeaser returns the leaser factory registered for a lease type.
+// Returns nil if no factory has been registered.
+func LookupLeaser(typ string) LeaserFactory {
+	leaserRegistry.mu.RLock()
+	defer leaserRegistry.mu.RUnlock()
+	return leaserRegistry.factories[typ]
+}
+
+// LeaserTypes returns a sorted list of registered lease types.
+func LeaserTypes() []string {
+	leaserRegistry.mu.RLock()
+	defer leaserRegistry.mu.RUnlock()
+
+	a := make([]string, 0, len(leaserRegistry.factories))
+	for typ := range leaserRegistry.factories {
+		a = append(a, typ)
+	}
+	sort.Strings(a)
+	return a
+}
+
 // StaticLeaser always returns a lease to a static primary.
 type StaticLeaser struct {
 	isPrimary    bool
@@ -156,6 +251,9 @@ type StaticLease struct {
 // ID always returns a blank string.
 func (l *StaticLease) ID() string { return "" }
 
+// Epoch always returns 1 as a static primary never changes.
+func (l *StaticLease) Epoch() uint64 { return 1 }
+
 // RenewedAt returns the Unix epoch in UTC.
 func (l *StaticLease) RenewedAt() time.Time { return time.Unix(0, 0).UTC() }
 
@@ -76,6 +76,54 @@ func TestStaticLease(t *testing.T) {
 	}
 }
 
+func TestRegisterLeaser(t *testing.T) {
+	factory := litefs.LeaserFactoryFunc(func(ctx context.Context, config litefs.LeaserConfig) (litefs.Leaser, error) {
+		var opt struct {
+			Primary bool `yaml:"primary"`
+		}
+		if err := config.Decode(&opt); err != nil {
+			return nil, err
+		}
+		return litefs.NewStaticLeaser(opt.Primary, config.Hostname, config.AdvertiseURL), nil
+	})
+	litefs.RegisterLeaser("test-register", factory)
+
+	if litefs.LookupLeaser("test-register") == nil {
+		t.Fatal("expected factory")
+	} else if litefs.LookupLeaser("test-no-such-type") != nil {
+		t.Fatal("expected no factory")
+	}
+
+	var found bool
+	for _, typ := range litefs.LeaserTypes() {
+		found = found || typ == "test-register"
+	}
+	if !found {
+		t.Fatalf("type not found: %v", litefs.LeaserTypes())
+	}
+
+	leaser, err := litefs.LookupLeaser("test-register").NewLeaser(context.Background(), litefs.LeaserConfig{
+		Type:         "test-register",
+		Hostname:     "localhost",
+		AdvertiseURL: "http://localhost:20202",
+		Decode:       func(v any) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := leaser.Hostname(), "localhost"; got != want {
+		t.Fatalf("Hostname=%q, want %q", got, want)
+	}
+
+	t.Run("ErrDuplicate", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		litefs.RegisterLeaser("test-register", factory)
+	})
+}
+
 // newPrimaryStaticLeaser returns a new instance of StaticLeaser for primary node testing.
 func newPrimaryStaticLeaser() *litefs.StaticLeaser {
 	return litefs.NewStaticLeaser(true, "localhost", "http://localhost:20202")
@@ -13,8 +13,10 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
+	"github.com/superfly/ltx"
 	"golang.org/x/exp/slog"
 )
 
@@ -59,14 +61,39 @@ func ValidateClusterID(id string) error {
 
 // NodeInfo represents basic info about a node.
 type NodeInfo struct {
+	ID        string `json:"id"`                  // node ID
 	ClusterID string `json:"clusterID,omitempty"` // cluster ID
+	Hostname  string `json:"hostname,omitempty"`  // hostname of this node
 	IsPrimary bool   `json:"isPrimary"`           // if true, node is currently primary
 	Candidate bool   `json:"candidate"`           // if true, node is eligible to be primary
 	Path      string `json:"path"`                // data directory
+	Lag       int64  `json:"lag"`                 // replication lag behind primary, in ms
+	Paused    bool   `json:"paused,omitempty"`    // if true, replication is paused
 
 	Primary struct {
-		Hostname string `json:"hostname"`
+		Hostname     string `json:"hostname"`
+		AdvertiseURL string `json:"advertiseURL,omitempty"`
 	} `json:"primary"`
+
+	// Lease held by the node. Only set on the primary.
+	Lease *LeaseInfo `json:"lease,omitempty"`
+
+	// Replicas currently streaming from the node. Only set on the primary.
+	Replicas []ReplicaInfo `json:"replicas,omitempty"`
+}
+
+// LeaseInfo represents the state of the primary lease.
+type LeaseInfo struct {
+	ID        string    `json:"id"`
+	Epoch     uint64    `json:"epoch"`
+	RenewedAt time.Time `json:"renewedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ReplicaInfo represents a replica connected to the primary.
+type ReplicaInfo struct {
+	ID    string              `json:"id"`    // node ID
+	TXIDs map[string]ltx.TXID `json:"txids"` // last TXID sent, by database
 }
 
 // Environment represents an interface for interacting with the host environment.
@@ -92,15 +119,32 @@ var NativeEndian = binary.LittleEndian
 var (
 	ErrDatabaseNotFound = fmt.Errorf("database not found")
 	ErrDatabaseExists   = fmt.Errorf("database already exists")
+	ErrDatabaseBusy     = errors.New("database in use")
+
+	ErrRenameNotSupported = errors.New("database rename not supported with backup enabled")
 
-	ErrNoPrimary     = errors.New("no primary")
-	ErrPrimaryExists = errors.New("primary exists")
-	ErrNotEligible   = errors.New("not eligible to become primary")
-	ErrLeaseExpired  = errors.New("lease expired")
-	ErrNoHaltPrimary = errors.New("no remote halt needed on primary node")
+	ErrNoPrimary        = errors.New("no primary")
+	ErrPrimaryExists    = errors.New("primary exists")
+	ErrNotEligible      = errors.New("not eligible to become primary")
+	ErrLeaseExpired     = errors.New("lease expired")
+	ErrStaleEpoch       = errors.New("stale lease epoch")
+	ErrNoHaltPrimary    = errors.New("no remote halt needed on primary node")
+	ErrPausePrimary     = errors.New("cannot pause replication on primary")
+	ErrPauseSyncReplica = errors.New("cannot pause replication with synchronous replication enabled")
 
 	ErrReadOnlyReplica  = fmt.Errorf("read only replica")
 	ErrDuplicateLTXFile = fmt.Errorf("duplicate ltx file")
+	ErrStreamTimeout    = errors.New("replication stream timeout")
+	ErrSyncReplication  = errors.New("synchronous replication timeout")
+
+	ErrDBCorrupted      = errors.New("database corrupted")
+	ErrChecksumMismatch = errors.New("database checksum mismatch")
+	ErrPositionMismatch = errors.New("position mismatch")
+	ErrTXIDOverflow     = errors.New("transaction id overflow")
+	ErrTxTooLarge       = errors.New("transaction exceeds size limit")
+	ErrInvalidLTXFile   = errors.New("invalid ltx file")
+	ErrPartialLTXFile   = errors.New("partial ltx file")
+	ErrDBIncomplete     = errors.New("database pages not fully fetched")
 )
 
 // SQLite constants
@@ -573,6 +617,21 @@ func (s *GuardSet) Guard(lockType LockType) *RWMutexGuard {
 	}
 }
 
+// Locked returns true if any of the guards are holding a lock.
+func (s *GuardSet) Locked() bool {
+	for _, g := range []*RWMutexGuard{
+		&s.pending, &s.shared, &s.reserved,
+		&s.write, &s.ckpt, &s.recover,
+		&s.read0, &s.read1, &s.read2, &s.read3, &s.read4,
+		&s.dms,
+	} {
+		if g.State() != RWMutexStateUnlocked {
+			return true
+		}
+	}
+	return false
+}
+
 // Unlock unlocks all the guards in reversed order that they are acquired by SQLite.
 func (s *GuardSet) Unlock() {
 	s.UnlockDatabase()
@@ -668,6 +727,103 @@ const (
 	DBModeWAL      = DBMode(1)
 )
 
+// CacheInvalidationStrategy represents how the kernel page cache is
+// invalidated when LTX files are applied to a database.
+type CacheInvalidationStrategy int
+
+// Cache invalidation strategies.
+const (
+	// InvalidatePerPage invalidates each page as it is written. This keeps
+	// the most data cached but issues a call to the kernel for every page.
+	InvalidatePerPage = CacheInvalidationStrategy(0)
+
+	// InvalidateAll invalidates the entire database file once the LTX file
+	// has been applied. This is useful for large transactions that touch a
+	// significant portion of the database.
+	InvalidateAll = CacheInvalidationStrategy(1)
+
+	// NoInvalidate skips database page invalidation entirely. Readers may see
+	// stale pages from the kernel cache so this is only appropriate when the
+	// application does not read from the mount while replicating.
+	NoInvalidate = CacheInvalidationStrategy(2)
+)
+
+// ParseCacheInvalidationStrategy returns the strategy for its string representation.
+func ParseCacheInvalidationStrategy(s string) (CacheInvalidationStrategy, error) {
+	switch s {
+	case "per-page":
+		return InvalidatePerPage, nil
+	case "all":
+		return InvalidateAll, nil
+	case "none":
+		return NoInvalidate, nil
+	default:
+		return 0, fmt.Errorf("invalid cache invalidation strategy: %q", s)
+	}
+}
+
+// String returns the string representation of v.
+func (v CacheInvalidationStrategy) String() string {
+	switch v {
+	case InvalidatePerPage:
+		return "per-page"
+	case InvalidateAll:
+		return "all"
+	case NoInvalidate:
+		return "none"
+	default:
+		return fmt.Sprintf("CacheInvalidationStrategy<%d>", v)
+	}
+}
+
+// ResyncMode represents how a replica handles a database whose position
+// cannot be reconciled with the primary, such as after a failover that lost
+// transactions.
+type ResyncMode int
+
+// Resync modes.
+const (
+	// ResyncModeFail reports the error & retries until the database is
+	// repaired manually.
+	ResyncModeFail = ResyncMode(0)
+
+	// ResyncModeAuto discards the local database & resyncs it from a
+	// snapshot of the primary.
+	ResyncModeAuto = ResyncMode(1)
+
+	// ResyncModePrompt suspends replication to the database until an
+	// operator resyncs it with Store.ResyncDB().
+	ResyncModePrompt = ResyncMode(2)
+)
+
+// ParseResyncMode returns the resync mode for its string representation.
+func ParseResyncMode(s string) (ResyncMode, error) {
+	switch s {
+	case "fail":
+		return ResyncModeFail, nil
+	case "auto":
+		return ResyncModeAuto, nil
+	case "prompt":
+		return ResyncModePrompt, nil
+	default:
+		return 0, fmt.Errorf("invalid resync mode: %q", s)
+	}
+}
+
+// String returns the string representation of v.
+func (v ResyncMode) String() string {
+	switch v {
+	case ResyncModeFail:
+		return "fail"
+	case ResyncModeAuto:
+		return "auto"
+	case ResyncModePrompt:
+		return "prompt"
+	default:
+		return fmt.Sprintf("ResyncMode<%d>", v)
+	}
+}
+
 // walIndexHdr is copied from wal.c
 type walIndexHdr struct {
 	version     uint32    // Wal-index version
@@ -0,0 +1,105 @@
+package litefs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// Log subsystems. Each subsystem can be assigned its own log level.
+const (
+	LogSubsystemFUSE  = "fuse"
+	LogSubsystemStore = "store"
+	LogSubsystemHTTP  = "http"
+	LogSubsystemLease = "lease"
+)
+
+// LogSubsystems returns a sorted list of all log subsystem names.
+func LogSubsystems() []string {
+	return []string{LogSubsystemFUSE, LogSubsystemHTTP, LogSubsystemLease, LogSubsystemStore}
+}
+
+// subsystemLogLevels holds level overrides by subsystem name. Subsystems
+// without an override use the global LogLevel.
+var subsystemLogLevels struct {
+	mu sync.RWMutex
+	m  map[string]slog.Level
+}
+
+// SetSubsystemLogLevels replaces the log level overrides for each subsystem.
+// Subsystems that are not specified revert to the global LogLevel.
+func SetSubsystemLogLevels(m map[string]slog.Level) error {
+	other := make(map[string]slog.Level, len(m))
+	for name, level := range m {
+		if !isLogSubsystem(name) {
+			return fmt.Errorf("invalid log subsystem: %q", name)
+		}
+		other[name] = level
+	}
+
+	subsystemLogLevels.mu.Lock()
+	defer subsystemLogLevels.mu.Unlock()
+	subsystemLogLevels.m = other
+	return nil
+}
+
+// SubsystemLogLevel returns the current log level of a subsystem.
+func SubsystemLogLevel(name string) slog.Level {
+	subsystemLogLevels.mu.RLock()
+	level, ok := subsystemLogLevels.m[name]
+	subsystemLogLevels.mu.RUnlock()
+	if ok {
+		return level
+	}
+	return LogLevel.Level()
+}
+
+func isLogSubsystem(name string) bool {
+	a := LogSubsystems()
+	i := sort.SearchStrings(a, name)
+	return i < len(a) && a[i] == name
+}
+
+// Logger returns a logger for a subsystem. Records include a "subsystem"
+// attribute & are filtered by the subsystem's log level instead of the
+// global level. Records are written to the default slog handler at the
+// time of logging so the logger can be created before logging is set up.
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem}).With(slog.String("subsystem", subsystem))
+}
+
+// subsystemHandler filters records by subsystem level & passes them to the
+// default handler.
+type subsystemHandler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler // attrs & groups to apply to the default handler
+}
+
+func (h *subsystemHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= SubsystemLogLevel(h.subsystem)
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	handler := slog.Default().Handler()
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(op func(slog.Handler) slog.Handler) *subsystemHandler {
+	other := &subsystemHandler{subsystem: h.subsystem}
+	other.ops = append(append(other.ops, h.ops...), op)
+	return other
+}
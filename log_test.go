@@ -0,0 +1,57 @@
+package litefs_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/superfly/litefs"
+	"golang.org/x/exp/slog"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: &litefs.LogLevel})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	t.Run("Attrs", func(t *testing.T) {
+		buf.Reset()
+		litefs.Logger(litefs.LogSubsystemStore).With(slog.String("db", "db")).Info("hello", slog.String("txid", "0000000000000001"))
+		if got, want := buf.String(), `"msg":"hello","subsystem":"store","db":"db","txid":"0000000000000001"}`; !strings.Contains(got, want) {
+			t.Fatalf("output=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("SubsystemLevel", func(t *testing.T) {
+		if err := litefs.SetSubsystemLogLevels(map[string]slog.Level{
+			litefs.LogSubsystemFUSE: slog.LevelDebug,
+			litefs.LogSubsystemHTTP: slog.LevelError,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = litefs.SetSubsystemLogLevels(nil) })
+
+		buf.Reset()
+		litefs.Logger(litefs.LogSubsystemFUSE).Debug("fuse debug")
+		litefs.Logger(litefs.LogSubsystemHTTP).Warn("http warn")
+		litefs.Logger(litefs.LogSubsystemStore).Debug("store debug")
+		litefs.Logger(litefs.LogSubsystemStore).Info("store info")
+
+		if got := buf.String(); !strings.Contains(got, "fuse debug") {
+			t.Fatalf("expected fuse debug log: %s", got)
+		} else if strings.Contains(got, "http warn") {
+			t.Fatalf("unexpected http warn log: %s", got)
+		} else if strings.Contains(got, "store debug") {
+			t.Fatalf("unexpected store debug log: %s", got)
+		} else if !strings.Contains(got, "store info") {
+			t.Fatalf("expected store info log: %s", got)
+		}
+	})
+
+	t.Run("ErrInvalidSubsystem", func(t *testing.T) {
+		if err := litefs.SetSubsystemLogLevels(map[string]slog.Level{"nosuch": slog.LevelDebug}); err == nil || err.Error() != `invalid log subsystem: "nosuch"` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -14,7 +14,9 @@ type Client struct {
 	AcquireHaltLockFunc func(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) (*litefs.HaltLock, error)
 	ReleaseHaltLockFunc func(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) error
 	CommitFunc          func(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64, r io.Reader) error
-	StreamFunc          func(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (litefs.Stream, error)
+	StreamFunc          func(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error)
+	AckFunc             func(ctx context.Context, primaryURL string, nodeID uint64, name string, txID ltx.TXID) error
+	FetchPageFunc       func(ctx context.Context, primaryURL string, nodeID uint64, name string, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error)
 }
 
 func (c *Client) AcquireHaltLock(ctx context.Context, primaryURL string, nodeID uint64, name string, lockID int64) (*litefs.HaltLock, error) {
@@ -29,13 +31,24 @@ func (c *Client) Commit(ctx context.Context, primaryURL string, nodeID uint64, n
 	return c.CommitFunc(ctx, primaryURL, nodeID, name, lockID, r)
 }
 
-func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (litefs.Stream, error) {
-	return c.StreamFunc(ctx, primaryURL, nodeID, posMap, filter)
+func (c *Client) Stream(ctx context.Context, primaryURL string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+	return c.StreamFunc(ctx, primaryURL, nodeID, posMap, filter, partials)
+}
+
+func (c *Client) Ack(ctx context.Context, primaryURL string, nodeID uint64, name string, txID ltx.TXID) error {
+	return c.AckFunc(ctx, primaryURL, nodeID, name, txID)
+}
+
+func (c *Client) FetchPage(ctx context.Context, primaryURL string, nodeID uint64, name string, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error) {
+	return c.FetchPageFunc(ctx, primaryURL, nodeID, name, pgno, txID)
 }
 
 type Stream struct {
 	io.ReadCloser
 	ClusterIDFunc func() string
+	EpochFunc     func() uint64
 }
 
 func (s *Stream) ClusterID() string { return s.ClusterIDFunc() }
+
+func (s *Stream) Epoch() uint64 { return s.EpochFunc() }
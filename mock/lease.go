@@ -58,6 +58,7 @@ var _ litefs.Lease = (*Lease)(nil)
 
 type Lease struct {
 	IDFunc        func() string
+	EpochFunc     func() uint64
 	RenewedAtFunc func() time.Time
 	TTLFunc       func() time.Duration
 	RenewFunc     func(ctx context.Context) error
@@ -70,6 +71,10 @@ func (l *Lease) ID() string {
 	return l.IDFunc()
 }
 
+func (l *Lease) Epoch() uint64 {
+	return l.EpochFunc()
+}
+
 func (l *Lease) RenewedAt() time.Time {
 	return l.RenewedAtFunc()
 }
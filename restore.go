@@ -0,0 +1,271 @@
+package litefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/superfly/ltx"
+)
+
+// ErrNoRestorePoint is returned when the LTX files available for a database
+// cannot be used to reconstruct it at the requested point in time.
+var ErrNoRestorePoint = errors.New("no restore point available")
+
+// RestoreSource provides access to the historical LTX files of a database.
+// It is implemented by backup clients that keep individual LTX files and by
+// the local LTX directories of a store.
+type RestoreSource interface {
+	// LTXFiles returns the TXID ranges of all LTX files available for a
+	// database, sorted by TXID.
+	LTXFiles(ctx context.Context, name string) ([]LTXFileInfo, error)
+
+	// OpenLTXFile returns a reader for a single LTX file.
+	OpenLTXFile(ctx context.Context, name string, minTXID, maxTXID ltx.TXID) (io.ReadCloser, error)
+}
+
+// LTXFileInfo represents the TXID range of an LTX file.
+type LTXFileInfo struct {
+	MinTXID ltx.TXID
+	MaxTXID ltx.TXID
+}
+
+// RestoreOptions specifies the point in time to restore a database to. If
+// both fields are zero then the latest available position is restored.
+type RestoreOptions struct {
+	// Restore up to and including this transaction.
+	TXID ltx.TXID
+
+	// Restore transactions committed at or before this time.
+	Timestamp time.Time
+}
+
+// Restore reconstructs the database by replaying LTX files from src onto the
+// latest snapshot before the restore point. The database contents are written
+// to w and the position of the restored database is returned.
+//
+// A snapshot is an LTX file starting at TXID 1. Returns ErrNoRestorePoint if
+// src has no snapshot at or before the restore point. Returns an error naming
+// the TXID if a file's pre-apply checksum does not match the post-apply
+// checksum of the file before it.
+func Restore(ctx context.Context, src RestoreSource, name string, w io.Writer, opt RestoreOptions) (ltx.Pos, error) {
+	infos, err := src.LTXFiles(ctx, name)
+	if err != nil {
+		return ltx.Pos{}, err
+	} else if len(infos) == 0 {
+		return ltx.Pos{}, ErrDatabaseNotFound
+	}
+
+	files, err := restoreChain(ctx, src, name, infos, opt)
+	if err != nil {
+		return ltx.Pos{}, err
+	}
+
+	var fileRdrs []*restoreFileReader
+	defer func() {
+		for _, r := range fileRdrs {
+			_ = r.Close()
+		}
+	}()
+	rdrs := make([]io.Reader, 0, len(files))
+	for _, info := range files {
+		rc, err := src.OpenLTXFile(ctx, name, info.MinTXID, info.MaxTXID)
+		if err != nil {
+			return ltx.Pos{}, err
+		}
+		r := &restoreFileReader{ReadCloser: rc}
+		fileRdrs = append(fileRdrs, r)
+		rdrs = append(rdrs, r)
+	}
+
+	// Compact files into a single snapshot & decode it as a database file.
+	// The compactor must finish before the deferred close of the readers.
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := ltx.NewCompactor(pw, rdrs).Compact(ctx)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+
+	dec := ltx.NewDecoder(pr)
+	err = dec.DecodeDatabaseTo(w)
+	_ = pr.Close()
+	if compactErr := <-done; compactErr != nil && err == nil {
+		err = compactErr
+	}
+	if err != nil {
+		return ltx.Pos{}, fmt.Errorf("decode database: %w", err)
+	}
+
+	// Ensure each file applies on top of the state left by the previous file.
+	if err := verifyRestoreChain(fileRdrs); err != nil {
+		return ltx.Pos{}, err
+	}
+	return dec.PostApplyPos(), nil
+}
+
+// verifyRestoreChain returns an error if the pre-apply checksum of a file does
+// not match the post-apply checksum of the file before it. The compactor only
+// checks that TXIDs are contiguous so this catches files from a different
+// history of the database, such as after a primary was restored.
+func verifyRestoreChain(rdrs []*restoreFileReader) error {
+	for i := 1; i < len(rdrs); i++ {
+		var hdr ltx.Header
+		var prevTrailer ltx.Trailer
+		if err := hdr.UnmarshalBinary(rdrs[i].head); err != nil {
+			return fmt.Errorf("decode ltx header: %w", err)
+		} else if err := prevTrailer.UnmarshalBinary(rdrs[i-1].tail); err != nil {
+			return fmt.Errorf("decode ltx trailer: %w", err)
+		}
+
+		if hdr.PreApplyChecksum != prevTrailer.PostApplyChecksum {
+			return fmt.Errorf("pre-apply checksum %s on TXID %s does not match previous post-apply checksum %s",
+				hdr.PreApplyChecksum, hdr.MinTXID.String(), prevTrailer.PostApplyChecksum)
+		}
+	}
+	return nil
+}
+
+// restoreFileReader records the header & trailer of an LTX file as it is read
+// so the checksum chain can be verified without reading the file twice.
+type restoreFileReader struct {
+	io.ReadCloser
+	head []byte
+	tail []byte
+}
+
+func (r *restoreFileReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	b := p[:n]
+
+	if need := ltx.HeaderSize - len(r.head); need > 0 {
+		r.head = append(r.head, b[:min(need, len(b))]...)
+	}
+
+	if len(b) >= ltx.TrailerSize {
+		r.tail = append(r.tail[:0], b[len(b)-ltx.TrailerSize:]...)
+	} else if r.tail = append(r.tail, b...); len(r.tail) > ltx.TrailerSize {
+		r.tail = append(r.tail[:0], r.tail[len(r.tail)-ltx.TrailerSize:]...)
+	}
+	return n, err
+}
+
+// restoreChain returns the contiguous list of files, starting from a
+// snapshot, that are required to restore the database to the restore point.
+func restoreChain(ctx context.Context, src RestoreSource, name string, infos []LTXFileInfo, opt RestoreOptions) ([]LTXFileInfo, error) {
+	// Returns true if the file is at or before the restore point.
+	before := func(info LTXFileInfo) (bool, error) {
+		if opt.TXID != 0 && info.MaxTXID > opt.TXID {
+			return false, nil
+		} else if opt.Timestamp.IsZero() {
+			return true, nil
+		}
+
+		rc, err := src.OpenLTXFile(ctx, name, info.MinTXID, info.MaxTXID)
+		if err != nil {
+			return false, err
+		}
+		defer func() { _ = rc.Close() }()
+
+		hdr, _, err := ltx.DecodeHeader(rc)
+		if err != nil {
+			return false, fmt.Errorf("decode ltx header: %w", err)
+		}
+		return !time.UnixMilli(hdr.Timestamp).After(opt.Timestamp), nil
+	}
+
+	// Find the latest snapshot before the restore point.
+	start := -1
+	for i := len(infos) - 1; i >= 0; i-- {
+		if infos[i].MinTXID != 1 {
+			continue
+		} else if ok, err := before(infos[i]); err != nil {
+			return nil, err
+		} else if ok {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil, fmt.Errorf("%w: no snapshot before restore point", ErrNoRestorePoint)
+	}
+
+	// Add contiguous files until the restore point is reached.
+	files := []LTXFileInfo{infos[start]}
+	for _, info := range infos[start+1:] {
+		if info.MinTXID != files[len(files)-1].MaxTXID+1 {
+			continue
+		} else if ok, err := before(info); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		files = append(files, info)
+	}
+
+	if max := files[len(files)-1].MaxTXID; opt.TXID != 0 && max != opt.TXID {
+		return nil, fmt.Errorf("%w: nearest transaction before %s is %s", ErrNoRestorePoint, opt.TXID, max)
+	}
+	return files, nil
+}
+
+// readLTXDir returns the TXID ranges of the LTX files in dir, sorted by TXID.
+func readLTXDir(dir string) ([]LTXFileInfo, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var infos []LTXFileInfo
+	for _, ent := range ents {
+		minTXID, maxTXID, err := ltx.ParseFilename(ent.Name())
+		if err != nil || ent.IsDir() {
+			continue
+		}
+		infos = append(infos, LTXFileInfo{MinTXID: minTXID, MaxTXID: maxTXID})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].MaxTXID != infos[j].MaxTXID {
+			return infos[i].MaxTXID < infos[j].MaxTXID
+		}
+		return infos[i].MinTXID > infos[j].MinTXID
+	})
+	return infos, nil
+}
+
+var _ RestoreSource = (*DataDirRestoreSource)(nil)
+
+// DataDirRestoreSource reads LTX files from the data directory of a store.
+// Only files still within the store's retention period are available. The
+// data directory only holds a snapshot after the first transaction or after a
+// replica receives one from the primary so it is usually unable to restore
+// once retention has removed it.
+type DataDirRestoreSource struct {
+	path string
+}
+
+// NewDataDirRestoreSource returns a new instance of DataDirRestoreSource.
+func NewDataDirRestoreSource(path string) *DataDirRestoreSource {
+	return &DataDirRestoreSource{path: path}
+}
+
+func (s *DataDirRestoreSource) ltxDir(name string) string {
+	return filepath.Join(s.path, "dbs", name, "ltx")
+}
+
+// LTXFiles returns the TXID ranges of all LTX files available for a database.
+func (s *DataDirRestoreSource) LTXFiles(ctx context.Context, name string) ([]LTXFileInfo, error) {
+	return readLTXDir(s.ltxDir(name))
+}
+
+// OpenLTXFile returns a reader for a single LTX file.
+func (s *DataDirRestoreSource) OpenLTXFile(ctx context.Context, name string, minTXID, maxTXID ltx.TXID) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.ltxDir(name), ltx.FormatFilename(minTXID, maxTXID)))
+}
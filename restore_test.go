@@ -0,0 +1,189 @@
+package litefs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/ltx"
+)
+
+func TestRestore(t *testing.T) {
+	// Writes three transactions to a backup client, one minute apart.
+	newBackupClient := func(tb testing.TB) *litefs.FileBackupClient {
+		c := newOpenFileBackupClient(tb)
+		ts := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for _, spec := range []*ltx.FileSpec{
+			{
+				Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1, Timestamp: ts.UnixMilli()},
+				Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+				Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+			},
+			{
+				Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 2, MaxTXID: 2, PreApplyChecksum: 0xe4e4aaa102377eee, Timestamp: ts.Add(1 * time.Minute).UnixMilli()},
+				Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)}},
+				Trailer: ltx.Trailer{PostApplyChecksum: 0xad2dffe333333333},
+			},
+			{
+				Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 3, MaxTXID: 3, PreApplyChecksum: 0xad2dffe333333333, Timestamp: ts.Add(2 * time.Minute).UnixMilli()},
+				Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{3}, 512)}},
+				Trailer: ltx.Trailer{PostApplyChecksum: 0x8b87423eeeeeeeee},
+			},
+		} {
+			if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(tb, spec)); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		return c
+	}
+
+	t.Run("Latest", func(t *testing.T) {
+		var buf bytes.Buffer
+		if pos, err := litefs.Restore(context.Background(), newBackupClient(t), "db", &buf, litefs.RestoreOptions{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := pos, (ltx.Pos{TXID: 3, PostApplyChecksum: 0x8b87423eeeeeeeee}); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+		if got, want := buf.Bytes(), append(bytes.Repeat([]byte{3}, 512), bytes.Repeat([]byte{2}, 512)...); !bytes.Equal(got, want) {
+			t.Fatal("database mismatch")
+		}
+	})
+
+	t.Run("TXID", func(t *testing.T) {
+		var buf bytes.Buffer
+		if pos, err := litefs.Restore(context.Background(), newBackupClient(t), "db", &buf, litefs.RestoreOptions{TXID: 2}); err != nil {
+			t.Fatal(err)
+		} else if got, want := pos, (ltx.Pos{TXID: 2, PostApplyChecksum: 0xad2dffe333333333}); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+		if got, want := buf.Bytes(), append(bytes.Repeat([]byte{1}, 512), bytes.Repeat([]byte{2}, 512)...); !bytes.Equal(got, want) {
+			t.Fatal("database mismatch")
+		}
+	})
+
+	t.Run("Timestamp", func(t *testing.T) {
+		var buf bytes.Buffer
+		opt := litefs.RestoreOptions{Timestamp: time.Date(2000, 1, 1, 0, 0, 30, 0, time.UTC)}
+		if pos, err := litefs.Restore(context.Background(), newBackupClient(t), "db", &buf, opt); err != nil {
+			t.Fatal(err)
+		} else if got, want := pos, (ltx.Pos{TXID: 1, PostApplyChecksum: 0xe4e4aaa102377eee}); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+		if got, want := buf.Bytes(), bytes.Repeat([]byte{1}, 512); !bytes.Equal(got, want) {
+			t.Fatal("database mismatch")
+		}
+	})
+
+	t.Run("ErrNoRestorePoint", func(t *testing.T) {
+		c := newBackupClient(t)
+
+		var buf bytes.Buffer
+		if _, err := litefs.Restore(context.Background(), c, "db", &buf, litefs.RestoreOptions{TXID: 10}); !errors.Is(err, litefs.ErrNoRestorePoint) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		opt := litefs.RestoreOptions{Timestamp: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)}
+		if _, err := litefs.Restore(context.Background(), c, "db", &buf, opt); !errors.Is(err, litefs.ErrNoRestorePoint) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrChecksumMismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		ltxDir := filepath.Join(dir, "dbs", "db", "ltx")
+		if err := os.MkdirAll(ltxDir, 0o777); err != nil {
+			t.Fatal(err)
+		}
+
+		// Write a second file that does not apply on top of the snapshot.
+		for _, spec := range []*ltx.FileSpec{
+			{
+				Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1, Timestamp: 1000},
+				Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+				Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+			},
+			{
+				Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 2, MaxTXID: 2, PreApplyChecksum: 0x8000000000000001, Timestamp: 2000},
+				Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)}},
+				Trailer: ltx.Trailer{PostApplyChecksum: 0xad2dffe333333333},
+			},
+		} {
+			var buf bytes.Buffer
+			if _, err := spec.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			} else if err := os.WriteFile(filepath.Join(ltxDir, ltx.FormatFilename(spec.Header.MinTXID, spec.Header.MaxTXID)), buf.Bytes(), 0o666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if _, err := litefs.Restore(context.Background(), litefs.NewDataDirRestoreSource(dir), "db", &buf, litefs.RestoreOptions{}); err == nil || err.Error() != `pre-apply checksum 8000000000000001 on TXID 0000000000000002 does not match previous post-apply checksum e4e4aaa102377eee` {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDatabaseNotFound", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := litefs.Restore(context.Background(), newBackupClient(t), "nosuchdb", &buf, litefs.RestoreOptions{}); err != litefs.ErrDatabaseNotFound {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestStore_RestoreDB(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db := newStoreDB(t, store, "test.db")
+
+		var buf bytes.Buffer
+		if pos, err := store.RestoreDB(context.Background(), "test.db", &buf, litefs.RestoreOptions{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := pos, db.Pos(); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		} else if got, want := buf.Bytes(), newSQLitePage1(); !bytes.Equal(got, want) {
+			t.Fatal("database mismatch")
+		}
+	})
+
+	// Ensure the backup client is used once retention removes the snapshot.
+	t.Run("BackupClient", func(t *testing.T) {
+		client := newOpenFileBackupClient(t)
+		store := newStore(t, newPrimaryStaticLeaser(), nil)
+		store.BackupClient = client
+		store.BackupDelay = 0
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+		db := newStoreDB(t, store, "test.db")
+
+		if err := store.SyncBackup(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(db.LTXPath(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if pos, err := store.RestoreDB(context.Background(), "test.db", &buf, litefs.RestoreOptions{}); err != nil {
+			t.Fatal(err)
+		} else if got, want := pos, db.Pos(); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("ErrNoRestorePoint", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		newStoreDB(t, store, "test.db")
+
+		var buf bytes.Buffer
+		if _, err := store.RestoreDB(context.Background(), "test.db", &buf, litefs.RestoreOptions{TXID: 2}); !errors.Is(err, litefs.ErrNoRestorePoint) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
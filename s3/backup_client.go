@@ -0,0 +1,760 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/superfly/litefs"
+	"github.com/superfly/ltx"
+	"golang.org/x/exp/slog"
+)
+
+// logger is the logger for the store subsystem.
+var logger = litefs.Logger(litefs.LogSubsystemStore)
+
+// Default settings.
+const (
+	DefaultRegion           = "us-east-1"
+	DefaultSnapshotInterval = 24 * time.Hour
+	DefaultRetention        = 24 * time.Hour
+)
+
+var (
+	_ litefs.BackupClient  = (*BackupClient)(nil)
+	_ litefs.RestoreSource = (*BackupClient)(nil)
+)
+
+// BackupClient implements a backup client that stores LTX files in an
+// S3-compatible bucket. Each database is stored under its own prefix with one
+// object per LTX file. Snapshots are periodically compacted from the
+// incremental files so that older files can be removed after the retention
+// period. Replicas restore from the bucket when no primary is reachable.
+type BackupClient struct {
+	mu            sync.Mutex
+	positions     map[string]ltx.Pos   // last known position, by database
+	lastSnapshots map[string]time.Time // last snapshot time, by database
+	snapshotting  map[string]bool      // databases with a snapshot in progress
+
+	closed bool           // if true, no new snapshots are started
+	wg     sync.WaitGroup // running background snapshots
+
+	// Bucket name & optional key prefix for all objects.
+	Bucket string
+	Prefix string
+
+	// S3 API endpoint. Defaults to AWS S3 for the region. Requests use
+	// path-style addressing so S3-compatible services work without DNS setup.
+	Endpoint string
+	Region   string
+
+	// Credentials used to sign requests.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Frequency that incremental LTX files are compacted into a snapshot.
+	SnapshotInterval time.Duration
+
+	// Time to keep LTX files after they have been compacted into a snapshot.
+	Retention time.Duration
+
+	HTTPClient *http.Client
+
+	// Returns the current time. Used for signing requests & retention.
+	Now func() time.Time
+}
+
+// NewBackupClient returns a new instance of BackupClient.
+func NewBackupClient(bucket, prefix string) *BackupClient {
+	return &BackupClient{
+		positions:     make(map[string]ltx.Pos),
+		lastSnapshots: make(map[string]time.Time),
+		snapshotting:  make(map[string]bool),
+
+		Bucket: bucket,
+		Prefix: strings.Trim(prefix, "/"),
+
+		Region:           DefaultRegion,
+		SnapshotInterval: DefaultSnapshotInterval,
+		Retention:        DefaultRetention,
+
+		HTTPClient: &http.Client{},
+		Now:        time.Now,
+	}
+}
+
+// Open validates the client configuration.
+func (c *BackupClient) Open() error {
+	if c.Bucket == "" {
+		return fmt.Errorf("s3 bucket required")
+	} else if c.Region == "" {
+		return fmt.Errorf("s3 region required")
+	} else if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("s3 credentials required")
+	}
+
+	if c.Endpoint == "" {
+		c.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.Region)
+	}
+	if u, err := url.Parse(c.Endpoint); err != nil {
+		return fmt.Errorf("cannot parse s3 endpoint: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid s3 endpoint scheme: %q", u.Scheme)
+	} else if u.Host == "" {
+		return fmt.Errorf("s3 endpoint host required: %q", c.Endpoint)
+	}
+
+	return nil
+}
+
+// Close prevents new background snapshots & waits for running ones to finish.
+func (c *BackupClient) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	return nil
+}
+
+// URL of the backup service.
+func (c *BackupClient) URL() string {
+	return (&url.URL{
+		Scheme: "s3",
+		Host:   c.Bucket,
+		Path:   "/" + c.Prefix,
+	}).String()
+}
+
+// PosMap returns the replication position for all databases on the backup
+// service. The positions are cached so that WriteTx does not need to list the
+// bucket on every transaction. The store fetches the position map at the start
+// of each backup stream so the cache is refreshed whenever a node becomes primary.
+func (c *BackupClient) PosMap(ctx context.Context) (map[string]ltx.Pos, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, prefixes, err := c.listObjects(ctx, c.dbPrefix(""), "/")
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]ltx.Pos)
+	for _, prefix := range prefixes {
+		name := path.Base(prefix)
+		pos, err := c.pos(ctx, name)
+		if err != nil {
+			return nil, err
+		} else if pos.IsZero() {
+			continue
+		}
+		m[name] = pos
+	}
+
+	c.positions = make(map[string]ltx.Pos, len(m))
+	for name, pos := range m {
+		c.positions[name] = pos
+	}
+	return m, nil
+}
+
+// pos returns the replication position for a single database. The TXID is
+// determined by the filename and the checksum is read from the trailer.
+func (c *BackupClient) pos(ctx context.Context, name string) (ltx.Pos, error) {
+	files, err := c.ltxFiles(ctx, name)
+	if err != nil {
+		return ltx.Pos{}, err
+	} else if len(files) == 0 {
+		return ltx.Pos{}, nil
+	}
+	last := files[len(files)-1]
+
+	rc, err := c.getObject(ctx, last.key, fmt.Sprintf("bytes=-%d", ltx.TrailerSize))
+	if err != nil {
+		return ltx.Pos{}, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	buf := make([]byte, ltx.TrailerSize)
+	var trailer ltx.Trailer
+	if _, err := io.ReadFull(rc, buf); err != nil {
+		return ltx.Pos{}, fmt.Errorf("read ltx trailer: %w", err)
+	} else if err := trailer.UnmarshalBinary(buf); err != nil {
+		return ltx.Pos{}, fmt.Errorf("unmarshal ltx trailer: %w", err)
+	}
+
+	return ltx.Pos{
+		TXID:              last.maxTXID,
+		PostApplyChecksum: trailer.PostApplyChecksum,
+	}, nil
+}
+
+// WriteTx writes an LTX file to the backup service. The file must be
+// contiguous with the latest LTX file on the backup service or else it
+// will return an ltx.PosMismatchError.
+func (c *BackupClient) WriteTx(ctx context.Context, name string, r io.Reader) (hwm ltx.TXID, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hdr, r, err := ltx.PeekHeader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Ensure LTX file is contiguous with current replication position. The
+	// position is only read from the bucket if it is not cached or if the
+	// cached position does not match.
+	pos, ok := c.positions[name]
+	if !ok || pos.TXID+1 != hdr.MinTXID || pos.PostApplyChecksum != hdr.PreApplyChecksum {
+		if pos, err = c.pos(ctx, name); err != nil {
+			return 0, err
+		}
+		c.positions[name] = pos
+	}
+	if pos.TXID+1 != hdr.MinTXID || pos.PostApplyChecksum != hdr.PreApplyChecksum {
+		return 0, ltx.NewPosMismatchError(pos)
+	}
+
+	// Buffer to a temporary file so the file can be verified before upload.
+	f, err := os.CreateTemp("", "litefs-s3-*.ltx")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	defer func() { _ = f.Close() }()
+
+	dec := ltx.NewDecoder(f)
+	if _, err := io.Copy(f, r); err != nil {
+		return 0, err
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	} else if err := dec.Verify(); err != nil {
+		return 0, err
+	}
+
+	// Remove the cached position until the upload is known to have succeeded.
+	delete(c.positions, name)
+	if err := c.putObject(ctx, c.ltxKey(name, hdr.MinTXID, hdr.MaxTXID), f); err != nil {
+		return 0, err
+	}
+	c.positions[name] = ltx.Pos{TXID: hdr.MaxTXID, PostApplyChecksum: dec.Trailer().PostApplyChecksum}
+
+	c.snapshotIfNeeded(name)
+
+	return hdr.MaxTXID, nil
+}
+
+// snapshotIfNeeded starts a background snapshot of a database if
+// SnapshotInterval has elapsed and no snapshot is already running for it.
+// Must be called while holding c.mu.
+func (c *BackupClient) snapshotIfNeeded(name string) {
+	if c.SnapshotInterval <= 0 || c.closed || c.snapshotting[name] {
+		return
+	}
+
+	now := c.Now()
+	if t, ok := c.lastSnapshots[name]; !ok {
+		c.lastSnapshots[name] = now
+		return
+	} else if now.Sub(t) < c.SnapshotInterval {
+		return
+	}
+	c.lastSnapshots[name] = now
+	c.snapshotting[name] = true
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		// Failure does not affect transactions since they are already stored.
+		if err := c.snapshot(context.Background(), name, now); err != nil {
+			logger.Warn("s3: cannot snapshot", slog.String("db", name), slog.Any("err", err))
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.snapshotting, name)
+	}()
+}
+
+// snapshot compacts the LTX files for a database into a new snapshot. LTX
+// files that are older than the retention period and are covered by the
+// snapshot are then deleted. Compaction runs without holding c.mu so that
+// transactions can continue to be written in the meantime.
+func (c *BackupClient) snapshot(ctx context.Context, name string, now time.Time) error {
+	files, err := c.ltxFiles(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Skip if the latest file is already a snapshot.
+	files = restoreFiles(files)
+	if len(files) < 2 {
+		return nil
+	}
+	snapshot := files[len(files)-1]
+	snapshot.minTXID, snapshot.key = 1, c.ltxKey(name, 1, snapshot.maxTXID)
+
+	if err := c.compactTo(ctx, snapshot.key, files); err != nil {
+		return err
+	}
+
+	// Remove files covered by the snapshot once they exceed retention. The lock
+	// is held so files are not removed while a snapshot is being fetched.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all, err := c.ltxFiles(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, file := range all {
+		if file.key == snapshot.key || file.maxTXID > snapshot.maxTXID {
+			continue
+		} else if now.Sub(file.lastModified) < c.Retention {
+			continue
+		}
+		if err := c.deleteObject(ctx, file.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compactTo compacts files into a single LTX file and uploads it to key.
+func (c *BackupClient) compactTo(ctx context.Context, key string, files []ltxFile) error {
+	f, err := os.CreateTemp("", "litefs-s3-*.ltx")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	defer func() { _ = f.Close() }()
+
+	rc, err := c.compact(ctx, files)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	if _, err := io.Copy(f, rc); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return c.putObject(ctx, key, f)
+}
+
+// FetchSnapshot requests a full snapshot of the database as it exists on
+// the backup service. This should be used if the LiteFS node has become
+// out of sync with the backup service.
+func (c *BackupClient) FetchSnapshot(ctx context.Context, name string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.ltxFiles(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an error if we have no LTX data for the database.
+	files = restoreFiles(files)
+	if len(files) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return c.compact(ctx, files)
+}
+
+// compact returns a reader that compacts files into a single snapshot.
+func (c *BackupClient) compact(ctx context.Context, files []ltxFile) (_ io.ReadCloser, retErr error) {
+	var rdrs []io.Reader
+	defer func() {
+		if retErr != nil {
+			for _, r := range rdrs {
+				_ = r.(io.Closer).Close()
+			}
+		}
+	}()
+	for _, file := range files {
+		rc, err := c.getObject(ctx, file.key, "")
+		if err != nil {
+			return nil, err
+		}
+		rdrs = append(rdrs, rc)
+	}
+
+	// Send compaction through a pipe so we can convert it to an io.Reader.
+	pr, pw := io.Pipe()
+	go func() {
+		defer func() {
+			for _, r := range rdrs {
+				_ = r.(io.Closer).Close()
+			}
+		}()
+
+		compactor := ltx.NewCompactor(pw, rdrs)
+		compactor.HeaderFlags = ltx.HeaderFlagCompressLZ4
+		_ = pw.CloseWithError(compactor.Compact(ctx))
+	}()
+	return pr, nil
+}
+
+// LTXFiles returns the TXID ranges of all LTX files stored for a database.
+func (c *BackupClient) LTXFiles(ctx context.Context, name string) ([]litefs.LTXFileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.ltxFiles(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]litefs.LTXFileInfo, len(files))
+	for i, file := range files {
+		infos[i] = litefs.LTXFileInfo{MinTXID: file.minTXID, MaxTXID: file.maxTXID}
+	}
+	return infos, nil
+}
+
+// OpenLTXFile returns a reader for a single LTX file.
+func (c *BackupClient) OpenLTXFile(ctx context.Context, name string, minTXID, maxTXID ltx.TXID) (io.ReadCloser, error) {
+	return c.getObject(ctx, c.ltxKey(name, minTXID, maxTXID), "")
+}
+
+// ltxFile represents an LTX file object in the bucket.
+type ltxFile struct {
+	key          string
+	minTXID      ltx.TXID
+	maxTXID      ltx.TXID
+	lastModified time.Time
+}
+
+// restoreFiles returns the latest snapshot and the files after it that are
+// required to restore the database. Files must be sorted by TXID.
+func restoreFiles(files []ltxFile) []ltxFile {
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].minTXID == 1 {
+			return files[i:]
+		}
+	}
+	return files
+}
+
+// ltxFiles returns all LTX files for a database, sorted by TXID range.
+func (c *BackupClient) ltxFiles(ctx context.Context, name string) ([]ltxFile, error) {
+	objects, _, err := c.listObjects(ctx, c.dbPrefix(name), "")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]ltxFile, 0, len(objects))
+	for _, obj := range objects {
+		minTXID, maxTXID, err := ltx.ParseFilename(path.Base(obj.Key))
+		if err != nil {
+			continue // skip non-ltx files
+		}
+		files = append(files, ltxFile{
+			key:          obj.Key,
+			minTXID:      minTXID,
+			maxTXID:      maxTXID,
+			lastModified: obj.LastModified,
+		})
+	}
+
+	// Sort by max TXID so the latest file is last. Snapshots are sorted
+	// after incremental files with the same max TXID.
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].maxTXID != files[j].maxTXID {
+			return files[i].maxTXID < files[j].maxTXID
+		}
+		return files[i].minTXID > files[j].minTXID
+	})
+	return files, nil
+}
+
+// dbPrefix returns the key prefix for a database. Returns the root prefix
+// for all databases if name is blank.
+func (c *BackupClient) dbPrefix(name string) string {
+	var prefix string
+	if c.Prefix != "" {
+		prefix = c.Prefix + "/"
+	}
+	if name != "" {
+		prefix += name + "/"
+	}
+	return prefix
+}
+
+// ltxKey returns the object key for an LTX file.
+func (c *BackupClient) ltxKey(name string, minTXID, maxTXID ltx.TXID) string {
+	return c.dbPrefix(name) + ltx.FormatFilename(minTXID, maxTXID)
+}
+
+type listObject struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// listObjects returns all objects & common prefixes under prefix.
+func (c *BackupClient) listObjects(ctx context.Context, prefix, delimiter string) (objects []listObject, prefixes []string, err error) {
+	var token string
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if delimiter != "" {
+			q.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodGet, "", q, nil, emptyPayloadHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := c.doRequest(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var result struct {
+			Contents       []listObject `xml:"Contents"`
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode list objects response: %w", err)
+		}
+
+		objects = append(objects, result.Contents...)
+		for _, p := range result.CommonPrefixes {
+			prefixes = append(prefixes, p.Prefix)
+		}
+
+		if !result.IsTruncated {
+			return objects, prefixes, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// getObject returns the contents of an object. An optional byte range may
+// be specified in the format of the HTTP "Range" header.
+func (c *BackupClient) getObject(ctx context.Context, key, byteRange string) (io.ReadCloser, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// putObject uploads the contents of f to key.
+func (c *BackupClient) putObject(ctx context.Context, key string, f *os.File) error {
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, key, nil, f, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = n
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// deleteObject removes an object from the bucket.
+func (c *BackupClient) deleteObject(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, key, nil, nil, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// newRequest returns a new signed request for an object key in the bucket.
+func (c *BackupClient) newRequest(ctx context.Context, method, key string, q url.Values, body io.Reader, payloadHash string) (*http.Request, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + c.Bucket + "/" + key
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(q)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, payloadHash)
+	return req, nil
+}
+
+// doRequest executes the request and returns an error if the response is not a 2XX.
+func (c *BackupClient) doRequest(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if err := xml.Unmarshal(buf, &e); err != nil || e.Code == "" {
+			return nil, fmt.Errorf("s3 error (%d): %s", resp.StatusCode, string(buf))
+		}
+		return nil, fmt.Errorf("s3 error (%d): %s: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return resp, nil
+}
+
+// emptyPayloadHash is the SHA256 hash of an empty request body.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds an AWS Signature Version 4 authorization header to req.
+func (c *BackupClient) sign(req *http.Request, payloadHash string) {
+	t := c.Now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	// Build canonical headers from the host & all "x-amz-*" & range headers.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k := strings.ToLower(k); strings.HasPrefix(k, "x-amz-") || k == "range" {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery returns the query string sorted & encoded for signing.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var a []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			a = append(a, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(a, "&")
+}
+
+// uriEncode encodes s as required by AWS signing. All characters except
+// unreserved characters are percent-encoded. Slashes are only encoded if
+// encodeSlash is true.
+func uriEncode(s string, encodeSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9'),
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			buf.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			buf.WriteByte(ch)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", ch)
+		}
+	}
+	return buf.String()
+}
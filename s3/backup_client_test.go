@@ -0,0 +1,394 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superfly/litefs/s3"
+	"github.com/superfly/ltx"
+)
+
+func TestBackupClient_URL(t *testing.T) {
+	c := s3.NewBackupClient("mybucket", "/path/to/data/")
+	if got, want := c.URL(), `s3://mybucket/path/to/data`; got != want {
+		t.Fatalf("URL=%s, want %s", got, want)
+	}
+}
+
+func TestBackupClient_WriteTx(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+		c := newOpenBackupClient(t, srv)
+
+		// Write several transaction files to the client.
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 2, MaxTXID: 2, PreApplyChecksum: 0xe4e4aaa102377eee},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0x99b1d11ab98cc555},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		if hwm, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 3, MaxTXID: 4, PreApplyChecksum: 0x99b1d11ab98cc555},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{3}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0x8b87423eeeeeeeee},
+		})); err != nil {
+			t.Fatal(err)
+		} else if got, want := hwm, ltx.TXID(4); got != want {
+			t.Fatalf("hwm=%s, want %s", got, want)
+		}
+
+		if got, want := srv.keys(), []string{
+			"prefix/db/0000000000000001-0000000000000001.ltx",
+			"prefix/db/0000000000000002-0000000000000002.ltx",
+			"prefix/db/0000000000000003-0000000000000004.ltx",
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("keys=%v, want %v", got, want)
+		}
+
+		// Only the first write should list the bucket as the position is cached.
+		if got, want := srv.lists(), 1; got != want {
+			t.Fatalf("lists=%d, want %d", got, want)
+		}
+
+		// Read snapshot from backup service.
+		spec := fetchSnapshot(t, c, "db")
+		if got, want := spec.Header.MinTXID, ltx.TXID(1); got != want {
+			t.Fatalf("MinTXID=%s, want %s", got, want)
+		} else if got, want := spec.Header.MaxTXID, ltx.TXID(4); got != want {
+			t.Fatalf("MaxTXID=%s, want %s", got, want)
+		} else if got, want := spec.Trailer.PostApplyChecksum, ltx.Checksum(0x8b87423eeeeeeeee); got != want {
+			t.Fatalf("PostApplyChecksum=%s, want %s", got, want)
+		}
+		if got, want := spec.Pages, []ltx.PageSpec{
+			{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{3}, 512)},
+			{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)},
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("pages=%#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("ErrPosMismatch", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+		c := newOpenBackupClient(t, srv)
+
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		// Write a transaction that doesn't line up with the TXID.
+		var pmErr *ltx.PosMismatchError
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 3, MaxTXID: 3, PreApplyChecksum: 0xe4e4aaa102377eee},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0x99b1d11ab98cc555},
+		})); !errors.As(err, &pmErr) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if got, want := pmErr.Pos, (ltx.Pos{TXID: 1, PostApplyChecksum: 0xe4e4aaa102377eee}); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+	})
+
+	// Ensure files are compacted into a snapshot & removed after retention.
+	t.Run("Snapshot", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+		c := newOpenBackupClient(t, srv)
+
+		now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		c.Now = func() time.Time { return now }
+		c.SnapshotInterval, c.Retention = time.Hour, 0
+
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Hour)
+		if _, err := c.WriteTx(context.Background(), "db", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 2, MaxTXID: 2, PreApplyChecksum: 0xe4e4aaa102377eee},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0xad2dffe333333333},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the background snapshot to complete.
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := srv.keys(), []string{
+			"prefix/db/0000000000000001-0000000000000002.ltx",
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("keys=%v, want %v", got, want)
+		}
+
+		// Position & snapshot should be unchanged.
+		if m, err := c.PosMap(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := m["db"], (ltx.Pos{TXID: 2, PostApplyChecksum: 0xad2dffe333333333}); got != want {
+			t.Fatalf("pos=%s, want %s", got, want)
+		}
+		if got, want := fetchSnapshot(t, c, "db").Pages, []ltx.PageSpec{
+			{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)},
+			{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)},
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("pages=%#v, want %#v", got, want)
+		}
+	})
+}
+
+func TestBackupClient_PosMap(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+		c := newOpenBackupClient(t, srv)
+
+		if _, err := c.WriteTx(context.Background(), "db1", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{1}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0xe4e4aaa102377eee},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.WriteTx(context.Background(), "db1", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 2, MinTXID: 2, MaxTXID: 2, PreApplyChecksum: 0xe4e4aaa102377eee},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 2}, Data: bytes.Repeat([]byte{2}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0x99b1d11ab98cc555},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.WriteTx(context.Background(), "db2", ltxFileSpecReader(t, &ltx.FileSpec{
+			Header:  ltx.Header{Version: 1, PageSize: 512, Commit: 1, MinTXID: 1, MaxTXID: 1},
+			Pages:   []ltx.PageSpec{{Header: ltx.PageHeader{Pgno: 1}, Data: bytes.Repeat([]byte{5}, 512)}},
+			Trailer: ltx.Trailer{PostApplyChecksum: 0x99b1d11ab98cc555},
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		if m, err := c.PosMap(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := m, map[string]ltx.Pos{
+			"db1": {TXID: 0x2, PostApplyChecksum: 0x99b1d11ab98cc555},
+			"db2": {TXID: 0x1, PostApplyChecksum: 0x99b1d11ab98cc555},
+		}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("map=%#v, want %#v", got, want)
+		}
+		if got, want := srv.auth(), "AWS4-HMAC-SHA256 Credential=AKID/"; !strings.HasPrefix(got, want) {
+			t.Fatalf("Authorization=%q, want prefix %q", got, want)
+		}
+	})
+
+	t.Run("NoDatabases", func(t *testing.T) {
+		srv := newFakeServer()
+		defer srv.Close()
+		c := newOpenBackupClient(t, srv)
+
+		if m, err := c.PosMap(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if got, want := m, map[string]ltx.Pos{}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("map=%#v, want %#v", got, want)
+		}
+	})
+}
+
+func newOpenBackupClient(tb testing.TB, srv *fakeServer) *s3.BackupClient {
+	tb.Helper()
+	c := s3.NewBackupClient("bucket", "prefix")
+	c.Endpoint = srv.URL
+	c.AccessKeyID, c.SecretAccessKey = "AKID", "SECRET"
+	if err := c.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// fetchSnapshot returns the decoded snapshot for a database.
+func fetchSnapshot(tb testing.TB, c *s3.BackupClient, name string) *ltx.FileSpec {
+	tb.Helper()
+	var spec ltx.FileSpec
+	if rc, err := c.FetchSnapshot(context.Background(), name); err != nil {
+		tb.Fatal(err)
+	} else if _, err := spec.ReadFrom(rc); err != nil {
+		tb.Fatal(err)
+	} else if err := rc.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return &spec
+}
+
+// ltxFileSpecReader returns a spec as an io.Reader of its serialized bytes.
+func ltxFileSpecReader(tb testing.TB, spec *ltx.FileSpec) io.Reader {
+	tb.Helper()
+	var buf bytes.Buffer
+	if _, err := spec.WriteTo(&buf); err != nil {
+		tb.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// fakeServer implements the subset of the S3 API used by the backup client.
+type fakeServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	objects       map[string][]byte
+	authorization string // last authorization header
+	listN         int    // number of list requests
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{objects: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *fakeServer) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedKeys()
+}
+
+func (s *fakeServer) lists() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listN
+}
+
+func (s *fakeServer) auth() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authorization
+}
+
+func (s *fakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.authorization = r.Header.Get("Authorization")
+
+	const prefix = "/bucket/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch r.Method {
+	case http.MethodGet:
+		if key == "" {
+			s.listN++
+			s.serveList(w, r)
+			return
+		}
+
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, `<Error><Code>NoSuchKey</Code></Error>`, http.StatusNotFound)
+			return
+		}
+
+		// Only suffix ranges are used by the client.
+		if v := strings.TrimPrefix(r.Header.Get("Range"), "bytes=-"); v != r.Header.Get("Range") {
+			n, _ := strconv.Atoi(v)
+			data = data[len(data)-n:]
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		_, _ = w.Write(data)
+
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = data
+
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeServer) serveList(w http.ResponseWriter, r *http.Request) {
+	prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+
+	type content struct {
+		Key          string
+		LastModified time.Time
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	var result struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range s.sortedKeys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				p := key[:len(prefix)+i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: p})
+				}
+				continue
+			}
+		}
+		result.Contents = append(result.Contents, content{Key: key})
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (s *fakeServer) sortedKeys() []string {
+	a := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
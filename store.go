@@ -1,6 +1,7 @@
 package litefs
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	crand "crypto/rand"
@@ -10,10 +11,11 @@ import (
 	"expvar"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -33,6 +35,10 @@ const (
 	DefaultReconnectDelay = 1 * time.Second
 	DefaultDemoteDelay    = 10 * time.Second
 
+	DefaultReplicationHeartbeatInterval = 10 * time.Second
+
+	DefaultCandidatePriorityDelay = 1 * time.Second
+
 	DefaultRetention                = 10 * time.Minute
 	DefaultRetentionMonitorInterval = 1 * time.Minute
 
@@ -42,6 +48,13 @@ const (
 
 	DefaultBackupDelay            = 1 * time.Second
 	DefaultBackupFullSyncInterval = 10 * time.Second
+
+	DefaultBarrierMaxDuration = 30 * time.Second
+
+	DefaultSyncReplicaTimeout = 5 * time.Second
+
+	DefaultBackpressureReplicaN = 1
+	DefaultBackpressureTimeout  = 5 * time.Second
 )
 
 const (
@@ -54,16 +67,15 @@ const (
 
 var ErrStoreClosed = fmt.Errorf("store closed")
 
-// GlobalStore represents a single store used for metrics collection.
-var GlobalStore atomic.Value
-
 // Store represents a collection of databases.
 type Store struct {
-	mu   sync.Mutex
-	path string
+	mu     sync.Mutex
+	lazyMu sync.Mutex // serializes lazy database opens
+	path   string
 
 	id                   uint64 // unique node id
 	clusterID            atomic.Value
+	epoch                atomic.Uint64 // highest lease epoch observed
 	dbs                  map[string]*DB
 	changeSetSubscribers map[*ChangeSetSubscriber]struct{}
 	eventSubscribers     map[*EventSubscriber]struct{}
@@ -72,9 +84,22 @@ type Store struct {
 	lease       Lease         // if not nil, store is current primary
 	primaryCh   chan struct{} // closed when primary loses leadership
 	primaryInfo *PrimaryInfo  // contains info about the current primary
-	candidate   bool          // if true, we are eligible to become the primary
+	candidate   atomic.Bool   // if true, we are eligible to become the primary
 	readyCh     chan struct{} // closed when primary found or acquired
 	demoteCh    chan struct{} // closed when Demote() is called
+	ackCh       chan struct{} // closed & replaced when a replica acks a tx
+
+	cancelStream context.CancelCauseFunc // cancels the replication stream, if connected
+	connected    atomic.Bool             // true while streaming from the primary
+	pauseCh      chan struct{}           // if not nil, replication is paused until closed
+
+	dbCreateHook func(dbName string)
+	dbDeleteHook func(dbName string)
+	dbRenameHook func(oldName, newName string)
+
+	registry  *prometheus.Registry // collectors for this store only
+	metrics   *storeMetrics
+	dbMetrics *dbMetrics
 
 	ctx    context.Context
 	cancel context.CancelCauseFunc
@@ -102,10 +127,32 @@ type Store struct {
 	// Time to wait after manually demoting trying to become primary again.
 	DemoteDelay time.Duration
 
+	// Election priority of this candidate. Lower values are preferred and
+	// zero is the highest priority. When there is no primary, a candidate
+	// waits CandidatePriority * CandidatePriorityDelay before attempting to
+	// acquire the lease so that a preferred candidate wins the election.
+	CandidatePriority      int
+	CandidatePriorityDelay time.Duration
+
+	// Interval between heartbeats sent by the primary on an idle replication
+	// stream. Replicas reconnect if no data is received within twice this
+	// interval, which detects stalled or half-open connections.
+	ReplicationHeartbeatInterval time.Duration
+
 	// Length of time to retain LTX files.
 	Retention                time.Duration
 	RetentionMonitorInterval time.Duration
 
+	// Max number & total size, in bytes, of LTX files kept per database.
+	// Older files are removed once either limit is exceeded, even if they
+	// are within the retention period. Replicas that fall behind the
+	// retained files receive a snapshot instead. Disabled if zero.
+	//
+	// The retention settings can be changed on an open store with
+	// SetRetentionPolicy().
+	RetentionMaxCount int
+	RetentionMaxSize  int64
+
 	// Max time to hold HALT lock and interval between expiration checks.
 	HaltLockTTL             time.Duration
 	HaltLockMonitorInterval time.Duration
@@ -113,6 +160,45 @@ type Store struct {
 	// Time to wait to acquire the HALT lock.
 	HaltAcquireTimeout time.Duration
 
+	// If true, write transactions on a replica implicitly acquire the HALT
+	// lock from the primary so they are forwarded to the primary on commit.
+	// Applications do not need to acquire the HALT lock themselves.
+	WriteForwarding bool
+
+	// Maximum number of pages & bytes of page data written by a single
+	// transaction. Writes past either limit fail with ErrTxTooLarge so
+	// SQLite rolls back the transaction. Unlimited if zero.
+	MaxTxPageN int
+	MaxTxSize  int64
+
+	// Max time to hold a snapshot barrier before it is automatically released.
+	BarrierMaxDuration time.Duration
+
+	// Number of replicas that must acknowledge a transaction before a commit
+	// returns on the primary. Replicas acknowledge each transaction once it
+	// is applied. The wait occurs after SQLite releases its locks so other
+	// connections can continue to read & write. The commit is durable on the
+	// primary by then so if not enough replicas acknowledge within
+	// SyncReplicaTimeout, the timeout is logged and the commit returns.
+	// Disabled if zero.
+	//
+	// This must be set on all nodes so that replicas send acknowledgments.
+	SyncReplicaN       int
+	SyncReplicaTimeout time.Duration
+
+	// Databases that use synchronous replication. If empty, all databases do.
+	SyncReplicaDBs []string
+
+	// Maximum number of transactions a replica can fall behind before
+	// commits on the primary are delayed. Commits wait until at least
+	// BackpressureReplicaN connected replicas are within the lag, or until
+	// BackpressureTimeout elapses. Replicas whose database filter excludes
+	// the database are not counted. The delay occurs after SQLite releases
+	// its locks so only the committing connection is slowed. Disabled if zero.
+	BackpressureMaxLag   int
+	BackpressureReplicaN int
+	BackpressureTimeout  time.Duration
+
 	// Time after a change is made before it is sent to the backup service.
 	// This allows multiple changes in quick succession to be batched together.
 	BackupDelay time.Duration
@@ -124,12 +210,52 @@ type Store struct {
 	// Callback to notify kernel of file changes.
 	Invalidator Invalidator
 
+	// Specifies how the kernel page cache is invalidated when applying LTX
+	// files. Defaults to invalidating each page individually.
+	CacheInvalidationStrategy CacheInvalidationStrategy
+
 	// Interface to interact with the host environment.
 	Environment Environment
 
-	// Specifies a subset of databases to replicate from the primary.
+	// Specifies a subset of databases to replicate from the primary. Entries
+	// are glob patterns & entries prefixed with "!" exclude matching names.
+	// See MatchDatabaseFilter(). Use SetDatabaseFilter() on an open store.
 	DatabaseFilter []string
 
+	// Number of consecutive errors applying replicated LTX data to a
+	// database before replication to that database is suspended. This
+	// prevents a single failing database from blocking all other databases.
+	// Frames that fail below the threshold are skipped without reconnecting
+	// & the stream reconnects once the threshold is crossed. Suspended
+	// databases can be resumed with ResumeDB(). Disabled if zero.
+	DBErrorThreshold int
+
+	// File extensions, including the leading dot, of files in the mount that
+	// are treated as SQLite databases. Other files are stored as regular,
+	// non-replicated files. If empty, all files are treated as databases.
+	DBExtensions []string
+
+	// Specifies the verification performed on existing databases when the
+	// store is opened. Returns ErrDBCorrupted if a database fails the check.
+	StartupIntegrityCheck IntegrityCheckLevel
+
+	// If true, existing databases are not opened when the store is opened.
+	// Instead, each database is opened on first access via OpenDB(). This
+	// reduces startup time when there are many databases.
+	LazyDBOpen bool
+
+	// Interval between checksum verifications of every database on a replica.
+	// Mismatches are reported as events & metrics. Disabled if zero.
+	ChecksumVerifyInterval time.Duration
+
+	// If true, a replica database that fails checksum verification is
+	// discarded & re-snapshotted from the primary.
+	ChecksumVerifyResync bool
+
+	// Specifies how a replica handles a database whose position cannot be
+	// reconciled with the primary. Defaults to ResyncModeFail.
+	ResyncMode ResyncMode
+
 	// If true, computes and verifies the checksum of the entire database
 	// after every transaction. Should only be used during testing.
 	StrictVerify bool
@@ -155,10 +281,10 @@ func NewStore(path string, candidate bool) *Store {
 		changeSetSubscribers: make(map[*ChangeSetSubscriber]struct{}),
 		eventSubscribers:     make(map[*EventSubscriber]struct{}),
 
-		candidate: candidate,
 		primaryCh: primaryCh,
 		readyCh:   make(chan struct{}),
 		demoteCh:  make(chan struct{}),
+		ackCh:     make(chan struct{}),
 
 		OS:   &internal.SystemOS{},
 		Exit: os.Exit,
@@ -166,6 +292,10 @@ func NewStore(path string, candidate bool) *Store {
 		ReconnectDelay: DefaultReconnectDelay,
 		DemoteDelay:    DefaultDemoteDelay,
 
+		ReplicationHeartbeatInterval: DefaultReplicationHeartbeatInterval,
+
+		CandidatePriorityDelay: DefaultCandidatePriorityDelay,
+
 		Retention:                DefaultRetention,
 		RetentionMonitorInterval: DefaultRetentionMonitorInterval,
 
@@ -173,6 +303,13 @@ func NewStore(path string, candidate bool) *Store {
 		HaltLockTTL:             DefaultHaltLockTTL,
 		HaltLockMonitorInterval: DefaultHaltLockMonitorInterval,
 
+		BarrierMaxDuration: DefaultBarrierMaxDuration,
+
+		SyncReplicaTimeout: DefaultSyncReplicaTimeout,
+
+		BackpressureReplicaN: DefaultBackpressureReplicaN,
+		BackpressureTimeout:  DefaultBackpressureTimeout,
+
 		BackupDelay:            DefaultBackupDelay,
 		BackupFullSyncInterval: DefaultBackupFullSyncInterval,
 
@@ -180,29 +317,132 @@ func NewStore(path string, candidate bool) *Store {
 	}
 	s.ctx, s.cancel = context.WithCancelCause(context.Background())
 	s.clusterID.Store("")
+	s.candidate.Store(candidate)
 	s.primaryTimestamp.Store(-1)
 
+	s.registry = prometheus.NewRegistry()
+	s.metrics = newStoreMetrics(s, s.registry)
+	s.dbMetrics = newDBMetrics(s.registry)
+
 	return s
 }
 
 // Path returns underlying data directory.
 func (s *Store) Path() string { return s.path }
 
+// Registry returns the Prometheus registry holding the store's metrics.
+// Each store has its own registry so multiple mounts in a single process
+// report separate values.
+func (s *Store) Registry() *prometheus.Registry { return s.registry }
+
 // DBDir returns the folder that stores all databases.
 func (s *Store) DBDir() string {
 	return filepath.Join(s.path, "dbs")
 }
 
+// FilesDir returns the folder that stores regular files which are not
+// treated as databases. See DBExtensions.
+func (s *Store) FilesDir() string {
+	return filepath.Join(s.path, "files")
+}
+
+// IsDBName returns true if name matches one of the DBExtensions. All names
+// are treated as databases if no extensions are specified.
+func (s *Store) IsDBName(name string) bool {
+	if len(s.DBExtensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(name)
+	for _, v := range s.DBExtensions {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDatabaseFilter returns true if name passes a database replication
+// filter. Patterns use filepath.Match syntax & patterns prefixed with "!"
+// exclude names. A name passes if it matches any include pattern, or there
+// are no include patterns, and it matches no exclude pattern.
+func MatchDatabaseFilter(filter []string, name string) bool {
+	included, hasIncludes := false, false
+	for _, pattern := range filter {
+		if exclude := strings.TrimPrefix(pattern, "!"); exclude != pattern {
+			if matched, _ := filepath.Match(exclude, name); matched {
+				return false
+			}
+			continue
+		}
+
+		hasIncludes = true
+		if matched, _ := filepath.Match(pattern, name); matched {
+			included = true
+		}
+	}
+	return included || !hasIncludes
+}
+
+// ValidateDatabaseFilter returns an error if any pattern in filter is malformed.
+func ValidateDatabaseFilter(filter []string) error {
+	for _, pattern := range filter {
+		if _, err := filepath.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+			return fmt.Errorf("invalid database filter pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
 // DBPath returns the folder that stores a single database.
 func (s *Store) DBPath(name string) string {
 	return filepath.Join(s.path, "dbs", name)
 }
 
+// RestoreDB writes the contents of a database, as of the restore point, to w
+// by replaying the LTX files retained by the store. See Restore().
+//
+// Retention usually removes the local snapshot so, if the retained files have
+// no restore point, the backup client is used instead when it can act as a
+// RestoreSource. Neither error is returned after writing to w.
+func (s *Store) RestoreDB(ctx context.Context, name string, w io.Writer, opt RestoreOptions) (ltx.Pos, error) {
+	pos, err := Restore(ctx, NewDataDirRestoreSource(s.path), name, w, opt)
+	if !errors.Is(err, ErrNoRestorePoint) && !errors.Is(err, ErrDatabaseNotFound) {
+		return pos, err
+	}
+
+	src, ok := s.BackupClient.(RestoreSource)
+	if !ok {
+		return pos, err
+	}
+	s.logger(LogSubsystemStore).Info("no local restore point, restoring from backup", slog.String("db", name), slog.Any("reason", err))
+	return Restore(ctx, src, name, w, opt)
+}
+
 // ClusterIDPath returns the filename where the cluster ID is stored.
 func (s *Store) ClusterIDPath() string {
 	return filepath.Join(s.path, "clusterid")
 }
 
+// EpochPath returns the filename where the highest observed epoch is stored.
+// Epochs are only comparable between leases from the same leaser & cluster so
+// the file is scoped to the leaser type & cluster ID. Switching to another
+// leaser or cluster starts again from an epoch of zero.
+func (s *Store) EpochPath() string {
+	name := "epoch"
+	if s.Leaser != nil {
+		name += "." + s.Leaser.Type()
+	}
+	if id := s.ClusterID(); id != "" {
+		name += "." + id
+	}
+	return filepath.Join(s.path, name)
+}
+
+// logger returns a logger for a subsystem that includes the node ID.
+func (s *Store) logger(subsystem string) *slog.Logger {
+	return Logger(subsystem).With(slog.String("node", FormatNodeID(s.id)))
+}
+
 // ID returns the unique identifier for this instance. Available after Open().
 // Persistent across restarts if underlying storage is persistent.
 func (s *Store) ID() uint64 {
@@ -255,6 +495,58 @@ func (s *Store) setClusterID(id string) error {
 	}
 
 	s.clusterID.Store(id)
+
+	// Epochs observed for the previous cluster ID do not apply to this one.
+	if err := s.readEpoch(); err != nil {
+		return fmt.Errorf("load epoch: %w", err)
+	}
+	return nil
+}
+
+// Epoch returns the highest lease epoch observed by the store, either from
+// acquiring the lease or from streaming from a primary.
+func (s *Store) Epoch() uint64 {
+	return s.epoch.Load()
+}
+
+// setEpoch saves the epoch to disk if it is higher than the current epoch.
+func (s *Store) setEpoch(epoch uint64) error {
+	if epoch <= s.Epoch() {
+		return nil // no-op
+	}
+	return s.writeEpoch(epoch)
+}
+
+// writeEpoch saves the epoch to disk, replacing the current epoch.
+func (s *Store) writeEpoch(epoch uint64) error {
+
+	filename := s.EpochPath()
+	tempFilename := filename + ".tmp"
+	defer func() { _ = s.OS.Remove("SETEPOCH", tempFilename) }()
+
+	f, err := s.OS.Create("SETEPOCH", tempFilename)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.WriteString(f, strconv.FormatUint(epoch, 10)+"\n"); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := s.OS.Rename("SETEPOCH", tempFilename, filename); err != nil {
+		return err
+	} else if err := internal.Sync(filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	s.epoch.Store(epoch)
 	return nil
 }
 
@@ -277,6 +569,11 @@ func (s *Store) Open() error {
 		return fmt.Errorf("load cluster id: %w", err)
 	}
 
+	// Load the highest observed epoch so stale primaries are fenced across restarts.
+	if err := s.readEpoch(); err != nil {
+		return fmt.Errorf("load epoch: %w", err)
+	}
+
 	if err := s.openDatabases(); err != nil {
 		return fmt.Errorf("open databases: %w", err)
 	}
@@ -287,6 +584,9 @@ func (s *Store) Open() error {
 	// Begin lock monitor.
 	s.g.Go(func() error { return s.monitorHaltLock(s.ctx) })
 
+	// Begin metrics monitor.
+	s.g.Go(func() error { return s.monitorMetrics(s.ctx) })
+
 	// Begin retention monitor.
 	if s.RetentionMonitorInterval > 0 {
 		s.g.Go(func() error { return s.monitorRetention(s.ctx) })
@@ -314,38 +614,97 @@ func (s *Store) readClusterID() error {
 	return nil
 }
 
+// readEpoch reads the highest observed epoch from the epoch file for the
+// current leaser & cluster. The epoch is zero if no epoch file exists.
+func (s *Store) readEpoch() error {
+	b, err := s.OS.ReadFile("READEPOCH", s.EpochPath())
+	if os.IsNotExist(err) {
+		s.epoch.Store(0)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	epoch, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid epoch: %w", err)
+	}
+	s.epoch.Store(epoch)
+
+	return nil
+}
+
 func (s *Store) openDatabases() error {
 	if err := s.OS.MkdirAll("OPENDATABASES", s.DBDir(), 0o777); err != nil {
 		return err
 	}
 
+	// Databases are opened on first access instead.
+	if s.LazyDBOpen {
+		return s.removePartialLTXFiles()
+	}
+
 	fis, err := s.OS.ReadDir("OPENDATABASES", s.DBDir())
 	if err != nil {
 		return fmt.Errorf("readdir: %w", err)
 	}
 	for _, fi := range fis {
-		if err := s.openDatabase(fi.Name()); err != nil {
+		db, err := s.openDatabase(fi.Name())
+		if err != nil {
 			return fmt.Errorf("open database(%q): %w", fi.Name(), err)
 		}
+
+		// Add to internal lookups.
+		s.dbs[db.Name()] = db
 	}
 
 	// Update metrics.
-	storeDBCountMetric.Set(float64(len(s.dbs)))
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
+
+	return nil
+}
 
+// removePartialLTXFiles removes LTX files left behind by an interrupted write
+// from databases that are not opened on startup. This ensures the position
+// read for an unopened database is that of its last complete transaction.
+func (s *Store) removePartialLTXFiles() error {
+	fis, err := s.OS.ReadDir("REMOVEPARTIALLTXFILES", s.DBDir())
+	if err != nil {
+		return fmt.Errorf("readdir: %w", err)
+	}
+	for _, fi := range fis {
+		db := NewDB(s, fi.Name(), s.DBPath(fi.Name()))
+		if _, err := db.maxLTXFile(context.Background()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove partial ltx files(%q): %w", fi.Name(), err)
+		}
+	}
 	return nil
 }
 
-func (s *Store) openDatabase(name string) error {
-	// Instantiate and open database.
+// openDatabase instantiates and opens an existing database. It is not added
+// to the store's lookups.
+func (s *Store) openDatabase(name string) (*DB, error) {
 	db := NewDB(s, name, s.DBPath(name))
+
+	// Verify the header before opening as invalid database files are cleared.
+	if s.StartupIntegrityCheck >= CheckHeader {
+		if err := db.checkDatabaseHeader(); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := db.Open(); err != nil {
-		return err
+		return nil, err
 	}
 
-	// Add to internal lookups.
-	s.dbs[db.Name()] = db
+	// B-tree pages are verified after the journal or WAL has been recovered.
+	if s.StartupIntegrityCheck >= CheckPageCount {
+		if err := db.checkDatabaseBTrees(s.StartupIntegrityCheck >= CheckFull); err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	return db, nil
 }
 
 // Close signals for the store to shut down.
@@ -360,22 +719,30 @@ func (s *Store) Close() (retErr error) {
 			continue
 		}
 
-		log.Printf("releasing halt lock on %q", db.Name())
+		s.logger(LogSubsystemStore).Info("releasing halt lock", slog.String("db", db.Name()))
 
 		if err := db.ReleaseRemoteHaltLock(context.Background(), haltLock.ID); err != nil {
-			log.Printf("cannot release halt lock on %q on shutdown", db.Name())
+			s.logger(LogSubsystemStore).Warn("cannot release halt lock on shutdown", slog.String("db", db.Name()), slog.Any("err", err))
 		}
 	}
 
 	return retErr
 }
 
-// ReadyCh returns a channel that is closed once the store has become primary
-// or once it has connected to the primary.
+// ReadyCh returns a channel that is closed once the store has become primary,
+// once it has connected to the primary, or once a replica has restored its
+// databases from the backup service while no primary is reachable.
 func (s *Store) ReadyCh() chan struct{} {
 	return s.readyCh
 }
 
+// IsReady returns true if the store has become primary, has connected to the
+// primary at least once, or has restored from the backup service.
+func (s *Store) IsReady() bool { return s.isReady() }
+
+// IsConnected returns true if the store is currently streaming from the primary.
+func (s *Store) IsConnected() bool { return s.connected.Load() }
+
 func (s *Store) isReady() bool {
 	select {
 	case <-s.readyCh:
@@ -441,6 +808,24 @@ func (s *Store) IsPrimary() bool {
 
 func (s *Store) isPrimary() bool { return s.lease != nil }
 
+// LeaseInfo returns the state of the lease held by the store.
+// Returns nil if the store is not the primary.
+func (s *Store) LeaseInfo() *LeaseInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lease == nil {
+		return nil
+	}
+	renewedAt := s.lease.RenewedAt()
+	return &LeaseInfo{
+		ID:        s.lease.ID(),
+		Epoch:     s.lease.Epoch(),
+		RenewedAt: renewedAt,
+		ExpiresAt: renewedAt.Add(s.lease.TTL()),
+	}
+}
+
 func (s *Store) setLease(lease Lease) {
 	// Create a new channel to notify about primary loss when becoming primary.
 	// Or close existing channel if we are losing our primary status.
@@ -459,9 +844,9 @@ func (s *Store) setLease(lease Lease) {
 
 	// Update metrics.
 	if s.isPrimary() {
-		storeIsPrimaryMetric.Set(1)
+		s.metrics.storeIsPrimaryMetric.Set(1)
 	} else {
-		storeIsPrimaryMetric.Set(0)
+		s.metrics.storeIsPrimaryMetric.Set(0)
 	}
 
 	s.notifyPrimaryChange()
@@ -514,7 +899,58 @@ func (s *Store) setPrimaryInfo(info *PrimaryInfo) {
 
 // Candidate returns true if store is eligible to be the primary.
 func (s *Store) Candidate() bool {
-	return s.candidate
+	return s.candidate.Load()
+}
+
+// SetCandidate sets whether the store is eligible to be the primary. The
+// store is demoted if it is the primary and is no longer eligible.
+func (s *Store) SetCandidate(v bool) {
+	if s.candidate.Swap(v) == v {
+		return // no change
+	}
+	if !v && s.IsPrimary() {
+		s.logger(LogSubsystemLease).Info("no longer a candidate, demoting")
+		s.Demote()
+	}
+}
+
+// SetDatabaseFilter updates the databases replicated from the primary. A
+// connected replication stream is restarted to apply the new filter.
+func (s *Store) SetDatabaseFilter(filter []string) error {
+	if err := ValidateDatabaseFilter(filter); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.DatabaseFilter = filter
+	if s.cancelStream != nil {
+		s.cancelStream(fmt.Errorf("%w: database filter changed", errReconnect))
+	}
+	return nil
+}
+
+// databaseFilter returns the current database filter.
+func (s *Store) databaseFilter() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.DatabaseFilter
+}
+
+// RetentionPolicy returns the retention period & limits for LTX files.
+func (s *Store) RetentionPolicy() (retention time.Duration, maxCount int, maxSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Retention, s.RetentionMaxCount, s.RetentionMaxSize
+}
+
+// SetRetentionPolicy updates the retention period & limits for LTX files.
+// The new policy is applied on the next retention check.
+func (s *Store) SetRetentionPolicy(retention time.Duration, maxCount int, maxSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Retention, s.RetentionMaxCount, s.RetentionMaxSize = retention, maxCount, maxSize
 }
 
 // DBByName returns a database by name.
@@ -525,6 +961,87 @@ func (s *Store) DB(name string) *DB {
 	return s.dbs[name]
 }
 
+// OpenDB returns a database by name. If LazyDBOpen is enabled and the
+// database exists in the data directory but has not been opened yet then it
+// is opened and added to the store. Returns ErrDatabaseNotFound if the
+// database does not exist.
+func (s *Store) OpenDB(name string) (*DB, error) {
+	if err := s.openLazyDB(name); err != nil {
+		return nil, err
+	}
+
+	db := s.DB(name)
+	if db == nil {
+		return nil, ErrDatabaseNotFound
+	}
+	return db, nil
+}
+
+// openLazyDB opens a database from the data directory if lazy opening is
+// enabled and it has not been opened yet. This is a no-op if the database is
+// already open or does not exist on disk.
+//
+// The store lock must not be held as opening a database can apply pending
+// LTX files, which marks the database as dirty on the store.
+func (s *Store) openLazyDB(name string) error {
+	if !s.LazyDBOpen {
+		return nil
+	}
+
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+
+	if s.DB(name) != nil {
+		return nil
+	} else if _, err := s.OS.Stat("OPENLAZYDB", s.DBPath(name)); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	db, err := s.openDatabase(name)
+	if err != nil {
+		return fmt.Errorf("open database(%q): %w", name, err)
+	}
+	TraceLog.Printf("[OpenLazyDB(%s)]", name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dbs[name] = db
+
+	// Notify listeners so the database is replicated immediately.
+	s.markDirty(name)
+
+	// Update metrics
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
+
+	return nil
+}
+
+// UnopenedDBNames returns the names of databases in the data directory that
+// have not been opened yet. Always returns nil if LazyDBOpen is disabled.
+func (s *Store) UnopenedDBNames() ([]string, error) {
+	if !s.LazyDBOpen {
+		return nil, nil
+	}
+
+	fis, err := s.OS.ReadDir("UNOPENEDDBNAMES", s.DBDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var a []string
+	for _, fi := range fis {
+		if s.dbs[fi.Name()] == nil {
+			a = append(a, fi.Name())
+		}
+	}
+	return a, nil
+}
+
 // DBs returns a list of databases.
 func (s *Store) DBs() []*DB {
 	s.mu.Lock()
@@ -545,6 +1062,10 @@ func (s *Store) CreateDB(name string) (db *DB, f *os.File, err error) {
 		TraceLog.Printf("[CreateDatabase(%s)]: %s", name, errorKeyValue(err))
 	}()
 
+	if err := s.openLazyDB(name); err != nil {
+		return nil, nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -577,19 +1098,24 @@ func (s *Store) CreateDB(name string) (db *DB, f *os.File, err error) {
 			return nil, nil, err
 		}
 		s.dbs[name] = db
+		s.notifyEvent(Event{Type: EventTypeCreateDB, DB: name})
 	}
 
 	// Notify listeners of change.
 	s.markDirty(name)
 
 	// Update metrics
-	storeDBCountMetric.Set(float64(len(s.dbs)))
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
 
 	return db, f, nil
 }
 
 // CreateDBIfNotExists creates an empty database with the given name.
 func (s *Store) CreateDBIfNotExists(name string) (*DB, error) {
+	if err := s.openLazyDB(name); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -614,48 +1140,333 @@ func (s *Store) CreateDBIfNotExists(name string) (*DB, error) {
 		return nil, err
 	}
 	s.dbs[name] = db
+	s.notifyEvent(Event{Type: EventTypeCreateDB, DB: name})
 
 	// Notify listeners of change.
 	s.markDirty(name)
 
 	// Update metrics
-	storeDBCountMetric.Set(float64(len(s.dbs)))
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
 
 	return db, nil
 }
 
-// PosMap returns a map of databases and their transactional position.
-func (s *Store) PosMap() map[string]ltx.Pos {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// DropDB deletes a database on the primary. The database directory, including
+// its LTX files, is removed & replicas remove their local copy when they
+// receive the drop.
+//
+// If a backup client is configured, the database is instead truncated with a
+// zero-commit LTX file. The backup service is the data authority so the
+// deletion must be recorded in its history or the database would be restored.
+func (s *Store) DropDB(ctx context.Context, name string) error {
+	if !s.IsPrimary() {
+		return ErrReadOnlyReplica
+	}
 
-	m := make(map[string]ltx.Pos, len(s.dbs))
-	for _, db := range s.dbs {
-		m[db.Name()] = db.Pos()
+	db, err := s.OpenDB(name)
+	if err != nil {
+		return err
 	}
-	return m
+	if s.BackupClient != nil {
+		return db.Drop(ctx)
+	}
+	return s.removeDB(name, false)
 }
 
-// SubscribeChangeSet creates a new subscriber for store changes.
-func (s *Store) SubscribeChangeSet(nodeID uint64) *ChangeSetSubscriber {
+// removeDB removes a database & its data directory from the store. Returns
+// ErrDatabaseBusy if the database is in use, unless force is set. Replicas
+// force the removal as they must apply changes from the primary.
+func (s *Store) removeDB(name string, force bool) (err error) {
+	defer func() {
+		TraceLog.Printf("[RemoveDB(%s)]: %s", name, errorKeyValue(err))
+	}()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sub := newChangeSetSubscriber(s, nodeID)
-	s.changeSetSubscribers[sub] = struct{}{}
+	// Databases may exist on disk without being opened if LazyDBOpen is set.
+	db := s.dbs[name]
+	if db == nil {
+		if _, err := s.OS.Stat("REMOVEDB", s.DBPath(name)); os.IsNotExist(err) {
+			return ErrDatabaseNotFound
+		} else if err != nil {
+			return err
+		}
+	} else if !force && db.InUse() {
+		return ErrDatabaseBusy
+	}
 
-	storeSubscriberCountMetric.Set(float64(len(s.changeSetSubscribers)))
-	return sub
-}
+	if db != nil {
+		db.close()
+	}
+	delete(s.dbs, name)
+	if err := s.OS.RemoveAll("REMOVEDB", s.DBPath(name)); err != nil {
+		return fmt.Errorf("remove database directory: %w", err)
+	} else if err := internal.Sync(s.DBDir()); err != nil {
+		return fmt.Errorf("sync database directory: %w", err)
+	}
+	s.notifyEvent(Event{Type: EventTypeDropDB, DB: name})
 
-// UnsubscribeChangeSet removes a subscriber from the store.
-func (s *Store) UnsubscribeChangeSet(sub *ChangeSetSubscriber) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// Notify listeners so that replicas receive a drop.
+	s.markDirty(name)
 
-	delete(s.changeSetSubscribers, sub)
-	storeSubscriberCountMetric.Set(float64(len(s.changeSetSubscribers)))
-}
+	// Update metrics
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
+
+	return nil
+}
+
+// RenameDB renames a database on the primary. The database directory,
+// including its LTX files, is moved so that replicas can apply the rename
+// without receiving a new snapshot. If a database already exists with the new
+// name then ErrDatabaseExists is returned.
+//
+// Renames are not supported when a backup client is configured as the
+// backup service cannot move the history of a database.
+func (s *Store) RenameDB(ctx context.Context, oldName, newName string) error {
+	if !s.IsPrimary() {
+		return ErrReadOnlyReplica
+	} else if s.BackupClient != nil {
+		return ErrRenameNotSupported
+	}
+	return s.renameDB(oldName, newName, false)
+}
+
+// renameDB moves a database to a new name in the store. Returns
+// ErrDatabaseBusy if either database is in use, unless force is set.
+func (s *Store) renameDB(oldName, newName string, force bool) (err error) {
+	defer func() {
+		TraceLog.Printf("[RenameDB(%s,%s)]: %s", oldName, newName, errorKeyValue(err))
+	}()
+
+	if err := s.openLazyDB(oldName); err != nil {
+		return err
+	} else if err := s.openLazyDB(newName); err != nil {
+		return err
+	}
+
+	// Prevent either name from being lazily opened while it is renamed.
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+
+	var oldDB *DB
+	if err := func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if oldDB = s.dbs[oldName]; oldDB == nil {
+			return ErrDatabaseNotFound
+		} else if !force && oldDB.InUse() {
+			return ErrDatabaseBusy
+		}
+
+		// Replace a previously deleted database but never a live one.
+		if db := s.dbs[newName]; db != nil {
+			if db.PageN() > 0 {
+				return ErrDatabaseExists
+			} else if !force && db.InUse() {
+				return ErrDatabaseBusy
+			}
+			db.close()
+			delete(s.dbs, newName)
+			if err := s.OS.RemoveAll("RENAMEDB", s.DBPath(newName)); err != nil {
+				return fmt.Errorf("remove deleted database directory: %w", err)
+			}
+		}
+
+		if err := s.OS.Rename("RENAMEDB", s.DBPath(oldName), s.DBPath(newName)); err != nil {
+			return fmt.Errorf("rename database directory: %w", err)
+		} else if err := internal.Sync(s.DBDir()); err != nil {
+			return fmt.Errorf("sync database directory: %w", err)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	// Reopen the database outside the lock as it may apply pending LTX files.
+	// The startup integrity checks are skipped as the data is unchanged.
+	db := NewDB(s, newName, s.DBPath(newName))
+	if err := db.Open(); err != nil {
+		// Move the directory back so the database remains under its old name.
+		db.close()
+		if e := s.OS.Rename("RENAMEDB", s.DBPath(newName), s.DBPath(oldName)); e != nil {
+			s.logger(LogSubsystemStore).Error("cannot restore database directory after rename error", slog.String("db", oldName), slog.Any("err", e))
+		}
+		return fmt.Errorf("open database(%q): %w", newName, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Swap the names under a single lock so the database is always found by
+	// at least one of them.
+	s.dbs[newName] = db
+	delete(s.dbs, oldName)
+	oldDB.close()
+
+	s.notifyEvent(Event{Type: EventTypeRenameDB, DB: newName, Data: &RenameDBEventData{OldName: oldName}})
+
+	// Notify listeners so that replicas receive the rename.
+	for sub := range s.changeSetSubscribers {
+		sub.MarkRenamed(oldName, newName)
+	}
+
+	// Update metrics
+	s.metrics.storeDBCountMetric.Set(float64(len(s.dbs)))
+
+	return nil
+}
+
+// invalidateDBEntries removes all files of a database from the file system
+// cache after it has been dropped or renamed by the primary.
+func (s *Store) invalidateDBEntries(name string) {
+	invalidator := s.Invalidator
+	if invalidator == nil {
+		return
+	}
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm", "-pos", "-lock"} {
+		_ = invalidator.InvalidateEntry(name + suffix)
+	}
+}
+
+// PosMap returns a map of databases and their transactional position.
+// Databases that have not been opened yet because of LazyDBOpen are included
+// using the position of their last LTX file on disk.
+func (s *Store) PosMap() map[string]ltx.Pos {
+	// Read unopened names first as it acquires the store lock.
+	names, err := s.UnopenedDBNames()
+	if err != nil {
+		s.logger(LogSubsystemStore).Warn("cannot read unopened databases", slog.Any("err", err))
+	}
+
+	s.mu.Lock()
+	m := make(map[string]ltx.Pos, len(s.dbs)+len(names))
+	for _, db := range s.dbs {
+		m[db.Name()] = db.Pos()
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := m[name]; ok {
+			continue // opened since names were read
+		}
+
+		pos, err := s.readUnopenedDBPos(name)
+		if err != nil {
+			s.logger(LogSubsystemStore).Warn("cannot read unopened database position", slog.String("db", name), slog.Any("err", err))
+			continue
+		} else if pos.IsZero() {
+			continue
+		}
+		m[name] = pos
+	}
+	return m
+}
+
+// readUnopenedDBPos returns the position of a database from the header &
+// trailer of its highest LTX file without opening the database. Partial files
+// are removed when the store is opened so only the trailer is checked here as
+// validating the full file on every reconnect would be too expensive.
+func (s *Store) readUnopenedDBPos(name string) (ltx.Pos, error) {
+	dir := filepath.Join(s.DBPath(name), "ltx")
+	ents, err := s.OS.ReadDir("READUNOPENEDDBPOS", dir)
+	if os.IsNotExist(err) {
+		return ltx.Pos{}, nil
+	} else if err != nil {
+		return ltx.Pos{}, err
+	}
+
+	var filename string
+	var maxTXID ltx.TXID
+	for _, ent := range ents {
+		if _, txID, err := ltx.ParseFilename(ent.Name()); err == nil && txID > maxTXID {
+			filename, maxTXID = ent.Name(), txID
+		}
+	}
+	if filename == "" {
+		return ltx.Pos{}, nil
+	}
+
+	f, err := s.OS.Open("READUNOPENEDDBPOS", filepath.Join(dir, filename))
+	if err != nil {
+		return ltx.Pos{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return ltx.Pos{}, err
+	} else if fi.Size() < ltx.HeaderSize+ltx.TrailerSize {
+		return ltx.Pos{}, fmt.Errorf("ltx file too small: %s", filename)
+	}
+
+	b := make([]byte, ltx.TrailerSize)
+	var trailer ltx.Trailer
+	if _, err := f.ReadAt(b, fi.Size()-ltx.TrailerSize); err != nil {
+		return ltx.Pos{}, err
+	} else if err := trailer.UnmarshalBinary(b); err != nil {
+		return ltx.Pos{}, err
+	} else if err := trailer.Validate(); err != nil {
+		return ltx.Pos{}, err
+	}
+	return ltx.Pos{TXID: maxTXID, PostApplyChecksum: trailer.PostApplyChecksum}, nil
+}
+
+// WaitForTX blocks until the database has applied at least txID or ctx is
+// done. Replicas can use this to provide read-your-writes consistency by
+// waiting for the TXID returned by a write on the primary. The database does
+// not need to exist when this is called.
+func (s *Store) WaitForTX(ctx context.Context, name string, txID ltx.TXID) error {
+	ticker := time.NewTicker(WaitInterval)
+	defer ticker.Stop()
+
+	for {
+		if db := s.DB(name); db != nil && db.TXID() >= txID {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// SubscribeChangeSet creates a new subscriber for store changes.
+func (s *Store) SubscribeChangeSet(nodeID uint64) *ChangeSetSubscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := newChangeSetSubscriber(s, nodeID)
+	s.changeSetSubscribers[sub] = struct{}{}
+
+	s.metrics.storeSubscriberCountMetric.Set(float64(len(s.changeSetSubscribers)))
+	return sub
+}
+
+// UnsubscribeChangeSet removes a subscriber from the store.
+func (s *Store) UnsubscribeChangeSet(sub *ChangeSetSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.changeSetSubscribers, sub)
+	s.metrics.storeSubscriberCountMetric.Set(float64(len(s.changeSetSubscribers)))
+}
+
+// ChangeSetSubscribers returns all current subscribers, sorted by node ID.
+func (s *Store) ChangeSetSubscribers() []*ChangeSetSubscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]*ChangeSetSubscriber, 0, len(s.changeSetSubscribers))
+	for sub := range s.changeSetSubscribers {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].NodeID() < subs[j].NodeID() })
+	return subs
+}
 
 // SubscriberByNodeID returns a subscriber by node ID.
 // Returns nil if the node is not currently subscribed to the store.
@@ -674,6 +1485,133 @@ func (s *Store) changeSetSubscriberByNodeID(nodeID uint64) *ChangeSetSubscriber
 	return nil
 }
 
+// Ack records that a replica has applied a database up to txID. Commits
+// waiting on synchronous replication are notified.
+func (s *Store) Ack(nodeID uint64, name string, txID ltx.TXID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.changeSetSubscriberByNodeID(nodeID)
+	if sub == nil {
+		return
+	}
+
+	sub.mu.Lock()
+	if txID > sub.ackTXIDs[name] {
+		sub.ackTXIDs[name] = txID
+	}
+	sub.mu.Unlock()
+
+	s.notifyReplicaPos()
+}
+
+// notifyReplicaPos wakes commits waiting on replica positions.
+// Must be called while holding the store lock.
+func (s *Store) notifyReplicaPos() {
+	close(s.ackCh)
+	s.ackCh = make(chan struct{})
+}
+
+// syncReplicationEnabled returns true if commits to the database must be
+// acknowledged by replicas.
+func (s *Store) syncReplicationEnabled(name string) bool {
+	if s.SyncReplicaN <= 0 {
+		return false
+	} else if len(s.SyncReplicaDBs) == 0 {
+		return true
+	}
+	for _, v := range s.SyncReplicaDBs {
+		if name == v {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForSyncReplicas blocks until SyncReplicaN replicas have acknowledged
+// txID on the database. Returns immediately if synchronous replication is
+// not enabled for the database or if the store is not the primary.
+func (s *Store) waitForSyncReplicas(ctx context.Context, name string, txID ltx.TXID) error {
+	if !s.syncReplicationEnabled(name) || !s.IsPrimary() {
+		return nil
+	}
+
+	timer := time.NewTimer(s.SyncReplicaTimeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		var n int
+		for sub := range s.changeSetSubscribers {
+			if sub.Replicates(name) && sub.AckTXID(name) >= txID {
+				n++
+			}
+		}
+		ackCh := s.ackCh
+		s.mu.Unlock()
+
+		if n >= s.SyncReplicaN {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-timer.C:
+			return fmt.Errorf("%w: db=%q txid=%s acks=%d", ErrSyncReplication, name, txID, n)
+		case <-ackCh:
+		}
+	}
+}
+
+// waitForBackpressure blocks while too few replicas are within
+// BackpressureMaxLag transactions of txID on the database. The wait is
+// abandoned after BackpressureTimeout so a stuck replica only slows writes.
+func (s *Store) waitForBackpressure(ctx context.Context, name string, txID ltx.TXID) error {
+	if s.BackpressureMaxLag <= 0 || !s.IsPrimary() {
+		return nil
+	}
+
+	timer := time.NewTimer(s.BackpressureTimeout)
+	defer timer.Stop()
+
+	var delayed bool
+	for {
+		s.mu.Lock()
+		var n, replicaN int
+		for sub := range s.changeSetSubscribers {
+			if !sub.Replicates(name) {
+				continue
+			}
+			replicaN++
+			if sub.TXID(name)+ltx.TXID(s.BackpressureMaxLag) >= txID {
+				n++
+			}
+		}
+		want := min(s.BackpressureReplicaN, replicaN)
+		ackCh := s.ackCh
+		s.mu.Unlock()
+
+		if n >= want {
+			return nil
+		}
+
+		if !delayed {
+			delayed = true
+			s.metrics.storeBackpressureDelayCountMetric.Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-timer.C:
+			s.logger(LogSubsystemStore).Warn("backpressure timeout, replicas lagging", slog.String("db", name), slog.String("txid", txID.String()), slog.Int("replicas", n), slog.Int("want", want))
+			return nil
+		case <-ackCh:
+		}
+	}
+}
+
 // MarkDirty marks a database dirty on all subscribers.
 func (s *Store) MarkDirty(name string) {
 	s.mu.Lock()
@@ -733,6 +1671,63 @@ func (s *Store) NotifyEvent(event Event) {
 	s.notifyEvent(event)
 }
 
+// SetDBCreateHook registers fn to be called after a database is created
+// through the file system. Hooks are called synchronously so they must be fast.
+func (s *Store) SetDBCreateHook(fn func(dbName string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dbCreateHook = fn
+}
+
+// SetDBDeleteHook registers fn to be called after a database is deleted
+// through the file system. Hooks are called synchronously so they must be fast.
+func (s *Store) SetDBDeleteHook(fn func(dbName string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dbDeleteHook = fn
+}
+
+// SetDBRenameHook registers fn to be called after a database is renamed
+// through the file system. Hooks are called synchronously so they must be fast.
+func (s *Store) SetDBRenameHook(fn func(oldName, newName string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dbRenameHook = fn
+}
+
+// NotifyDBCreate calls the registered create hook, if any.
+func (s *Store) NotifyDBCreate(dbName string) {
+	s.mu.Lock()
+	fn := s.dbCreateHook
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(dbName)
+	}
+}
+
+// NotifyDBDelete calls the registered delete hook, if any.
+func (s *Store) NotifyDBDelete(dbName string) {
+	s.mu.Lock()
+	fn := s.dbDeleteHook
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(dbName)
+	}
+}
+
+// NotifyDBRename calls the registered rename hook, if any.
+func (s *Store) NotifyDBRename(oldName, newName string) {
+	s.mu.Lock()
+	fn := s.dbRenameHook
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn(oldName, newName)
+	}
+}
+
 func (s *Store) notifyEvent(event Event) {
 	for sub := range s.eventSubscribers {
 		select {
@@ -764,6 +1759,7 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 	s.Environment.SetPrimaryStatus(ctx, false)
 
 	var handoffLeaseID string
+	var bootstrapped bool // true once restored from backup while no primary is reachable
 	for {
 		// Exit if store is closed.
 		if err := ctx.Err(); err != nil {
@@ -773,20 +1769,20 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 		// If a cluster ID exists on the server, ensure it matches what we have.
 		var info PrimaryInfo
 		if leaserClusterID, err := s.Leaser.ClusterID(ctx); err != nil {
-			log.Printf("cannot fetch cluster ID from %q lease, retrying: %s", s.Leaser.Type(), err)
+			s.logger(LogSubsystemLease).Warn("cannot fetch cluster ID from lease, retrying", slog.String("lease", s.Leaser.Type()), slog.Any("err", err))
 			sleepWithContext(ctx, s.ReconnectDelay)
 			continue
 
 		} else if leaserClusterID != "" && s.ClusterID() != "" && leaserClusterID != s.ClusterID() {
-			log.Printf("cannot connect, %q lease already initialized with different ID: %s", s.Leaser.Type(), leaserClusterID)
+			s.logger(LogSubsystemLease).Error("cannot connect, lease already initialized with different cluster ID", slog.String("lease", s.Leaser.Type()), slog.String("cluster", leaserClusterID))
 			sleepWithContext(ctx, s.ReconnectDelay)
 			continue
 
 		} else if leaserClusterID != "" && s.ClusterID() == "" {
-			log.Printf("cannot become primary, local node has no cluster ID and %q lease already initialized with cluster ID %s", s.Leaser.Type(), leaserClusterID)
+			s.logger(LogSubsystemLease).Info("cannot become primary, local node has no cluster ID and lease already initialized with cluster ID", slog.String("lease", s.Leaser.Type()), slog.String("cluster", leaserClusterID))
 
 			if info, err = s.Leaser.PrimaryInfo(ctx); err != nil {
-				log.Printf("cannot find primary, retrying: %s", err)
+				s.logger(LogSubsystemLease).Warn("cannot find primary, retrying", slog.Any("err", err))
 				sleepWithContext(ctx, s.ReconnectDelay)
 				continue
 			}
@@ -806,9 +1802,9 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 
 				// We'll only try to acquire the lease once. If it fails, then it
 				// reverts back to the regular primary/replica flow.
-				log.Printf("%s: acquiring existing lease from handoff", FormatNodeID(s.id))
+				s.logger(LogSubsystemLease).Info("acquiring existing lease from handoff")
 				if lease, err = s.Leaser.AcquireExisting(ctx, leaseID); err != nil {
-					log.Printf("%s: cannot acquire existing lease from handoff, retrying: %s", FormatNodeID(s.id), err)
+					s.logger(LogSubsystemLease).Warn("cannot acquire existing lease from handoff, retrying", slog.Any("err", err))
 					sleepWithContext(ctx, s.ReconnectDelay)
 					continue
 				}
@@ -816,12 +1812,19 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 			} else {
 				// Otherwise, attempt to either obtain a primary lock or read the current primary.
 				lease, info, err = s.acquireLeaseOrPrimaryInfo(ctx)
-				if err == ErrNoPrimary && !s.candidate {
-					log.Printf("%s: cannot find primary & ineligible to become primary, retrying: %s", FormatNodeID(s.id), err)
+				if err == ErrNoPrimary && !s.Candidate() {
+					s.logger(LogSubsystemLease).Info("cannot find primary & ineligible to become primary, retrying", slog.Any("err", err))
+					if !bootstrapped {
+						if err := s.bootstrapFromBackup(ctx); err != nil {
+							s.logger(LogSubsystemStore).Warn("cannot bootstrap from backup, retrying", slog.Any("err", err))
+						} else {
+							bootstrapped = true
+						}
+					}
 					sleepWithContext(ctx, s.ReconnectDelay)
 					continue
 				} else if err != nil {
-					log.Printf("%s: cannot acquire lease or find primary, retrying: %s", FormatNodeID(s.id), err)
+					s.logger(LogSubsystemLease).Warn("cannot acquire lease or find primary, retrying", slog.Any("err", err))
 					sleepWithContext(ctx, s.ReconnectDelay)
 					continue
 				}
@@ -829,26 +1832,41 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 
 			// Monitor as primary if we have obtained a lease.
 			if lease != nil {
-				log.Printf("%s: primary lease acquired, advertising as %s", FormatNodeID(s.id), s.Leaser.AdvertiseURL())
-				if err := s.monitorLeaseAsPrimary(ctx, lease); err != nil {
-					log.Printf("%s: primary lease lost, retrying: %s", FormatNodeID(s.id), err)
+				bootstrapped = false
+				s.logger(LogSubsystemLease).Info("primary lease acquired", slog.String("advertise-url", s.Leaser.AdvertiseURL()))
+				if err := s.monitorLeaseAsPrimary(ctx, lease); errors.Is(err, ErrStaleEpoch) {
+					s.logger(LogSubsystemLease).Warn("primary lease is stale, retrying", slog.Any("err", err))
+					sleepWithContext(ctx, s.ReconnectDelay)
+					continue
+				} else if err != nil {
+					s.logger(LogSubsystemLease).Warn("primary lease lost, retrying", slog.Any("err", err))
 				}
 				if err := s.Recover(ctx); err != nil {
-					log.Printf("%s: state change recovery error (primary): %s", FormatNodeID(s.id), err)
+					s.logger(LogSubsystemStore).Error("state change recovery error", slog.String("role", "primary"), slog.Any("err", err))
 				}
 				continue
 			}
 		}
 
 		// Monitor as replica if another primary already exists.
-		log.Printf("%s: existing primary found (%s), connecting as replica to %q", FormatNodeID(s.id), info.Hostname, info.AdvertiseURL)
+		s.logger(LogSubsystemLease).Info("existing primary found, connecting as replica", slog.String("primary", info.Hostname), slog.String("url", info.AdvertiseURL))
 		if handoffLeaseID, err = s.monitorLeaseAsReplica(ctx, info); err == nil {
-			log.Printf("%s: disconnected from primary, retrying", FormatNodeID(s.id))
+			s.logger(LogSubsystemStore).Info("disconnected from primary, retrying")
+			bootstrapped = false
 		} else {
-			log.Printf("%s: disconnected from primary with error, retrying: %s", FormatNodeID(s.id), err)
+			s.logger(LogSubsystemStore).Warn("disconnected from primary with error, retrying", slog.Any("err", err))
+
+			// Fall back to the backup service if the primary is unreachable.
+			if !bootstrapped && handoffLeaseID == "" {
+				if err := s.bootstrapFromBackup(ctx); err != nil {
+					s.logger(LogSubsystemStore).Warn("cannot bootstrap from backup, retrying", slog.Any("err", err))
+				} else {
+					bootstrapped = true
+				}
+			}
 		}
 		if err := s.Recover(ctx); err != nil {
-			log.Printf("%s: state change recovery error (replica): %s", FormatNodeID(s.id), err)
+			s.logger(LogSubsystemStore).Error("state change recovery error", slog.String("role", "replica"), slog.Any("err", err))
 		}
 
 		// Ignore the sleep if we are receiving a handed off lease.
@@ -861,7 +1879,7 @@ func (s *Store) monitorLease(ctx context.Context) (err error) {
 func (s *Store) acquireLeaseOrPrimaryInfo(ctx context.Context) (Lease, PrimaryInfo, error) {
 	// Attempt to find an existing primary first.
 	info, err := s.Leaser.PrimaryInfo(ctx)
-	if err == ErrNoPrimary && !s.candidate {
+	if err == ErrNoPrimary && !s.Candidate() {
 		return nil, info, err // no primary, not eligible to become primary
 	} else if err != nil && err != ErrNoPrimary {
 		return nil, info, fmt.Errorf("fetch primary url: %w", err)
@@ -869,6 +1887,22 @@ func (s *Store) acquireLeaseOrPrimaryInfo(ctx context.Context) (Lease, PrimaryIn
 		return nil, info, nil
 	}
 
+	// Lower priority candidates wait before acquiring so that a preferred
+	// candidate can become primary first.
+	if d := time.Duration(s.CandidatePriority) * s.CandidatePriorityDelay; d > 0 {
+		s.logger(LogSubsystemLease).Info("no primary, waiting for higher priority candidates", slog.Duration("delay", d))
+		sleepWithContext(ctx, d)
+		if err := ctx.Err(); err != nil {
+			return nil, info, err
+		}
+
+		if info, err = s.Leaser.PrimaryInfo(ctx); err == nil {
+			return nil, info, nil
+		} else if err != ErrNoPrimary {
+			return nil, info, fmt.Errorf("fetch primary url: %w", err)
+		}
+	}
+
 	// If no primary, attempt to become primary.
 	lease, err := s.Leaser.Acquire(ctx)
 	if err == ErrPrimaryExists {
@@ -897,25 +1931,30 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 	closeLeaseOnExit := true
 	defer func() {
 		if closeLeaseOnExit {
-			log.Printf("%s: exiting primary, destroying lease", FormatNodeID(s.id))
+			s.logger(LogSubsystemLease).Info("exiting primary, destroying lease")
 			if err := lease.Close(); err != nil {
-				log.Printf("%s: cannot remove lease: %s", FormatNodeID(s.id), err)
+				s.logger(LogSubsystemLease).Warn("cannot remove lease", slog.Any("err", err))
 			}
 		} else {
-			log.Printf("%s: exiting primary, preserving lease for handoff", FormatNodeID(s.id))
+			s.logger(LogSubsystemLease).Info("exiting primary, preserving lease for handoff")
 		}
 
 		// Pause momentarily if this was a manual demotion.
 		if demoted {
-			log.Printf("%s: waiting for %s after demotion", FormatNodeID(s.id), s.DemoteDelay)
+			s.logger(LogSubsystemLease).Info("waiting after demotion", slog.Duration("delay", s.DemoteDelay))
 			sleepWithContext(ctx, s.DemoteDelay)
 		}
 	}()
 
 	// If the leaser doesn't have a cluster ID yet, generate one or set it to ours.
+	// A missing cluster ID also means the leaser's state was recreated so its
+	// epochs may have started over.
+	var leaserReset bool
 	if v, err := s.Leaser.ClusterID(ctx); err != nil {
 		return fmt.Errorf("set cluster id: %w", err)
 	} else if v == "" {
+		leaserReset = true
+
 		// Use existing ID or generate a new one.
 		clusterID := s.ClusterID()
 		if clusterID == "" {
@@ -932,12 +1971,39 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 			return fmt.Errorf("set local cluster id: %w", err)
 		}
 
-		log.Printf("set cluster id on %q lease %q", s.Leaser.Type(), clusterID)
+		s.logger(LogSubsystemLease).Info("set cluster id on lease", slog.String("lease", s.Leaser.Type()), slog.String("cluster", clusterID))
+	}
+
+	// Fence this node if the lease is older than one already observed, unless
+	// the leaser was reset. Replicas reject a stale primary anyway but it
+	// must not accept writes either. The lease is released & reacquired later
+	// which advances the epoch on leasers that count acquisitions.
+	if epoch := lease.Epoch(); epoch < s.Epoch() {
+		if !leaserReset {
+			return fmt.Errorf("%w: lease epoch %d is older than %d", ErrStaleEpoch, epoch, s.Epoch())
+		}
+
+		s.logger(LogSubsystemLease).Warn("leaser reset, restarting epoch", slog.String("lease", s.Leaser.Type()), slog.Uint64("epoch", epoch), slog.Uint64("prev", s.Epoch()))
+		if err := s.writeEpoch(epoch); err != nil {
+			return fmt.Errorf("reset epoch: %w", err)
+		}
+	}
+
+	// Persist the lease epoch so this node rejects older primaries once it
+	// is demoted back to a replica.
+	if err := s.setEpoch(lease.Epoch()); err != nil {
+		return fmt.Errorf("set epoch: %w", err)
 	}
 
-	// Mark as the primary node while we're in this function.
+	// Mark as the primary node while we're in this function. A paused
+	// replica resumes as the primary cannot hold its position.
 	s.mu.Lock()
 	s.setLease(lease)
+	if s.pauseCh != nil {
+		close(s.pauseCh)
+		s.pauseCh = nil
+		s.logger(LogSubsystemStore).Warn("replication resumed after becoming primary")
+	}
 	primaryCtx := s.primaryCtx(context.Background())
 	demoteCh := s.demoteCh
 	s.mu.Unlock()
@@ -976,7 +2042,13 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 			//
 			// If we just have a connection error then we'll try to more
 			// aggressively retry the renewal until we exceed TTL.
-			if err := lease.Renew(ctx); err == ErrLeaseExpired {
+			err := lease.Renew(ctx)
+			s.metrics.storeLeaseRenewCountMetric.Inc()
+			if err != nil {
+				s.metrics.storeLeaseRenewErrorCountMetric.Inc()
+			}
+
+			if err == ErrLeaseExpired {
 				return err
 			} else if err != nil {
 				// If our next renewal will exceed TTL, exit now.
@@ -986,7 +2058,7 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 				}
 
 				// Otherwise log error and try again after a shorter period.
-				log.Printf("%s: lease renewal error, retrying: %s", FormatNodeID(s.id), err)
+				s.logger(LogSubsystemLease).Warn("lease renewal error, retrying", slog.Any("err", err))
 				waitDur = time.Second
 				continue
 			}
@@ -996,12 +2068,12 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 
 		case <-demoteCh:
 			demoted = true
-			log.Printf("%s: node manually demoted", FormatNodeID(s.id))
+			s.logger(LogSubsystemLease).Info("node manually demoted")
 			return nil
 
 		case nodeID := <-lease.HandoffCh():
 			if err := s.processHandoff(ctx, nodeID, lease); err != nil {
-				log.Printf("%s: handoff unsuccessful, continuing as primary", FormatNodeID(s.id))
+				s.logger(LogSubsystemLease).Warn("handoff unsuccessful, continuing as primary", slog.Any("err", err))
 				continue
 			}
 			closeLeaseOnExit = false
@@ -1016,8 +2088,8 @@ func (s *Store) monitorLeaseAsPrimary(ctx context.Context, lease Lease) error {
 // monitorPrimaryBackup executes in the background while the node is primary.
 // The context is canceled when the primary status is lost.
 func (s *Store) monitorPrimaryBackup(ctx context.Context) {
-	log.Printf("begin primary backup stream: url=%s", s.BackupClient.URL())
-	defer log.Printf("primary backup stream exiting")
+	s.logger(LogSubsystemStore).Info("begin primary backup stream", slog.String("url", s.BackupClient.URL()))
+	defer s.logger(LogSubsystemStore).Info("primary backup stream exiting")
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -1028,7 +2100,7 @@ func (s *Store) monitorPrimaryBackup(ctx context.Context) {
 			return
 		case <-ticker.C:
 			if err := s.streamBackup(ctx, false); err != nil {
-				log.Printf("backup stream failed, retrying: %s", err)
+				s.logger(LogSubsystemStore).Warn("backup stream failed, retrying", slog.Any("err", err))
 			}
 		}
 	}
@@ -1045,8 +2117,8 @@ func (s *Store) streamBackup(ctx context.Context, oneTime bool) (err error) {
 	subscription := s.SubscribeChangeSet(0)
 	defer func() { _ = subscription.Close() }()
 
-	slog.Info("begin streaming backup", slog.Duration("full-sync-interval", s.BackupFullSyncInterval))
-	defer func() { slog.Info("exiting streaming backup") }()
+	s.logger(LogSubsystemStore).Info("begin streaming backup", slog.Duration("full-sync-interval", s.BackupFullSyncInterval))
+	defer func() { s.logger(LogSubsystemStore).Info("exiting streaming backup") }()
 
 	timer := time.NewTimer(0)
 	defer timer.Stop()
@@ -1066,7 +2138,7 @@ LOOP:
 		// If we don't have a position map yet or if it's been reset then fetch
 		// a new one from the backup server and update our dirty set.
 		if posMap == nil {
-			slog.Debug("fetching position map from backup server")
+			s.logger(LogSubsystemStore).Debug("fetching position map from backup server")
 			if posMap, err = s.BackupClient.PosMap(ctx); err != nil {
 				return fmt.Errorf("fetch position map: %w", err)
 			}
@@ -1078,7 +2150,7 @@ LOOP:
 			}
 		}
 
-		slog.Debug("syncing databases to backup", slog.Int("dirty", len(dirtySet)))
+		s.logger(LogSubsystemStore).Debug("syncing databases to backup", slog.Int("dirty", len(dirtySet)))
 
 		// Send pending transactions for each database.
 		for name := range dirtySet {
@@ -1099,14 +2171,14 @@ LOOP:
 
 			// If the position returned is empty then clear it from our map.
 			if newPos.IsZero() {
-				slog.Debug("no position, removing from backup sync", slog.String("name", name))
+				s.logger(LogSubsystemStore).Debug("no position, removing from backup sync", slog.String("db", name))
 				delete(posMap, name)
 				continue
 			}
 
 			// Update the latest position on the backup service for this database.
-			slog.Debug("database synced to backup",
-				slog.String("name", name),
+			s.logger(LogSubsystemStore).Debug("database synced to backup",
+				slog.String("db", name),
 				slog.String("pos", newPos.String()))
 			posMap[name] = newPos
 		}
@@ -1138,12 +2210,12 @@ LOOP:
 }
 
 func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.Pos) (newPos ltx.Pos, err error) {
-	slog.Debug("sync database to backup", slog.String("name", name))
+	s.logger(LogSubsystemStore).Debug("sync database to backup", slog.String("db", name))
 
 	db := s.DB(name)
 	if db == nil {
 		// TODO: Handle database deletion
-		slog.Warn("restoring from backup", slog.String("name", name), slog.String("reason", "no-local"))
+		s.logger(LogSubsystemStore).Warn("restoring from backup", slog.String("db", name), slog.String("reason", "no-local"))
 		return ltx.Pos{}, ltx.NewPosMismatchError(remotePos)
 	}
 
@@ -1162,15 +2234,14 @@ func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.P
 	// If the position from the backup server is ahead of the primary then we
 	// need to perform a recovery so that we snapshot from the backup server.
 	if remotePos.TXID > localPos.TXID {
-		slog.Warn("restoring from backup",
-			slog.String("name", name),
+		s.logger(LogSubsystemStore).Warn("restoring from backup",
+			slog.String("db", name),
 			slog.Group("pos",
 				slog.String("local", localPos.String()),
 				slog.String("remote", remotePos.String()),
 			),
 			slog.String("reason", "remote-ahead"),
 		)
-		log.Printf("backup of database %q is ahead of local copy, restoring from backup", name)
 		return ltx.Pos{}, ltx.NewPosMismatchError(remotePos) // backup TXID ahead of primary, needs recovery
 	}
 
@@ -1179,8 +2250,8 @@ func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.P
 	// server. If it does, then we can exit as we're already in sync.
 	if remotePos.TXID == localPos.TXID {
 		if remotePos.PostApplyChecksum != localPos.PostApplyChecksum {
-			slog.Warn("restoring from backup",
-				slog.String("name", name),
+			s.logger(LogSubsystemStore).Warn("restoring from backup",
+				slog.String("db", name),
 				slog.Group("pos",
 					slog.String("local", localPos.String()),
 					slog.String("remote", remotePos.String()),
@@ -1190,7 +2261,7 @@ func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.P
 			return ltx.Pos{}, ltx.NewPosMismatchError(remotePos) // same TXID, different checksum
 		}
 
-		slog.Debug("database in sync with backup, skipping", slog.String("name", name))
+		s.logger(LogSubsystemStore).Debug("database in sync with backup, skipping", slog.String("db", name))
 		return localPos, nil // already in sync
 	}
 
@@ -1209,8 +2280,8 @@ func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.P
 	for txID, n := remotePos.TXID+1, 0; txID <= localPos.TXID && n < MaxBackupLTXFileN; txID, n = txID+1, n+1 {
 		f, err := db.OpenLTXFile(txID)
 		if os.IsNotExist(err) {
-			slog.Warn("restoring from backup",
-				slog.String("name", name),
+			s.logger(LogSubsystemStore).Warn("restoring from backup",
+				slog.String("db", name),
 				slog.Group("pos",
 					slog.String("local", localPos.String()),
 					slog.String("remote", remotePos.String()),
@@ -1243,8 +2314,8 @@ func (s *Store) streamBackupDB(ctx context.Context, name string, remotePos ltx.P
 	var pmErr *ltx.PosMismatchError
 	hwm, err := s.BackupClient.WriteTx(ctx, name, pr)
 	if errors.As(err, &pmErr) {
-		slog.Warn("restoring from backup",
-			slog.String("name", name),
+		s.logger(LogSubsystemStore).Warn("restoring from backup",
+			slog.String("db", name),
 			slog.Group("pos",
 				slog.String("local", localPos.String()),
 				slog.String("remote", pmErr.Pos.String()),
@@ -1284,13 +2355,54 @@ func (s *Store) streamBackupDBSnapshot(ctx context.Context, db *DB) (newPos ltx.
 	return pos, nil
 }
 
-// restoreDBFromBackup pulls the current snapshot from the backup service and
-// restores it to the local database. The backup service acts as the data
-// authority so this occurs when we cannot provide a contiguous series of
-// transaction files to the backup service.
-func (s *Store) restoreDBFromBackup(ctx context.Context, name string) (newPos ltx.Pos, err error) {
-	// Read the snapshot from the backup service.
-	rc, err := s.BackupClient.FetchSnapshot(ctx, name)
+// bootstrapFromBackup restores databases from the backup service while no
+// primary is reachable so a replica can serve reads from the latest backup.
+// Databases that are at or ahead of the backup are left untouched. The store
+// is marked as ready once every database has been restored. This is a no-op
+// if there is no backup client or the backup service has no databases.
+func (s *Store) bootstrapFromBackup(ctx context.Context) error {
+	if s.BackupClient == nil {
+		return nil
+	}
+
+	posMap, err := s.BackupClient.PosMap(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch position map: %w", err)
+	} else if len(posMap) == 0 {
+		return nil
+	}
+
+	filter := s.databaseFilter()
+	for name, remotePos := range posMap {
+		if !MatchDatabaseFilter(filter, name) {
+			continue
+		}
+		if db := s.DB(name); db != nil {
+			if localPos := db.Pos(); localPos == remotePos || localPos.TXID > remotePos.TXID {
+				continue
+			}
+		}
+
+		s.logger(LogSubsystemStore).Info("no primary available, restoring from backup",
+			slog.String("db", name),
+			slog.String("pos", remotePos.String()),
+		)
+		if _, err := s.restoreDBFromBackup(ctx, name); err != nil {
+			return fmt.Errorf("restore from backup (%q): %w", name, err)
+		}
+	}
+
+	s.markReady()
+	return nil
+}
+
+// restoreDBFromBackup pulls the current snapshot from the backup service and
+// restores it to the local database. The backup service acts as the data
+// authority so this occurs when we cannot provide a contiguous series of
+// transaction files to the backup service.
+func (s *Store) restoreDBFromBackup(ctx context.Context, name string) (newPos ltx.Pos, err error) {
+	// Read the snapshot from the backup service.
+	rc, err := s.BackupClient.FetchSnapshot(ctx, name)
 	if err != nil {
 		return ltx.Pos{}, fmt.Errorf("fetch backup snapshot: %w", err)
 	}
@@ -1303,8 +2415,8 @@ func (s *Store) restoreDBFromBackup(ctx context.Context, name string) (newPos lt
 	}
 
 	t := time.Now()
-	slog.Debug("beginning database restore from backup",
-		slog.String("name", name),
+	s.logger(LogSubsystemStore).Debug("beginning database restore from backup",
+		slog.String("db", name),
 		slog.String("prev_pos", db.Pos().String()),
 	)
 
@@ -1331,8 +2443,8 @@ func (s *Store) restoreDBFromBackup(ctx context.Context, name string) (newPos lt
 	}
 	newPos = db.Pos()
 
-	slog.Warn("database restore complete",
-		slog.String("name", name),
+	s.logger(LogSubsystemStore).Warn("database restore complete",
+		slog.String("db", name),
 		slog.String("pos", newPos.String()),
 		slog.Duration("elapsed", time.Since(t)),
 	)
@@ -1381,13 +2493,47 @@ func (s *Store) monitorLeaseAsReplica(ctx context.Context, info PrimaryInfo) (ha
 		s.setPrimaryInfo(nil)
 	}()
 
+	// Request a snapshot for any database that failed checksum verification.
 	posMap := s.PosMap()
-	st, err := s.Client.Stream(ctx, info.AdvertiseURL, s.id, posMap, s.DatabaseFilter)
+	for name := range posMap {
+		if db := s.DB(name); db != nil && db.resync.CompareAndSwap(true, false) {
+			s.logger(LogSubsystemStore).Info("requesting snapshot to resync database", slog.String("db", name))
+			posMap[name] = ltx.Pos{}
+		}
+	}
+
+	// The stream is canceled with errResync if a database needs to be
+	// resynced so that it reconnects with a zero position.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	defer func() {
+		if cause := context.Cause(ctx); errors.Is(cause, errResync) || errors.Is(cause, errReconnect) {
+			handoffLeaseID, err = "", cause
+		}
+	}()
+
+	s.mu.Lock()
+	s.cancelStream = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.cancelStream = nil
+	}()
+
+	st, err := s.Client.Stream(ctx, info.AdvertiseURL, s.id, posMap, s.databaseFilter(), s.partialSnapshots())
 	if err != nil {
+		// Drop any cached primary info as the primary may have moved.
+		if c, ok := s.Leaser.(PrimaryInfoCacher); ok {
+			c.InvalidatePrimaryInfo()
+		}
 		return "", fmt.Errorf("connect to primary: %s ('%s')", err, info.AdvertiseURL)
 	}
 	defer func() { _ = st.Close() }()
 
+	s.connected.Store(true)
+	defer s.connected.Store(false)
+
 	// Adopt cluster ID from primary node if we don't have a cluster ID yet.
 	if s.ClusterID() == "" && st.ClusterID() != "" {
 		if err := s.setClusterID(st.ClusterID()); err != nil {
@@ -1400,41 +2546,551 @@ func (s *Store) monitorLeaseAsReplica(ctx context.Context, info PrimaryInfo) (ha
 		return "", fmt.Errorf("cannot stream from primary with a different cluster id: %s <> %s", s.ClusterID(), st.ClusterID())
 	}
 
+	// Fence off primaries holding a lease older than one we have already
+	// observed. Primaries that do not send an epoch are not checked.
+	if epoch := st.Epoch(); epoch != 0 {
+		if epoch < s.Epoch() {
+			return "", fmt.Errorf("%w: primary epoch %d is older than %d", ErrStaleEpoch, epoch, s.Epoch())
+		} else if err := s.setEpoch(epoch); err != nil {
+			return "", fmt.Errorf("set epoch: %w", err)
+		}
+	}
+
+	// Acknowledge applied transactions in the background so the primary can
+	// release commits waiting on synchronous replication.
+	var acks *ackQueue
+	if s.SyncReplicaN > 0 {
+		acks = newAckQueue()
+		ackCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() { defer close(done); s.sendAcks(ackCtx, info.AdvertiseURL, acks) }()
+		defer func() { cancel(); <-done }()
+	}
+
+	// Periodically verify database checksums against the primary's.
+	if s.ChecksumVerifyInterval > 0 {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := s.monitorChecksums(ctx); errors.Is(err, ErrChecksumMismatch) {
+				cancel(fmt.Errorf("%w: %w", errResync, err))
+			}
+		}()
+		defer func() { cancel(nil); <-done }()
+	}
+
+	// Close the stream if the primary stops sending data.
+	var r io.Reader = st
+	if s.ReplicationHeartbeatInterval > 0 {
+		hr := newHeartbeatReader(st, 2*s.ReplicationHeartbeatInterval)
+		defer hr.Stop()
+		r = hr
+	}
+
+	// Frames received while replication is paused are spooled to disk so
+	// that heartbeats continue to be read & the primary is not held up.
+	// The spool is applied once replication resumes. It is discarded if the
+	// stream disconnects as the primary resends from our current position.
+	var spool *streamSpool
+	defer func() {
+		if spool != nil {
+			_ = spool.Close()
+		}
+	}()
+
+	// Frames are read in a separate goroutine so that a spool can be applied
+	// as soon as replication resumes, even if the primary sends nothing else.
+	// The next frame is only read once the payload of the previous one has
+	// been consumed.
+	frameCh, nextCh := make(chan streamFrameResult), make(chan struct{}, 1)
+	readDone := make(chan struct{})
+	defer close(readDone)
+	go func() {
+		for {
+			frame, err := ReadStreamFrame(r)
+			select {
+			case frameCh <- streamFrameResult{frame: frame, err: err}:
+			case <-readDone:
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			select {
+			case <-nextCh:
+			case <-readDone:
+				return
+			}
+		}
+	}()
+
 	for {
-		frame, err := ReadStreamFrame(st)
+		// Apply spooled frames, in order, once replication is resumed.
+		pauseCh := s.replicationPauseCh()
+		if spool != nil && pauseCh == nil {
+			handoffLeaseID, done, err := s.applyStreamSpool(ctx, spool, acks)
+			_ = spool.Close()
+			spool = nil
+			if err != nil || done {
+				return handoffLeaseID, err
+			}
+		}
+
+		// Only wake on resume if there are spooled frames to apply.
+		var resumeCh <-chan struct{}
+		if spool != nil {
+			resumeCh = pauseCh
+		}
+
+		var result streamFrameResult
+		select {
+		case result = <-frameCh:
+		case <-resumeCh:
+			continue
+		case <-ctx.Done():
+			return "", context.Cause(ctx)
+		}
+
+		frame, err := result.frame, result.err
 		if err == io.EOF {
 			return "", nil // clean disconnect
 		} else if err != nil {
 			return "", fmt.Errorf("next frame: %w", err)
 		}
 
-		switch frame := frame.(type) {
-		case *LTXStreamFrame:
-			if err := s.processLTXStreamFrame(ctx, frame, chunk.NewReader(st)); err != nil {
-				return "", fmt.Errorf("process ltx stream frame: %w", err)
+		// Hold our position while paused. Only heartbeats are processed so
+		// that a primary that stops responding is still detected.
+		if _, ok := frame.(*HeartbeatStreamFrame); !ok && (spool != nil || s.ReplicationPaused()) {
+			if spool == nil {
+				if spool, err = s.createStreamSpool(); err != nil {
+					return "", fmt.Errorf("create stream spool: %w", err)
+				}
 			}
-		case *ReadyStreamFrame:
-			// Mark store as ready once we've received an initial replication set.
-			s.markReady()
-		case *EndStreamFrame:
-			// Server cleanly disconnected
-			return "", nil
-		case *DropDBStreamFrame:
-			log.Printf("deprecated drop db frame received, skipping")
-		case *HandoffStreamFrame:
-			return frame.LeaseID, nil
-		case *HWMStreamFrame:
-			if db := s.DB(frame.Name); db != nil {
-				db.SetHWM(frame.TXID)
+			if err := spool.WriteFrame(frame, r); err != nil {
+				return "", fmt.Errorf("spool stream frame: %w", err)
 			}
-		case *HeartbeatStreamFrame:
-			s.setPrimaryTimestamp(frame.Timestamp)
-		default:
-			return "", fmt.Errorf("invalid stream frame type: 0x%02x", frame.Type())
+
+			// A handoff makes this node the primary so any spooled changes
+			// must be applied first.
+			if _, ok := frame.(*HandoffStreamFrame); ok {
+				s.logger(LogSubsystemStore).Warn("lease handed off while paused, resuming replication")
+				s.ResumeReplication()
+			}
+			nextCh <- struct{}{}
+			continue
+		}
+
+		if handoffLeaseID, done, err := s.processStreamFrame(ctx, frame, r, acks); err != nil || done {
+			return handoffLeaseID, err
+		}
+		nextCh <- struct{}{}
+	}
+}
+
+// streamFrameResult is a frame, or the error reading it, from the primary.
+type streamFrameResult struct {
+	frame StreamFrame
+	err   error
+}
+
+// processStreamFrame applies a single frame received from the primary. Frame
+// payloads are read from r. Returns done if the stream has ended, along with
+// the lease ID if the primary handed off its lease.
+func (s *Store) processStreamFrame(ctx context.Context, frame StreamFrame, r io.Reader, acks *ackQueue) (handoffLeaseID string, done bool, err error) {
+	switch frame := frame.(type) {
+	case *LTXStreamFrame:
+		src := chunk.NewReader(r)
+		if err := s.processLTXStreamFrame(ctx, frame, src); err != nil && s.skipLTXStreamFrame(ctx, frame.Name, src, err) {
+			return "", false, nil
+		} else if errors.Is(err, ErrPositionMismatch) {
+			return "", false, s.handleDivergedDB(frame.Name, err)
+		} else if err != nil {
+			return "", false, fmt.Errorf("process ltx stream frame: %w", err)
+		}
+		if db := s.DB(frame.Name); acks != nil && db != nil && s.syncReplicationEnabled(frame.Name) {
+			acks.push(frame.Name, db.TXID())
+		}
+	case *LTXResumeStreamFrame:
+		if err := s.processLTXResumeStreamFrame(ctx, frame, chunk.NewReader(r)); err != nil {
+			return "", false, fmt.Errorf("process ltx resume stream frame: %w", err)
+		}
+		if db := s.DB(frame.Name); acks != nil && db != nil && s.syncReplicationEnabled(frame.Name) {
+			acks.push(frame.Name, db.TXID())
+		}
+	case *LazySnapshotStreamFrame:
+		if err := s.processLazySnapshotStreamFrame(ctx, frame, chunk.NewReader(r)); err != nil {
+			return "", false, fmt.Errorf("process lazy snapshot stream frame: %w", err)
+		}
+		if db := s.DB(frame.Name); acks != nil && db != nil && s.syncReplicationEnabled(frame.Name) {
+			acks.push(frame.Name, db.TXID())
+		}
+	case *ReadyStreamFrame:
+		// Mark store as ready once we've received an initial replication set.
+		s.markReady()
+	case *EndStreamFrame:
+		// Server cleanly disconnected
+		return "", true, nil
+	case *DropDBStreamFrame:
+		if err := s.removeDB(frame.Name, true); err == ErrDatabaseNotFound {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, fmt.Errorf("drop db: %w", err)
+		}
+		s.invalidateDBEntries(frame.Name)
+		s.logger(LogSubsystemStore).Info("database dropped by primary", slog.String("db", frame.Name))
+	case *RenameDBStreamFrame:
+		if err := s.renameDB(frame.OldName, frame.NewName, true); err != nil {
+			return "", false, fmt.Errorf("rename db: %w", err)
+		}
+		s.invalidateDBEntries(frame.OldName)
+		s.invalidateDBEntries(frame.NewName)
+		s.logger(LogSubsystemStore).Info("database renamed by primary", slog.String("db", frame.NewName), slog.String("prev", frame.OldName))
+	case *HandoffStreamFrame:
+		return frame.LeaseID, true, nil
+	case *HWMStreamFrame:
+		if db := s.DB(frame.Name); db != nil {
+			db.SetHWM(frame.TXID)
+		}
+	case *HeartbeatStreamFrame:
+		s.setPrimaryTimestamp(frame.Timestamp)
+	default:
+		return "", false, fmt.Errorf("invalid stream frame type: 0x%02x", frame.Type())
+	}
+	return "", false, nil
+}
+
+// applyStreamSpool processes the frames spooled while replication was paused.
+func (s *Store) applyStreamSpool(ctx context.Context, spool *streamSpool, acks *ackQueue) (handoffLeaseID string, done bool, err error) {
+	r, err := spool.Reader()
+	if err != nil {
+		return "", false, fmt.Errorf("read stream spool: %w", err)
+	}
+
+	for {
+		frame, err := ReadStreamFrame(r)
+		if err == io.EOF {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, fmt.Errorf("next spooled frame: %w", err)
+		}
+
+		if handoffLeaseID, done, err := s.processStreamFrame(ctx, frame, r, acks); err != nil || done {
+			return handoffLeaseID, done, err
 		}
 	}
 }
 
+// createStreamSpool returns a new, empty spool for stream frames received
+// while replication is paused.
+func (s *Store) createStreamSpool() (*streamSpool, error) {
+	f, err := s.OS.Create("SPOOL", filepath.Join(s.path, "stream.spool"))
+	if err != nil {
+		return nil, err
+	}
+	return &streamSpool{
+		os: s.OS,
+		f:  f,
+		w:  bufio.NewWriter(f),
+
+		// Track how far behind the primary each database is while paused.
+		onLTXHeader: func(name string, hdr ltx.Header) {
+			if db := s.DB(name); db != nil {
+				db.setPrimaryTXID(hdr.MaxTXID)
+			}
+		},
+	}, nil
+}
+
+// streamSpool is a file of stream frames, & their payloads, in the same
+// format as the replication stream.
+type streamSpool struct {
+	os OS
+	f  *os.File
+	w  *bufio.Writer
+
+	// If set, called with the header of each spooled LTX file.
+	onLTXHeader func(name string, hdr ltx.Header)
+}
+
+// WriteFrame appends frame to the spool. Payloads are copied from r.
+func (s *streamSpool) WriteFrame(frame StreamFrame, r io.Reader) error {
+	if err := WriteStreamFrame(s.w, frame); err != nil {
+		return err
+	}
+
+	switch frame.(type) {
+	case *LTXStreamFrame, *LTXResumeStreamFrame, *LazySnapshotStreamFrame:
+		var src io.Reader = chunk.NewReader(r)
+		if frame, ok := frame.(*LTXStreamFrame); ok && s.onLTXHeader != nil {
+			hdr, data, err := ltx.DecodeHeader(src)
+			if err != nil {
+				return fmt.Errorf("peek ltx header: %w", err)
+			}
+			s.onLTXHeader(frame.Name, hdr)
+			src = io.MultiReader(bytes.NewReader(data), src)
+		}
+
+		cw := chunk.NewWriter(s.w)
+		if _, err := io.Copy(cw, src); err != nil {
+			return err
+		} else if err := cw.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reader flushes pending writes & returns a reader from the start of the spool.
+func (s *streamSpool) Reader() (io.Reader, error) {
+	if err := s.w.Flush(); err != nil {
+		return nil, err
+	} else if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(s.f), nil
+}
+
+// Close closes & removes the spool file.
+func (s *streamSpool) Close() error {
+	_ = s.f.Close()
+	return s.os.Remove("SPOOL", s.f.Name())
+}
+
+// handleDivergedDB handles a database whose position cannot be reconciled
+// with the primary based on the ResyncMode. Returns an error so the stream
+// is reconnected.
+func (s *Store) handleDivergedDB(name string, err error) error {
+	db := s.DB(name)
+	if db == nil {
+		return err
+	}
+
+	switch s.ResyncMode {
+	case ResyncModeAuto:
+		s.logger(LogSubsystemStore).Warn("database diverged from primary, resyncing from snapshot", slog.String("db", name), slog.Any("err", err))
+		db.resync.Store(true)
+		return fmt.Errorf("%w: %w", errResync, err)
+
+	case ResyncModePrompt:
+		if db.suspended.CompareAndSwap(false, true) {
+			s.logger(LogSubsystemStore).Error("database diverged from primary, replication suspended until resynced", slog.String("db", name), slog.Any("err", err))
+			s.dbMetrics.dbSuspendedCountMetricVec.WithLabelValues(name).Inc()
+		}
+		return fmt.Errorf("process ltx stream frame: %w", err)
+
+	default:
+		return fmt.Errorf("process ltx stream frame: %w", err)
+	}
+}
+
+// ResyncDB discards the local state of a database on a replica and requests
+// a snapshot from the primary. This also resumes a suspended database.
+func (s *Store) ResyncDB(name string) error {
+	db := s.DB(name)
+	if db == nil {
+		return ErrDatabaseNotFound
+	} else if s.IsPrimary() {
+		return fmt.Errorf("cannot resync database on primary")
+	}
+
+	db.resync.Store(true)
+	db.applyErrorN.Store(0)
+	db.suspended.Store(false)
+	s.logger(LogSubsystemStore).Info("database resync requested", slog.String("db", name))
+
+	// Reconnect to the primary so the snapshot is requested immediately.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancelStream != nil {
+		s.cancelStream(errResync)
+	}
+	return nil
+}
+
+// PauseReplication stops a replica from applying changes from the primary
+// until ResumeReplication is called. The replica holds its current position
+// so its databases do not change while paused. Changes continue to be read
+// from the primary & are spooled to disk until replication is resumed.
+//
+// Replicas using synchronous replication cannot be paused as the primary
+// would wait on acknowledgments for every commit.
+func (s *Store) PauseReplication() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isPrimary() {
+		return ErrPausePrimary
+	} else if s.SyncReplicaN > 0 {
+		return ErrPauseSyncReplica
+	} else if s.pauseCh != nil {
+		return nil // already paused
+	}
+	s.pauseCh = make(chan struct{})
+	s.logger(LogSubsystemStore).Info("replication paused")
+	return nil
+}
+
+// ResumeReplication resumes applying changes from the primary after a call
+// to PauseReplication. This is a no-op if replication is not paused.
+func (s *Store) ResumeReplication() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pauseCh == nil {
+		return
+	}
+	close(s.pauseCh)
+	s.pauseCh = nil
+	s.logger(LogSubsystemStore).Info("replication resumed")
+}
+
+// ReplicationPaused returns true if replication is currently paused.
+func (s *Store) ReplicationPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pauseCh != nil
+}
+
+// replicationPauseCh returns a channel that is closed when replication is
+// resumed. Returns nil if replication is not paused.
+func (s *Store) replicationPauseCh() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pauseCh
+}
+
+// waitReplicationResumed blocks while replication is paused.
+func (s *Store) waitReplicationResumed(ctx context.Context) error {
+	ch := s.replicationPauseCh()
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// errResync is the cause used to cancel a replication stream so that the
+// replica reconnects & resyncs one or more databases.
+var errResync = errors.New("resync")
+
+// errReconnect is the cause used to cancel a replication stream so that the
+// replica reconnects with updated settings.
+var errReconnect = errors.New("reconnect")
+
+// monitorChecksums verifies database checksums every ChecksumVerifyInterval.
+// Returns ErrChecksumMismatch if a database needs to be resynced.
+func (s *Store) monitorChecksums(ctx context.Context) error {
+	ticker := time.NewTicker(s.ChecksumVerifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := s.VerifyChecksums(ctx); errors.Is(err, ErrChecksumMismatch) && s.ChecksumVerifyResync {
+			return err
+		}
+	}
+}
+
+// VerifyChecksums recomputes the checksum of every open database & compares
+// it against the checksum of its current position. Mismatches are reported
+// as events & metrics and are marked for resync if ChecksumVerifyResync is
+// set. Returns ErrChecksumMismatch if any database failed verification.
+func (s *Store) VerifyChecksums(ctx context.Context) error {
+	var mismatchErr error
+	for _, db := range s.DBs() {
+		pos, err := db.Verify(ctx)
+		if err == nil {
+			continue
+		} else if !errors.Is(err, ErrChecksumMismatch) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger(LogSubsystemStore).Warn("cannot verify database checksum", slog.String("db", db.Name()), slog.Any("err", err))
+			continue
+		}
+
+		s.logger(LogSubsystemStore).Error("database checksum mismatch", slog.String("db", db.Name()), slog.Any("err", err))
+		s.dbMetrics.dbChecksumMismatchCountMetricVec.WithLabelValues(db.Name()).Inc()
+		if s.ChecksumVerifyResync {
+			db.resync.Store(true)
+		}
+
+		s.NotifyEvent(Event{
+			Type: EventTypeChecksumMismatch,
+			DB:   db.Name(),
+			Data: ChecksumMismatchEventData{
+				TXID:              pos.TXID,
+				PostApplyChecksum: pos.PostApplyChecksum,
+				Resync:            s.ChecksumVerifyResync,
+			},
+		})
+		mismatchErr = err
+	}
+	return mismatchErr
+}
+
+// sendAcks sends acknowledgments queued by the replication stream to the
+// primary until ctx is canceled.
+func (s *Store) sendAcks(ctx context.Context, primaryURL string, acks *ackQueue) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-acks.notifyCh:
+		}
+
+		for name, txID := range acks.pop() {
+			if err := s.Client.Ack(ctx, primaryURL, s.id, name, txID); err != nil && ctx.Err() == nil {
+				s.logger(LogSubsystemStore).Warn("cannot send ack", slog.String("db", name), slog.String("txid", txID.String()), slog.Any("err", err))
+			}
+		}
+	}
+}
+
+// ackQueue holds the latest TXID to acknowledge for each database.
+// TXIDs pushed while an acknowledgment is in-flight are coalesced.
+type ackQueue struct {
+	mu       sync.Mutex
+	pending  map[string]ltx.TXID
+	notifyCh chan struct{}
+}
+
+func newAckQueue() *ackQueue {
+	return &ackQueue{
+		pending:  make(map[string]ltx.TXID),
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+func (q *ackQueue) push(name string, txID ltx.TXID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending[name] = txID
+
+	select {
+	case q.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (q *ackQueue) pop() map[string]ltx.TXID {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = make(map[string]ltx.TXID)
+	return pending
+}
+
 // monitorRetention periodically enforces retention of LTX files on the databases.
 func (s *Store) monitorRetention(ctx context.Context) error {
 	ticker := time.NewTicker(s.RetentionMonitorInterval)
@@ -1452,6 +3108,26 @@ func (s *Store) monitorRetention(ctx context.Context) error {
 	}
 }
 
+// monitorMetrics periodically updates metrics that are too expensive to
+// compute on every write.
+func (s *Store) monitorMetrics(ctx context.Context) error {
+	ticker := time.NewTicker(MetricsMonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for _, db := range s.DBs() {
+				if n, err := db.WALFileSize(); err == nil {
+					s.dbMetrics.dbWALSizeBytesMetricVec.WithLabelValues(db.Name()).Set(float64(n))
+				}
+			}
+		}
+	}
+}
+
 // monitorHaltLock periodically check all halt locks for expiration.
 func (s *Store) monitorHaltLock(ctx context.Context) error {
 	ticker := time.NewTicker(s.HaltLockMonitorInterval)
@@ -1491,11 +3167,12 @@ func (s *Store) Recover(ctx context.Context) (err error) {
 // EnforceRetention enforces retention of LTX files on all databases.
 func (s *Store) EnforceRetention(ctx context.Context) (err error) {
 	// Skip enforcement if not set.
-	if s.Retention <= 0 {
+	retention, _, _ := s.RetentionPolicy()
+	if retention <= 0 {
 		return nil
 	}
 
-	minTime := time.Now().Add(-s.Retention).UTC()
+	minTime := time.Now().Add(-retention).UTC()
 
 	for _, db := range s.DBs() {
 		if e := db.EnforceRetention(ctx, minTime); err == nil {
@@ -1505,17 +3182,71 @@ func (s *Store) EnforceRetention(ctx context.Context) (err error) {
 	return nil
 }
 
+// AcquireSnapshotBarrier blocks writes on all databases and flushes their
+// files to disk so that a consistent file system snapshot can be taken.
+//
+// The barrier is automatically released after BarrierMaxDuration so that a
+// caller that does not release it cannot starve writers indefinitely.
+func (s *Store) AcquireSnapshotBarrier(ctx context.Context) (_ SnapshotBarrier, retErr error) {
+	dbs := s.DBs()
+	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name() < dbs[j].Name() })
+
+	b := &snapshotBarrier{}
+	defer func() {
+		if retErr != nil {
+			b.Release()
+		}
+	}()
+
+	for _, db := range dbs {
+		guardSet, err := db.AcquireWriteLock(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("acquire write lock (%s): %w", db.Name(), err)
+		}
+		b.guardSets = append(b.guardSets, guardSet)
+
+		if err := db.SyncDatabase(ctx); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("sync database (%s): %w", db.Name(), err)
+		} else if err := db.SyncWAL(ctx); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("sync wal (%s): %w", db.Name(), err)
+		}
+
+		if txID := db.TXID(); txID > b.txID {
+			b.txID = txID
+		}
+	}
+
+	if d := s.BarrierMaxDuration; d > 0 {
+		b.timer = time.AfterFunc(d, func() {
+			s.logger(LogSubsystemStore).Warn("snapshot barrier exceeded max duration, releasing", slog.Duration("max", d))
+			b.Release()
+		})
+	}
+
+	return b, nil
+}
+
 func (s *Store) processLTXStreamFrame(ctx context.Context, frame *LTXStreamFrame, src io.Reader) (err error) {
 	db, err := s.CreateDBIfNotExists(frame.Name)
 	if err != nil {
 		return fmt.Errorf("create database: %w", err)
 	}
 
+	// Discard data for suspended databases so other databases can continue.
+	if db.Suspended() {
+		if _, err := io.Copy(io.Discard, src); err != nil {
+			return fmt.Errorf("discard ltx body: %w", err)
+		}
+		return nil
+	}
+	defer func() { s.trackDBApplyError(ctx, db, err) }()
+
 	hdr, data, err := ltx.DecodeHeader(src)
 	if err != nil {
 		return fmt.Errorf("peek ltx header: %w", err)
 	}
 	src = io.MultiReader(bytes.NewReader(data), src)
+	db.setPrimaryTXID(hdr.MaxTXID)
 
 	TraceLog.Printf("[ProcessLTXStreamFrame.Begin(%s)]: txid=%s-%s, preApplyChecksum=%s", db.Name(), hdr.MinTXID.String(), hdr.MaxTXID.String(), hdr.PreApplyChecksum)
 	defer func() {
@@ -1562,14 +3293,24 @@ func (s *Store) processLTXStreamFrame(ctx context.Context, frame *LTXStreamFrame
 			PostApplyChecksum: hdr.PreApplyChecksum,
 		}
 		if pos := db.Pos(); pos != expectedPos {
-			return fmt.Errorf("position mismatch on db %q: %s <> %s", db.Name(), pos, expectedPos)
+			return fmt.Errorf("%w on db %q: %s <> %s", ErrPositionMismatch, db.Name(), pos, expectedPos)
 		}
 	}
 
 	// Write LTX file to a temporary file and we'll atomically rename later.
+	// Snapshots are written to a fixed path & kept if the transfer is
+	// interrupted so that the primary can resume it on the next stream.
 	path := db.LTXPath(hdr.MinTXID, hdr.MaxTXID)
 	tmpPath := fmt.Sprintf("%s.%d.tmp", path, rand.Int())
-	defer func() { _ = s.OS.Remove("PROCESSLTX", tmpPath) }()
+	if hdr.IsSnapshot() {
+		tmpPath = db.PartialSnapshotPath()
+	}
+	var keepTmp bool
+	defer func() {
+		if !keepTmp {
+			_ = s.OS.Remove("PROCESSLTX", tmpPath)
+		}
+	}()
 
 	f, err := s.OS.Create("PROCESSLTX", tmpPath)
 	if err != nil {
@@ -1579,8 +3320,12 @@ func (s *Store) processLTXStreamFrame(ctx context.Context, frame *LTXStreamFrame
 
 	n, err := io.Copy(f, src)
 	if err != nil {
+		keepTmp = hdr.IsSnapshot() && f.Sync() == nil
 		return fmt.Errorf("write ltx file: %w", err)
-	} else if err := f.Sync(); err != nil {
+	}
+	s.dbMetrics.dbLTXRecvBytesMetricVec.WithLabelValues(db.Name()).Add(float64(n))
+
+	if err := f.Sync(); err != nil {
 		return fmt.Errorf("fsync ltx file: %w", err)
 	}
 
@@ -1592,16 +3337,19 @@ func (s *Store) processLTXStreamFrame(ctx context.Context, frame *LTXStreamFrame
 	}
 
 	// Update metrics
-	dbLTXCountMetricVec.WithLabelValues(db.Name()).Inc()
-	dbLTXBytesMetricVec.WithLabelValues(db.Name()).Set(float64(n))
+	s.dbMetrics.dbLTXCountMetricVec.WithLabelValues(db.Name()).Inc()
+	s.dbMetrics.dbLTXBytesMetricVec.WithLabelValues(db.Name()).Set(float64(n))
 
-	// Remove other LTX files after a snapshot.
+	// Remove other LTX files after a snapshot. Otherwise remove any partial
+	// snapshot left by an earlier stream as it is no longer needed.
 	if hdr.IsSnapshot() {
 		dir, file := filepath.Split(path)
-		log.Printf("snapshot received for %q, removing other ltx files: %s", db.Name(), file)
+		s.logger(LogSubsystemStore).Info("snapshot received, removing other ltx files", slog.String("db", db.Name()), slog.String("txid", hdr.MaxTXID.String()), slog.String("file", file))
 		if err := removeFilesExcept(s.OS, dir, file); err != nil {
 			return fmt.Errorf("remove ltx except snapshot: %w", err)
 		}
+	} else if err := s.OS.Remove("PROCESSLTX", db.PartialSnapshotPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove partial snapshot: %w", err)
 	}
 
 	// Attempt to apply the LTX file to the database.
@@ -1619,6 +3367,166 @@ func (s *Store) processLTXStreamFrame(ctx context.Context, frame *LTXStreamFrame
 	return nil
 }
 
+// processLTXResumeStreamFrame appends the remainder of a snapshot to the
+// partial snapshot received on a previous stream & then applies it.
+func (s *Store) processLTXResumeStreamFrame(ctx context.Context, frame *LTXResumeStreamFrame, src io.Reader) (err error) {
+	db := s.DB(frame.Name)
+	if db == nil {
+		return fmt.Errorf("database not found: %q", frame.Name)
+	}
+
+	// Move the partial snapshot aside as it is rewritten while processing.
+	path := db.PartialSnapshotPath() + ".resume"
+	if err := s.OS.Rename("PROCESSLTX", db.PartialSnapshotPath(), path); err != nil {
+		return fmt.Errorf("rename partial snapshot: %w", err)
+	}
+	defer func() { _ = s.OS.Remove("PROCESSLTX", path) }()
+
+	f, err := s.OS.Open("PROCESSLTX", path)
+	if err != nil {
+		return fmt.Errorf("open partial snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if fi, err := f.Stat(); err != nil {
+		return fmt.Errorf("stat partial snapshot: %w", err)
+	} else if fi.Size() < frame.Offset {
+		return fmt.Errorf("partial snapshot smaller than resume offset: %d < %d", fi.Size(), frame.Offset)
+	}
+
+	s.logger(LogSubsystemStore).Info("resuming snapshot", slog.String("db", db.Name()), slog.Int64("offset", frame.Offset))
+	return s.processLTXStreamFrame(ctx, &LTXStreamFrame{Name: frame.Name}, io.MultiReader(io.LimitReader(f, frame.Offset), src))
+}
+
+// processLazySnapshotStreamFrame bootstraps a database from a lazy snapshot.
+// The remaining pages are fetched from the primary as they are read.
+func (s *Store) processLazySnapshotStreamFrame(ctx context.Context, frame *LazySnapshotStreamFrame, src io.Reader) (err error) {
+	db, err := s.CreateDBIfNotExists(frame.Name)
+	if err != nil {
+		return fmt.Errorf("create database: %w", err)
+	}
+
+	// Discard data for suspended databases so other databases can continue.
+	if db.Suspended() {
+		if _, err := io.Copy(io.Discard, src); err != nil {
+			return fmt.Errorf("discard lazy snapshot body: %w", err)
+		}
+		return nil
+	}
+	defer func() { s.trackDBApplyError(ctx, db, err) }()
+
+	if err := db.ApplyLazySnapshot(ctx, src); err != nil {
+		return fmt.Errorf("apply lazy snapshot: %w", err)
+	} else if _, err := io.Copy(io.Discard, src); err != nil {
+		return fmt.Errorf("discard lazy snapshot trailer: %w", err)
+	}
+	return nil
+}
+
+// partialSnapshots returns the snapshots partially received on previous
+// streams so that the primary can resume them instead of starting over.
+func (s *Store) partialSnapshots() []PartialSnapshot {
+	var a []PartialSnapshot
+	for _, db := range s.DBs() {
+		f, err := s.OS.Open("PARTIALSNAPSHOT", db.PartialSnapshotPath())
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			s.logger(LogSubsystemStore).Warn("cannot open partial snapshot", slog.String("db", db.Name()), slog.Any("err", err))
+			continue
+		}
+
+		hdr, _, err := ltx.DecodeHeader(f)
+		fi, statErr := f.Stat()
+		_ = f.Close()
+		if err != nil || statErr != nil || !hdr.IsSnapshot() {
+			continue
+		}
+
+		a = append(a, PartialSnapshot{
+			Name:      db.Name(),
+			TXID:      hdr.MaxTXID,
+			NodeID:    hdr.NodeID,
+			Timestamp: hdr.Timestamp,
+			Size:      fi.Size(),
+		})
+	}
+
+	sort.Slice(a, func(i, j int) bool { return a[i].Name < a[j].Name })
+	return a
+}
+
+// trackDBApplyError records the result of applying replicated data to db and
+// suspends the database after DBErrorThreshold consecutive errors.
+func (s *Store) trackDBApplyError(ctx context.Context, db *DB, err error) {
+	if err == nil {
+		db.applyErrorN.Store(0)
+		return
+	} else if s.DBErrorThreshold <= 0 || ctx.Err() != nil {
+		return
+	}
+
+	if n := db.applyErrorN.Add(1); int(n) >= s.DBErrorThreshold && db.suspended.CompareAndSwap(false, true) {
+		s.logger(LogSubsystemStore).Error("database replication suspended",
+			slog.String("db", db.Name()),
+			slog.Int("errors", int(n)),
+			slog.Any("err", err))
+		s.dbMetrics.dbSuspendedCountMetricVec.WithLabelValues(db.Name()).Inc()
+	}
+}
+
+// skipLTXStreamFrame returns true if a frame that failed to apply is skipped
+// so the stream continues for other databases. Frames are only skipped while
+// the database is below DBErrorThreshold & the rest of the frame can be read
+// from src. Otherwise the stream reconnects & the primary resends from the
+// database's current position.
+//
+// A first position mismatch is not skipped as it is handled by ResyncMode.
+// Later mismatches are usually caused by a frame that was skipped earlier.
+func (s *Store) skipLTXStreamFrame(ctx context.Context, name string, src io.Reader, err error) bool {
+	db := s.DB(name)
+	if db == nil || s.DBErrorThreshold <= 0 || ctx.Err() != nil || db.Suspended() {
+		return false
+	}
+
+	n := db.applyErrorN.Load()
+	if n == 0 || (n == 1 && errors.Is(err, ErrPositionMismatch)) {
+		return false
+	} else if _, err := io.Copy(io.Discard, src); err != nil {
+		return false
+	}
+
+	s.logger(LogSubsystemStore).Warn("skipping ltx frame after apply error",
+		slog.String("db", name),
+		slog.Int("errors", int(n)),
+		slog.Any("err", err))
+	return true
+}
+
+// ResumeDB clears the suspended state of a database so that replication
+// is applied to it again. The replica reconnects to the primary so that
+// transactions discarded while suspended are resent.
+func (s *Store) ResumeDB(name string) error {
+	db := s.DB(name)
+	if db == nil {
+		return ErrDatabaseNotFound
+	}
+
+	if db.applyErrorN.Swap(0) == 0 && !db.suspended.Load() {
+		return nil
+	}
+	if db.suspended.CompareAndSwap(true, false) {
+		s.logger(LogSubsystemStore).Info("database replication resumed", slog.String("db", name))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancelStream != nil {
+		s.cancelStream(fmt.Errorf("%w: database resumed", errReconnect))
+	}
+	return nil
+}
+
 // ltxHeaderFlags returns flags used for the LTX header.
 func (s *Store) ltxHeaderFlags() uint32 {
 	var flags uint32
@@ -1641,7 +3549,7 @@ func (s *Store) setPrimaryTimestamp(ts int64) {
 
 	if invalidator := s.Invalidator; invalidator != nil {
 		if err := invalidator.InvalidateLag(); err != nil {
-			log.Printf("error invalidating .lag cache: %s\n", err)
+			s.logger(LogSubsystemStore).Warn("cannot invalidate .lag cache", slog.Any("err", err))
 		}
 	}
 }
@@ -1669,7 +3577,7 @@ func (v *StoreVar) String() string {
 	s := (*Store)(v)
 	m := &storeVarJSON{
 		IsPrimary: s.IsPrimary(),
-		Candidate: s.candidate,
+		Candidate: s.Candidate(),
 		DBs:       make(map[string]*dbVarJSON),
 	}
 
@@ -1681,6 +3589,7 @@ func (v *StoreVar) String() string {
 			TXID:     pos.TXID.String(),
 			Checksum: pos.PostApplyChecksum.String(),
 		}
+		dbJSON.WALSize, _ = db.WALFileSize()
 
 		dbJSON.Locks.Pending = db.pendingLock.State().String()
 		dbJSON.Locks.Shared = db.sharedLock.State().String()
@@ -1712,6 +3621,38 @@ type storeVarJSON struct {
 	DBs       map[string]*dbVarJSON `json:"dbs"`
 }
 
+// SnapshotBarrier represents a pause on writes across all databases.
+type SnapshotBarrier interface {
+	// TXID returns the highest transaction ID across all databases at the
+	// time the barrier was acquired.
+	TXID() ltx.TXID
+
+	// Release resumes writes. It is safe to call multiple times.
+	Release()
+}
+
+var _ SnapshotBarrier = (*snapshotBarrier)(nil)
+
+type snapshotBarrier struct {
+	once      sync.Once
+	txID      ltx.TXID
+	guardSets []*GuardSet
+	timer     *time.Timer
+}
+
+func (b *snapshotBarrier) TXID() ltx.TXID { return b.txID }
+
+func (b *snapshotBarrier) Release() {
+	b.once.Do(func() {
+		if b.timer != nil {
+			b.timer.Stop()
+		}
+		for _, guardSet := range b.guardSets {
+			guardSet.Unlock()
+		}
+	})
+}
+
 // ChangeSetSubscriber subscribes to changes to databases in the store.
 //
 // It implements a set of "dirty" databases instead of a channel of all events
@@ -1726,6 +3667,11 @@ type ChangeSetSubscriber struct {
 	notifyCh  chan struct{}
 	dirtySet  map[string]struct{}
 	handoffCh chan string
+	renames   []DBRename          // database renames since the last call to Renames()
+	ackTXIDs  map[string]ltx.TXID // highest acknowledged TXID by database
+	txIDs     map[string]ltx.TXID // last TXID sent by database
+	replica   bool                // true if subscribed by a replica stream
+	filter    []string            // database filter requested by the replica
 }
 
 // newChangeSetSubscriber returns a new instance of Subscriber associated with a store.
@@ -1736,6 +3682,8 @@ func newChangeSetSubscriber(store *Store, nodeID uint64) *ChangeSetSubscriber {
 		notifyCh:  make(chan struct{}, 1),
 		dirtySet:  make(map[string]struct{}),
 		handoffCh: make(chan string),
+		ackTXIDs:  make(map[string]ltx.TXID),
+		txIDs:     make(map[string]ltx.TXID),
 	}
 	return s
 }
@@ -1767,6 +3715,95 @@ func (s *ChangeSetSubscriber) MarkDirty(name string) {
 	}
 }
 
+// MarkRenamed records a database rename. Both names are also marked as dirty.
+func (s *ChangeSetSubscriber) MarkRenamed(oldName, newName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renames = append(s.renames, DBRename{OldName: oldName, NewName: newName})
+	s.dirtySet[oldName] = struct{}{}
+	s.dirtySet[newName] = struct{}{}
+
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Renames returns the database renames, in order, since the last call to
+// Renames(). This call clears the list.
+func (s *ChangeSetSubscriber) Renames() []DBRename {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	renames := s.renames
+	s.renames = nil
+	return renames
+}
+
+// DBRename represents the rename of a database.
+type DBRename struct {
+	OldName string
+	NewName string
+}
+
+// SetDatabaseFilter marks the subscriber as a replica stream that receives
+// the databases passing filter. See MatchDatabaseFilter().
+func (s *ChangeSetSubscriber) SetDatabaseFilter(filter []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replica, s.filter = true, filter
+}
+
+// Replicates returns true if the subscriber is a replica stream that receives
+// the database. Only these subscribers count toward synchronous replication
+// & backpressure.
+func (s *ChangeSetSubscriber) Replicates(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replica && MatchDatabaseFilter(s.filter, name)
+}
+
+// AckTXID returns the highest TXID acknowledged by the node for a database.
+func (s *ChangeSetSubscriber) AckTXID(name string) ltx.TXID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ackTXIDs[name]
+}
+
+// SetPosMap records the positions sent to the node for each database.
+func (s *ChangeSetSubscriber) SetPosMap(posMap map[string]ltx.Pos) {
+	s.mu.Lock()
+	s.txIDs = make(map[string]ltx.TXID, len(posMap))
+	for name, pos := range posMap {
+		s.txIDs[name] = pos.TXID
+	}
+	s.mu.Unlock()
+
+	// Notify commits waiting on backpressure.
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.notifyReplicaPos()
+}
+
+// TXID returns the last TXID sent to the node for a database.
+func (s *ChangeSetSubscriber) TXID(name string) ltx.TXID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.txIDs[name]
+}
+
+// TXIDs returns the last TXID sent to the node for each database.
+func (s *ChangeSetSubscriber) TXIDs() map[string]ltx.TXID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := make(map[string]ltx.TXID, len(s.txIDs))
+	for name, txID := range s.txIDs {
+		m[name] = txID
+	}
+	return m
+}
+
 // DirtySet returns a set of database IDs that have changed since the last call
 // to DirtySet(). This call clears the set.
 func (s *ChangeSetSubscriber) DirtySet() map[string]struct{} {
@@ -1807,6 +3844,12 @@ const (
 	EventTypeInit          = "init"
 	EventTypeTx            = "tx"
 	EventTypePrimaryChange = "primaryChange"
+	EventTypeCreateDB      = "createDB"
+	EventTypeDropDB        = "dropDB"
+	EventTypeRenameDB      = "renameDB"
+
+	EventTypeChecksumMismatch = "checksumMismatch"
+	EventTypeTxLimitExceeded  = "txLimitExceeded"
 )
 
 // Event represents a generic event.
@@ -1832,9 +3875,18 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 		e.Data = &TxEventData{}
 	case EventTypePrimaryChange:
 		e.Data = &PrimaryChangeEventData{}
+	case EventTypeRenameDB:
+		e.Data = &RenameDBEventData{}
+	case EventTypeChecksumMismatch:
+		e.Data = &ChecksumMismatchEventData{}
+	case EventTypeTxLimitExceeded:
+		e.Data = &TxLimitExceededEventData{}
 	default:
 		e.Data = nil
 	}
+	if len(v.Data) == 0 {
+		return nil
+	}
 	if err := json.Unmarshal(v.Data, &e.Data); err != nil {
 		return err
 	}
@@ -1865,6 +3917,23 @@ type PrimaryChangeEventData struct {
 	Hostname  string `json:"hostname,omitempty"`
 }
 
+type RenameDBEventData struct {
+	OldName string `json:"oldName"`
+}
+
+type ChecksumMismatchEventData struct {
+	TXID              ltx.TXID     `json:"txID"`
+	PostApplyChecksum ltx.Checksum `json:"postApplyChecksum"` // expected checksum
+	Resync            bool         `json:"resync"`            // if true, db will be re-snapshotted
+}
+
+type TxLimitExceededEventData struct {
+	PageN    int   `json:"pageN"` // pages written by the transaction so far
+	Size     int64 `json:"size"`  // bytes of page data written so far
+	MaxPageN int   `json:"maxPageN,omitempty"`
+	MaxSize  int64 `json:"maxSize,omitempty"`
+}
+
 var _ context.Context = (*primaryCtx)(nil)
 
 // primaryCtx represents a context that is marked done when the node loses its primary status.
@@ -1952,30 +4021,87 @@ func sleepWithContext(ctx context.Context, d time.Duration) {
 	}
 }
 
-// Store metrics.
-var (
-	storeDBCountMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "litefs_db_count",
-		Help: "Number of managed databases.",
-	})
-
-	storeIsPrimaryMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "litefs_is_primary",
-		Help: "Primary status of the node.",
-	})
+// storeMetrics holds the store-level collectors for a single store.
+type storeMetrics struct {
+	storeDBCountMetric                prometheus.Gauge
+	storeIsPrimaryMetric              prometheus.Gauge
+	storeSubscriberCountMetric        prometheus.Gauge
+	storeLeaseRenewCountMetric        prometheus.Counter
+	storeLeaseRenewErrorCountMetric   prometheus.Counter
+	storeBackpressureDelayCountMetric prometheus.Counter
+}
 
-	storeSubscriberCountMetric = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "litefs_subscriber_count",
-		Help: "Number of connected subscribers",
-	})
+func newStoreMetrics(s *Store, reg prometheus.Registerer) *storeMetrics {
+	f := promauto.With(reg)
 
-	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	f.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "litefs_lag_seconds",
 		Help: "Lag behind the primary node, in seconds",
-	}, func() float64 {
-		if s := GlobalStore.Load(); s != nil {
-			return s.(*Store).Lag().Seconds()
-		}
-		return 0
+	}, func() float64 { return s.Lag().Seconds() })
+
+	return &storeMetrics{
+		storeDBCountMetric: f.NewGauge(prometheus.GaugeOpts{
+			Name: "litefs_db_count",
+			Help: "Number of managed databases.",
+		}),
+
+		storeIsPrimaryMetric: f.NewGauge(prometheus.GaugeOpts{
+			Name: "litefs_is_primary",
+			Help: "Primary status of the node.",
+		}),
+
+		storeSubscriberCountMetric: f.NewGauge(prometheus.GaugeOpts{
+			Name: "litefs_subscriber_count",
+			Help: "Number of connected subscribers",
+		}),
+
+		storeLeaseRenewCountMetric: f.NewCounter(prometheus.CounterOpts{
+			Name: "litefs_lease_renew_count",
+			Help: "Number of lease renewal attempts by the primary.",
+		}),
+
+		storeLeaseRenewErrorCountMetric: f.NewCounter(prometheus.CounterOpts{
+			Name: "litefs_lease_renew_error_count",
+			Help: "Number of failed lease renewal attempts by the primary.",
+		}),
+
+		storeBackpressureDelayCountMetric: f.NewCounter(prometheus.CounterOpts{
+			Name: "litefs_backpressure_delay_count",
+			Help: "Number of commits delayed by lagging replicas.",
+		}),
+	}
+}
+
+// heartbeatReader wraps a replication stream and closes it if a read blocks
+// for longer than the timeout. Time spent outside of Read() is not counted so
+// slow processing of frames on the replica does not trigger the timeout.
+type heartbeatReader struct {
+	rc       io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newHeartbeatReader(rc io.ReadCloser, timeout time.Duration) *heartbeatReader {
+	r := &heartbeatReader{rc: rc, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.timedOut.Store(true)
+		_ = r.rc.Close()
 	})
-)
+	r.timer.Stop()
+	return r
+}
+
+// Stop stops the timeout timer.
+func (r *heartbeatReader) Stop() { r.timer.Stop() }
+
+func (r *heartbeatReader) Read(p []byte) (n int, err error) {
+	r.timer.Reset(r.timeout)
+	n, err = r.rc.Read(p)
+	r.timer.Stop()
+
+	if r.timedOut.Load() {
+		return n, ErrStreamTimeout
+	}
+	return n, err
+}
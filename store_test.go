@@ -3,18 +3,27 @@ package litefs_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/superfly/litefs"
+	"github.com/superfly/litefs/internal/chunk"
 	"github.com/superfly/litefs/internal/testingutil"
 	"github.com/superfly/litefs/mock"
 	"github.com/superfly/ltx"
+	"golang.org/x/sync/errgroup"
 )
 
 // Ensure store can create a new, empty database.
@@ -124,6 +133,305 @@ func TestStore_Open(t *testing.T) {
 	})
 }
 
+func TestStore_Open_StartupIntegrityCheck(t *testing.T) {
+	t.Run("ErrDBCorrupted/Header", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-invalid-database-header")
+		store.StartupIntegrityCheck = litefs.CheckHeader
+		if err := store.Open(); !errors.Is(err, litefs.ErrDBCorrupted) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDBCorrupted/RootPage", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+		store.StartupIntegrityCheck = litefs.CheckPageCount
+
+		// Remove the LTX file so the corrupted page is not recovered on open.
+		dbDir := filepath.Join(store.Path(), "dbs", "sqlite.db")
+		if err := os.RemoveAll(filepath.Join(dbDir, "ltx")); err != nil {
+			t.Fatal(err)
+		}
+		corruptFile(t, filepath.Join(dbDir, "database"), 4096)
+
+		if err := store.Open(); !errors.Is(err, litefs.ErrDBCorrupted) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDBCorrupted/SchemaRecord", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+		store.StartupIntegrityCheck = litefs.CheckPageCount
+
+		dbDir := filepath.Join(store.Path(), "dbs", "sqlite.db")
+		if err := os.RemoveAll(filepath.Join(dbDir, "ltx")); err != nil {
+			t.Fatal(err)
+		}
+
+		// Set the record header size of the first schema cell to zero.
+		dbPath := filepath.Join(dbDir, "database")
+		page := mustReadFile(t, dbPath)[:4096]
+		offset := int(binary.BigEndian.Uint16(page[108:]))
+		for i := 0; i < 2; i++ { // skip payload size & rowid varints
+			for page[offset]&0x80 != 0 {
+				offset++
+			}
+			offset++
+		}
+		f, err := os.OpenFile(dbPath, os.O_RDWR, 0o666)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := f.WriteAt([]byte{0}, int64(offset)); err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.Open(); !errors.Is(err, litefs.ErrDBCorrupted) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	for _, level := range []litefs.IntegrityCheckLevel{litefs.CheckHeader, litefs.CheckPageCount, litefs.CheckFull} {
+		t.Run("OK/"+level.String(), func(t *testing.T) {
+			store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+			store.StartupIntegrityCheck = level
+			if err := store.Open(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// Invalid headers are cleared when checks are disabled.
+	t.Run("CheckNone", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-invalid-database-header")
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// Ensure partial LTX files are removed on open and the database is recovered
+// from the last complete transaction.
+func TestStore_Open_PartialLTXFile(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := store.CreateDB("test.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a small snapshot followed by a ~1MB transaction.
+	page1 := make([]byte, 4096)
+	copy(page1, "SQLite format 3\x00\x10\x00\x01\x01")
+	binary.BigEndian.PutUint32(page1[28:], 1) // page count
+	pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: page1}, 1)
+
+	pages := make(map[uint32][]byte)
+	for pgno := uint32(2); pgno <= 257; pgno++ {
+		pages[pgno] = make([]byte, 4096)
+		_, _ = rand.Read(pages[pgno])
+	}
+	applyLTXStream(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, pages, 257)
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncate the last LTX file to simulate a partial write.
+	ltxPath := db.LTXPath(2, 2)
+	if fi, err := os.Stat(ltxPath); err != nil {
+		t.Fatal(err)
+	} else if err := os.Truncate(ltxPath, fi.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the store on the same data directory.
+	store = litefs.NewStore(store.Path(), true)
+	store.Leaser = newPrimaryStaticLeaser()
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if _, err := os.Stat(ltxPath); !os.IsNotExist(err) {
+		t.Fatalf("expected partial ltx file to be removed: %v", err)
+	}
+	if got, want := store.DB("test.db").Pos(), pos; got != want {
+		t.Fatalf("Pos=%s, want %s", got, want)
+	}
+}
+
+// Ensure complete LTX files that fail verification are quarantined on open
+// instead of being removed.
+func TestStore_Open_CorruptLTXFile(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := store.CreateDB("test.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	page1 := make([]byte, 4096)
+	copy(page1, "SQLite format 3\x00\x10\x00\x01\x01")
+	binary.BigEndian.PutUint32(page1[28:], 1) // page count
+	pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: page1}, 1)
+	applyLTXStream(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{1: page1}, 1)
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the file checksum in the trailer of the last LTX file.
+	ltxPath := db.LTXPath(2, 2)
+	buf, err := os.ReadFile(ltxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[len(buf)-1] ^= 0xFF
+	if err := os.WriteFile(ltxPath, buf, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	store = litefs.NewStore(store.Path(), true)
+	store.Leaser = newPrimaryStaticLeaser()
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if _, err := os.Stat(ltxPath); !os.IsNotExist(err) {
+		t.Fatalf("expected corrupted ltx file to be moved: %v", err)
+	} else if got, err := os.ReadFile(filepath.Join(db.QuarantineDir(), filepath.Base(ltxPath))); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, buf) {
+		t.Fatal("quarantined ltx file mismatch")
+	}
+	if got, want := store.DB("test.db").Pos(), pos; got != want {
+		t.Fatalf("Pos=%s, want %s", got, want)
+	}
+}
+
+// Ensure databases are opened on first access when LazyDBOpen is enabled.
+func TestStore_Open_LazyDBOpen(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+
+	page1 := make([]byte, 4096)
+	copy(page1, "SQLite format 3\x00\x10\x00\x01\x01")
+	binary.BigEndian.PutUint32(page1[28:], 1) // page count
+
+	posMap := make(map[string]ltx.Pos)
+	for i := 0; i < 10; i++ {
+		db, err := store.CreateDBIfNotExists(fmt.Sprintf("db%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		posMap[db.Name()] = applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: page1}, 1)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the store lazily & ensure no databases are opened.
+	store = litefs.NewStore(store.Path(), true)
+	store.Leaser = newPrimaryStaticLeaser()
+	store.LazyDBOpen = true
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if got, want := len(store.DBs()), 0; got != want {
+		t.Fatalf("len(DBs)=%d, want %d", got, want)
+	}
+	if names, err := store.UnopenedDBNames(); err != nil {
+		t.Fatal(err)
+	} else if got, want := len(names), 10; got != want {
+		t.Fatalf("len(UnopenedDBNames)=%d, want %d", got, want)
+	}
+
+	// Unopened databases report their on-disk position so replicas do not
+	// request a snapshot of each one on reconnect.
+	if got, want := store.PosMap(), posMap; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PosMap=%v, want %v", got, want)
+	} else if got, want := len(store.DBs()), 0; got != want {
+		t.Fatalf("len(DBs)=%d, want %d", got, want)
+	}
+
+	// Accessing a database opens it & notifies subscribers for replication.
+	sub := store.SubscribeChangeSet(0)
+	defer func() { _ = sub.Close() }()
+
+	db, err := store.OpenDB("db4")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := db.Pos(), posMap["db4"]; got != want {
+		t.Fatalf("Pos=%s, want %s", got, want)
+	}
+	if got, want := len(store.DBs()), 1; got != want {
+		t.Fatalf("len(DBs)=%d, want %d", got, want)
+	}
+	if _, ok := sub.DirtySet()["db4"]; !ok {
+		t.Fatal("expected db4 to be marked dirty")
+	}
+
+	// Creating an existing database must open it rather than overwrite it.
+	if db, err := store.CreateDBIfNotExists("db7"); err != nil {
+		t.Fatal(err)
+	} else if got, want := db.Pos(), posMap["db7"]; got != want {
+		t.Fatalf("Pos=%s, want %s", got, want)
+	}
+	if _, _, err := store.CreateDB("db8"); err != litefs.ErrDatabaseExists {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := store.OpenDB("missing"); err != litefs.ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure partial LTX files of unopened databases are removed on open so their
+// position is that of the last complete transaction.
+func TestStore_Open_LazyDBOpen_PartialLTXFile(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, err := store.CreateDBIfNotExists("test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page1 := newSQLitePage1()
+	pos := applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: page1}, 1)
+	applyLTXStream(t, db, ltx.Header{MinTXID: 2, MaxTXID: 2, PreApplyChecksum: pos.PostApplyChecksum}, map[uint32][]byte{1: page1}, 1)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncate the last LTX file to simulate an interrupted write.
+	ltxPath := db.LTXPath(2, 2)
+	if fi, err := os.Stat(ltxPath); err != nil {
+		t.Fatal(err)
+	} else if err := os.Truncate(ltxPath, fi.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+
+	store = litefs.NewStore(store.Path(), true)
+	store.Leaser = newPrimaryStaticLeaser()
+	store.LazyDBOpen = true
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if _, err := os.Stat(ltxPath); !os.IsNotExist(err) {
+		t.Fatalf("expected partial ltx file to be removed: %v", err)
+	} else if got, want := store.PosMap(), map[string]ltx.Pos{"test.db": pos}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PosMap=%v, want %v", got, want)
+	} else if got, want := len(store.DBs()), 0; got != want {
+		t.Fatalf("len(DBs)=%d, want %d", got, want)
+	}
+}
+
 // Ensures that an existing database can write a snapshot after open.
 // See: https://github.com/superfly/litefs/issues/173
 func TestStore_OpenAndWriteSnapshot(t *testing.T) {
@@ -132,46 +440,1667 @@ func TestStore_OpenAndWriteSnapshot(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	var buf bytes.Buffer
-	db := store.DB("sqlite.db")
-	if _, _, err := db.WriteSnapshotTo(context.Background(), &buf); err != nil {
+	var buf bytes.Buffer
+	db := store.DB("sqlite.db")
+	if _, _, err := db.WriteSnapshotTo(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := ltx.NewDecoder(&buf)
+	if err := dec.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_AcquireSnapshotBarrier(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		db := store.DB("sqlite.db")
+
+		barrier, err := store.AcquireSnapshotBarrier(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := barrier.TXID(), db.TXID(); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+
+		// Writes should be blocked while the barrier is held.
+		if guardSet := db.TryAcquireWriteLock(); guardSet != nil {
+			guardSet.Unlock()
+			t.Fatal("expected write lock to be blocked")
+		}
+
+		// Writes should resume once the barrier is released.
+		barrier.Release()
+		guardSet := db.TryAcquireWriteLock()
+		if guardSet == nil {
+			t.Fatal("expected write lock after release")
+		}
+		guardSet.Unlock()
+
+		// Releasing again should be a no-op.
+		barrier.Release()
+	})
+
+	t.Run("MaxDuration", func(t *testing.T) {
+		store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+		store.BarrierMaxDuration = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		db := store.DB("sqlite.db")
+
+		barrier, err := store.AcquireSnapshotBarrier(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer barrier.Release()
+
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			guardSet := db.TryAcquireWriteLock()
+			if guardSet == nil {
+				return fmt.Errorf("expected barrier to expire")
+			}
+			guardSet.Unlock()
+			return nil
+		})
+	})
+}
+
+func TestStore_IsDBName(t *testing.T) {
+	store := litefs.NewStore(t.TempDir(), true)
+	if !store.IsDBName("notes.txt") {
+		t.Fatal("expected all names to be databases by default")
+	}
+
+	store.DBExtensions = []string{".db", ".sqlite"}
+	for name, want := range map[string]bool{"test.db": true, "test.sqlite": true, "notes.txt": false, "db": false} {
+		if got := store.IsDBName(name); got != want {
+			t.Fatalf("IsDBName(%q)=%v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMatchDatabaseFilter(t *testing.T) {
+	for _, tt := range []struct {
+		filter []string
+		name   string
+		want   bool
+	}{
+		{nil, "x.db", true},
+		{[]string{"x.db"}, "x.db", true},
+		{[]string{"x.db"}, "y.db", false},
+		{[]string{"app-*.db"}, "app-1.db", true},
+		{[]string{"app-*.db"}, "cache.db", false},
+		{[]string{"!cache.db"}, "x.db", true},
+		{[]string{"!cache*"}, "cache.db", false},
+		{[]string{"*.db", "!tmp-*"}, "tmp-1.db", false},
+		{[]string{"*.db", "!tmp-*"}, "app.db", true},
+	} {
+		if got := litefs.MatchDatabaseFilter(tt.filter, tt.name); got != tt.want {
+			t.Errorf("MatchDatabaseFilter(%q, %q)=%v, want %v", tt.filter, tt.name, got, tt.want)
+		}
+	}
+
+	if err := litefs.ValidateDatabaseFilter([]string{"!["}); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestStore_SetDatabaseFilter(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	if err := store.SetDatabaseFilter([]string{"app-*.db"}); err != nil {
+		t.Fatal(err)
+	} else if got, want := store.DatabaseFilter, []string{"app-*.db"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("DatabaseFilter=%v, want %v", got, want)
+	}
+
+	if err := store.SetDatabaseFilter([]string{"!["}); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	} else if got, want := store.DatabaseFilter, []string{"app-*.db"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("DatabaseFilter=%v, want %v", got, want)
+	}
+}
+
+// Ensure repeated apply errors on one database suspend only that database.
+func TestStore_DBErrorThreshold(t *testing.T) {
+	var healthy atomic.Bool // if true, primary sends valid data for "a.db"
+	leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			var buf bytes.Buffer
+			if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+				return nil, err
+			}
+
+			// Database "b.db" always advances by one transaction.
+			writeLTXStreamFrame(t, &buf, "b.db", ltx.Header{MinTXID: 1, MaxTXID: posMap["b.db"].TXID + 1})
+
+			// Database "a.db" fails with a position mismatch until healthy.
+			if healthy.Load() {
+				writeLTXStreamFrame(t, &buf, "a.db", ltx.Header{MinTXID: 1, MaxTXID: posMap["a.db"].TXID + 1})
+			} else {
+				writeLTXStreamFrame(t, &buf, "a.db", ltx.Header{MinTXID: 100, MaxTXID: 100, PreApplyChecksum: ltx.ChecksumFlag | 1})
+			}
+
+			return &mock.Stream{
+				ReadCloser:    io.NopCloser(&buf),
+				ClusterIDFunc: func() string { return "" },
+				EpochFunc:     func() uint64 { return 0 },
+			}, nil
+		},
+	}
+
+	store := newStore(t, leaser, &client)
+	store.DBErrorThreshold = 3
+	store.ReconnectDelay = 10 * time.Millisecond
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the failing database to be suspended.
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if db := store.DB("a.db"); db == nil || !db.Suspended() {
+			return fmt.Errorf("expected a.db to be suspended")
+		}
+		return nil
+	})
+
+	// Ensure the other database continues to replicate.
+	txID := store.DB("b.db").Pos().TXID
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if got := store.DB("b.db").Pos().TXID; got <= txID+2 {
+			return fmt.Errorf("b.db txid=%s, expected > %s", got, txID+2)
+		}
+		return nil
+	})
+	if got, want := store.DB("a.db").Pos().TXID, ltx.TXID(0); got != want {
+		t.Fatalf("a.db txid=%s, want %s", got, want)
+	}
+
+	// Fix the primary & resume replication.
+	healthy.Store(true)
+	if err := store.ResumeDB("a.db"); err != nil {
+		t.Fatal(err)
+	} else if store.DB("a.db").Suspended() {
+		t.Fatal("expected a.db to be resumed")
+	}
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if got := store.DB("a.db").Pos().TXID; got == 0 {
+			return fmt.Errorf("a.db has not replicated")
+		}
+		return nil
+	})
+
+	if err := store.ResumeDB("missing.db"); err != litefs.ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure apply errors below the threshold skip the failing frame without
+// reconnecting the stream for other databases.
+func TestStore_DBErrorThreshold_SkipFrame(t *testing.T) {
+	var streamN atomic.Int32
+	leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			pr, pw := io.Pipe()
+			go func() { <-ctx.Done(); _ = pw.CloseWithError(ctx.Err()) }()
+
+			// Only the first stream sends data so a reconnect stops replication.
+			if streamN.Add(1) == 1 {
+				var buf bytes.Buffer
+				if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+					return nil, err
+				}
+				writeInvalidLTXStreamFrame(t, &buf, "a.db")
+				writeLTXStreamFrame(t, &buf, "b.db", ltx.Header{MinTXID: 1, MaxTXID: 1})
+				writeInvalidLTXStreamFrame(t, &buf, "a.db")
+				writeLTXStreamFrame(t, &buf, "b.db", ltx.Header{MinTXID: 1, MaxTXID: 2})
+				go func() { _, _ = buf.WriteTo(pw) }()
+			}
+
+			return &mock.Stream{
+				ReadCloser:    pr,
+				ClusterIDFunc: func() string { return "" },
+				EpochFunc:     func() uint64 { return 0 },
+			}, nil
+		},
+	}
+
+	store := newStore(t, leaser, &client)
+	store.DBErrorThreshold = 3
+	store.ReconnectDelay = 10 * time.Millisecond
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if db := store.DB("b.db"); db == nil || db.Pos().TXID != 2 {
+			return fmt.Errorf("expected b.db to replicate")
+		}
+		return nil
+	})
+	if db := store.DB("a.db"); db == nil || db.Suspended() {
+		t.Fatal("expected a.db to exist & not be suspended")
+	} else if got, want := streamN.Load(), int32(1); got != want {
+		t.Fatalf("streams=%d, want %d", got, want)
+	}
+}
+
+// Ensure a replica handles a database that diverged from the primary based on its resync mode.
+func TestStore_ResyncMode(t *testing.T) {
+	// Returns a client that sends a diverged transaction if the replica
+	// already has "a.db". Signals resyncCh when a snapshot is requested.
+	newClient := func(tb testing.TB, resyncCh chan struct{}) *mock.Client {
+		return &mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				var buf bytes.Buffer
+				if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+					return nil, err
+				}
+
+				if pos, ok := posMap["a.db"]; !ok || pos.TXID == 0 {
+					if ok {
+						select {
+						case resyncCh <- struct{}{}:
+						default:
+						}
+					}
+					writeLTXStreamFrame(tb, &buf, "a.db", ltx.Header{MinTXID: 1, MaxTXID: 1})
+				} else {
+					writeLTXStreamFrame(tb, &buf, "a.db", ltx.Header{MinTXID: 100, MaxTXID: 100, PreApplyChecksum: ltx.ChecksumFlag | 1})
+				}
+
+				return &mock.Stream{
+					ReadCloser:    io.NopCloser(&buf),
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+	}
+
+	t.Run("Auto", func(t *testing.T) {
+		resyncCh := make(chan struct{}, 1)
+		store := newStore(t, litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202"), newClient(t, resyncCh))
+		store.ResyncMode = litefs.ResyncModeAuto
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-resyncCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for resync")
+		}
+	})
+
+	t.Run("Prompt", func(t *testing.T) {
+		resyncCh := make(chan struct{}, 1)
+		store := newStore(t, litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202"), newClient(t, resyncCh))
+		store.ResyncMode = litefs.ResyncModePrompt
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the diverged database to be suspended.
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			if db := store.DB("a.db"); db == nil || !db.Suspended() {
+				return fmt.Errorf("expected a.db to be suspended")
+			}
+			return nil
+		})
+		select {
+		case <-resyncCh:
+			t.Fatal("unexpected resync before it was requested")
+		default:
+		}
+
+		// Resync manually.
+		if err := store.ResyncDB("a.db"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-resyncCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for resync")
+		}
+
+		if err := store.ResyncDB("missing.db"); err != litefs.ErrDatabaseNotFound {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+// Ensure a replica reconnects if the replication stream stalls.
+func TestStore_ReplicationHeartbeatInterval(t *testing.T) {
+	posMapCh := make(chan map[string]ltx.Pos, 1)
+	leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			select {
+			case posMapCh <- posMap:
+			default:
+			}
+
+			// Send a single transaction and then stall without closing the
+			// connection, similar to a half-open TCP connection.
+			var buf bytes.Buffer
+			if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+				return nil, err
+			}
+			writeLTXStreamFrame(t, &buf, "db", ltx.Header{MinTXID: 1, MaxTXID: posMap["db"].TXID + 1})
+			if err := litefs.WriteStreamFrame(&buf, &litefs.HeartbeatStreamFrame{Timestamp: time.Now().UnixMilli()}); err != nil {
+				return nil, err
+			}
+
+			pr, pw := io.Pipe()
+			go func() { _, _ = pw.Write(buf.Bytes()) }()
+			return &mock.Stream{
+				ReadCloser:    pr,
+				ClusterIDFunc: func() string { return "" },
+				EpochFunc:     func() uint64 { return 0 },
+			}, nil
+		},
+	}
+
+	store := newStore(t, leaser, &client)
+	store.ReplicationHeartbeatInterval = 100 * time.Millisecond
+	store.ReconnectDelay = 10 * time.Millisecond
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if posMap := <-posMapCh; len(posMap) != 0 {
+		t.Fatalf("unexpected initial position map: %v", posMap)
+	}
+
+	// Wait for the replica to time out the stalled stream & reconnect.
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for reconnect")
+	case posMap := <-posMapCh:
+		if got, want := posMap["db"].TXID, ltx.TXID(1); got != want {
+			t.Fatalf("txid=%s, want %s", got, want)
+		}
+	}
+
+	// Ensure replication resumes on the new stream.
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if got := store.DB("db").Pos().TXID; got < 2 {
+			return fmt.Errorf("txid=%s, expected >= 2", got)
+		}
+		return nil
+	})
+}
+
+// Ensure a preferred candidate wins the election when started at the same time.
+func TestStore_CandidatePriority(t *testing.T) {
+	var mu sync.Mutex
+	var holder string // hostname of the current primary
+
+	newLeaser := func(hostname string) *mock.Leaser {
+		return &mock.Leaser{
+			CloseFunc:        func() error { return nil },
+			HostnameFunc:     func() string { return hostname },
+			AdvertiseURLFunc: func() string { return "http://" + hostname + ":20202" },
+			AcquireFunc: func(ctx context.Context) (litefs.Lease, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if holder != "" {
+					return nil, litefs.ErrPrimaryExists
+				}
+				holder = hostname
+				return &mock.Lease{
+					EpochFunc:     func() uint64 { return 1 },
+					RenewedAtFunc: func() time.Time { return time.Now() },
+					TTLFunc:       func() time.Duration { return 10 * time.Second },
+					RenewFunc:     func(ctx context.Context) error { return nil },
+					HandoffChFunc: func() <-chan uint64 { return nil },
+					CloseFunc:     func() error { return nil },
+				}, nil
+			},
+			PrimaryInfoFunc: func(ctx context.Context) (litefs.PrimaryInfo, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if holder == "" {
+					return litefs.PrimaryInfo{}, litefs.ErrNoPrimary
+				}
+				return litefs.PrimaryInfo{Hostname: holder, AdvertiseURL: "http://" + holder + ":20202"}, nil
+			},
+			ClusterIDFunc:    func(ctx context.Context) (string, error) { return "", nil },
+			SetClusterIDFunc: func(ctx context.Context, id string) error { return nil },
+		}
+	}
+
+	// Start the lower priority node first.
+	store0 := newStore(t, newLeaser("node0"), nil)
+	store0.CandidatePriority = 2
+	store0.CandidatePriorityDelay = 100 * time.Millisecond
+	if err := store0.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	store1 := newOpenStore(t, newLeaser("node1"), nil)
+	if !store1.IsPrimary() {
+		t.Fatal("expected preferred node to be primary")
+	}
+
+	// Ensure the lower priority node does not acquire the lease.
+	time.Sleep(300 * time.Millisecond)
+	if store0.IsPrimary() {
+		t.Fatal("expected lower priority node to not be primary")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := holder, "node1"; got != want {
+		t.Fatalf("holder=%s, want %s", got, want)
+	}
+}
+
+func TestPrimaryInfo_Clone(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		info := &litefs.PrimaryInfo{Hostname: "foo", AdvertiseURL: "bar"}
+		if other := info.Clone(); *other != *info {
+			t.Fatal("mismatch")
+		}
+	})
+	t.Run("Nil", func(t *testing.T) {
+		var info *litefs.PrimaryInfo
+		if other := info.Clone(); other != nil {
+			t.Fatal("expected nil")
+		}
+	})
+}
+
+// Ensure store generates a unique ID.
+func TestStore_ID(t *testing.T) {
+	store := newStore(t, newPrimaryStaticLeaser(), nil)
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	} else if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	id := store.ID()
+	if id == 0 {
+		t.Fatal("expected id")
+	}
+}
+
+func TestStore_Ack(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	sub := store.SubscribeChangeSet(100)
+	defer func() { _ = sub.Close() }()
+
+	store.Ack(100, "db", 5)
+	if got, want := sub.AckTXID("db"), ltx.TXID(5); got != want {
+		t.Fatalf("AckTXID=%s, want %s", got, want)
+	}
+
+	// Ensure acks for earlier transactions do not move the position back.
+	store.Ack(100, "db", 3)
+	if got, want := sub.AckTXID("db"), ltx.TXID(5); got != want {
+		t.Fatalf("AckTXID=%s, want %s", got, want)
+	}
+
+	// Ensure acks from unknown nodes are ignored.
+	store.Ack(200, "db", 10)
+	if got, want := sub.AckTXID("db"), ltx.TXID(5); got != want {
+		t.Fatalf("AckTXID=%s, want %s", got, want)
+	}
+}
+
+func TestChangeSetSubscriber_Replicates(t *testing.T) {
+	store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	sub := store.SubscribeChangeSet(100)
+	defer func() { _ = sub.Close() }()
+
+	// Ensure subscribers that are not replica streams, such as the backup
+	// stream, do not replicate any databases.
+	if sub.Replicates("a.db") {
+		t.Fatal("expected non-replica subscriber to not replicate database")
+	}
+
+	sub.SetDatabaseFilter(nil)
+	if !sub.Replicates("a.db") {
+		t.Fatal("expected unfiltered replica to replicate database")
+	}
+
+	sub.SetDatabaseFilter([]string{"!a.db"})
+	if sub.Replicates("a.db") {
+		t.Fatal("expected excluded database to not be replicated")
+	} else if !sub.Replicates("b.db") {
+		t.Fatal("expected database to be replicated")
+	}
+}
+
+func TestStore_VerifyChecksums(t *testing.T) {
+	store := newStoreFromFixture(t, newPrimaryStaticLeaser(), nil, "testdata/store/open-and-write-snapshot")
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.VerifyChecksums(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := store.SubscribeEvents()
+	defer sub.Stop()
+	<-sub.C() // init event
+
+	// Corrupt a page underneath the store so it no longer matches its position.
+	corruptFile(t, store.DB("sqlite.db").DatabasePath(), 4096)
+	if err := store.VerifyChecksums(context.Background()); !errors.Is(err, litefs.ErrChecksumMismatch) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case event := <-sub.C():
+		if got, want := event.Type, litefs.EventTypeChecksumMismatch; got != want {
+			t.Fatalf("Type=%q, want %q", got, want)
+		} else if got, want := event.DB, "sqlite.db"; got != want {
+			t.Fatalf("DB=%q, want %q", got, want)
+		} else if got, want := event.Data.(litefs.ChecksumMismatchEventData).TXID, store.DB("sqlite.db").TXID(); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
+func TestStore_LeaseInfo(t *testing.T) {
+	t.Run("Primary", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		info := store.LeaseInfo()
+		if info == nil {
+			t.Fatal("expected lease info")
+		} else if !info.ExpiresAt.After(info.RenewedAt) {
+			t.Fatalf("expected expiration after renewal: %s <= %s", info.ExpiresAt, info.RenewedAt)
+		} else if got, want := info.Epoch, uint64(1); got != want {
+			t.Fatalf("Epoch=%d, want %d", got, want)
+		}
+
+		sub := store.SubscribeChangeSet(2)
+		defer func() { _ = sub.Close() }()
+		sub.SetPosMap(map[string]ltx.Pos{"test.db": {TXID: 3}})
+		if subs := store.ChangeSetSubscribers(); len(subs) != 1 || subs[0] != sub {
+			t.Fatalf("unexpected subscribers: %v", subs)
+		} else if got, want := subs[0].TXIDs()["test.db"], ltx.TXID(3); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("Replica", func(t *testing.T) {
+		store := newStore(t, litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202"), nil)
+		if info := store.LeaseInfo(); info != nil {
+			t.Fatalf("unexpected lease info: %#v", info)
+		}
+	})
+}
+
+// Ensure a replica rejects streams from a primary with an older lease epoch.
+func TestStore_Epoch(t *testing.T) {
+	var n atomic.Int64
+	leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			var buf bytes.Buffer
+			if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+				return nil, err
+			}
+
+			// The first connections are to a stale primary.
+			name, epoch := "stale.db", uint64(3)
+			if n.Add(1) > 2 {
+				name, epoch = "a.db", uint64(6)
+			}
+			writeLTXStreamFrame(t, &buf, name, ltx.Header{MinTXID: 1, MaxTXID: posMap[name].TXID + 1})
+
+			return &mock.Stream{
+				ReadCloser:    io.NopCloser(&buf),
+				ClusterIDFunc: func() string { return "" },
+				EpochFunc:     func() uint64 { return epoch },
+			}, nil
+		},
+	}
+
+	store := newStore(t, leaser, &client)
+	store.ReconnectDelay = 10 * time.Millisecond
+	if err := os.WriteFile(store.EpochPath(), []byte("5\n"), 0o666); err != nil {
+		t.Fatal(err)
+	} else if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if db := store.DB("a.db"); db == nil || db.Pos().TXID == 0 {
+			return fmt.Errorf("a.db has not replicated")
+		}
+		return nil
+	})
+	if db := store.DB("stale.db"); db != nil {
+		t.Fatal("expected stale primary to be rejected")
+	}
+
+	if got, want := store.Epoch(), uint64(6); got != want {
+		t.Fatalf("Epoch=%d, want %d", got, want)
+	} else if buf, err := os.ReadFile(store.EpochPath()); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(buf), "6\n"; got != want {
+		t.Fatalf("epoch file=%q, want %q", got, want)
+	}
+}
+
+// Ensure epochs observed with a different leaser or cluster are ignored.
+func TestStore_EpochPath(t *testing.T) {
+	store := newStore(t, newPrimaryStaticLeaser(), nil)
+	if err := os.MkdirAll(store.Path(), 0o777); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(store.Path(), "epoch.consul"), []byte("9\n"), 0o666); err != nil {
+		t.Fatal(err)
+	} else if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-store.ReadyCh()
+
+	if got, want := store.EpochPath(), filepath.Join(store.Path(), "epoch.static."+store.ClusterID()); got != want {
+		t.Fatalf("EpochPath=%s, want %s", got, want)
+	} else if got, want := store.Epoch(), uint64(1); got != want {
+		t.Fatalf("Epoch=%d, want %d", got, want)
+	} else if buf, err := os.ReadFile(store.EpochPath()); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(buf), "1\n"; got != want {
+		t.Fatalf("epoch file=%q, want %q", got, want)
+	}
+}
+
+// Ensure a node does not become primary with a lease older than one observed.
+func TestStore_StaleLease(t *testing.T) {
+	newLeaser := func(clusterID string, closeN *atomic.Int64) *mock.Leaser {
+		return &mock.Leaser{
+			CloseFunc:        func() error { return nil },
+			AdvertiseURLFunc: func() string { return "http://localhost:20202" },
+			AcquireFunc: func(ctx context.Context) (litefs.Lease, error) {
+				return &mock.Lease{
+					EpochFunc:     func() uint64 { return 2 },
+					RenewedAtFunc: func() time.Time { return time.Now() },
+					TTLFunc:       func() time.Duration { return 10 * time.Second },
+					RenewFunc:     func(ctx context.Context) error { return nil },
+					HandoffChFunc: func() <-chan uint64 { return nil },
+					CloseFunc:     func() error { closeN.Add(1); return nil },
+				}, nil
+			},
+			PrimaryInfoFunc: func(ctx context.Context) (litefs.PrimaryInfo, error) {
+				return litefs.PrimaryInfo{}, litefs.ErrNoPrimary
+			},
+			ClusterIDFunc:    func(ctx context.Context) (string, error) { return clusterID, nil },
+			SetClusterIDFunc: func(ctx context.Context, id string) error { return nil },
+		}
+	}
+
+	t.Run("Fenced", func(t *testing.T) {
+		var closeN atomic.Int64
+		clusterID := litefs.GenerateClusterID()
+		store := newStore(t, newLeaser(clusterID, &closeN), nil)
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := os.MkdirAll(store.Path(), 0o777); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(store.ClusterIDPath(), []byte(clusterID+"\n"), 0o666); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(filepath.Join(store.Path(), "epoch.mock."+clusterID), []byte("5\n"), 0o666); err != nil {
+			t.Fatal(err)
+		} else if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			if closeN.Load() < 2 {
+				return fmt.Errorf("lease not released")
+			}
+			return nil
+		})
+		if store.IsPrimary() {
+			t.Fatal("expected node to not become primary")
+		} else if got, want := store.Epoch(), uint64(5); got != want {
+			t.Fatalf("Epoch=%d, want %d", got, want)
+		}
+	})
+
+	// Ensure the epoch restarts if the leaser's state was recreated.
+	t.Run("LeaserReset", func(t *testing.T) {
+		var closeN atomic.Int64
+		store := newStore(t, newLeaser("", &closeN), nil)
+		if err := os.MkdirAll(store.Path(), 0o777); err != nil {
+			t.Fatal(err)
+		} else if err := os.WriteFile(store.EpochPath(), []byte("5\n"), 0o666); err != nil {
+			t.Fatal(err)
+		} else if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		if !store.IsPrimary() {
+			t.Fatal("expected node to become primary")
+		} else if got, want := store.Epoch(), uint64(2); got != want {
+			t.Fatalf("Epoch=%d, want %d", got, want)
+		}
+	})
+}
+
+func TestStore_DropDB(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db := newStoreDB(t, store, "test.db")
+
+		sub := store.SubscribeChangeSet(2)
+		defer func() { _ = sub.Close() }()
+
+		if err := store.DropDB(context.Background(), "test.db"); err != nil {
+			t.Fatal(err)
+		} else if store.DB("test.db") != nil {
+			t.Fatal("expected database to be removed")
+		} else if _, err := os.Stat(db.Path()); !os.IsNotExist(err) {
+			t.Fatalf("expected database directory to be removed: %v", err)
+		} else if _, ok := sub.DirtySet()["test.db"]; !ok {
+			t.Fatal("expected database to be marked dirty")
+		}
+	})
+
+	t.Run("ErrDatabaseBusy", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db := newStoreDB(t, store, "test.db")
+
+		// Ensure the database cannot be dropped while a handle is open.
+		db.AddHandle()
+		if err := store.DropDB(context.Background(), "test.db"); err != litefs.ErrDatabaseBusy {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		db.RemoveHandle()
+
+		// Ensure the database cannot be dropped while a lock is held.
+		guardSet := db.TryAcquireWriteLock()
+		if guardSet == nil {
+			t.Fatal("expected write lock")
+		} else if err := store.DropDB(context.Background(), "test.db"); err != litefs.ErrDatabaseBusy {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		guardSet.Unlock()
+
+		if err := store.DropDB(context.Background(), "test.db"); err != nil {
+			t.Fatal(err)
+		} else if db.Writeable() {
+			t.Fatal("expected dropped database to be closed")
+		}
+	})
+
+	// A backup service requires a zero-commit LTX file to record the deletion.
+	t.Run("BackupClient", func(t *testing.T) {
+		store := newStore(t, newPrimaryStaticLeaser(), nil)
+		store.BackupClient = newOpenFileBackupClient(t)
+		store.BackupDelay = 0
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+		db := newStoreDB(t, store, "test.db")
+
+		if err := store.DropDB(context.Background(), "test.db"); err != nil {
+			t.Fatal(err)
+		} else if store.DB("test.db") != db {
+			t.Fatal("expected database to remain in store")
+		} else if got, want := db.PageN(), uint32(0); got != want {
+			t.Fatalf("PageN=%d, want %d", got, want)
+		} else if got, want := db.TXID(), ltx.TXID(2); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("ErrDatabaseNotFound", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		if err := store.DropDB(context.Background(), "nosuchdb"); err != litefs.ErrDatabaseNotFound {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestStore_RenameDB(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		pos := newStoreDB(t, store, "old.db").Pos()
+
+		sub := store.SubscribeChangeSet(2)
+		defer func() { _ = sub.Close() }()
+
+		if err := store.RenameDB(context.Background(), "old.db", "new.db"); err != nil {
+			t.Fatal(err)
+		} else if store.DB("old.db") != nil {
+			t.Fatal("expected old database to be removed")
+		}
+
+		db := store.DB("new.db")
+		if db == nil {
+			t.Fatal("expected new database")
+		} else if got, want := db.Pos(), pos; got != want {
+			t.Fatalf("Pos=%s, want %s", got, want)
+		} else if _, err := os.Stat(db.LTXPath(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := sub.Renames(), []litefs.DBRename{{OldName: "old.db", NewName: "new.db"}}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Renames=%v, want %v", got, want)
+		} else if got := sub.Renames(); len(got) != 0 {
+			t.Fatalf("expected renames to be cleared: %v", got)
+		}
+	})
+
+	t.Run("ErrDatabaseExists", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		newStoreDB(t, store, "a.db")
+		newStoreDB(t, store, "b.db")
+		if err := store.RenameDB(context.Background(), "a.db", "b.db"); err != litefs.ErrDatabaseExists {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDatabaseBusy", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		db := newStoreDB(t, store, "old.db")
+
+		db.AddHandle()
+		if err := store.RenameDB(context.Background(), "old.db", "new.db"); err != litefs.ErrDatabaseBusy {
+			t.Fatalf("unexpected error: %v", err)
+		} else if store.DB("old.db") != db {
+			t.Fatal("expected database to remain")
+		}
+		db.RemoveHandle()
+
+		if err := store.RenameDB(context.Background(), "old.db", "new.db"); err != nil {
+			t.Fatal(err)
+		} else if db.Writeable() {
+			t.Fatal("expected renamed database to be closed")
+		}
+	})
+
+	t.Run("ErrRenameNotSupported", func(t *testing.T) {
+		store := newStore(t, newPrimaryStaticLeaser(), nil)
+		store.BackupClient = newOpenFileBackupClient(t)
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		if err := store.RenameDB(context.Background(), "a.db", "b.db"); err != litefs.ErrRenameNotSupported {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	// Ensure a replica applies drop & rename frames from the primary.
+	t.Run("Replica", func(t *testing.T) {
+		var n atomic.Int64
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				var buf bytes.Buffer
+				if n.Add(1) == 1 {
+					writeLTXStreamFramePage(t, &buf, "a.db", ltx.Header{MinTXID: 1, MaxTXID: 1}, newSQLitePage1())
+					writeLTXStreamFrame(t, &buf, "c.db", ltx.Header{MinTXID: 1, MaxTXID: 1})
+					if err := litefs.WriteStreamFrame(&buf, &litefs.RenameDBStreamFrame{OldName: "a.db", NewName: "b.db"}); err != nil {
+						return nil, err
+					} else if err := litefs.WriteStreamFrame(&buf, &litefs.DropDBStreamFrame{Name: "c.db"}); err != nil {
+						return nil, err
+					}
+				}
+				if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+					return nil, err
+				}
+
+				return &mock.Stream{
+					ReadCloser:    io.NopCloser(&buf),
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+
+		store := newStore(t, leaser, &client)
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		if db := store.DB("b.db"); db == nil {
+			t.Fatal("expected renamed database")
+		} else if got, want := db.TXID(), ltx.TXID(1); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		}
+		if db := store.DB("a.db"); db != nil {
+			t.Fatal("expected old database to be removed")
+		} else if db := store.DB("c.db"); db != nil {
+			t.Fatal("expected dropped database to be removed")
+		} else if _, err := os.Stat(store.DBPath("c.db")); !os.IsNotExist(err) {
+			t.Fatalf("expected dropped database directory to be removed: %v", err)
+		}
+	})
+}
+
+func TestStore_ResumeSnapshot(t *testing.T) {
+	primary := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db := newStoreDB(t, primary, "a.db")
+
+	var buf bytes.Buffer
+	hdr, _, err := db.WriteSnapshotTo(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := buf.Bytes()
+	partial := litefs.PartialSnapshot{Name: "a.db", TXID: hdr.MaxTXID, NodeID: primary.ID(), Timestamp: hdr.Timestamp, Size: 200}
+
+	t.Run("OK", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, _, err := db.ResumeSnapshotTo(context.Background(), &buf, partial); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(append(snapshot[:200:200], buf.Bytes()...), snapshot) {
+			t.Fatal("resumed snapshot mismatch")
+		}
+	})
+
+	t.Run("ErrNodeID", func(t *testing.T) {
+		other := partial
+		other.NodeID++
+		if _, _, err := db.ResumeSnapshotTo(context.Background(), io.Discard, other); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("ErrPositionChanged", func(t *testing.T) {
+		other := partial
+		other.TXID++
+		if _, _, err := db.ResumeSnapshotTo(context.Background(), io.Discard, other); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	// Ensure a replica keeps an interrupted snapshot & resumes it on reconnect.
+	t.Run("Replica", func(t *testing.T) {
+		var n atomic.Int64
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				var buf bytes.Buffer
+				if n.Add(1) == 1 {
+					// Disconnect partway through the snapshot.
+					if err := litefs.WriteStreamFrame(&buf, &litefs.LTXStreamFrame{Name: "a.db"}); err != nil {
+						return nil, err
+					} else if _, err := chunk.NewWriter(&buf).Write(snapshot[:200]); err != nil {
+						return nil, err
+					}
+				} else {
+					if got, want := partials, []litefs.PartialSnapshot{partial}; !reflect.DeepEqual(got, want) {
+						t.Errorf("partials=%#v, want %#v", got, want)
+					}
+
+					cw := chunk.NewWriter(&buf)
+					if err := litefs.WriteStreamFrame(&buf, &litefs.LTXResumeStreamFrame{Offset: 200, Name: "a.db"}); err != nil {
+						return nil, err
+					} else if _, err := cw.Write(snapshot[200:]); err != nil {
+						return nil, err
+					} else if err := cw.Close(); err != nil {
+						return nil, err
+					} else if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+						return nil, err
+					}
+				}
+
+				return &mock.Stream{
+					ReadCloser:    io.NopCloser(&buf),
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+
+		store := newStore(t, leaser, &client)
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		if db := store.DB("a.db"); db == nil {
+			t.Fatal("expected database")
+		} else if got, want := db.TXID(), hdr.MaxTXID; got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		} else if _, err := os.Stat(db.PartialSnapshotPath()); !os.IsNotExist(err) {
+			t.Fatalf("expected partial snapshot to be removed: %v", err)
+		}
+	})
+}
+
+func TestStore_LazySnapshot(t *testing.T) {
+	primary := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+	db, f, err := primary.CreateDB("a.db")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	page1 := newSQLitePage1()
+	binary.BigEndian.PutUint32(page1[28:], 3) // page count
+	pages := map[uint32][]byte{1: page1, 2: bytes.Repeat([]byte{2}, 4096), 3: bytes.Repeat([]byte{3}, 4096)}
+	applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, pages, 3)
+
+	var snapshot bytes.Buffer
+	if _, err := db.WriteLazySnapshotTo(context.Background(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Returns a replica bootstrapped from the lazy snapshot. Pages are fetched
+	// from the primary database & passed through fn before being returned.
+	newReplica := func(tb testing.TB, fn func(pgno uint32, data []byte) []byte) *litefs.DB {
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				var buf bytes.Buffer
+				cw := chunk.NewWriter(&buf)
+				if err := litefs.WriteStreamFrame(&buf, &litefs.LazySnapshotStreamFrame{Name: "a.db"}); err != nil {
+					return nil, err
+				} else if _, err := cw.Write(snapshot.Bytes()); err != nil {
+					return nil, err
+				} else if err := cw.Close(); err != nil {
+					return nil, err
+				} else if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+					return nil, err
+				}
+
+				// Keep the stream connected so pages can be fetched from the primary.
+				pr, pw := io.Pipe()
+				go func() {
+					_, _ = pw.Write(buf.Bytes())
+					<-ctx.Done()
+					_ = pw.Close()
+				}()
+
+				return &mock.Stream{
+					ReadCloser:    pr,
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+			FetchPageFunc: func(ctx context.Context, primaryURL string, nodeID uint64, name string, pgno uint32, txID ltx.TXID) ([]byte, ltx.TXID, error) {
+				data, pageTXID, err := db.ReadPage(ctx, pgno, txID)
+				if err != nil {
+					return nil, 0, err
+				}
+				return fn(pgno, data), pageTXID, nil
+			},
+		}
+
+		store := newStore(tb, leaser, &client)
+		store.ReconnectDelay = 10 * time.Millisecond
+		if err := store.Open(); err != nil {
+			tb.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		other := store.DB("a.db")
+		if other == nil {
+			tb.Fatal("expected database")
+		} else if got, want := other.Pos(), db.Pos(); got != want {
+			tb.Fatalf("Pos=%s, want %s", got, want)
+		} else if !other.IsLazy() {
+			tb.Fatal("expected lazy database")
+		}
+		return other
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		other := newReplica(t, func(pgno uint32, data []byte) []byte { return data })
+
+		f, err := other.OpenDatabase(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+
+		buf := make([]byte, 4096)
+		for pgno := uint32(1); pgno <= 3; pgno++ {
+			if _, err := other.ReadDatabaseAt(context.Background(), f, buf, int64(pgno-1)*4096, 0); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(buf, pages[pgno]) {
+				t.Fatalf("page %d mismatch", pgno)
+			}
+		}
+
+		if other.IsLazy() {
+			t.Fatal("expected database to be complete")
+		} else if _, err := os.Stat(other.LazyPath()); !os.IsNotExist(err) {
+			t.Fatalf("expected lazy marker to be removed: %v", err)
+		} else if _, err := other.Verify(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrChecksumMismatch", func(t *testing.T) {
+		other := newReplica(t, func(pgno uint32, data []byte) []byte {
+			return bytes.Repeat([]byte{9}, len(data))
+		})
+
+		f, err := other.OpenDatabase(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+
+		if _, err := other.ReadDatabaseAt(context.Background(), f, make([]byte, 4096), 4096, 0); !errors.Is(err, litefs.ErrChecksumMismatch) {
+			t.Fatalf("unexpected error: %v", err)
+		} else if !other.IsLazy() {
+			t.Fatal("expected database to remain lazy")
+		}
+	})
+
+	// Ensure concurrent reads of the same page only fetch it once.
+	t.Run("Concurrent", func(t *testing.T) {
+		var n atomic.Int64
+		other := newReplica(t, func(pgno uint32, data []byte) []byte {
+			n.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return data
+		})
+
+		f, err := other.OpenDatabase(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+
+		var g errgroup.Group
+		for i := 0; i < 8; i++ {
+			g.Go(func() error {
+				buf := make([]byte, 4096)
+				if _, err := other.ReadDatabaseAt(context.Background(), f, buf, 4096, 0); err != nil {
+					return err
+				} else if !bytes.Equal(buf, pages[2]) {
+					return fmt.Errorf("page mismatch")
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatal(err)
+		} else if got, want := n.Load(), int64(1); got != want {
+			t.Fatalf("fetch count=%d, want %d", got, want)
+		}
+	})
+
+	t.Run("ErrPositionMismatch", func(t *testing.T) {
+		if _, _, err := db.ReadPage(context.Background(), 2, 2); !errors.Is(err, litefs.ErrPositionMismatch) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestStore_PauseReplication(t *testing.T) {
+	t.Run("Replica", func(t *testing.T) {
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				var buf bytes.Buffer
+				if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
+					return nil, err
+				}
+				return &mock.Stream{
+					ReadCloser:    io.NopCloser(&buf),
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+
+		store := newStore(t, leaser, &client)
+		if err := store.PauseReplication(); err != nil {
+			t.Fatal(err)
+		} else if !store.ReplicationPaused() {
+			t.Fatal("expected replication paused")
+		} else if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Frames are not processed while paused.
+		select {
+		case <-store.ReadyCh():
+			t.Fatal("expected store to not be ready while paused")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		store.ResumeReplication()
+		if store.ReplicationPaused() {
+			t.Fatal("expected replication resumed")
+		}
+
+		select {
+		case <-store.ReadyCh():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for store to be ready")
+		}
+	})
+
+	// Ensure frames continue to be read while paused & are applied on resume.
+	t.Run("Spool", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer func() { _ = pw.Close() }()
+
+		var n atomic.Int64
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				if n.Add(1) > 1 {
+					return nil, fmt.Errorf("unexpected reconnect")
+				}
+				return &mock.Stream{
+					ReadCloser:    pr,
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+
+		store := newStore(t, leaser, &client)
+		if err := store.PauseReplication(); err != nil {
+			t.Fatal(err)
+		} else if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Writes to the pipe only complete once the store has read them.
+		writeLTXStreamFramePage(t, pw, "a.db", ltx.Header{MinTXID: 1, MaxTXID: 1}, newSQLitePage1())
+		if err := litefs.WriteStreamFrame(pw, &litefs.ReadyStreamFrame{}); err != nil {
+			t.Fatal(err)
+		} else if err := litefs.WriteStreamFrame(pw, &litefs.HeartbeatStreamFrame{Timestamp: 1000}); err != nil {
+			t.Fatal(err)
+		}
+
+		if store.DB("a.db") != nil {
+			t.Fatal("expected database to not be applied while paused")
+		} else if store.IsReady() {
+			t.Fatal("expected store to not be ready while paused")
+		}
+
+		// Spooled frames are applied before the next frame is processed.
+		store.ResumeReplication()
+		if err := litefs.WriteStreamFrame(pw, &litefs.HeartbeatStreamFrame{Timestamp: 2000}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-store.ReadyCh():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for store to be ready")
+		}
+		if db := store.DB("a.db"); db == nil {
+			t.Fatal("expected database")
+		} else if got, want := db.TXID(), ltx.TXID(1); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		} else if _, err := os.Stat(filepath.Join(store.Path(), "stream.spool")); !os.IsNotExist(err) {
+			t.Fatalf("expected spool to be removed: %v", err)
+		}
+	})
+
+	// Ensure spooled frames are applied on resume if the primary is idle.
+	t.Run("SpoolWithoutFurtherFrames", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer func() { _ = pw.Close() }()
+
+		var n atomic.Int64
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				if n.Add(1) > 1 {
+					return nil, fmt.Errorf("unexpected reconnect")
+				}
+				return &mock.Stream{
+					ReadCloser:    pr,
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+
+		store := newStore(t, leaser, &client)
+		if err := store.PauseReplication(); err != nil {
+			t.Fatal(err)
+		} else if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		writeLTXStreamFramePage(t, pw, "a.db", ltx.Header{MinTXID: 1, MaxTXID: 1}, newSQLitePage1())
+		if err := litefs.WriteStreamFrame(pw, &litefs.ReadyStreamFrame{}); err != nil {
+			t.Fatal(err)
+		} else if err := litefs.WriteStreamFrame(pw, &litefs.HeartbeatStreamFrame{Timestamp: 1000}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the heartbeat to be processed so the store is blocked on
+		// the stream. No frames are sent after resuming so the spool must be
+		// applied without waiting on the stream.
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			if got, want := store.PrimaryTimestamp(), int64(1000); got != want {
+				return fmt.Errorf("PrimaryTimestamp=%d, want %d", got, want)
+			}
+			return nil
+		})
+		time.Sleep(100 * time.Millisecond)
+		store.ResumeReplication()
+
+		select {
+		case <-store.ReadyCh():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for store to be ready")
+		}
+		if db := store.DB("a.db"); db == nil {
+			t.Fatal("expected database")
+		} else if got, want := db.TXID(), ltx.TXID(1); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
+		} else if _, err := os.Stat(filepath.Join(store.Path(), "stream.spool")); !os.IsNotExist(err) {
+			t.Fatalf("expected spool to be removed: %v", err)
+		}
+	})
+
+	t.Run("ErrPauseSyncReplica", func(t *testing.T) {
+		store := newStore(t, litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202"), nil)
+		store.SyncReplicaN = 1
+		if err := store.PauseReplication(); err != litefs.ErrPauseSyncReplica {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrPausePrimary", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		<-store.ReadyCh()
+		if err := store.PauseReplication(); err != litefs.ErrPausePrimary {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestStore_Metrics(t *testing.T) {
+	// Ensure lease renewals & failed renewals are counted.
+	t.Run("LeaseRenew", func(t *testing.T) {
+		var renewN atomic.Int64
+		leaser := &mock.Leaser{
+			CloseFunc:        func() error { return nil },
+			HostnameFunc:     func() string { return "localhost" },
+			AdvertiseURLFunc: func() string { return "http://localhost:20202" },
+			AcquireFunc: func(ctx context.Context) (litefs.Lease, error) {
+				return &mock.Lease{
+					EpochFunc:     func() uint64 { return 1 },
+					RenewedAtFunc: func() time.Time { return time.Now() },
+					TTLFunc:       func() time.Duration { return 20 * time.Millisecond },
+					RenewFunc: func(ctx context.Context) error {
+						if renewN.Add(1)%2 == 0 {
+							return fmt.Errorf("marker")
+						}
+						return nil
+					},
+					HandoffChFunc: func() <-chan uint64 { return nil },
+					CloseFunc:     func() error { return nil },
+				}, nil
+			},
+			PrimaryInfoFunc: func(ctx context.Context) (litefs.PrimaryInfo, error) {
+				return litefs.PrimaryInfo{}, litefs.ErrNoPrimary
+			},
+			ClusterIDFunc:    func(ctx context.Context) (string, error) { return "", nil },
+			SetClusterIDFunc: func(ctx context.Context, id string) error { return nil },
+		}
+		store := newOpenStore(t, leaser, nil)
+
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			if got := metricValue(t, store.Registry(), "litefs_lease_renew_count"); got < 2 {
+				return fmt.Errorf("renew count=%v, want at least 2", got)
+			} else if got := metricValue(t, store.Registry(), "litefs_lease_renew_error_count"); got < 1 {
+				return fmt.Errorf("renew error count=%v, want at least 1", got)
+			}
+			return nil
+		})
+	})
+
+	// Ensure received bytes & the TXID lag are reported by replicas.
+	t.Run("Replica", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer func() { _ = pw.Close() }()
+
+		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
+		client := mock.Client{
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+				return &mock.Stream{
+					ReadCloser:    pr,
+					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
+				}, nil
+			},
+		}
+		store := newStore(t, leaser, &client)
+		if err := store.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		recvN0 := metricValue(t, store.Registry(), "litefs_db_ltx_recv_bytes", "db", "metrics.db")
+		writeLTXStreamFramePage(t, pw, "metrics.db", ltx.Header{MinTXID: 1, MaxTXID: 1}, newSQLitePage1())
+		if err := litefs.WriteStreamFrame(pw, &litefs.ReadyStreamFrame{}); err != nil {
+			t.Fatal(err)
+		}
+		<-store.ReadyCh()
+
+		db := store.DB("metrics.db")
+		if db == nil {
+			t.Fatal("expected database")
+		} else if got := metricValue(t, store.Registry(), "litefs_db_ltx_recv_bytes", "db", "metrics.db") - recvN0; got <= 0 {
+			t.Fatalf("recv bytes=%v, want > 0", got)
+		} else if got, want := metricValue(t, store.Registry(), "litefs_db_replica_lag_txid", "db", "metrics.db"), 0.0; got != want {
+			t.Fatalf("lag=%v, want %v", got, want)
+		}
+
+		// Transactions received while paused are reported as lag.
+		if err := store.PauseReplication(); err != nil {
+			t.Fatal(err)
+		}
+		writeLTXStreamFramePage(t, pw, "metrics.db", ltx.Header{MinTXID: 1, MaxTXID: 2}, newSQLitePage1())
+		if err := litefs.WriteStreamFrame(pw, &litefs.HeartbeatStreamFrame{Timestamp: 1000}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := db.ReplicaLag(), uint64(1); got != want {
+			t.Fatalf("ReplicaLag=%d, want %d", got, want)
+		} else if got, want := metricValue(t, store.Registry(), "litefs_db_replica_lag_txid", "db", "metrics.db"), 1.0; got != want {
+			t.Fatalf("lag=%v, want %v", got, want)
+		}
+
+		store.ResumeReplication()
+		testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+			if got, want := metricValue(t, store.Registry(), "litefs_db_replica_lag_txid", "db", "metrics.db"), 0.0; got != want {
+				return fmt.Errorf("lag=%v, want %v", got, want)
+			}
+			return nil
+		})
+	})
+
+	// Ensure stores in the same process report their own values.
+	t.Run("PerStore", func(t *testing.T) {
+		store0 := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		store1 := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		<-store0.ReadyCh()
+		<-store1.ReadyCh()
+
+		if _, err := store0.CreateDBIfNotExists("db0"); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := metricValue(t, store0.Registry(), "litefs_db_count"), 1.0; got != want {
+			t.Fatalf("store0 db count=%v, want %v", got, want)
+		} else if got, want := metricValue(t, store1.Registry(), "litefs_db_count"), 0.0; got != want {
+			t.Fatalf("store1 db count=%v, want %v", got, want)
+		}
+	})
+}
+
+// Ensure a replica restores from the backup service when no primary exists.
+func TestStore_BootstrapFromBackup(t *testing.T) {
+	client := newOpenFileBackupClient(t)
+
+	// Write a database to the backup service from a primary.
+	store0 := newStore(t, newPrimaryStaticLeaser(), nil)
+	store0.BackupClient = client
+	if err := store0.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-store0.ReadyCh()
+	db0 := newStoreDB(t, store0, "test.db")
+	if err := store0.SyncBackup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a replica that is unable to find a primary.
+	leaser := &mock.Leaser{
+		CloseFunc:        func() error { return nil },
+		HostnameFunc:     func() string { return "localhost" },
+		AdvertiseURLFunc: func() string { return "http://localhost:20203" },
+		PrimaryInfoFunc: func(ctx context.Context) (litefs.PrimaryInfo, error) {
+			return litefs.PrimaryInfo{}, litefs.ErrNoPrimary
+		},
+		ClusterIDFunc: func(ctx context.Context) (string, error) { return "", nil },
+	}
+	store1 := newStore(t, leaser, nil)
+	store1.SetCandidate(false)
+	store1.BackupClient = client
+	if err := store1.Open(); err != nil {
 		t.Fatal(err)
 	}
 
-	dec := ltx.NewDecoder(&buf)
-	if err := dec.Verify(); err != nil {
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for store ready")
+	case <-store1.ReadyCh():
+	}
+
+	if store1.IsPrimary() {
+		t.Fatal("expected replica")
+	} else if db1 := store1.DB("test.db"); db1 == nil {
+		t.Fatal("expected database")
+	} else if got, want := db1.Pos(), db0.Pos(); got != want {
+		t.Fatalf("pos=%s, want %s", got, want)
+	}
+}
+
+// Ensure a cached primary is looked up again after a connection failure.
+func TestStore_InvalidatePrimaryInfo(t *testing.T) {
+	var lookupN atomic.Int64
+	leaser := litefs.NewDNSLeaser(false, "primary.internal", "localhost", "")
+	leaser.Port = 20202
+	leaser.RefreshInterval = 1 * time.Hour
+	leaser.Resolver = &mockDNSResolver{
+		LookupHostFunc: func(ctx context.Context, host string) ([]string, error) {
+			lookupN.Add(1)
+			return []string{"10.0.0.1"}, nil
+		},
+	}
+	client := mock.Client{
+		StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
+			return nil, fmt.Errorf("marker")
+		},
+	}
+
+	store := newStore(t, leaser, &client)
+	store.SetCandidate(false)
+	store.ReconnectDelay = 10 * time.Millisecond
+	if err := store.Open(); err != nil {
 		t.Fatal(err)
 	}
+
+	testingutil.RetryUntil(t, 1*time.Millisecond, 5*time.Second, func() error {
+		if got := lookupN.Load(); got < 2 {
+			return fmt.Errorf("lookups=%d, want at least 2", got)
+		}
+		return nil
+	})
 }
 
-func TestPrimaryInfo_Clone(t *testing.T) {
+func TestStore_WaitForTX(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
-		info := &litefs.PrimaryInfo{Hostname: "foo", AdvertiseURL: "bar"}
-		if other := info.Clone(); *other != *info {
-			t.Fatal("mismatch")
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+
+		// Create the database & apply a transaction after the wait begins.
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			db, f, err := store.CreateDB("test.db")
+			if err != nil {
+				t.Error(err)
+				return
+			} else if err := f.Close(); err != nil {
+				t.Error(err)
+				return
+			}
+			applyLTXStream(t, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: bytes.Repeat([]byte{1}, 4096)}, 1)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := store.WaitForTX(ctx, "test.db", 1); err != nil {
+			t.Fatal(err)
+		} else if got, want := store.DB("test.db").TXID(), ltx.TXID(1); got != want {
+			t.Fatalf("TXID=%s, want %s", got, want)
 		}
 	})
-	t.Run("Nil", func(t *testing.T) {
-		var info *litefs.PrimaryInfo
-		if other := info.Clone(); other != nil {
-			t.Fatal("expected nil")
+
+	t.Run("Timeout", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := store.WaitForTX(ctx, "test.db", 1); err != context.DeadlineExceeded {
+			t.Fatalf("unexpected error: %v", err)
 		}
 	})
 }
 
-// Ensure store generates a unique ID.
-func TestStore_ID(t *testing.T) {
-	store := newStore(t, newPrimaryStaticLeaser(), nil)
-	if err := store.Open(); err != nil {
-		t.Fatal(err)
-	} else if err := store.Close(); err != nil {
-		t.Fatal(err)
-	}
+func TestStore_SubscribeEvents(t *testing.T) {
+	t.Run("CreateDB", func(t *testing.T) {
+		store := newOpenStore(t, newPrimaryStaticLeaser(), nil)
+		sub := store.SubscribeEvents()
+		defer sub.Stop()
 
-	id := store.ID()
-	if id == 0 {
-		t.Fatal("expected id")
-	}
+		if event := <-sub.C(); event.Type != litefs.EventTypeInit {
+			t.Fatalf("type=%s, want %s", event.Type, litefs.EventTypeInit)
+		}
+
+		if _, f, err := store.CreateDB("test.db"); err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if event := <-sub.C(); event.Type != litefs.EventTypeCreateDB {
+			t.Fatalf("type=%s, want %s", event.Type, litefs.EventTypeCreateDB)
+		} else if event.DB != "test.db" {
+			t.Fatalf("db=%s, want %s", event.DB, "test.db")
+		}
+
+		// Ensure event can be decoded without any data.
+		buf, err := json.Marshal(litefs.Event{Type: litefs.EventTypeCreateDB, DB: "test.db"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var other litefs.Event
+		if err := json.Unmarshal(buf, &other); err != nil {
+			t.Fatal(err)
+		} else if got, want := other, (litefs.Event{Type: litefs.EventTypeCreateDB, DB: "test.db"}); got != want {
+			t.Fatalf("event=%#v, want %#v", got, want)
+		}
+	})
 }
 
 // Ensure store returns a context that is done when node loses primary status.
@@ -188,6 +2117,7 @@ func TestStore_PrimaryCtx(t *testing.T) {
 		isPrimary.Store(true)
 
 		lease := mock.Lease{
+			EpochFunc:     func() uint64 { return 1 },
 			RenewedAtFunc: func() time.Time { return time.Time{} },
 			TTLFunc:       func() time.Duration { return 10 * time.Millisecond },
 			RenewFunc: func(ctx context.Context) error {
@@ -220,10 +2150,11 @@ func TestStore_PrimaryCtx(t *testing.T) {
 		}
 
 		client := mock.Client{
-			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (litefs.Stream, error) {
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
 				return &mock.Stream{
 					ReadCloser:    io.NopCloser(&bytes.Buffer{}),
 					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
 				}, nil
 			},
 		}
@@ -253,7 +2184,7 @@ func TestStore_PrimaryCtx(t *testing.T) {
 	t.Run("InitialReplica", func(t *testing.T) {
 		leaser := litefs.NewStaticLeaser(false, "localhost", "http://localhost:20202")
 		client := mock.Client{
-			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string) (litefs.Stream, error) {
+			StreamFunc: func(ctx context.Context, rawurl string, nodeID uint64, posMap map[string]ltx.Pos, filter []string, partials []litefs.PartialSnapshot) (litefs.Stream, error) {
 				var buf bytes.Buffer
 				if err := litefs.WriteStreamFrame(&buf, &litefs.ReadyStreamFrame{}); err != nil {
 					return nil, err
@@ -261,6 +2192,7 @@ func TestStore_PrimaryCtx(t *testing.T) {
 				return &mock.Stream{
 					ReadCloser:    io.NopCloser(&buf),
 					ClusterIDFunc: func() string { return "" },
+					EpochFunc:     func() uint64 { return 0 },
 				}, nil
 			},
 		}
@@ -293,6 +2225,83 @@ func TestStore_PrimaryCtx(t *testing.T) {
 	})
 }
 
+// writeLTXStreamFrame writes an LTX stream frame for name to w. The file
+// contains a single page filled with the low byte of the max TXID.
+func writeLTXStreamFrame(tb testing.TB, w io.Writer, name string, hdr ltx.Header) {
+	tb.Helper()
+	writeLTXStreamFramePage(tb, w, name, hdr, bytes.Repeat([]byte{byte(hdr.MaxTXID)}, 4096))
+}
+
+// writeLTXStreamFramePage writes an LTX stream frame for name to w. The file
+// contains page as its only page.
+func writeLTXStreamFramePage(tb testing.TB, w io.Writer, name string, hdr ltx.Header, page []byte) {
+	tb.Helper()
+
+	hdr.Version, hdr.PageSize, hdr.Commit = ltx.Version, uint32(len(page)), 1
+	hdr.Timestamp = time.Now().UnixMilli()
+
+	chksum := ltx.ChecksumFlag | ltx.ChecksumPage(1, page)
+	if !hdr.IsSnapshot() {
+		chksum = ltx.ChecksumFlag | (hdr.PreApplyChecksum ^ chksum)
+	}
+
+	if err := litefs.WriteStreamFrame(w, &litefs.LTXStreamFrame{Name: name}); err != nil {
+		tb.Fatal(err)
+	}
+	cw := chunk.NewWriter(w)
+	enc := ltx.NewEncoder(cw)
+	if err := enc.EncodeHeader(hdr); err != nil {
+		tb.Fatal(err)
+	} else if err := enc.EncodePage(ltx.PageHeader{Pgno: 1}, page); err != nil {
+		tb.Fatal(err)
+	}
+	enc.SetPostApplyChecksum(chksum)
+	if err := enc.Close(); err != nil {
+		tb.Fatal(err)
+	} else if err := cw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// newStoreDB creates a database on store with a single committed transaction.
+func newStoreDB(tb testing.TB, store *litefs.Store, name string) *litefs.DB {
+	tb.Helper()
+
+	db, f, err := store.CreateDB(name)
+	if err != nil {
+		tb.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	applyLTXStream(tb, db, ltx.Header{MinTXID: 1, MaxTXID: 1}, map[uint32][]byte{1: newSQLitePage1()}, 1)
+	return db
+}
+
+// newSQLitePage1 returns a 4KB first page with a valid SQLite header so the
+// database is not cleared when it is reopened.
+func newSQLitePage1() []byte {
+	page1 := make([]byte, 4096)
+	copy(page1, "SQLite format 3\x00\x10\x00\x01\x01")
+	binary.BigEndian.PutUint32(page1[28:], 1) // page count
+	return page1
+}
+
+// writeInvalidLTXStreamFrame writes an LTX stream frame for name to w with a
+// body that cannot be decoded.
+func writeInvalidLTXStreamFrame(tb testing.TB, w io.Writer, name string) {
+	tb.Helper()
+
+	if err := litefs.WriteStreamFrame(w, &litefs.LTXStreamFrame{Name: name}); err != nil {
+		tb.Fatal(err)
+	}
+	cw := chunk.NewWriter(w)
+	if _, err := cw.Write(bytes.Repeat([]byte{0xFF}, 200)); err != nil {
+		tb.Fatal(err)
+	} else if err := cw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
 // newStore returns a new instance of a Store on a temporary directory.
 // This store will automatically close when the test ends.
 func newStore(tb testing.TB, leaser litefs.Leaser, client litefs.Client) *litefs.Store {
@@ -323,9 +2332,60 @@ func newOpenStore(tb testing.TB, leaser litefs.Leaser, client litefs.Client) *li
 	return store
 }
 
+// corruptFile overwrites the byte at offset in filename.
+func corruptFile(tb testing.TB, filename string, offset int64) {
+	tb.Helper()
+
+	f, err := os.OpenFile(filename, os.O_RDWR, 0o666)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.WriteAt([]byte{0xFF}, offset); err != nil {
+		tb.Fatal(err)
+	}
+}
+
 func newStoreFromFixture(tb testing.TB, leaser litefs.Leaser, client litefs.Client, path string) *litefs.Store {
 	tb.Helper()
 	store := newStore(tb, leaser, client)
 	testingutil.MustCopyDir(tb, path, store.Path())
 	return store
 }
+
+// metricValue returns the value of the counter or gauge gathered from g under
+// name & the given label name/value pairs. Returns zero if no value has been
+// recorded yet.
+func metricValue(tb testing.TB, g prometheus.Gatherer, name string, labelPairs ...string) float64 {
+	tb.Helper()
+
+	mfs, err := g.Gather()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+
+	METRICS:
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			for i := 0; i+1 < len(labelPairs); i += 2 {
+				if labels[labelPairs[i]] != labelPairs[i+1] {
+					continue METRICS
+				}
+			}
+			if c := m.GetCounter(); c != nil {
+				return c.GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+		return 0
+	}
+	return 0
+}